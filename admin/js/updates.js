@@ -8,7 +8,7 @@
             // Форма загрузки удалена - загрузка только через FTP
             // Оставляем функции загрузки в коде для совместимости, но они не вызываются
             this.isInitialized = true;
-            await this.loadUpdates();
+            await Promise.all([this.loadUpdates(), this.loadIngestStatus()]);
         },
 
         getToken() {
@@ -156,6 +156,107 @@
             `;
         },
 
+        // Состояние приема из папки входящих: ожидающие файлы, итоги за 30 дней, последние записи журнала
+        async loadIngestStatus() {
+            const container = document.getElementById('release-ingest-status');
+            if (!container) return;
+            container.innerHTML = '<p>Загрузка...</p>';
+
+            try {
+                const data = await fetchData('/api/v1/admin/release-ingest/status');
+                container.innerHTML = this.renderIngestStatus(data.data || {});
+            } catch (err) {
+                console.error('❌ [loadIngestStatus] Ошибка загрузки состояния приема:', err);
+                container.innerHTML = `<p style="color:red;">Ошибка загрузки: ${this.escape(this.formatErrorMessage(err, 'list'))}</p>`;
+            }
+        },
+
+        async scanInbox() {
+            try {
+                await fetchData('/api/v1/admin/release-ingest/scan', { method: 'POST' });
+                window.ui?.showMessage ? window.ui.showMessage('Проверка папки запущена', 'success') : alert('Проверка папки запущена');
+                // Обработка идет в фоне - показываем результат через несколько секунд
+                setTimeout(() => {
+                    this.loadIngestStatus();
+                    this.loadUpdates();
+                }, 3000);
+            } catch (err) {
+                console.error('Ошибка запуска проверки папки:', err);
+                const message = this.formatErrorMessage(err, 'scan');
+                window.ui?.showMessage ? window.ui.showMessage(message, 'error') : alert(message);
+            }
+        },
+
+        renderIngestStatus(status) {
+            if (!status.enabled) {
+                return `<p style="color: #666;">Прием из FTP выключен (<code>RELEASE_INGEST_ENABLED=false</code>). Обновления можно добавить командой <code>import-releases</code>.</p>`;
+            }
+
+            const counts = status.counts || {};
+            const lastScan = status.lastScanAt ? new Date(status.lastScanAt).toLocaleString('ru-RU') : 'еще не выполнялась';
+            const summary = `
+                <p style="margin: 10px 0;">
+                    Папка: <code>${this.escape(status.inboxDir)}</code> · карантин: <code>${this.escape(status.quarantineDir)}</code><br>
+                    Последняя проверка: ${lastScan} ·
+                    за 30 дней принято <strong>${counts.imported || 0}</strong>,
+                    повторов <strong>${counts.duplicate || 0}</strong>,
+                    отклонено <strong style="color: ${counts.rejected ? '#d9534f' : 'inherit'};">${counts.rejected || 0}</strong>
+                </p>
+                ${status.lastError ? `<p style="color: #d9534f;">Ошибка: ${this.escape(status.lastError)}</p>` : ''}
+            `;
+
+            const pending = status.pending || [];
+            const pendingRows = pending.map(f => `
+                <tr>
+                    <td>${this.escape(f.path)}</td>
+                    <td>${this.formatSize(f.size)}</td>
+                    <td>${new Date(f.modifiedAt).toLocaleString('ru-RU')}</td>
+                    <td>${f.settled ? 'ждет обработки' : 'загружается'}</td>
+                </tr>
+            `).join('');
+            const pendingTable = pending.length === 0 ? '<p style="color: #999;">Во входящих нет файлов</p>' : `
+                <div class="table-responsive">
+                    <table class="data-table">
+                        <thead>
+                            <tr><th>Файл во входящих</th><th>Размер</th><th>Изменен</th><th>Состояние</th></tr>
+                        </thead>
+                        <tbody>${pendingRows}</tbody>
+                    </table>
+                </div>
+            `;
+
+            const statusLabels = { imported: 'принят', duplicate: 'повтор', rejected: 'отклонен' };
+            const recent = status.recent || [];
+            const recentRows = recent.map(r => `
+                <tr>
+                    <td>${new Date(r.createdAt).toLocaleString('ru-RU')}</td>
+                    <td>${this.escape(r.fileName)}</td>
+                    <td>${this.escape(r.platform)}${r.type ? ' / ' + this.escape(r.type) : ''}</td>
+                    <td>${this.escape(r.version)}</td>
+                    <td style="color: ${r.status === 'rejected' ? '#d9534f' : 'inherit'};">${statusLabels[r.status] || this.escape(r.status)}</td>
+                    <td>${r.error ? this.escape(r.error) : '-'}</td>
+                </tr>
+            `).join('');
+            const recentTable = recent.length === 0 ? '' : `
+                <div class="table-responsive" style="margin-top: 15px;">
+                    <table class="data-table">
+                        <thead>
+                            <tr><th>Время</th><th>Файл</th><th>Платформа</th><th>Версия</th><th>Результат</th><th>Причина</th></tr>
+                        </thead>
+                        <tbody>${recentRows}</tbody>
+                    </table>
+                </div>
+            `;
+
+            return summary + pendingTable + recentTable;
+        },
+
+        escape(value) {
+            const div = document.createElement('div');
+            div.textContent = value == null ? '' : String(value);
+            return div.innerHTML;
+        },
+
         formatSize(bytes) {
             if (!bytes || bytes <= 0) return '0 B';
             const units = ['B', 'KB', 'MB', 'GB'];
@@ -340,6 +441,9 @@
             if (context === 'delete') {
                 return `Ошибка удаления: ${rawMessage}`;
             }
+            if (context === 'scan') {
+                return `Ошибка проверки папки: ${rawMessage}`;
+            }
             return rawMessage;
         }
     };
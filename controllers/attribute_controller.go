@@ -0,0 +1,298 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"gorm.io/gorm"
+)
+
+// AttributeController управляет схемами характеристик категорий
+type AttributeController struct{}
+
+// respondProductAttributesError отвечает на ошибку проверки характеристик товара
+func respondProductAttributesError(c *gin.Context, err error) {
+	var validationErr *services.AttributeValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid attributes",
+			"details": validationErr.Problems,
+		})
+		return
+	}
+	log.Printf("❌ Ошибка проверки характеристик: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": "Failed to validate attributes",
+	})
+}
+
+// GetCategoryAttributes возвращает характеристики категории вместе с унаследованными
+func (ac *AttributeController) GetCategoryAttributes(c *gin.Context) {
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid category ID",
+		))
+		return
+	}
+
+	var category models.Category
+	if err := database.DB.Select("id").First(&category, "id = ?", categoryID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+				models.ErrNotFound,
+				"Category not found",
+			))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Database error",
+			))
+		}
+		return
+	}
+
+	schema, err := services.GetCategoryAttributeSchema(database.DB, categoryID)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to fetch attributes",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(schema))
+}
+
+// bindAttributeRequest читает и проверяет описание характеристики
+func bindAttributeRequest(c *gin.Context) (models.CategoryAttributeRequest, bool) {
+	var req models.CategoryAttributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return req, false
+	}
+	if err := services.ValidateAttributeDefinition(&req); err != nil {
+		var validationErr *services.AttributeValidationError
+		errors.As(err, &validationErr)
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid attribute",
+			validationErr.Problems,
+		))
+		return req, false
+	}
+	return req, true
+}
+
+// attributeCodeTaken проверяет, занят ли код в категории (кроме exceptID)
+func attributeCodeTaken(categoryID uuid.UUID, code string, exceptID uuid.UUID) (bool, error) {
+	var count int64
+	err := database.DB.Model(&models.CategoryAttribute{}).
+		Where("category_id = ? AND code = ? AND id <> ?", categoryID, code, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CreateCategoryAttribute добавляет характеристику в категорию (только для админов)
+func (ac *AttributeController) CreateCategoryAttribute(c *gin.Context) {
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid category ID",
+		))
+		return
+	}
+
+	req, ok := bindAttributeRequest(c)
+	if !ok {
+		return
+	}
+
+	var category models.Category
+	if err := database.DB.Select("id").First(&category, "id = ?", categoryID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+			models.ErrNotFound,
+			"Category not found",
+		))
+		return
+	}
+
+	if taken, err := attributeCodeTaken(categoryID, req.Code, uuid.Nil); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Database error",
+		))
+		return
+	} else if taken {
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+			models.ErrConflict,
+			"Attribute with this code already exists in the category",
+		))
+		return
+	}
+
+	attribute := models.CategoryAttribute{
+		CategoryID: categoryID,
+		Code:       req.Code,
+		Name:       req.Name,
+		Type:       req.Type,
+		Options:    req.Options,
+		Unit:       req.Unit,
+		Required:   req.Required,
+		Filterable: req.Filterable,
+		SortOrder:  req.SortOrder,
+	}
+	if err := database.DB.Create(&attribute).Error; err != nil {
+		log.Printf("❌ Ошибка создания характеристики: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to create attribute",
+		))
+		return
+	}
+
+	log.Printf("✅ Характеристика %s добавлена в категорию %s", attribute.Code, categoryID)
+	c.JSON(http.StatusCreated, models.SuccessResponse(attribute, "Attribute created successfully"))
+}
+
+// findCategoryAttribute загружает характеристику категории из параметров пути
+func findCategoryAttribute(c *gin.Context) (*models.CategoryAttribute, bool) {
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid category ID",
+		))
+		return nil, false
+	}
+	attributeID, err := uuid.Parse(c.Param("attributeId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid attribute ID",
+		))
+		return nil, false
+	}
+
+	var attribute models.CategoryAttribute
+	if err := database.DB.First(&attribute, "id = ? AND category_id = ?", attributeID, categoryID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+				models.ErrNotFound,
+				"Attribute not found",
+			))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Database error",
+			))
+		}
+		return nil, false
+	}
+	return &attribute, true
+}
+
+// UpdateCategoryAttribute изменяет характеристику (только для админов).
+// Уже сохраненные значения товаров не перепроверяются - только при следующем сохранении товара.
+func (ac *AttributeController) UpdateCategoryAttribute(c *gin.Context) {
+	attribute, ok := findCategoryAttribute(c)
+	if !ok {
+		return
+	}
+	req, ok := bindAttributeRequest(c)
+	if !ok {
+		return
+	}
+
+	if taken, err := attributeCodeTaken(attribute.CategoryID, req.Code, attribute.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Database error",
+		))
+		return
+	} else if taken {
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+			models.ErrConflict,
+			"Attribute with this code already exists in the category",
+		))
+		return
+	}
+
+	oldCode := attribute.Code
+	attribute.Code = req.Code
+	attribute.Name = req.Name
+	attribute.Type = req.Type
+	attribute.Options = req.Options
+	attribute.Unit = req.Unit
+	attribute.Required = req.Required
+	attribute.Filterable = req.Filterable
+	attribute.SortOrder = req.SortOrder
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(attribute).Error; err != nil {
+			return err
+		}
+		if oldCode == attribute.Code {
+			return nil
+		}
+		// Переименовываем ключ в характеристиках товаров категории и подкатегорий
+		// (кроме подкатегорий со своей характеристикой с тем же кодом)
+		return tx.Model(&models.Product{}).
+			Where(services.CategorySubtreeCondition("category_id"), attribute.CategoryID).
+			Where("category_id NOT IN (SELECT category_id FROM category_attributes WHERE code = ?)", oldCode).
+			Where("jsonb_exists(attributes, ?)", oldCode).
+			Update("attributes", gorm.Expr("(attributes - ?::text) || jsonb_build_object(?::text, attributes -> ?::text)", oldCode, attribute.Code, oldCode)).Error
+	})
+	if err != nil {
+		log.Printf("❌ Ошибка обновления характеристики: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to update attribute",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(attribute, "Attribute updated successfully"))
+}
+
+// DeleteCategoryAttribute удаляет характеристику и ее значения у товаров (только для админов)
+func (ac *AttributeController) DeleteCategoryAttribute(c *gin.Context) {
+	attribute, ok := findCategoryAttribute(c)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(attribute).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Product{}).
+			Where(services.CategorySubtreeCondition("category_id"), attribute.CategoryID).
+			Where("category_id NOT IN (SELECT category_id FROM category_attributes WHERE code = ?)", attribute.Code).
+			Where("jsonb_exists(attributes, ?)", attribute.Code).
+			Update("attributes", gorm.Expr("attributes - ?::text", attribute.Code)).Error
+	})
+	if err != nil {
+		log.Printf("❌ Ошибка удаления характеристики: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to delete attribute",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(nil, "Attribute deleted successfully"))
+}
@@ -1,721 +1,1246 @@
-package controllers
-
-import (
-	"fmt"
-	"io"
-	"log"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
-
-	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
-	"github.com/mm-api/mm-api/database"
-	"github.com/mm-api/mm-api/models"
-	"github.com/mm-api/mm-api/services"
-	"github.com/mm-api/mm-api/utils"
-	"gorm.io/gorm"
-)
-
-// AuthController обрабатывает запросы аутентификации
-type AuthController struct{}
-
-// Register регистрирует нового пользователя
-func (ac *AuthController) Register(c *gin.Context) {
-	var req models.UserRegisterRequest
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid request data",
-			err.Error(),
-		))
-		return
-	}
-
-    // Проверяем, существует ли пользователь с таким телефоном
-    var existingUser models.User
-    if err := database.DB.Where("phone = ?", req.Phone).First(&existingUser).Error; err == nil {
-        // Если это гость — конвертируем в обычного пользователя
-        if existingUser.IsGuest {
-            // Проверяем email, если передан: не занят ли другим
-            if req.Email != "" {
-                var emailOwner models.User
-                if err := database.DB.Where("email = ?", req.Email).First(&emailOwner).Error; err == nil && emailOwner.ID != existingUser.ID {
-                    c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
-                        models.ErrUserAlreadyExists,
-                        "User with this email already exists",
-                    ))
-                    return
-                }
-                existingUser.Email = req.Email
-            }
-
-            // Обновляем имя, пароль, статус гостя
-            existingUser.Name = req.Name
-            if err := existingUser.HashPassword(req.Password); err != nil {
-                c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-                    models.ErrInternalError,
-                    "Failed to hash password",
-                ))
-                return
-            }
-            existingUser.IsGuest = false
-            existingUser.IsActive = true
-
-            if err := database.DB.Save(&existingUser).Error; err != nil {
-                c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-                    models.ErrInternalError,
-                    "Failed to update user",
-                ))
-                return
-            }
-
-            // Убедимся, что у пользователя роль user
-            var userRole models.Role
-            if err := database.DB.Where("name = ?", "user").First(&userRole).Error; err == nil {
-                existingUser.RoleID = &userRole.ID
-                _ = database.DB.Save(&existingUser).Error
-                database.DB.Preload("Role").First(&existingUser, existingUser.ID)
-            }
-
-            // Генерируем JWT токен
-            roleName := "user"
-            if existingUser.Role != nil {
-                roleName = existingUser.Role.Name
-            }
-            token, err := utils.GenerateJWT(existingUser.ID, existingUser.Email, roleName)
-            if err != nil {
-                c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-                    models.ErrInternalError,
-                    "Failed to generate token",
-                ))
-                return
-            }
-
-            authResponse := models.AuthResponse{
-                User:         existingUser.ToResponse(),
-                Token:        token,
-                RefreshToken: token,
-            }
-
-            c.JSON(http.StatusOK, models.SuccessResponse(
-                authResponse,
-                "Guest account upgraded and registered successfully",
-            ))
-            return
-        }
-
-        // Если это не гость — конфликт
-        c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
-            models.ErrUserAlreadyExists,
-            "User with this phone already exists",
-        ))
-        return
-    }
-
-	// Получаем роль "user" по умолчанию
-	var defaultRole models.Role
-	if err := database.DB.Where("name = ?", "user").First(&defaultRole).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to find default user role",
-		))
-		return
-	}
-
-    // Создаем нового пользователя
-	user := models.User{
-		Name:     req.Name,
-        Email:    req.Email, // может быть пустым
-		Phone:    req.Phone,
-		IsActive: true,
-		RoleID:   &defaultRole.ID, // Присваиваем роль пользователя по умолчанию
-	}
-
-	// Хешируем пароль
-	if err := user.HashPassword(req.Password); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to hash password",
-		))
-		return
-	}
-
-    // Если email не указан, генерируем временный
-    if user.Email == "" {
-        user.Email = "user_" + uuid.New().String() + "@temp.local"
-    } else {
-        // Проверяем уникальность email
-        var emailOwner models.User
-        if err := database.DB.Where("email = ?", user.Email).First(&emailOwner).Error; err == nil {
-            c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
-                models.ErrUserAlreadyExists,
-                "User with this email already exists",
-            ))
-            return
-        }
-    }
-
-    // Сохраняем в базу данных
-	if err := database.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to create user",
-		))
-		return
-	}
-
-	// Получаем роль пользователя
-	roleName := "user" // По умолчанию
-	if user.Role != nil {
-		roleName = user.Role.Name
-	}
-
-	// Генерируем JWT токен
-	token, err := utils.GenerateJWT(user.ID, user.Email, roleName)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to generate token",
-		))
-		return
-	}
-
-	// Создаем настройки по умолчанию для пользователя
-	settings := models.UserSettings{
-		UserID:               user.ID,
-		Language:             "ru",
-		Theme:                "system",
-		NotificationsEnabled: true,
-		EmailNotifications:   true,
-		PushNotifications:    true,
-	}
-	database.DB.Create(&settings)
-
-	authResponse := models.AuthResponse{
-		User:         user.ToResponse(),
-		Token:        token,
-		RefreshToken: token, // TODO: Реализовать отдельные refresh токены
-	}
-
-	c.JSON(http.StatusCreated, models.SuccessResponse(
-		authResponse,
-		"User registered successfully",
-	))
-}
-
-// Login выполняет вход пользователя
-func (ac *AuthController) Login(c *gin.Context) {
-	var req models.UserLoginRequest
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid request data",
-			err.Error(),
-		))
-		return
-	}
-
-	// Ищем пользователя по телефону
-	var user models.User
-	if err := database.DB.Preload("Addresses").Preload("Role").Where("phone = ? AND is_active = ?", req.Phone, true).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
-				models.ErrInvalidCredentials,
-				"Invalid phone or password",
-			))
-		} else {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-				models.ErrInternalError,
-				"Database error",
-			))
-		}
-		return
-	}
-
-	// Проверяем пароль
-	if !user.CheckPassword(req.Password) {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
-			models.ErrInvalidCredentials,
-			"Invalid phone or password",
-		))
-		return
-	}
-
-	// Получаем роль пользователя
-	roleName := "user" // По умолчанию
-	if user.Role != nil {
-		roleName = user.Role.Name
-	}
-
-	// Генерируем JWT токен
-	token, err := utils.GenerateJWT(user.ID, user.Email, roleName)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to generate token",
-		))
-		return
-	}
-
-	authResponse := models.AuthResponse{
-		User:         user.ToResponse(),
-		Token:        token,
-		RefreshToken: token, // TODO: Реализовать отдельные refresh токены
-	}
-
-	// Отправляем push-уведомления о непрочитанных уведомлениях при входе
-	go sendUnreadNotificationsPush(user.ID)
-
-	c.JSON(http.StatusOK, models.SuccessResponse(
-		authResponse,
-		"Login successful",
-	))
-}
-
-// CreateGuestToken создает токен для гостевого пользователя или входит в существующий аккаунт
-func (ac *AuthController) CreateGuestToken(c *gin.Context) {
-	var req struct {
-		Name  string `json:"name" binding:"required"`
-		Phone string `json:"phone" binding:"required"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid request data",
-			err.Error(),
-		))
-		return
-	}
-
-	// Получаем роль "user" для гостей
-	var userRole models.Role
-	if err := database.DB.Where("name = ?", "user").First(&userRole).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to find user role",
-		))
-		return
-	}
-
-	// Ищем пользователя по номеру телефона
-	var user models.User
-	err := database.DB.Where("phone = ?", req.Phone).First(&user).Error
-	if err == gorm.ErrRecordNotFound {
-		// Создаем нового пользователя автоматически
-		user = models.User{
-			Name:     req.Name,
-			Email:    "guest_" + uuid.New().String() + "@temp.local", // Временный email
-			Phone:    req.Phone,
-			Password: "auto_password_" + uuid.New().String(), // Автоматический пароль
-			IsGuest:  true,
-			IsActive: true,
-			RoleID:   &userRole.ID,
-		}
-		if err := database.DB.Create(&user).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-				models.ErrInternalError,
-				"Failed to create user",
-			))
-			return
-		}
-	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Database error",
-		))
-		return
-	} else {
-		// Пользователь уже существует - обновляем имя если нужно
-		if user.Name != req.Name {
-			user.Name = req.Name
-			if err := database.DB.Save(&user).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-					models.ErrInternalError,
-					"Failed to update user",
-				))
-				return
-			}
-		}
-	}
-
-	// Загружаем роль для ответа
-	database.DB.Preload("Role").First(&user, user.ID)
-
-	// Генерируем JWT токен
-	token, err := utils.GenerateJWT(user.ID, user.Email, "user")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to generate token",
-		))
-		return
-	}
-
-	authResponse := models.AuthResponse{
-		User:         user.ToResponse(),
-		Token:        token,
-		RefreshToken: token, // TODO: Реализовать отдельные refresh токены
-	}
-
-	// Определяем сообщение в зависимости от того, новый это пользователь или существующий
-	message := "User logged in successfully"
-	if err == gorm.ErrRecordNotFound {
-		message = "User created and logged in successfully"
-	}
-
-	c.JSON(http.StatusOK, models.SuccessResponse(
-		authResponse,
-		message,
-	))
-}
-
-// Profile возвращает профиль текущего пользователя
-func (ac *AuthController) Profile(c *gin.Context) {
-	user, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
-			models.ErrAuthRequired,
-			"User not found",
-		))
-		return
-	}
-
-	userModel := user.(models.User)
-
-	// Загружаем связанные данные
-	database.DB.Preload("Addresses").First(&userModel, userModel.ID)
-
-	c.JSON(http.StatusOK, models.SuccessResponse(userModel.ToResponse()))
-}
-
-// UpdateProfile обновляет профиль пользователя
-func (ac *AuthController) UpdateProfile(c *gin.Context) {
-	user, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
-			models.ErrAuthRequired,
-			"User not found",
-		))
-		return
-	}
-
-	var req models.UserUpdateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid request data",
-			err.Error(),
-		))
-		return
-	}
-
-	userModel := user.(models.User)
-
-	// Обновляем только переданные поля
-	if req.Name != nil {
-		userModel.Name = *req.Name
-	}
-
-	if req.Phone != nil {
-		userModel.Phone = *req.Phone
-	}
-
-	if req.DateOfBirth != nil {
-		userModel.DateOfBirth = req.DateOfBirth
-	}
-
-	if req.Gender != nil {
-		userModel.Gender = *req.Gender
-	}
-
-	if err := database.DB.Save(&userModel).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to update profile",
-		))
-		return
-	}
-
-	// Загружаем обновленные данные
-	database.DB.Preload("Addresses").First(&userModel, userModel.ID)
-
-	c.JSON(http.StatusOK, models.SuccessResponse(
-		userModel.ToResponse(),
-		"Profile updated successfully",
-	))
-}
-
-// UploadAvatar загружает аватар пользователя
-func (ac *AuthController) UploadAvatar(c *gin.Context) {
-	// Получаем текущего пользователя
-	user, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
-			models.ErrAuthRequired,
-			"Пользователь не авторизован",
-		))
-		return
-	}
-
-	userModel := user.(models.User)
-
-	// Получаем файл из формы
-	file, header, err := c.Request.FormFile("avatar")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Файл не предоставлен",
-			err.Error(),
-		))
-		return
-	}
-	defer file.Close()
-
-	// Используем UploadController для загрузки
-	uploadController := &UploadController{}
-	
-	// Создаем временный файл для чтения содержимого
-	tempFile, err := os.CreateTemp("", "avatar-*.tmp")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Ошибка создания временного файла",
-		))
-		return
-	}
-	defer os.Remove(tempFile.Name())
-	defer tempFile.Close()
-
-	// Копируем содержимое файла
-	if _, err := io.Copy(tempFile, file); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Ошибка сохранения файла",
-		))
-		return
-	}
-
-	// Перемещаем указатель в начало файла
-	tempFile.Seek(0, 0)
-
-	// Загружаем изображение
-	folder := "avatars"
-	uploadDir := fmt.Sprintf("images/%s", folder)
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Ошибка создания папки",
-		))
-		return
-	}
-
-	// Генерируем уникальное имя файла
-	ext := filepath.Ext(header.Filename)
-	if ext == "" {
-		ext = ".jpg"
-	}
-	ext = strings.ToLower(ext)
-	filename := fmt.Sprintf("%s%s", uuid.New().String(), ext)
-	filePath := filepath.Join(uploadDir, filename)
-
-	// Сохраняем файл через метод UploadController
-	contentType := header.Header.Get("Content-Type")
-	finalFilename, _, err := uploadController.CompressAndSaveImage(tempFile, filePath, ext, contentType)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Ошибка сохранения изображения",
-			err.Error(),
-		))
-		return
-	}
-
-	// Обновляем filename если формат изменился
-	if finalFilename != filename {
-		filename = finalFilename
-	}
-
-	// Формируем URL
-	avatarURL := uploadController.GetImageURL(filename, folder)
-
-	// Удаляем старый аватар, если он есть
-	if userModel.Avatar != "" {
-		oldFilename := filepath.Base(userModel.Avatar)
-		oldPath := filepath.Join(uploadDir, oldFilename)
-		if _, err := os.Stat(oldPath); err == nil {
-			os.Remove(oldPath)
-		}
-	}
-
-	// Обновляем аватар пользователя
-	userModel.Avatar = avatarURL
-	if err := database.DB.Save(&userModel).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Ошибка обновления аватара",
-		))
-		return
-	}
-
-	// Загружаем обновленные данные
-	database.DB.Preload("Addresses").Preload("Role").First(&userModel, userModel.ID)
-
-	c.JSON(http.StatusOK, models.SuccessResponse(
-		userModel.ToResponse(),
-		"Аватар успешно загружен",
-	))
-}
-
-// RefreshToken обновляет JWT токен
-func (ac *AuthController) RefreshToken(c *gin.Context) {
-	type RefreshRequest struct {
-		RefreshToken string `json:"refreshToken" binding:"required"`
-	}
-
-	var req RefreshRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid request data",
-			err.Error(),
-		))
-		return
-	}
-
-	newToken, err := utils.RefreshJWT(req.RefreshToken)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
-			models.ErrAuthInvalid,
-			"Failed to refresh token",
-		))
-		return
-	}
-
-	c.JSON(http.StatusOK, models.SuccessResponse(map[string]string{
-		"token":        newToken,
-		"refreshToken": newToken, // TODO: Генерировать отдельный refresh токен
-	}))
-}
-
-// ForgotPassword инициирует восстановление пароля по телефону
-func (ac *AuthController) ForgotPassword(c *gin.Context) {
-	var req models.UserForgotPasswordRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid request data",
-			err.Error(),
-		))
-		return
-	}
-
-	// Заглушка: в проде отправляем SMS код или ссылку
-	// Здесь подтверждаем, что телефон существует (но не раскрываем факт отсутствия)
-	var user models.User
-	_ = database.DB.Where("phone = ?", req.Phone).First(&user)
-
-	c.JSON(http.StatusOK, models.SuccessResponse(map[string]string{
-		"status":  "pending",
-		"message": "If this phone exists, a reset code was sent",
-	}))
-}
-
-// DeleteAccount удаляет аккаунт текущего пользователя
-func (ac *AuthController) DeleteAccount(c *gin.Context) {
-	user, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
-			models.ErrAuthRequired,
-			"User not found",
-		))
-		return
-	}
-
-	userModel := user.(models.User)
-
-	// Проверяем, что пользователь активен
-	if !userModel.IsActive {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Account is already deleted",
-		))
-		return
-	}
-
-	// Мягкое удаление - деактивируем аккаунт
-	userModel.IsActive = false
-	if err := database.DB.Save(&userModel).Error; err != nil {
-		log.Printf("❌ Ошибка удаления аккаунта пользователя %s: %v", userModel.ID, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to delete account",
-		))
-		return
-	}
-
-	log.Printf("✅ Аккаунт пользователя %s успешно удален (деактивирован)", userModel.ID)
-
-	c.JSON(http.StatusOK, models.SuccessResponse(
-		nil,
-		"Account deleted successfully",
-	))
-}
-
-// sendUnreadNotificationsPush отправляет push-уведомления о непрочитанных уведомлениях при входе
-func sendUnreadNotificationsPush(userID uuid.UUID) {
-	// Получаем все непрочитанные уведомления пользователя
-	var unreadNotifications []models.Notification
-	if err := database.DB.Where("user_id = ? AND is_read = ?", userID, false).
-		Order("timestamp DESC").
-		Limit(5). // Отправляем только последние 5 непрочитанных
-		Find(&unreadNotifications).Error; err != nil {
-		log.Printf("⚠️ Ошибка получения непрочитанных уведомлений для пользователя %s: %v", userID, err)
-		return
-	}
-
-	if len(unreadNotifications) == 0 {
-		return // Нет непрочитанных уведомлений
-	}
-
-	// Получаем все активные токены устройств пользователя
-	var deviceTokens []models.DeviceToken
-	if err := database.DB.Where("user_id = ? AND is_active = ?", userID, true).Find(&deviceTokens).Error; err != nil {
-		log.Printf("⚠️ Ошибка получения токенов устройств для пользователя %s: %v", userID, err)
-		return
-	}
-
-	if len(deviceTokens) == 0 {
-		return // Нет активных токенов
-	}
-
-	// Группируем токены по платформам
-	var fcmTokens []string
-	for _, token := range deviceTokens {
-		if token.Platform == "android" || token.Platform == "web" || token.Platform == "ios" {
-			fcmTokens = append(fcmTokens, token.Token)
-		}
-	}
-
-	if len(fcmTokens) == 0 {
-		return
-	}
-
-	// Отправляем push-уведомление о непрочитанных уведомлениях
-	fcmService := services.GetFCMService()
-	if fcmService != nil {
-		title := "У вас есть непрочитанные уведомления"
-		body := fmt.Sprintf("У вас %d непрочитанных уведомлений", len(unreadNotifications))
-		actionURL := "/admin#dashboard" // Переход на дашборд с уведомлениями
-
-		if err := fcmService.SendPushNotificationToMultiple(fcmTokens, title, body, actionURL); err != nil {
-			log.Printf("❌ Ошибка отправки push-уведомления о непрочитанных уведомлениях: %v", err)
-		} else {
-			log.Printf("✅ Push-уведомление о непрочитанных уведомлениях отправлено пользователю %s", userID)
-		}
-	}
-}
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"github.com/mm-api/mm-api/storage"
+	"github.com/mm-api/mm-api/utils"
+	"gorm.io/gorm"
+)
+
+// AuthController обрабатывает запросы аутентификации
+type AuthController struct{}
+
+// Register регистрирует нового пользователя
+func (ac *AuthController) Register(c *gin.Context) {
+	var req models.UserRegisterRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+    // Телефон уже занят зарегистрированным аккаунтом
+    var registeredCount int64
+    database.DB.Model(&models.User{}).Where("phone IN ? AND is_guest = ?", phoneVariants(req.Phone), false).Count(&registeredCount)
+    if registeredCount > 0 {
+        c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+            models.ErrUserAlreadyExists,
+            "User with this phone already exists",
+        ))
+        return
+    }
+
+    // Гостевой аккаунт с этим телефоном становится зарегистрированным (второй пользователь
+    // с тем же телефоном не создается). Гостевые заказы доступны только владельцу телефона,
+    // поэтому при настроенных SMS нужен код (purpose=register).
+    var guest models.User
+    if err := database.DB.Where("phone IN ? AND is_guest = ? AND is_active = ?", phoneVariants(req.Phone), true, true).
+        Order("created_at DESC").First(&guest).Error; err == nil {
+        ac.registerGuest(c, guest, req)
+        return
+    }
+
+	// Получаем роль "user" по умолчанию
+	var defaultRole models.Role
+	if err := database.DB.Where("name = ?", "user").First(&defaultRole).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to find default user role",
+		))
+		return
+	}
+
+    // Создаем нового пользователя
+	user := models.User{
+		Name:     req.Name,
+        Email:    req.Email, // может быть пустым
+		Phone:    req.Phone,
+		IsActive: true,
+		RoleID:   &defaultRole.ID, // Присваиваем роль пользователя по умолчанию
+	}
+
+	// Хешируем пароль
+	if err := user.HashPassword(req.Password); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to hash password",
+		))
+		return
+	}
+
+    // Если email не указан, генерируем временный
+    if user.Email == "" {
+        user.Email = "user_" + uuid.New().String() + "@temp.local"
+    } else {
+        // Проверяем уникальность email
+        var emailOwner models.User
+        if err := database.DB.Where("email = ?", user.Email).First(&emailOwner).Error; err == nil {
+            c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+                models.ErrUserAlreadyExists,
+                "User with this email already exists",
+            ))
+            return
+        }
+    }
+
+    // Сохраняем в базу данных
+	if err := database.DB.Create(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to create user",
+		))
+		return
+	}
+
+	// Получаем роль пользователя
+	roleName := "user" // По умолчанию
+	if user.Role != nil {
+		roleName = user.Role.Name
+	}
+
+	// Генерируем JWT токен
+	token, err := utils.GenerateJWT(user.ID, user.Email, roleName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to generate token",
+		))
+		return
+	}
+
+	// Создаем настройки по умолчанию для пользователя
+	settings := models.UserSettings{
+		UserID:               user.ID,
+		Language:             "ru",
+		Theme:                "system",
+		NotificationsEnabled: true,
+		EmailNotifications:   true,
+		PushNotifications:    true,
+	}
+	database.DB.Create(&settings)
+
+	authResponse := models.AuthResponse{
+		User:         user.ToResponse(),
+		Token:        token,
+		RefreshToken: token, // TODO: Реализовать отдельные refresh токены
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse(
+		authResponse,
+		"User registered successfully",
+	))
+}
+
+// Login выполняет вход пользователя
+func (ac *AuthController) Login(c *gin.Context) {
+	var req models.UserLoginRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	// Ищем пользователя по телефону
+	var user models.User
+	if err := database.DB.Preload("Addresses").Preload("Role").Where("phone IN ? AND is_active = ? AND is_guest = ?", phoneVariants(req.Phone), true, false).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+				models.ErrInvalidCredentials,
+				"Invalid phone or password",
+			))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Database error",
+			))
+		}
+		return
+	}
+
+	// Проверяем пароль
+	if !user.CheckPassword(req.Password) {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrInvalidCredentials,
+			"Invalid phone or password",
+		))
+		return
+	}
+
+	// Получаем роль пользователя
+	roleName := "user" // По умолчанию
+	if user.Role != nil {
+		roleName = user.Role.Name
+	}
+
+	// Генерируем JWT токен
+	token, err := utils.GenerateJWT(user.ID, user.Email, roleName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to generate token",
+		))
+		return
+	}
+
+	authResponse := models.AuthResponse{
+		User:         user.ToResponse(),
+		Token:        token,
+		RefreshToken: token, // TODO: Реализовать отдельные refresh токены
+	}
+
+	// Ставим в очередь push-уведомление о непрочитанных уведомлениях при входе
+	enqueueUnreadNotificationsPush(user.ID)
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		authResponse,
+		"Login successful",
+	))
+}
+
+// CreateGuestToken создает токен для гостевого пользователя.
+// Гостевой аккаунт отделен от зарегистрированных: если телефон принадлежит
+// зарегистрированному пользователю, создается отдельный гость, а вход в аккаунт
+// возможен только по коду из SMS (LoginWithPhoneCode).
+func (ac *AuthController) CreateGuestToken(c *gin.Context) {
+	var req struct {
+		Name  string `json:"name" binding:"required"`
+		Phone string `json:"phone" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	// Получаем роль "user" для гостей
+	var userRole models.Role
+	if err := database.DB.Where("name = ?", "user").First(&userRole).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to find user role",
+		))
+		return
+	}
+
+	// Ищем только активного гостя с этим номером телефона
+	var user models.User
+	err := database.DB.Where("phone IN ? AND is_guest = ? AND is_active = ?", phoneVariants(req.Phone), true, true).First(&user).Error
+	if err == gorm.ErrRecordNotFound {
+		// Создаем нового пользователя автоматически
+		user = models.User{
+			Name:     req.Name,
+			Email:    "guest_" + uuid.New().String() + "@temp.local", // Временный email
+			Phone:    req.Phone,
+			Password: "auto_password_" + uuid.New().String(), // Автоматический пароль
+			IsGuest:  true,
+			IsActive: true,
+			RoleID:   &userRole.ID,
+		}
+		if err := database.DB.Create(&user).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Failed to create user",
+			))
+			return
+		}
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Database error",
+		))
+		return
+	} else {
+		// Пользователь уже существует - обновляем имя если нужно
+		if user.Name != req.Name {
+			user.Name = req.Name
+			if err := database.DB.Save(&user).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+					models.ErrInternalError,
+					"Failed to update user",
+				))
+				return
+			}
+		}
+	}
+
+	// Загружаем роль для ответа
+	database.DB.Preload("Role").First(&user, user.ID)
+
+	// Генерируем JWT токен
+	token, err := utils.GenerateJWT(user.ID, user.Email, "user")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to generate token",
+		))
+		return
+	}
+
+	authResponse := models.AuthResponse{
+		User:          user.ToResponse(),
+		Token:         token,
+		RefreshToken:  token, // TODO: Реализовать отдельные refresh токены
+		AccountExists: phoneHasRegisteredAccount(req.Phone),
+	}
+
+	// Определяем сообщение в зависимости от того, новый это пользователь или существующий
+	message := "User logged in successfully"
+	if err == gorm.ErrRecordNotFound {
+		message = "User created and logged in successfully"
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		authResponse,
+		message,
+	))
+}
+
+// phoneHasRegisteredAccount проверяет, принадлежит ли телефон зарегистрированному аккаунту
+func phoneHasRegisteredAccount(phone string) bool {
+	var count int64
+	database.DB.Model(&models.User{}).Where("phone IN ? AND is_guest = ? AND is_active = ?", phoneVariants(phone), false, true).Count(&count)
+	return count > 0
+}
+
+// phoneVariants номер телефона для поиска пользователей: нормализованный (как в кодах
+// подтверждения) и "как есть" (аккаунты, сохраненные до нормализации)
+func phoneVariants(phone string) []string {
+	normalized := utils.NormalizePhone(phone)
+	if normalized == "" || normalized == phone {
+		return []string{phone}
+	}
+	return []string{normalized, phone}
+}
+
+// phoneCodeErrorResponse преобразует ошибку проверки кода в HTTP ответ
+func phoneCodeErrorResponse(c *gin.Context, err error) {
+	switch err {
+	case services.ErrPhoneCodeInvalid:
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthInvalid,
+			"Invalid or expired code",
+		))
+	case services.ErrPhoneCodeAttempts:
+		c.JSON(http.StatusTooManyRequests, models.ErrorResponseWithCode(
+			models.ErrRateLimitExceeded,
+			"Too many attempts, request a new code",
+		))
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to verify code",
+		))
+	}
+}
+
+// SendPhoneCode отправляет одноразовый код подтверждения телефона по SMS
+func (ac *AuthController) SendPhoneCode(c *gin.Context) {
+	var req models.PhoneCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+	if req.Purpose == "" {
+		req.Purpose = models.PhoneVerificationLogin
+	}
+
+	if err := services.SendPhoneCode(req.Phone, req.Purpose); err != nil {
+		if err == services.ErrPhoneCodeTooFrequent {
+			c.JSON(http.StatusTooManyRequests, models.ErrorResponseWithCode(
+				models.ErrRateLimitExceeded,
+				"Code was already sent, try again later",
+			))
+			return
+		}
+		if err == services.ErrSMSNotConfigured {
+			c.JSON(http.StatusServiceUnavailable, models.ErrorResponseWithCode(
+				models.ErrServiceUnavailable,
+				"SMS delivery is not configured",
+			))
+			return
+		}
+		log.Printf("❌ Ошибка отправки кода на %s: %v", req.Phone, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to send code",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(map[string]string{
+		"status": "sent",
+	}, "Verification code sent"))
+}
+
+// LoginWithPhoneCode выполняет вход в зарегистрированный аккаунт по коду из SMS
+func (ac *AuthController) LoginWithPhoneCode(c *gin.Context) {
+	var req models.PhoneCodeLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	if err := services.VerifyPhoneCode(req.Phone, models.PhoneVerificationLogin, req.Code); err != nil {
+		phoneCodeErrorResponse(c, err)
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Preload("Addresses").Preload("Role").
+		Where("phone IN ? AND is_active = ? AND is_guest = ?", phoneVariants(req.Phone), true, false).
+		First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrInvalidCredentials,
+			"Account with this phone not found",
+		))
+		return
+	}
+
+	// Код из SMS подтверждает владение телефоном
+	if !user.IsPhoneVerified {
+		user.IsPhoneVerified = true
+		database.DB.Model(&user).Update("is_phone_verified", true)
+	}
+
+	roleName := "user"
+	if user.Role != nil {
+		roleName = user.Role.Name
+	}
+	token, err := utils.GenerateJWT(user.ID, user.Email, roleName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to generate token",
+		))
+		return
+	}
+
+	enqueueUnreadNotificationsPush(user.ID)
+
+	c.JSON(http.StatusOK, models.SuccessResponse(models.AuthResponse{
+		User:         user.ToResponse(),
+		Token:        token,
+		RefreshToken: token, // TODO: Реализовать отдельные refresh токены
+	}, "Login successful"))
+}
+
+// MergeGuestAccount переносит гостевые заказы, корзину и избранное в зарегистрированный аккаунт
+// после подтверждения телефона кодом (purpose=guest_merge).
+// Гость: данные переносятся в зарегистрированный аккаунт с его телефоном, в ответе токен этого аккаунта.
+// Зарегистрированный пользователь: переносятся все гостевые аккаунты с указанным (или своим) телефоном.
+func (ac *AuthController) MergeGuestAccount(c *gin.Context) {
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+	currentUser := userValue.(models.User)
+
+	var req models.GuestMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	phone := req.Phone
+	if currentUser.IsGuest || phone == "" {
+		phone = currentUser.Phone
+	}
+	if phone == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Phone is required",
+		))
+		return
+	}
+
+	if err := services.VerifyPhoneCode(phone, models.PhoneVerificationGuestMerge, req.Code); err != nil {
+		phoneCodeErrorResponse(c, err)
+		return
+	}
+
+	// Определяем целевой аккаунт
+	target := currentUser
+	if currentUser.IsGuest {
+		if err := database.DB.Where("phone IN ? AND is_guest = ? AND is_active = ?", phoneVariants(phone), false, true).First(&target).Error; err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+				models.ErrNotFound,
+				"Registered account with this phone not found",
+			))
+			return
+		}
+	}
+
+	var guestIDs []uuid.UUID
+	if err := database.DB.Model(&models.User{}).
+		Where("phone IN ? AND is_guest = ? AND is_active = ?", phoneVariants(phone), true, true).
+		Pluck("id", &guestIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Database error",
+		))
+		return
+	}
+
+	var summary models.AccountMergeSummary
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		summary, err = services.MergeGuestAccounts(tx, guestIDs, target.ID)
+		if err != nil {
+			return err
+		}
+		if utils.NormalizePhone(target.Phone) == utils.NormalizePhone(phone) && !target.IsPhoneVerified {
+			return tx.Model(&target).Update("is_phone_verified", true).Error
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("❌ Ошибка объединения гостевых аккаунтов с %s: %v", target.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to merge guest account",
+		))
+		return
+	}
+
+	response := gin.H{"merge": summary}
+
+	// Гостю выдаем токен аккаунта, в который перенесены данные
+	if currentUser.IsGuest {
+		authResponse, err := authResponseFor(target.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Failed to generate token",
+			))
+			return
+		}
+		response["auth"] = authResponse
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(response, "Guest data merged successfully"))
+}
+
+// authResponseFor загружает пользователя и выдает для него JWT токен
+func authResponseFor(userID uuid.UUID) (models.AuthResponse, error) {
+	var user models.User
+	if err := database.DB.Preload("Addresses").Preload("Role").First(&user, "id = ?", userID).Error; err != nil {
+		return models.AuthResponse{}, err
+	}
+	roleName := "user"
+	if user.Role != nil {
+		roleName = user.Role.Name
+	}
+	token, err := utils.GenerateJWT(user.ID, user.Email, roleName)
+	if err != nil {
+		return models.AuthResponse{}, err
+	}
+	return models.AuthResponse{
+		User:         user.ToResponse(),
+		Token:        token,
+		RefreshToken: token, // TODO: Реализовать отдельные refresh токены
+	}, nil
+}
+
+// UpgradeGuestAccount превращает гостя в зарегистрированного пользователя.
+// Телефон подтверждается кодом (purpose=register); остальные активные гости
+// с этим телефоном присоединяются к новому аккаунту.
+func (ac *AuthController) UpgradeGuestAccount(c *gin.Context) {
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+	guest := userValue.(models.User)
+	if !guest.IsGuest {
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+			models.ErrConflict,
+			"Account is already registered",
+		))
+		return
+	}
+
+	var req models.GuestUpgradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	phone := req.Phone
+	if phone == "" {
+		phone = guest.Phone
+	}
+	if phone == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Phone is required",
+		))
+		return
+	}
+
+	// Зарегистрированный аккаунт с этим телефоном уже есть - нужно объединение, а не регистрация
+	if phoneHasRegisteredAccount(phone) {
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+			models.ErrUserAlreadyExists,
+			"User with this phone already exists, use /users/merge-guest",
+		))
+		return
+	}
+	if req.Email != "" {
+		var count int64
+		database.DB.Model(&models.User{}).Where("email = ? AND id <> ?", req.Email, guest.ID).Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+				models.ErrUserAlreadyExists,
+				"User with this email already exists",
+			))
+			return
+		}
+	}
+
+	if err := services.VerifyPhoneCode(phone, models.PhoneVerificationRegister, req.Code); err != nil {
+		phoneCodeErrorResponse(c, err)
+		return
+	}
+
+	var userRole models.Role
+	if err := database.DB.Where("name = ?", "user").First(&userRole).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to find default user role",
+		))
+		return
+	}
+
+	upgraded := guest
+	if err := upgraded.HashPassword(req.Password); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to hash password",
+		))
+		return
+	}
+
+	var summary models.AccountMergeSummary
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"password":          upgraded.Password,
+			"phone":             phone,
+			"is_guest":          false,
+			"is_phone_verified": true,
+			"is_active":         true,
+			"role_id":           userRole.ID,
+		}
+		if req.Name != "" {
+			updates["name"] = req.Name
+		}
+		if req.Email != "" {
+			updates["email"] = req.Email
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", guest.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		// Другие гостевые сессии с тем же телефоном
+		var guestIDs []uuid.UUID
+		if err := tx.Model(&models.User{}).
+			Where("phone IN ? AND is_guest = ? AND is_active = ? AND id <> ?", phoneVariants(phone), true, true, guest.ID).
+			Pluck("id", &guestIDs).Error; err != nil {
+			return err
+		}
+		var err error
+		summary, err = services.MergeGuestAccounts(tx, guestIDs, guest.ID)
+		if err != nil {
+			return err
+		}
+
+		// Настройки по умолчанию, если гость их еще не создал
+		settings := models.UserSettings{
+			UserID:               guest.ID,
+			Language:             "ru",
+			Theme:                "system",
+			NotificationsEnabled: true,
+			EmailNotifications:   true,
+			PushNotifications:    true,
+		}
+		return tx.Where("user_id = ?", guest.ID).FirstOrCreate(&settings).Error
+	})
+	if err != nil {
+		log.Printf("❌ Ошибка регистрации гостя %s: %v", guest.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to upgrade guest account",
+		))
+		return
+	}
+
+	authResponse, err := authResponseFor(guest.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to generate token",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"auth":  authResponse,
+		"merge": summary,
+	}, "Guest account upgraded successfully"))
+}
+
+// registerGuest регистрация по телефону гостевого аккаунта: гость превращается в
+// зарегистрированного пользователя, остальные гости с этим телефоном присоединяются к нему
+func (ac *AuthController) registerGuest(c *gin.Context, guest models.User, req models.UserRegisterRequest) {
+	// Пока SMS не настроены, код доставить нельзя - гость переводится без него, как раньше,
+	// но телефон не отмечается подтвержденным
+	requireCode := services.PhoneCodesAvailable()
+	if requireCode && req.Code == "" {
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+			models.ErrConflict,
+			"Phone belongs to a guest account: request a code (purpose=register) and repeat registration with it",
+		))
+		return
+	}
+	if req.Email != "" {
+		var count int64
+		database.DB.Model(&models.User{}).Where("email = ? AND id <> ?", req.Email, guest.ID).Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+				models.ErrUserAlreadyExists,
+				"User with this email already exists",
+			))
+			return
+		}
+	}
+	if requireCode {
+		if err := services.VerifyPhoneCode(req.Phone, models.PhoneVerificationRegister, req.Code); err != nil {
+			phoneCodeErrorResponse(c, err)
+			return
+		}
+	}
+
+	var userRole models.Role
+	if err := database.DB.Where("name = ?", "user").First(&userRole).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to find default user role",
+		))
+		return
+	}
+	upgraded := guest
+	if err := upgraded.HashPassword(req.Password); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to hash password",
+		))
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"name":     req.Name,
+			"password": upgraded.Password,
+			"is_guest": false,
+			"role_id":  userRole.ID,
+		}
+		if requireCode {
+			updates["is_phone_verified"] = true
+		}
+		if req.Email != "" {
+			updates["email"] = req.Email
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", guest.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		var guestIDs []uuid.UUID
+		if err := tx.Model(&models.User{}).
+			Where("phone IN ? AND is_guest = ? AND is_active = ? AND id <> ?", phoneVariants(req.Phone), true, true, guest.ID).
+			Pluck("id", &guestIDs).Error; err != nil {
+			return err
+		}
+		if _, err := services.MergeGuestAccounts(tx, guestIDs, guest.ID); err != nil {
+			return err
+		}
+
+		settings := models.UserSettings{
+			UserID:               guest.ID,
+			Language:             "ru",
+			Theme:                "system",
+			NotificationsEnabled: true,
+			EmailNotifications:   true,
+			PushNotifications:    true,
+		}
+		return tx.Where("user_id = ?", guest.ID).FirstOrCreate(&settings).Error
+	})
+	if err != nil {
+		log.Printf("❌ Ошибка регистрации гостя %s: %v", guest.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to upgrade guest account",
+		))
+		return
+	}
+
+	authResponse, err := authResponseFor(guest.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to generate token",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		authResponse,
+		"Guest account upgraded and registered successfully",
+	))
+}
+
+// MergeAccount присоединяет второй зарегистрированный аккаунт того же человека.
+// Текущий аккаунт должен иметь подтвержденный телефон, владение вторым
+// подтверждается кодом на его телефон (purpose=account_merge).
+func (ac *AuthController) MergeAccount(c *gin.Context) {
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+	currentUser := userValue.(models.User)
+	if currentUser.IsGuest {
+		c.JSON(http.StatusForbidden, models.ErrorResponseWithCode(
+			models.ErrForbidden,
+			"Guest accounts must be upgraded first",
+		))
+		return
+	}
+	if !currentUser.IsPhoneVerified {
+		c.JSON(http.StatusForbidden, models.ErrorResponseWithCode(
+			models.ErrForbidden,
+			"Verify your phone first (POST /auth/phone/login)",
+		))
+		return
+	}
+
+	var req models.AccountMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	var source models.User
+	if err := database.DB.Preload("Role").
+		Where("phone IN ? AND is_guest = ? AND is_active = ? AND id <> ?", phoneVariants(req.Phone), false, true, currentUser.ID).
+		First(&source).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+			models.ErrNotFound,
+			"Account with this phone not found",
+		))
+		return
+	}
+
+	// Аккаунты магазинов и администраторов не объединяются автоматически
+	if source.Role != nil && source.Role.Name != "user" {
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+			models.ErrConflict,
+			"Only customer accounts can be merged",
+		))
+		return
+	}
+
+	if err := services.VerifyPhoneCode(req.Phone, models.PhoneVerificationAccountMerge, req.Code); err != nil {
+		phoneCodeErrorResponse(c, err)
+		return
+	}
+
+	var summary models.AccountMergeSummary
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		summary, err = services.MergeAccounts(tx, source.ID, currentUser.ID, req.CartStrategy)
+		return err
+	})
+	if err != nil {
+		log.Printf("❌ Ошибка объединения аккаунта %s с %s: %v", source.ID, currentUser.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to merge accounts",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"merge": summary}, "Accounts merged successfully"))
+}
+
+// Profile возвращает профиль текущего пользователя
+func (ac *AuthController) Profile(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Загружаем связанные данные
+	database.DB.Preload("Addresses").First(&userModel, userModel.ID)
+
+	c.JSON(http.StatusOK, models.SuccessResponse(userModel.ToResponse()))
+}
+
+// UpdateProfile обновляет профиль пользователя
+func (ac *AuthController) UpdateProfile(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+
+	var req models.UserUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Обновляем только переданные поля
+	if req.Name != nil {
+		userModel.Name = *req.Name
+	}
+
+	if req.Phone != nil {
+		userModel.Phone = *req.Phone
+	}
+
+	if req.DateOfBirth != nil {
+		userModel.DateOfBirth = req.DateOfBirth
+	}
+
+	if req.Gender != nil {
+		userModel.Gender = *req.Gender
+	}
+
+	if err := database.DB.Save(&userModel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to update profile",
+		))
+		return
+	}
+
+	// Загружаем обновленные данные
+	database.DB.Preload("Addresses").First(&userModel, userModel.ID)
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		userModel.ToResponse(),
+		"Profile updated successfully",
+	))
+}
+
+// UploadAvatar загружает аватар пользователя
+func (ac *AuthController) UploadAvatar(c *gin.Context) {
+	// Получаем текущего пользователя
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"Пользователь не авторизован",
+		))
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Получаем файл из формы
+	file, header, err := c.Request.FormFile("avatar")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Файл не предоставлен",
+			err.Error(),
+		))
+		return
+	}
+	defer file.Close()
+
+	// Используем UploadController для загрузки
+	uploadController := &UploadController{}
+	
+	// Создаем временный файл для чтения содержимого
+	tempFile, err := os.CreateTemp("", "avatar-*.tmp")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка создания временного файла",
+		))
+		return
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	// Копируем содержимое файла
+	if _, err := io.Copy(tempFile, file); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка сохранения файла",
+		))
+		return
+	}
+
+	// Перемещаем указатель в начало файла
+	tempFile.Seek(0, 0)
+
+	// Загружаем изображение
+	folder := "avatars"
+	uploadDir := fmt.Sprintf("images/%s", folder)
+
+	// Генерируем уникальное имя файла
+	ext := filepath.Ext(header.Filename)
+	if ext == "" {
+		ext = ".jpg"
+	}
+	ext = strings.ToLower(ext)
+	filename := fmt.Sprintf("%s%s", uuid.New().String(), ext)
+	filePath := storage.JoinKey(uploadDir, filename)
+
+	// Сохраняем файл через метод UploadController
+	contentType := header.Header.Get("Content-Type")
+	finalFilename, _, err := uploadController.CompressAndSaveImage(tempFile, filePath, ext, contentType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка сохранения изображения",
+			err.Error(),
+		))
+		return
+	}
+
+	// Обновляем filename если формат изменился
+	if finalFilename != filename {
+		filename = finalFilename
+	}
+
+	// Формируем URL
+	avatarURL := uploadController.GetImageURL(filename, folder)
+
+	services.RegisterMediaUpload(database.DB, avatarURL, 0, "", &userModel.ID)
+
+	// Обновляем аватар пользователя; старый файл удалит сборщик мусора медиа
+	oldAvatar := userModel.Avatar
+	userModel.Avatar = avatarURL
+	if err := database.DB.Save(&userModel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка обновления аватара",
+		))
+		return
+	}
+	services.ReleaseMedia(database.DB, oldAvatar)
+
+	// Загружаем обновленные данные
+	database.DB.Preload("Addresses").Preload("Role").First(&userModel, userModel.ID)
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		userModel.ToResponse(),
+		"Аватар успешно загружен",
+	))
+}
+
+// RefreshToken обновляет JWT токен
+func (ac *AuthController) RefreshToken(c *gin.Context) {
+	type RefreshRequest struct {
+		RefreshToken string `json:"refreshToken" binding:"required"`
+	}
+
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	newToken, err := utils.RefreshJWT(req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthInvalid,
+			"Failed to refresh token",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(map[string]string{
+		"token":        newToken,
+		"refreshToken": newToken, // TODO: Генерировать отдельный refresh токен
+	}))
+}
+
+// ForgotPassword инициирует восстановление пароля по телефону
+func (ac *AuthController) ForgotPassword(c *gin.Context) {
+	var req models.UserForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	// Заглушка: в проде отправляем SMS код или ссылку
+	// Здесь подтверждаем, что телефон существует (но не раскрываем факт отсутствия)
+	var user models.User
+	_ = database.DB.Where("phone IN ?", phoneVariants(req.Phone)).First(&user)
+
+	c.JSON(http.StatusOK, models.SuccessResponse(map[string]string{
+		"status":  "pending",
+		"message": "If this phone exists, a reset code was sent",
+	}))
+}
+
+// DeleteAccount удаляет аккаунт текущего пользователя
+func (ac *AuthController) DeleteAccount(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Проверяем, что пользователь активен
+	if !userModel.IsActive {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Account is already deleted",
+		))
+		return
+	}
+
+	// Мягкое удаление - деактивируем аккаунт
+	userModel.IsActive = false
+	if err := database.DB.Save(&userModel).Error; err != nil {
+		log.Printf("❌ Ошибка удаления аккаунта пользователя %s: %v", userModel.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to delete account",
+		))
+		return
+	}
+
+	log.Printf("✅ Аккаунт пользователя %s успешно удален (деактивирован)", userModel.ID)
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		nil,
+		"Account deleted successfully",
+	))
+}
+
+// enqueueUnreadNotificationsPush ставит в очередь push-уведомление о непрочитанных уведомлениях при входе
+func enqueueUnreadNotificationsPush(userID uuid.UUID) {
+	// Получаем все непрочитанные уведомления пользователя
+	var unreadNotifications []models.Notification
+	if err := database.DB.Where("user_id = ? AND is_read = ?", userID, false).
+		Order("timestamp DESC").
+		Limit(5). // Отправляем только последние 5 непрочитанных
+		Find(&unreadNotifications).Error; err != nil {
+		log.Printf("⚠️ Ошибка получения непрочитанных уведомлений для пользователя %s: %v", userID, err)
+		return
+	}
+
+	if len(unreadNotifications) == 0 {
+		return // Нет непрочитанных уведомлений
+	}
+
+	// Проверяем, есть ли у пользователя активные устройства
+	var activeTokens int64
+	if err := database.DB.Model(&models.DeviceToken{}).Where("user_id = ? AND is_active = ?", userID, true).Count(&activeTokens).Error; err != nil {
+		log.Printf("⚠️ Ошибка получения токенов устройств для пользователя %s: %v", userID, err)
+		return
+	}
+
+	if activeTokens == 0 {
+		return // Нет активных токенов
+	}
+
+	// Ставим push-уведомление о непрочитанных уведомлениях в очередь
+	title := "У вас есть непрочитанные уведомления"
+	body := fmt.Sprintf("У вас %d непрочитанных уведомлений", len(unreadNotifications))
+	actionURL := "/admin#dashboard" // Переход на дашборд с уведомлениями
+
+	result, err := services.DispatchPush(database.DB, userID, models.NotificationTypeReminder, title, body, actionURL, nil)
+	if err != nil {
+		log.Printf("❌ Ошибка постановки push-уведомления о непрочитанных уведомлениях в очередь: %v", err)
+	} else if result.Queued {
+		log.Printf("✅ Push-уведомление о непрочитанных уведомлениях поставлено в очередь для пользователя %s", userID)
+	}
+}
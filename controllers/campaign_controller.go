@@ -0,0 +1,563 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CampaignController обрабатывает запросы маркетинговых рассылок магазинов
+type CampaignController struct{}
+
+// resolveCampaignShop определяет магазин текущего пользователя.
+// Владелец магазина работает со своим магазином, админ и суперадмин указывают ?shopId=.
+func (cc *CampaignController) resolveCampaignShop(c *gin.Context) (*models.Shop, bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"Пользователь не найден",
+		))
+		return nil, false
+	}
+	currentUser := user.(models.User)
+
+	var shop models.Shop
+	query := database.DB
+	if isAdminUser(currentUser) {
+		shopID, err := uuid.Parse(c.Query("shopId"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+				models.ErrValidationError,
+				"Укажите корректный shopId",
+			))
+			return nil, false
+		}
+		query = query.Where("id = ?", shopID)
+	} else {
+		query = query.Where("owner_id = ?", currentUser.ID)
+	}
+
+	if err := query.First(&shop).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+				models.ErrNotFound,
+				"Магазин не найден",
+			))
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка базы данных",
+		))
+		return nil, false
+	}
+	return &shop, true
+}
+
+// loadCampaign загружает рассылку магазина по :id
+func (cc *CampaignController) loadCampaign(c *gin.Context, shop *models.Shop) (*models.Campaign, bool) {
+	campaignID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Неверный ID рассылки",
+		))
+		return nil, false
+	}
+
+	var campaign models.Campaign
+	if err := database.DB.Where("id = ? AND shop_id = ?", campaignID, shop.ID).First(&campaign).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+				models.ErrNotFound,
+				"Рассылка не найдена",
+			))
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка базы данных",
+		))
+		return nil, false
+	}
+	return &campaign, true
+}
+
+// applyCampaignRequest проверяет запрос и переносит его поля в рассылку
+func (cc *CampaignController) applyCampaignRequest(c *gin.Context, shop *models.Shop, campaign *models.Campaign, req *models.CampaignRequest) bool {
+	if req.MinBonusAmount != nil && req.MaxBonusAmount != nil && *req.MinBonusAmount > *req.MaxBonusAmount {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"minBonusAmount не может быть больше maxBonusAmount",
+		))
+		return false
+	}
+	if req.ScheduledAt != nil && req.ScheduledAt.Before(time.Now().Add(-time.Minute)) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Время отправки не может быть в прошлом",
+		))
+		return false
+	}
+	if req.ProductID != nil {
+		var count int64
+		database.DB.Model(&models.Product{}).
+			Where("id = ? AND (shop_id = ? OR owner_id = ?)", *req.ProductID, shop.ID, shop.OwnerID).
+			Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+				models.ErrValidationError,
+				"Товар не найден в магазине",
+			))
+			return false
+		}
+	}
+
+	campaign.Title = req.Title
+	campaign.Body = req.Body
+	campaign.ImageURL = req.ImageURL
+	campaign.ProductID = req.ProductID
+	campaign.DeepLink = req.DeepLink
+	campaign.Audience = req.Audience
+	if campaign.Audience == "" {
+		campaign.Audience = models.CampaignAudienceSubscribers
+	}
+	campaign.CityID = req.CityID
+	campaign.MinBonusAmount = req.MinBonusAmount
+	campaign.MaxBonusAmount = req.MaxBonusAmount
+	campaign.PurchasedWithinDays = req.PurchasedWithinDays
+	campaign.NotPurchasedForDays = req.NotPurchasedForDays
+	campaign.ScheduledAt = req.ScheduledAt
+	campaign.Status = models.CampaignStatusDraft
+	if req.ScheduledAt != nil {
+		campaign.Status = models.CampaignStatusScheduled
+	}
+	return true
+}
+
+// GetCampaigns получает рассылки магазина
+// GET /api/v1/shop/campaigns
+func (cc *CampaignController) GetCampaigns(c *gin.Context) {
+	shop, ok := cc.resolveCampaignShop(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	query := database.DB.Model(&models.Campaign{}).Where("shop_id = ?", shop.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var campaigns []models.Campaign
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&campaigns).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка получения рассылок",
+		))
+		return
+	}
+
+	responses := make([]models.CampaignResponse, len(campaigns))
+	for i, campaign := range campaigns {
+		responses[i] = campaign.ToResponse()
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+	pagination := models.PaginationInfo{
+		Page:       page,
+		Limit:      limit,
+		Total:      int(total),
+		TotalPages: totalPages,
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedSuccessResponse(responses, pagination))
+}
+
+// GetCampaign получает рассылку со статистикой
+// GET /api/v1/shop/campaigns/:id
+func (cc *CampaignController) GetCampaign(c *gin.Context) {
+	shop, ok := cc.resolveCampaignShop(c)
+	if !ok {
+		return
+	}
+	campaign, ok := cc.loadCampaign(c, shop)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(campaign.ToResponse()))
+}
+
+// CreateCampaign создает рассылку (черновик или сразу запланированную)
+// POST /api/v1/shop/campaigns
+func (cc *CampaignController) CreateCampaign(c *gin.Context) {
+	shop, ok := cc.resolveCampaignShop(c)
+	if !ok {
+		return
+	}
+
+	var req models.CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Неверные данные запроса",
+			err.Error(),
+		))
+		return
+	}
+
+	user, _ := c.Get("user")
+	campaign := models.Campaign{
+		ShopID:    shop.ID,
+		CreatedBy: user.(models.User).ID,
+	}
+	if !cc.applyCampaignRequest(c, shop, &campaign, &req) {
+		return
+	}
+
+	if err := database.DB.Create(&campaign).Error; err != nil {
+		log.Printf("❌ [CreateCampaign] Ошибка создания рассылки: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка создания рассылки",
+		))
+		return
+	}
+
+	log.Printf("📣 Рассылка %s создана магазином %s (статус: %s)", campaign.ID, shop.ID, campaign.Status)
+	c.JSON(http.StatusCreated, models.SuccessResponse(campaign.ToResponse(), "Рассылка создана"))
+}
+
+// UpdateCampaign обновляет рассылку, пока она не начала отправляться
+// PUT /api/v1/shop/campaigns/:id
+func (cc *CampaignController) UpdateCampaign(c *gin.Context) {
+	shop, ok := cc.resolveCampaignShop(c)
+	if !ok {
+		return
+	}
+	campaign, ok := cc.loadCampaign(c, shop)
+	if !ok {
+		return
+	}
+	if !campaign.IsEditable() {
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+			models.ErrConflict,
+			"Рассылку нельзя изменить после начала отправки",
+		))
+		return
+	}
+
+	var req models.CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Неверные данные запроса",
+			err.Error(),
+		))
+		return
+	}
+	if !cc.applyCampaignRequest(c, shop, campaign, &req) {
+		return
+	}
+
+	// Обновляем только если рассылку не успел захватить воркер
+	result := database.DB.Model(campaign).
+		Where("status IN ?", []models.CampaignStatus{models.CampaignStatusDraft, models.CampaignStatusScheduled}).
+		Updates(map[string]interface{}{
+			"title":                  campaign.Title,
+			"body":                   campaign.Body,
+			"image_url":              campaign.ImageURL,
+			"product_id":             campaign.ProductID,
+			"deep_link":              campaign.DeepLink,
+			"audience":               campaign.Audience,
+			"city_id":                campaign.CityID,
+			"min_bonus_amount":       campaign.MinBonusAmount,
+			"max_bonus_amount":       campaign.MaxBonusAmount,
+			"purchased_within_days":  campaign.PurchasedWithinDays,
+			"not_purchased_for_days": campaign.NotPurchasedForDays,
+			"scheduled_at":           campaign.ScheduledAt,
+			"status":                 campaign.Status,
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка обновления рассылки",
+		))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+			models.ErrConflict,
+			"Рассылку нельзя изменить после начала отправки",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(campaign.ToResponse(), "Рассылка обновлена"))
+}
+
+// ScheduleCampaign планирует рассылку на указанное время или на сейчас
+// POST /api/v1/shop/campaigns/:id/schedule
+func (cc *CampaignController) ScheduleCampaign(c *gin.Context) {
+	shop, ok := cc.resolveCampaignShop(c)
+	if !ok {
+		return
+	}
+	campaign, ok := cc.loadCampaign(c, shop)
+	if !ok {
+		return
+	}
+
+	var req models.CampaignScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err.Error() != "EOF" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Неверные данные запроса",
+			err.Error(),
+		))
+		return
+	}
+
+	scheduledAt := time.Now()
+	if req.ScheduledAt != nil {
+		if req.ScheduledAt.Before(scheduledAt.Add(-time.Minute)) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+				models.ErrValidationError,
+				"Время отправки не может быть в прошлом",
+			))
+			return
+		}
+		scheduledAt = *req.ScheduledAt
+	}
+
+	// Проверяем квоту заранее, чтобы владелец сразу видел проблему
+	quota, err := services.GetCampaignQuota(database.DB, shop.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка проверки квоты",
+		))
+		return
+	}
+	if quota.Remaining == 0 {
+		c.JSON(http.StatusForbidden, models.ErrorResponseWithCode(
+			models.ErrForbidden,
+			"Месячная квота рассылок исчерпана или у магазина нет действующей лицензии",
+			quota,
+		))
+		return
+	}
+
+	result := database.DB.Model(campaign).
+		Where("status IN ?", []models.CampaignStatus{models.CampaignStatusDraft, models.CampaignStatusScheduled}).
+		Updates(map[string]interface{}{
+			"status":       models.CampaignStatusScheduled,
+			"scheduled_at": scheduledAt,
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка планирования рассылки",
+		))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+			models.ErrConflict,
+			"Рассылка уже отправляется или завершена",
+		))
+		return
+	}
+
+	campaign.Status = models.CampaignStatusScheduled
+	campaign.ScheduledAt = &scheduledAt
+	c.JSON(http.StatusOK, models.SuccessResponse(campaign.ToResponse(), "Рассылка запланирована"))
+}
+
+// CancelCampaign отменяет рассылку. Уже отправленные сообщения не отзываются.
+// POST /api/v1/shop/campaigns/:id/cancel
+func (cc *CampaignController) CancelCampaign(c *gin.Context) {
+	shop, ok := cc.resolveCampaignShop(c)
+	if !ok {
+		return
+	}
+	campaign, ok := cc.loadCampaign(c, shop)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(campaign).
+			Where("status IN ?", []models.CampaignStatus{
+				models.CampaignStatusDraft, models.CampaignStatusScheduled, models.CampaignStatusSending,
+			}).
+			Updates(map[string]interface{}{
+				"status":       models.CampaignStatusCancelled,
+				"completed_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// Неотправленные получатели не расходуют квоту
+		return tx.Model(&models.CampaignRecipient{}).
+			Where("campaign_id = ? AND status = ?", campaign.ID, models.CampaignRecipientPending).
+			Update("status", models.CampaignRecipientSkipped).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+			models.ErrConflict,
+			"Рассылка уже завершена",
+		))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка отмены рассылки",
+		))
+		return
+	}
+
+	campaign.Status = models.CampaignStatusCancelled
+	campaign.CompletedAt = &now
+	c.JSON(http.StatusOK, models.SuccessResponse(campaign.ToResponse(), "Рассылка отменена"))
+}
+
+// PreviewCampaignAudience возвращает размер аудитории рассылки с учетом квоты
+// GET /api/v1/shop/campaigns/:id/audience
+func (cc *CampaignController) PreviewCampaignAudience(c *gin.Context) {
+	shop, ok := cc.resolveCampaignShop(c)
+	if !ok {
+		return
+	}
+	campaign, ok := cc.loadCampaign(c, shop)
+	if !ok {
+		return
+	}
+
+	userIDs, err := services.ResolveCampaignAudience(database.DB, campaign)
+	if err != nil {
+		log.Printf("❌ [PreviewCampaignAudience] %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка расчета аудитории",
+		))
+		return
+	}
+	quota, err := services.GetCampaignQuota(database.DB, shop.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка проверки квоты",
+		))
+		return
+	}
+
+	willReceive := len(userIDs)
+	if willReceive > quota.Remaining {
+		willReceive = quota.Remaining
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"audienceSize": len(userIDs),
+		"willReceive":  willReceive,
+		"quota":        quota,
+	}))
+}
+
+// GetCampaignQuota возвращает месячную квоту рассылок магазина
+// GET /api/v1/shop/campaigns/quota
+func (cc *CampaignController) GetCampaignQuota(c *gin.Context) {
+	shop, ok := cc.resolveCampaignShop(c)
+	if !ok {
+		return
+	}
+
+	quota, err := services.GetCampaignQuota(database.DB, shop.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка проверки квоты",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(quota))
+}
+
+// TrackCampaignOpen отмечает открытие уведомления рассылки
+// POST /api/v1/campaigns/recipients/:id/open
+func (cc *CampaignController) TrackCampaignOpen(c *gin.Context) {
+	cc.trackCampaignEvent(c, false)
+}
+
+// TrackCampaignClick отмечает переход по уведомлению рассылки
+// POST /api/v1/campaigns/recipients/:id/click
+func (cc *CampaignController) TrackCampaignClick(c *gin.Context) {
+	cc.trackCampaignEvent(c, true)
+}
+
+// trackCampaignEvent общая обработка событий открытия и перехода
+func (cc *CampaignController) trackCampaignEvent(c *gin.Context, click bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"Пользователь не найден",
+		))
+		return
+	}
+
+	recipientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Неверный ID получателя",
+		))
+		return
+	}
+
+	if err := services.TrackCampaignEvent(recipientID, user.(models.User).ID, click); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+				models.ErrNotFound,
+				"Получатель рассылки не найден",
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Ошибка сохранения статистики",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(nil, "Событие учтено"))
+}
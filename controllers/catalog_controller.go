@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+)
+
+// CatalogController отвечает за каталог с фильтрами по вариациям и фасетами
+type CatalogController struct{}
+
+// catalogQueryList читает список из повторяющихся параметров и/или через запятую:
+// ?size=S&size=M или ?size=S,M
+func catalogQueryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(key) {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// catalogQueryUUID читает необязательный UUID параметр
+func catalogQueryUUID(c *gin.Context, key string) (*uuid.UUID, bool) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, true
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, false
+	}
+	return &id, true
+}
+
+// catalogQueryPrice читает необязательную цену
+func catalogQueryPrice(c *gin.Context, key string) (*float64, bool) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, true
+	}
+	price, err := strconv.ParseFloat(raw, 64)
+	if err != nil || price < 0 {
+		return nil, false
+	}
+	return &price, true
+}
+
+// parseCatalogFilter разбирает параметры каталога
+func parseCatalogFilter(c *gin.Context) (models.CatalogFilter, bool) {
+	list, ok := parseListQuery(c, "variations", "category", "shop")
+	if !ok {
+		return models.CatalogFilter{}, false
+	}
+
+	filter := models.CatalogFilter{
+		Search:  strings.TrimSpace(c.Query("search")),
+		Gender:  c.Query("gender"),
+		Brands:  catalogQueryList(c, "brand"),
+		Sizes:   catalogQueryList(c, "size"),
+		Colors:  catalogQueryList(c, "color"),
+		OnSale:  c.Query("on_sale") == "true",
+		InStock: c.Query("in_stock") == "true",
+	}
+
+	if filter.CategoryID, ok = catalogQueryUUID(c, "category"); !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid category"))
+		return filter, false
+	}
+	if filter.CityID, ok = catalogQueryUUID(c, "city_id"); !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid city_id"))
+		return filter, false
+	}
+	if filter.ShopID, ok = catalogQueryUUID(c, "shop_id"); !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid shop_id"))
+		return filter, false
+	}
+	if filter.MinPrice, ok = catalogQueryPrice(c, "min_price"); !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid min_price"))
+		return filter, false
+	}
+	if filter.MaxPrice, ok = catalogQueryPrice(c, "max_price"); !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid max_price"))
+		return filter, false
+	}
+
+	// Характеристики: attr.<код>=значение1,значение2
+	for key := range c.Request.URL.Query() {
+		code := strings.TrimPrefix(key, models.CatalogAttributeParamPrefix)
+		if code == key || code == "" {
+			continue
+		}
+		if values := catalogQueryList(c, key); len(values) > 0 {
+			if filter.Attributes == nil {
+				filter.Attributes = make(map[string][]string)
+			}
+			filter.Attributes[code] = values
+		}
+	}
+
+	// Сортировка: по умолчанию по релевантности при поиске, иначе новые
+	filter.SortBy = models.CatalogSort(c.Query("sort_by"))
+	switch filter.SortBy {
+	case models.CatalogSortNewest, models.CatalogSortName, models.CatalogSortPrice, models.CatalogSortDiscount, models.CatalogSortRating, models.CatalogSortRelevance:
+	default:
+		filter.SortBy = models.CatalogSortNewest
+		if filter.Search != "" && !list.CursorMode {
+			filter.SortBy = models.CatalogSortRelevance
+		}
+	}
+	sortOrder := c.Query("sort_order")
+	if sortOrder == "" {
+		// Цена и название по возрастанию, остальное - по убыванию
+		filter.SortDesc = filter.SortBy != models.CatalogSortPrice && filter.SortBy != models.CatalogSortName
+	} else {
+		filter.SortDesc = sortOrder == "desc"
+	}
+
+	// Пагинация: курсор работает только для новых первыми
+	filter.Page = list.Page
+	filter.Limit = list.Limit
+	filter.CursorMode = list.CursorMode
+	filter.After = list.After
+	if filter.CursorMode && (filter.SortBy != models.CatalogSortNewest || !filter.SortDesc) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Cursor pagination supports only sort_by=created_at&sort_order=desc",
+		))
+		return filter, false
+	}
+
+	// Связи товара: по умолчанию вариации, категория и магазин
+	if list.Expands("variations", true) {
+		filter.Relations = append(filter.Relations, "Variations")
+	}
+	if list.Expands("category", true) {
+		filter.Relations = append(filter.Relations, "Category")
+	}
+	if list.Expands("shop", true) {
+		filter.Relations = append(filter.Relations, "Shop")
+	}
+
+	return filter, true
+}
+
+// GetProducts возвращает товары каталога с фильтрами по вариациям.
+// С параметром facets=true в ответ добавляются счетчики фильтров.
+func (cc *CatalogController) GetProducts(c *gin.Context) {
+	filter, ok := parseCatalogFilter(c)
+	if !ok {
+		return
+	}
+
+	products, total, err := services.QueryCatalog(database.DB, filter)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch products",
+		})
+		return
+	}
+
+	var cursor models.CursorInfo
+	if filter.CursorMode {
+		products, cursor = models.CursorPage(products, filter.Limit, productCursor)
+	}
+
+	productResponses := make([]models.ProductResponse, len(products))
+	for i, product := range products {
+		productResponses[i] = product.ToResponse()
+	}
+
+	if filter.CursorMode {
+		c.JSON(http.StatusOK, models.CursorSuccessResponse(productResponses, cursor))
+		return
+	}
+
+	response := gin.H{
+		"products": productResponses,
+		"pagination": gin.H{
+			"page":  filter.Page,
+			"limit": filter.Limit,
+			"total": total,
+			"pages": (total + int64(filter.Limit) - 1) / int64(filter.Limit),
+		},
+	}
+
+	if c.Query("facets") == "true" {
+		facets, err := services.GetCatalogFacets(database.DB, filter)
+		if err != nil {
+			log.Printf("❌ %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch facets",
+			})
+			return
+		}
+		response["facets"] = facets
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetFacets возвращает только счетчики фильтров (для шторки фильтров)
+func (cc *CatalogController) GetFacets(c *gin.Context) {
+	filter, ok := parseCatalogFilter(c)
+	if !ok {
+		return
+	}
+
+	facets, err := services.GetCatalogFacets(database.DB, filter)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch facets",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"facets": facets,
+	})
+}
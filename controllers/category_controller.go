@@ -1,341 +1,539 @@
-package controllers
-
-import (
-	"net/http"
-	"strconv"
-
-	"github.com/mm-api/mm-api/database"
-	"github.com/mm-api/mm-api/models"
-	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
-	"gorm.io/gorm"
-)
-
-// CategoryController обрабатывает запросы категорий
-type CategoryController struct{}
-
-// GetCategories возвращает все категории
-func (cc *CategoryController) GetCategories(c *gin.Context) {
-	var categories []models.Category
-
-	if err := database.DB.Where("parent_id IS NULL").Preload("Subcategories").Order("sort_order ASC").Find(&categories).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to fetch categories",
-		))
-		return
-	}
-
-	// Преобразуем в response
-	categoryTrees := make([]models.CategoryTree, len(categories))
-	for i, category := range categories {
-		categoryTrees[i] = category.ToTree()
-	}
-
-	c.JSON(http.StatusOK, models.SuccessResponse(categoryTrees))
-}
-
-// GetCategory возвращает категорию по ID
-func (cc *CategoryController) GetCategory(c *gin.Context) {
-	id := c.Param("id")
-	categoryID, err := uuid.Parse(id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid category ID",
-		))
-		return
-	}
-
-	var category models.Category
-	if err := database.DB.Preload("Subcategories").First(&category, "id = ?", categoryID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
-				models.ErrNotFound,
-				"Category not found",
-			))
-		} else {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-				models.ErrInternalError,
-				"Database error",
-			))
-		}
-		return
-	}
-
-	c.JSON(http.StatusOK, models.SuccessResponse(category.ToResponse()))
-}
-
-// GetCategoryProducts возвращает товары в категории
-func (cc *CategoryController) GetCategoryProducts(c *gin.Context) {
-	id := c.Param("id")
-	categoryID, err := uuid.Parse(id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid category ID",
-		))
-		return
-	}
-
-	// Проверяем существование категории
-	var category models.Category
-	if err := database.DB.First(&category, "id = ?", categoryID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
-				models.ErrNotFound,
-				"Category not found",
-			))
-		} else {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-				models.ErrInternalError,
-				"Database error",
-			))
-		}
-		return
-	}
-
-	var products []models.Product
-	query := database.DB.Model(&models.Product{}).Where("category_id = ?", categoryID)
-
-	// Фильтрация по полу (gender) - для раздела "Детям": male, female, unisex
-	if gender := c.Query("gender"); gender != "" {
-		query = query.Where("gender = ?", gender)
-	}
-
-	// Фильтрация по доступности
-	if inStock := c.Query("in_stock"); inStock == "true" {
-		query = query.Where("is_available = ?", true)
-	}
-
-	// Поиск
-	if search := c.Query("search"); search != "" {
-		query = query.Where("name ILIKE ? OR description ILIKE ?", "%"+search+"%", "%"+search+"%")
-	}
-
-	// Сортировка
-	sortBy := c.DefaultQuery("sort_by", "created_at")
-	sortOrder := c.DefaultQuery("sort_order", "desc")
-	allowedSorts := map[string]bool{
-		"name":       true,
-		"price":      true,
-		"rating":     true,
-		"created_at": true,
-	}
-
-	if allowedSorts[sortBy] {
-		if sortOrder == "asc" {
-			query = query.Order(sortBy + " ASC")
-		} else {
-			query = query.Order(sortBy + " DESC")
-		}
-	}
-
-	// Пагинация
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 20
-	}
-
-	offset := (page - 1) * limit
-
-	// Получаем общее количество
-	var total int64
-	query.Count(&total)
-
-	// Получаем продукты с загрузкой Owner.Role для информации о магазине
-	if err := query.Offset(offset).Limit(limit).Preload("Variations").Preload("Category").Preload("Owner.Role").Find(&products).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to fetch products",
-		))
-		return
-	}
-
-	// Преобразуем в response
-	productResponses := make([]models.ProductResponse, len(products))
-	for i, product := range products {
-		productResponses[i] = product.ToResponse()
-	}
-
-	// Вычисляем пагинацию
-	totalPages := (int(total) + limit - 1) / limit
-	pagination := models.PaginationInfo{
-		Page:       page,
-		Limit:      limit,
-		Total:      int(total),
-		TotalPages: totalPages,
-	}
-
-	c.JSON(http.StatusOK, models.PaginatedSuccessResponse(productResponses, pagination))
-}
-
-// CreateCategory создает новую категорию (только для админов)
-func (cc *CategoryController) CreateCategory(c *gin.Context) {
-	var req models.CategoryRequest
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid request data",
-			err.Error(),
-		))
-		return
-	}
-
-	// Если указан родитель, проверяем его существование
-	if req.ParentID != nil {
-		var parent models.Category
-		if err := database.DB.First(&parent, "id = ?", *req.ParentID).Error; err != nil {
-			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-				models.ErrValidationError,
-				"Parent category not found",
-			))
-			return
-		}
-	}
-
-	category := models.Category{
-		Name:        req.Name,
-		Description: req.Description,
-		IconURL:     req.IconURL,
-		ParentID:    req.ParentID,
-		SortOrder:   req.SortOrder,
-		IsActive:    req.IsActive,
-	}
-
-	if err := database.DB.Create(&category).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to create category",
-		))
-		return
-	}
-
-	c.JSON(http.StatusCreated, models.SuccessResponse(
-		category.ToResponse(),
-		"Category created successfully",
-	))
-}
-
-// UpdateCategory обновляет категорию (только для админов)
-func (cc *CategoryController) UpdateCategory(c *gin.Context) {
-	id := c.Param("id")
-	categoryID, err := uuid.Parse(id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid category ID",
-		))
-		return
-	}
-
-	var req models.CategoryRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid request data",
-			err.Error(),
-		))
-		return
-	}
-
-	var category models.Category
-	if err := database.DB.First(&category, "id = ?", categoryID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
-				models.ErrNotFound,
-				"Category not found",
-			))
-		} else {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-				models.ErrInternalError,
-				"Database error",
-			))
-		}
-		return
-	}
-
-	// Обновляем поля
-	category.Name = req.Name
-	category.Description = req.Description
-	category.IconURL = req.IconURL
-	category.ParentID = req.ParentID
-	category.SortOrder = req.SortOrder
-	category.IsActive = req.IsActive
-
-	if err := database.DB.Save(&category).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to update category",
-		))
-		return
-	}
-
-	c.JSON(http.StatusOK, models.SuccessResponse(
-		category.ToResponse(),
-		"Category updated successfully",
-	))
-}
-
-// DeleteCategory удаляет категорию (только для админов)
-func (cc *CategoryController) DeleteCategory(c *gin.Context) {
-	id := c.Param("id")
-	categoryID, err := uuid.Parse(id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid category ID",
-		))
-		return
-	}
-
-	// Проверяем, есть ли продукты в этой категории
-	var productCount int64
-	database.DB.Model(&models.Product{}).Where("category_id = ?", categoryID).Count(&productCount)
-	if productCount > 0 {
-		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Cannot delete category with existing products",
-		))
-		return
-	}
-
-	// Проверяем, есть ли подкатегории
-	var subcategoryCount int64
-	database.DB.Model(&models.Category{}).Where("parent_id = ?", categoryID).Count(&subcategoryCount)
-	if subcategoryCount > 0 {
-		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Cannot delete category with subcategories",
-		))
-		return
-	}
-
-	result := database.DB.Delete(&models.Category{}, "id = ?", categoryID)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to delete category",
-		))
-		return
-	}
-
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
-			models.ErrNotFound,
-			"Category not found",
-		))
-		return
-	}
-
-	c.JSON(http.StatusOK, models.SuccessResponse(
-		nil,
-		"Category deleted successfully",
-	))
-}
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CategoryController обрабатывает запросы категорий
+type CategoryController struct{}
+
+// refreshCategoryCounts ставит пересчет сохраненного productCount категорий в фон
+// после изменений; счетчики обновляются через несколько секунд
+func refreshCategoryCounts() {
+	services.ScheduleCategoryCountRefresh()
+}
+
+// GetCategories возвращает полное дерево активных категорий с количеством товаров.
+// city_id ограничивает подсчет товарами города.
+func (cc *CategoryController) GetCategories(c *gin.Context) {
+	cc.respondCategoryTree(c, false)
+}
+
+// GetCategoryTreeAdmin возвращает дерево вместе с выключенными категориями (для админки)
+func (cc *CategoryController) GetCategoryTreeAdmin(c *gin.Context) {
+	cc.respondCategoryTree(c, true)
+}
+
+// respondCategoryTree строит дерево категорий и отправляет его клиенту
+func (cc *CategoryController) respondCategoryTree(c *gin.Context, includeInactive bool) {
+	cityID, ok := catalogQueryUUID(c, "city_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid city_id",
+		))
+		return
+	}
+
+	tree, err := services.BuildCategoryTree(database.DB, cityID, includeInactive)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to fetch categories",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(tree))
+}
+
+// GetCategoryBreadcrumbs возвращает путь от корня до категории
+func (cc *CategoryController) GetCategoryBreadcrumbs(c *gin.Context) {
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid category ID",
+		))
+		return
+	}
+
+	breadcrumbs, err := services.GetCategoryBreadcrumbs(database.DB, categoryID)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Database error",
+		))
+		return
+	}
+	if len(breadcrumbs) == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+			models.ErrNotFound,
+			"Category not found",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(breadcrumbs))
+}
+
+// GetCategory возвращает категорию по ID
+func (cc *CategoryController) GetCategory(c *gin.Context) {
+	id := c.Param("id")
+	categoryID, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid category ID",
+		))
+		return
+	}
+
+	var category models.Category
+	if err := database.DB.Preload("Subcategories", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC, name ASC")
+	}).First(&category, "id = ?", categoryID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+				models.ErrNotFound,
+				"Category not found",
+			))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Database error",
+			))
+		}
+		return
+	}
+
+	response := category.ToResponse()
+	breadcrumbs, err := services.GetCategoryBreadcrumbs(database.DB, categoryID)
+	if err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+	response.Breadcrumbs = breadcrumbs
+
+	c.JSON(http.StatusOK, models.SuccessResponse(response))
+}
+
+// GetCategoryProducts возвращает товары в категории
+func (cc *CategoryController) GetCategoryProducts(c *gin.Context) {
+	id := c.Param("id")
+	categoryID, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid category ID",
+		))
+		return
+	}
+
+	// Проверяем существование категории
+	var category models.Category
+	if err := database.DB.First(&category, "id = ?", categoryID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+				models.ErrNotFound,
+				"Category not found",
+			))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Database error",
+			))
+		}
+		return
+	}
+
+	// Товары категории вместе со всеми подкатегориями
+	var products []models.Product
+	query := database.DB.Model(&models.Product{}).Where(services.CategorySubtreeCondition("products.category_id"), categoryID)
+
+	// Фильтрация по городу (как в дереве категорий)
+	cityID, ok := catalogQueryUUID(c, "city_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid city_id",
+		))
+		return
+	}
+	if cityID != nil {
+		query = query.Where("products.city_id = ? OR products.shop_id IN (SELECT id FROM shops WHERE city_id = ?)", *cityID, *cityID)
+	}
+
+	// Фильтрация по полу (gender) - для раздела "Детям": male, female, unisex
+	if gender := c.Query("gender"); gender != "" {
+		query = query.Where("gender = ?", gender)
+	}
+
+	// Фильтрация по доступности
+	if inStock := c.Query("in_stock"); inStock == "true" {
+		query = query.Where("is_available = ?", true)
+	}
+
+	// Полнотекстовый поиск (морфология, опечатки; без sort_by - по релевантности)
+	if search := c.Query("search"); search != "" {
+		query = services.GetSearchIndex().Apply(query, search, c.Query("sort_by") == "")
+	}
+
+	// Сортировка
+	sortBy := c.DefaultQuery("sort_by", "created_at")
+	sortOrder := c.DefaultQuery("sort_order", "desc")
+	// Цена и скидка считаются по вариациям товара
+	if orderBy, ok := services.CatalogOrder(sortBy, sortOrder == "asc"); ok {
+		query = query.Order(orderBy)
+	}
+
+	// Пагинация
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	// Получаем общее количество
+	var total int64
+	query.Count(&total)
+
+	// Получаем продукты с загрузкой Owner.Role для информации о магазине
+	if err := query.Offset(offset).Limit(limit).Preload("Variations").Preload("Category").Preload("Owner.Role").Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to fetch products",
+		))
+		return
+	}
+
+	// Преобразуем в response
+	productResponses := make([]models.ProductResponse, len(products))
+	for i, product := range products {
+		productResponses[i] = product.ToResponse()
+	}
+
+	// Вычисляем пагинацию
+	totalPages := (int(total) + limit - 1) / limit
+	pagination := models.PaginationInfo{
+		Page:       page,
+		Limit:      limit,
+		Total:      int(total),
+		TotalPages: totalPages,
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedSuccessResponse(productResponses, pagination))
+}
+
+// CreateCategory создает новую категорию (только для админов)
+func (cc *CategoryController) CreateCategory(c *gin.Context) {
+	var req models.CategoryRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	// Если указан родитель, проверяем его существование
+	if req.ParentID != nil {
+		var parent models.Category
+		if err := database.DB.First(&parent, "id = ?", *req.ParentID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+				models.ErrValidationError,
+				"Parent category not found",
+			))
+			return
+		}
+	}
+
+	category := models.Category{
+		Name:        req.Name,
+		Description: req.Description,
+		IconURL:     req.IconURL,
+		ParentID:    req.ParentID,
+		SortOrder:   req.SortOrder,
+		IsActive:    req.IsActive,
+	}
+
+	if err := database.DB.Create(&category).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to create category",
+		))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse(
+		category.ToResponse(),
+		"Category created successfully",
+	))
+}
+
+// UpdateCategory обновляет категорию (только для админов)
+func (cc *CategoryController) UpdateCategory(c *gin.Context) {
+	id := c.Param("id")
+	categoryID, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid category ID",
+		))
+		return
+	}
+
+	var req models.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	var category models.Category
+	if err := database.DB.First(&category, "id = ?", categoryID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+				models.ErrNotFound,
+				"Category not found",
+			))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Database error",
+			))
+		}
+		return
+	}
+
+	// Обновляем поля
+	parentChanged := !sameCategoryParent(category.ParentID, req.ParentID)
+	category.Name = req.Name
+	category.Description = req.Description
+	category.IconURL = req.IconURL
+	category.ParentID = req.ParentID
+	category.SortOrder = req.SortOrder
+	category.IsActive = req.IsActive
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if parentChanged {
+			if err := services.LockCategoryTree(tx); err != nil {
+				return err
+			}
+			if err := services.ValidateCategoryParent(tx, category.ID, category.ParentID); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&category).Error
+	})
+	if err != nil {
+		respondCategoryMoveError(c, err, "Failed to update category")
+		return
+	}
+	if parentChanged {
+		refreshCategoryCounts()
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		category.ToResponse(),
+		"Category updated successfully",
+	))
+}
+
+// sameCategoryParent сравнивает родителей категории
+func sameCategoryParent(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// respondCategoryMoveError отвечает на ошибку изменения иерархии
+func respondCategoryMoveError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrCategoryCycle):
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Category cannot be moved under itself or its subcategory",
+		))
+	case errors.Is(err, services.ErrCategoryNotSibling):
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"All categories must belong to the given parent",
+		))
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Parent category not found",
+		))
+	default:
+		log.Printf("❌ %s: %v", fallback, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			fallback,
+		))
+	}
+}
+
+// MoveCategory переносит категорию под другого родителя (только для админов)
+func (cc *CategoryController) MoveCategory(c *gin.Context) {
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid category ID",
+		))
+		return
+	}
+
+	var req models.CategoryMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	var category models.Category
+	if err := database.DB.First(&category, "id = ?", categoryID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+				models.ErrNotFound,
+				"Category not found",
+			))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Database error",
+			))
+		}
+		return
+	}
+
+	if err := services.MoveCategory(database.DB, &category, req.ParentID, req.SortOrder); err != nil {
+		respondCategoryMoveError(c, err, "Failed to move category")
+		return
+	}
+	refreshCategoryCounts()
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		category.ToResponse(),
+		"Category moved successfully",
+	))
+}
+
+// ReorderCategories задает порядок подкатегорий одного родителя (только для админов)
+func (cc *CategoryController) ReorderCategories(c *gin.Context) {
+	var req models.CategoryReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	seen := make(map[uuid.UUID]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		if seen[id] {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+				models.ErrValidationError,
+				"Duplicate category ID in ids",
+			))
+			return
+		}
+		seen[id] = true
+	}
+
+	if err := services.ReorderCategories(database.DB, req.ParentID, req.IDs); err != nil {
+		respondCategoryMoveError(c, err, "Failed to reorder categories")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		nil,
+		"Categories reordered successfully",
+	))
+}
+
+// DeleteCategory удаляет категорию (только для админов)
+func (cc *CategoryController) DeleteCategory(c *gin.Context) {
+	id := c.Param("id")
+	categoryID, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid category ID",
+		))
+		return
+	}
+
+	// Проверяем, есть ли продукты в этой категории
+	var productCount int64
+	database.DB.Model(&models.Product{}).Where("category_id = ?", categoryID).Count(&productCount)
+	if productCount > 0 {
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Cannot delete category with existing products",
+		))
+		return
+	}
+
+	// Проверяем, есть ли подкатегории
+	var subcategoryCount int64
+	database.DB.Model(&models.Category{}).Where("parent_id = ?", categoryID).Count(&subcategoryCount)
+	if subcategoryCount > 0 {
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Cannot delete category with subcategories",
+		))
+		return
+	}
+
+	result := database.DB.Delete(&models.Category{}, "id = ?", categoryID)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to delete category",
+		))
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+			models.ErrNotFound,
+			"Category not found",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		nil,
+		"Category deleted successfully",
+	))
+}
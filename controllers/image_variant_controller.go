@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mm-api/mm-api/storage"
+	"github.com/mm-api/mm-api/utils"
+)
+
+// imageVariantQueueSize сколько загруженных фото может ждать создания вариантов.
+// В очереди только ключи: изображение заново читается из хранилища воркером.
+const imageVariantQueueSize = 256
+
+// imageVariantLock блокировка варианта с числом владельцев и ожидающих.
+// Запись удаляется из карты, только когда блокировка больше никому не нужна:
+// иначе ожидающий запрос и новый запрос держали бы разные мьютексы одного ключа.
+type imageVariantLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+var (
+	// imageVariantLocks не дает нескольким запросам одновременно создавать один и тот же вариант
+	imageVariantLocksMu sync.Mutex
+	imageVariantLocks   = make(map[string]*imageVariantLock)
+
+	imageVariantQueue     chan string
+	imageVariantQueueOnce sync.Once
+)
+
+// lockImageVariant блокирует создание варианта по ключу
+func lockImageVariant(key string) func() {
+	imageVariantLocksMu.Lock()
+	lock := imageVariantLocks[key]
+	if lock == nil {
+		lock = &imageVariantLock{}
+		imageVariantLocks[key] = lock
+	}
+	lock.refs++
+	imageVariantLocksMu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+
+		imageVariantLocksMu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(imageVariantLocks, key)
+		}
+		imageVariantLocksMu.Unlock()
+	}
+}
+
+// enqueueImageVariants ставит создание вариантов фото в очередь фиксированного числа
+// воркеров (IMAGE_VARIANT_WORKERS). Загрузку не задерживает: при заполненной очереди
+// фото пропускается, его варианты создаст ResizeImage при первом запросе.
+func enqueueImageVariants(sourceKey string) {
+	imageVariantQueueOnce.Do(startImageVariantWorkers)
+	select {
+	case imageVariantQueue <- sourceKey:
+	default:
+		log.Printf("⚠️ Очередь вариантов изображений заполнена, %s будет обработано при первом запросе", sourceKey)
+	}
+}
+
+// startImageVariantWorkers запускает воркеры очереди вариантов
+func startImageVariantWorkers() {
+	imageVariantQueue = make(chan string, imageVariantQueueSize)
+	for i := 0; i < utils.ImageVariantSettings().Workers; i++ {
+		go func() {
+			for sourceKey := range imageVariantQueue {
+				ctx := context.Background()
+				img, err := loadStorageImage(ctx, sourceKey)
+				if err != nil {
+					log.Printf("⚠️ Не удалось прочитать %s для создания вариантов: %v", sourceKey, err)
+					continue
+				}
+				generateImageVariants(ctx, sourceKey, img)
+			}
+		}()
+	}
+}
+
+// renderImageVariant создает вариант изображения и сохраняет его в хранилище
+func renderImageVariant(ctx context.Context, img image.Image, sourceKey string, width int, format string) ([]byte, error) {
+	data, err := utils.RenderImageVariant(img, width, format)
+	if err != nil {
+		return nil, err
+	}
+	variantKey := utils.ImageVariantKey(sourceKey, width, format)
+	if err := storage.Default().Put(ctx, variantKey, bytes.NewReader(data), int64(len(data)), utils.ImageVariantContentType(format)); err != nil {
+		return nil, fmt.Errorf("ошибка сохранения варианта %s: %v", variantKey, err)
+	}
+	return data, nil
+}
+
+// generateImageVariants создает все размеры каталога для загруженного фото
+func generateImageVariants(ctx context.Context, sourceKey string, img image.Image) {
+	created := 0
+	for _, rendition := range utils.ImageRenditions {
+		for _, format := range utils.ImageVariantFormats() {
+			unlock := lockImageVariant(utils.ImageVariantKey(sourceKey, rendition.Width, format))
+			_, err := renderImageVariant(ctx, img, sourceKey, rendition.Width, format)
+			unlock()
+			if err != nil {
+				log.Printf("⚠️ Не удалось создать вариант %s (%s) для %s: %v", rendition.Name, format, sourceKey, err)
+				continue
+			}
+			created++
+		}
+	}
+	log.Printf("✅ Создано вариантов изображения %s: %d", sourceKey, created)
+}
+
+// loadStorageImage читает и декодирует изображение из хранилища
+func loadStorageImage(ctx context.Context, key string) (image.Image, error) {
+	reader, _, err := storage.Default().Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return utils.DecodeImageAutoOrient(data)
+}
+
+// ResizeImage отдает уменьшенную копию изображения из хранилища.
+// Параметры подписаны сервером (см. utils.ImageVariantURL), результат кэшируется
+// в хранилище (images/_variants/...) и при следующих запросах отдается готовым.
+func (ic *ImageController) ResizeImage(c *gin.Context) {
+	width, err := strconv.Atoi(c.Param("width"))
+	format := c.Param("format")
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err != nil || width <= 0 || width > utils.MaxImageVariantWidth ||
+		(format != utils.ImageFormatJPEG && format != utils.ImageFormatWebP) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid width or format",
+		})
+		return
+	}
+	if !utils.VerifyImageVariantSignature(key, width, format, c.Query("s")) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Invalid signature",
+		})
+		return
+	}
+	if format == utils.ImageFormatWebP && !utils.WebPSupported() {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "WebP is not supported by the server",
+		})
+		return
+	}
+
+	cleaned, err := storage.CleanKey(key)
+	if err != nil || !strings.HasPrefix(cleaned, "images/") || strings.HasPrefix(cleaned, utils.ImageVariantPrefix) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid image path",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	store := storage.Default()
+	contentType := utils.ImageVariantContentType(format)
+	variantKey := utils.ImageVariantKey(cleaned, width, format)
+	if _, ok := store.(*storage.LocalStorage); ok {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	}
+
+	// Готовый вариант из кэша
+	if _, err := store.Stat(ctx, variantKey); err == nil {
+		serveStorageObject(c, variantKey, "", contentType)
+		return
+	}
+
+	unlock := lockImageVariant(variantKey)
+	defer unlock()
+
+	// Пока ждали блокировку, вариант мог создать другой запрос
+	if _, err := store.Stat(ctx, variantKey); err == nil {
+		serveStorageObject(c, variantKey, "", contentType)
+		return
+	}
+
+	img, err := loadStorageImage(ctx, cleaned)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Image not found",
+			})
+			return
+		}
+		log.Printf("❌ Ошибка загрузки изображения %s: %v", cleaned, err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "Failed to decode image",
+		})
+		return
+	}
+
+	data, err := renderImageVariant(ctx, img, cleaned, width, format)
+	if err != nil {
+		log.Printf("❌ Ошибка создания варианта %s: %v", variantKey, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to resize image",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Data(http.StatusOK, contentType, data)
+}
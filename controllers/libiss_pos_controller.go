@@ -3,19 +3,23 @@ package controllers
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/config"
 	"github.com/mm-api/mm-api/database"
 	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"github.com/mm-api/mm-api/storage"
+	"gorm.io/gorm"
 )
 
 // LibissPosController обрабатывает загрузку и выдачу файлов программ libiss_pos
@@ -110,42 +114,15 @@ func (lc *LibissPosController) UploadFile(c *gin.Context) {
 		return
 	}
 
-	// Создаем директорию для хранения файлов
-	dir := filepath.Join("libiss_pos", string(fileType))
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		log.Printf("❌ Failed to create directory %s: %v", dir, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"error":   "failed to create upload directory",
-			"details": err.Error(),
-		})
-		return
-	}
-
 	// Генерируем уникальное имя файла
 	filename := fmt.Sprintf("%s_%s_%s%s", fileType, version, uuid.NewString()[:8], ext)
-	filePath := filepath.Join(dir, filename)
+	filePath := storage.JoinKey("libiss_pos", string(fileType), filename)
 
-	// Создаем файл
-	dst, err := os.Create(filePath)
-	if err != nil {
-		log.Printf("❌ Failed to create file %s: %v", filePath, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"error":   "failed to create file",
-			"details": err.Error(),
-		})
-		return
-	}
-	defer dst.Close()
-
-	// Сохраняем файл и вычисляем SHA256
+	// Сохраняем файл в хранилище и вычисляем SHA256
 	hasher := sha256.New()
-	size, err := io.Copy(io.MultiWriter(dst, hasher), file)
-	if err != nil {
+	counter := &countingReader{r: io.TeeReader(file, hasher)}
+	if err := storage.Default().Put(c.Request.Context(), filePath, counter, header.Size, "application/octet-stream"); err != nil {
 		log.Printf("❌ Failed to save file %s: %v", filePath, err)
-		// Удаляем файл при ошибке
-		os.Remove(filePath)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   "failed to save file",
@@ -153,6 +130,7 @@ func (lc *LibissPosController) UploadFile(c *gin.Context) {
 		})
 		return
 	}
+	size := counter.n
 
 	checksum := hex.EncodeToString(hasher.Sum(nil))
 
@@ -190,7 +168,7 @@ func (lc *LibissPosController) UploadFile(c *gin.Context) {
 	if err := database.DB.Create(&libissFile).Error; err != nil {
 		log.Printf("❌ Failed to save file metadata: %v", err)
 		// Удаляем файл при ошибке
-		os.Remove(filePath)
+		storage.Default().Delete(c.Request.Context(), filePath)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   "failed to save file metadata",
@@ -200,6 +178,7 @@ func (lc *LibissPosController) UploadFile(c *gin.Context) {
 	}
 
 	log.Printf("✅ File uploaded successfully: %s (type: %s, version: %s)", filename, fileType, version)
+	services.NudgeReleasePatches()
 
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
@@ -236,6 +215,66 @@ func (lc *LibissPosController) ListFiles(c *gin.Context) {
 	})
 }
 
+// releaseETag ETag файла релиза: его SHA256 не меняется, пока не меняется содержимое
+func releaseETag(checksum string) string {
+	return `"` + checksum + `"`
+}
+
+// etagMatches проверяет заголовок If-None-Match (список ETag или *)
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// isNewDownload начинается ли скачивание с начала файла. Докачка (Range не с нуля)
+// и HEAD не считаются: иначе прерванное скачивание учитывалось бы несколько раз.
+func isNewDownload(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	rangeHeader := strings.TrimSpace(r.Header.Get("Range"))
+	return rangeHeader == "" || strings.HasPrefix(strings.ReplaceAll(rangeHeader, " ", ""), "bytes=0-")
+}
+
+// serveLibissPosFile отдает файл с поддержкой Range, ETag/If-None-Match и учетом скачиваний
+func serveLibissPosFile(c *gin.Context, libissFile *models.LibissPosFile) {
+	// Проверяем существование файла
+	if _, err := storage.Default().Stat(c.Request.Context(), libissFile.FilePath); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "file not found in storage",
+		})
+		return
+	}
+
+	etag := releaseETag(libissFile.ChecksumSHA256)
+	c.Header("ETag", etag)
+	c.Header("Accept-Ranges", "bytes")
+	c.Header("X-Checksum-SHA256", libissFile.ChecksumSHA256)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	// Увеличиваем счетчик скачиваний атомарно в БД: одновременные скачивания не теряются.
+	// UpdateColumn не меняет updated_at (от него зависит манифест).
+	if isNewDownload(c.Request) {
+		if err := database.DB.Model(&models.LibissPosFile{}).
+			Where("id = ?", libissFile.ID).
+			UpdateColumn("download_count", gorm.Expr("download_count + ?", 1)).Error; err != nil {
+			log.Printf("⚠️ Не удалось учесть скачивание %s: %v", libissFile.FileName, err)
+		}
+	}
+
+	// Отдаем файл (локально) или перенаправляем на временную ссылку (S3)
+	serveStorageObject(c, libissFile.FilePath, libissFile.OriginalName, "application/octet-stream")
+}
+
 // DownloadFile скачивает файл (требует аутентификации)
 func (lc *LibissPosController) DownloadFile(c *gin.Context) {
 	filename := c.Param("filename")
@@ -266,22 +305,7 @@ func (lc *LibissPosController) DownloadFile(c *gin.Context) {
 		return
 	}
 
-	// Проверяем существование файла
-	if _, err := os.Stat(libissFile.FilePath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"error":   "file not found on disk",
-		})
-		return
-	}
-
-	// Увеличиваем счетчик скачиваний
-	database.DB.Model(&libissFile).Update("download_count", libissFile.DownloadCount+1)
-
-	// Отправляем файл
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", libissFile.OriginalName))
-	c.Header("Content-Type", "application/octet-stream")
-	c.File(libissFile.FilePath)
+	serveLibissPosFile(c, &libissFile)
 }
 
 // PublicDownload скачивает файл публично (без аутентификации)
@@ -314,22 +338,7 @@ func (lc *LibissPosController) PublicDownload(c *gin.Context) {
 		return
 	}
 
-	// Проверяем существование файла
-	if _, err := os.Stat(libissFile.FilePath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"error":   "file not found on disk",
-		})
-		return
-	}
-
-	// Увеличиваем счетчик скачиваний
-	database.DB.Model(&libissFile).Update("download_count", libissFile.DownloadCount+1)
-
-	// Отправляем файл
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", libissFile.OriginalName))
-	c.Header("Content-Type", "application/octet-stream")
-	c.File(libissFile.FilePath)
+	serveLibissPosFile(c, &libissFile)
 }
 
 // DeleteFile удаляет файл (только для админов)
@@ -353,12 +362,18 @@ func (lc *LibissPosController) DeleteFile(c *gin.Context) {
 		return
 	}
 
-	// Удаляем файл с диска
-	if err := os.Remove(libissFile.FilePath); err != nil {
-		log.Printf("⚠️ Failed to delete file from disk: %v", err)
+	deletion := services.NewLibissPosFileDeletion(libissFile, models.ReleaseDeletionTriggerManual, "deleted by admin", releaseActorID(c))
+
+	// Удаляем файл из хранилища
+	if err := storage.Default().Delete(c.Request.Context(), libissFile.FilePath); err != nil {
+		log.Printf("⚠️ Failed to delete file from storage: %v", err)
+		deletion.FileError = err.Error()
 		// Продолжаем удаление записи из БД даже если файл не найден
 	}
 
+	// Патчи от этой версии и к ней больше не нужны
+	services.DeleteReleasePatches(c.Request.Context(), database.DB, libissFile.ID)
+
 	// Удаляем запись из БД
 	if err := database.DB.Delete(&libissFile).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -367,6 +382,7 @@ func (lc *LibissPosController) DeleteFile(c *gin.Context) {
 		})
 		return
 	}
+	services.RecordReleaseDeletion(database.DB, deletion)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -400,7 +416,8 @@ func (lc *LibissPosController) GetFileInfo(c *gin.Context) {
 	})
 }
 
-// GetLatestFile возвращает последний активный файл по типу и платформе
+// GetLatestFile возвращает последний активный файл по типу и платформе.
+// Необязательные deviceId и version - версия, установленная на кассе.
 func (lc *LibissPosController) GetLatestFile(c *gin.Context) {
 	fileTypeStr := c.Query("type")
 	platformStr := c.Query("platform")
@@ -447,9 +464,57 @@ func (lc *LibissPosController) GetLatestFile(c *gin.Context) {
 		return
 	}
 
+	// Касса с deviceId и version сообщает установленную версию: ее не удалит политика хранения
+	services.RecordLibissPosCheckIn(database.DB, string(libissFile.Type), string(libissFile.Platform),
+		c.Query("deviceId"), c.Query("version"), libissFile.Version, c.ClientIP(), config.GetConfig().UpdateCheckInLimit)
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    libissFile,
 	})
 }
 
+// GetManifest подписанный манифест активных публичных файлов платформы.
+// GET /libiss-pos/manifest?platform=windows[&type=full]
+// Клиент проверяет подпись ключом из /libiss-pos/signing-key, затем сверяет SHA256
+// скачанного файла с манифестом перед установкой.
+func (lc *LibissPosController) GetManifest(c *gin.Context) {
+	platform := c.Query("platform")
+	if platform == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "platform is required",
+		})
+		return
+	}
+
+	manifest, err := services.BuildLibissPosManifest(database.DB, platform, c.Query("type"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to fetch files",
+		})
+		return
+	}
+	signed, err := services.SignReleaseManifest(manifest)
+	if errors.Is(err, services.ErrReleaseSigningDisabled) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "release signing is not configured",
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Ошибка подписи манифеста libiss_pos: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to sign manifest",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    signed,
+	})
+}
@@ -439,9 +439,21 @@ func (lc *LicenseController) GetMyLicenses(c *gin.Context) {
 }
 
 // GetLicenses возвращает список всех лицензий (админ)
+// С параметром after возвращает страницу по курсору, без него - весь список.
 func (lc *LicenseController) GetLicenses(c *gin.Context) {
+	list, ok := parseListQuery(c, "shop", "user")
+	if !ok {
+		return
+	}
+
 	var licenses []models.License
-	query := database.DB.Preload("Shop").Preload("User")
+	query := database.DB.Model(&models.License{})
+	if list.Expands("shop", true) {
+		query = query.Preload("Shop")
+	}
+	if list.Expands("user", true) {
+		query = query.Preload("User")
+	}
 
 	// Фильтры
 	if shopID := c.Query("shopId"); shopID != "" {
@@ -454,7 +466,13 @@ func (lc *LicenseController) GetLicenses(c *gin.Context) {
 		query = query.Where("subscription_status = ?", status)
 	}
 
-	if err := query.Order("created_at DESC").Find(&licenses).Error; err != nil {
+	if list.CursorMode {
+		query = models.ApplyCursor(query, list.After, "created_at", "id", list.Limit)
+	} else {
+		query = query.Order("created_at DESC")
+	}
+
+	if err := query.Find(&licenses).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   "Failed to fetch licenses",
@@ -462,11 +480,23 @@ func (lc *LicenseController) GetLicenses(c *gin.Context) {
 		return
 	}
 
+	var cursor models.CursorInfo
+	if list.CursorMode {
+		licenses, cursor = models.CursorPage(licenses, list.Limit, func(l *models.License) models.Cursor {
+			return models.Cursor{Time: l.CreatedAt, ID: l.ID}
+		})
+	}
+
 	responses := make([]models.LicenseResponse, len(licenses))
 	for i, license := range licenses {
 		responses[i] = license.ToResponse()
 	}
 
+	if list.CursorMode {
+		c.JSON(http.StatusOK, models.CursorSuccessResponse(responses, cursor))
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mm-api/mm-api/models"
+	"gorm.io/gorm"
+)
+
+// listQuery общие параметры списков: страница или курсор и раскрываемые связи.
+// Курсорный режим включается параметром after (пустой after= - первая страница)
+// и не выполняет COUNT; без after работает прежняя пагинация page/limit.
+type listQuery struct {
+	Page       int
+	Limit      int
+	CursorMode bool
+	After      *models.Cursor
+
+	expand    map[string]bool
+	expandSet bool
+}
+
+// parseListQuery разбирает page/limit/after/expand. allowedExpand - допустимые
+// значения expand для списка. При ошибке отвечает 400 и возвращает false.
+func parseListQuery(c *gin.Context, allowedExpand ...string) (listQuery, bool) {
+	query := listQuery{}
+
+	query.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	query.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	if after, ok := c.GetQuery("after"); ok {
+		cursor, err := models.DecodeCursor(after)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid cursor"))
+			return query, false
+		}
+		query.CursorMode = true
+		query.After = cursor
+	}
+
+	if raw, ok := c.GetQuery("expand"); ok {
+		query.expandSet = true
+		query.expand = make(map[string]bool)
+		allowed := make(map[string]bool, len(allowedExpand))
+		for _, name := range allowedExpand {
+			allowed[name] = true
+		}
+		for _, name := range strings.Split(raw, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			if !allowed[name] {
+				c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+					models.ErrValidationError,
+					fmt.Sprintf("Unknown expand value: %s", name),
+					gin.H{"allowed": allowedExpand},
+				))
+				return query, false
+			}
+			query.expand[name] = true
+		}
+	}
+
+	return query, true
+}
+
+// Expands сообщает, нужно ли загружать связь. Без параметра expand
+// загружаются связи по умолчанию (как раньше), с ним - только перечисленные.
+func (q listQuery) Expands(relation string, byDefault bool) bool {
+	if !q.expandSet {
+		return byDefault
+	}
+	return q.expand[relation]
+}
+
+// Paginate применяет курсор (сортировка по времени и id) или OFFSET.
+// В режиме OFFSET сортировку задает сам обработчик.
+func (q listQuery) Paginate(query *gorm.DB, timeColumn, idColumn string) *gorm.DB {
+	if q.CursorMode {
+		return models.ApplyCursor(query, q.After, timeColumn, idColumn, q.Limit)
+	}
+	return query.Offset((q.Page - 1) * q.Limit).Limit(q.Limit)
+}
+
+// Pagination формирует данные пагинации для режима page/limit
+func (q listQuery) Pagination(total int64) models.PaginationInfo {
+	return models.PaginationInfo{
+		Page:       q.Page,
+		Limit:      q.Limit,
+		Total:      int(total),
+		TotalPages: int((total + int64(q.Limit) - 1) / int64(q.Limit)),
+	}
+}
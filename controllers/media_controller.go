@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/config"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"gorm.io/gorm"
+)
+
+// MediaController реестр загруженных файлов и сборка мусора (админ)
+type MediaController struct{}
+
+func mediaAssetCursor(a *models.MediaAsset) models.Cursor {
+	return models.Cursor{Time: a.CreatedAt, ID: a.ID}
+}
+
+// uploaderID ID текущего пользователя для реестра медиа (nil без аутентификации)
+func uploaderID(c *gin.Context) *uuid.UUID {
+	if value, exists := c.Get("user"); exists {
+		if user, ok := value.(models.User); ok {
+			return &user.ID
+		}
+	}
+	return nil
+}
+
+// releaseReplacedMedia освобождает файлы, которые были у сущности раньше и не остались после изменения
+func releaseReplacedMedia(oldURLs, newURLs []string) {
+	kept := make(map[string]bool, len(newURLs))
+	for _, url := range newURLs {
+		kept[url] = true
+	}
+	var released []string
+	for _, url := range oldURLs {
+		if !kept[url] {
+			released = append(released, url)
+		}
+	}
+	services.ReleaseMedia(database.DB, released...)
+}
+
+// mediaSweepConfig настройки сборщика мусора из конфигурации
+func mediaSweepConfig() services.MediaSweepConfig {
+	cfg := config.GetConfig()
+	return services.MediaSweepConfig{
+		GracePeriod: cfg.GetMediaGCGracePeriod(),
+		DryRun:      cfg.MediaGCDryRun,
+		Discover:    cfg.MediaGCDiscover,
+		Folders:     cfg.GetMediaGCFolders(),
+	}
+}
+
+// GetMediaAssets возвращает файлы реестра.
+// Фильтры: status (orphaned, referenced), folder, provider.
+func (mc *MediaController) GetMediaAssets(c *gin.Context) {
+	list, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&models.MediaAsset{})
+	switch c.Query("status") {
+	case "":
+	case "orphaned":
+		query = query.Where("reference_count = 0")
+	case "referenced":
+		query = query.Where("reference_count > 0")
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Status must be orphaned or referenced"))
+		return
+	}
+	if folder := c.Query("folder"); folder != "" {
+		query = query.Where("folder = ?", folder)
+	}
+	if provider := c.Query("provider"); provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+
+	var total int64
+	if !list.CursorMode {
+		query.Count(&total)
+		query = query.Order("media_assets.created_at DESC, media_assets.id DESC")
+	}
+
+	var assets []models.MediaAsset
+	if err := list.Paginate(query, "media_assets.created_at", "media_assets.id").Find(&assets).Error; err != nil {
+		log.Printf("❌ Ошибка получения реестра медиа: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to fetch media assets"))
+		return
+	}
+
+	if list.CursorMode {
+		assets, cursor := models.CursorPage(assets, list.Limit, mediaAssetCursor)
+		c.JSON(http.StatusOK, models.CursorSuccessResponse(assets, cursor))
+		return
+	}
+	c.JSON(http.StatusOK, models.PaginatedSuccessResponse(assets, list.Pagination(total)))
+}
+
+// GetMediaAsset возвращает файл со ссылающимися на него сущностями
+func (mc *MediaController) GetMediaAsset(c *gin.Context) {
+	assetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid media asset ID"))
+		return
+	}
+
+	var asset models.MediaAsset
+	if err := database.DB.Preload("References").First(&asset, "id = ?", assetID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(models.ErrNotFound, "Media asset not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to fetch media asset"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(asset))
+}
+
+// runMediaSweep запускает проход сборщика мусора и отвечает отчетом
+func runMediaSweep(c *gin.Context, cfg services.MediaSweepConfig) {
+	report, err := services.SweepOrphanedMedia(c.Request.Context(), database.DB, cfg)
+	if errors.Is(err, services.ErrMediaSweepInProgress) {
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(models.ErrConflict, "Media sweep is already running"))
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Ошибка сборки мусора медиа: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Media sweep failed", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(report))
+}
+
+// GetOrphanReport отчет dry-run: какие файлы будут удалены при следующем проходе.
+// grace_period (например 72h) позволяет посмотреть отчет для другого периода ожидания.
+func (mc *MediaController) GetOrphanReport(c *gin.Context) {
+	cfg := mediaSweepConfig()
+	cfg.DryRun = true
+	if raw := c.Query("grace_period"); raw != "" {
+		period, err := time.ParseDuration(raw)
+		if err != nil || period <= 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid grace_period"))
+			return
+		}
+		cfg.GracePeriod = period
+	}
+	runMediaSweep(c, cfg)
+}
+
+// SweepMedia запускает сборку мусора немедленно. Режим по умолчанию - MEDIA_GC_DRY_RUN;
+// {"dryRun": false} удаляет файлы, {"dryRun": true} - только отчет.
+func (mc *MediaController) SweepMedia(c *gin.Context) {
+	var req struct {
+		DryRun *bool `json:"dryRun"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid request data", err.Error()))
+			return
+		}
+	}
+
+	cfg := mediaSweepConfig()
+	if req.DryRun != nil {
+		cfg.DryRun = *req.DryRun
+	}
+	runMediaSweep(c, cfg)
+}
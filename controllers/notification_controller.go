@@ -1,293 +1,421 @@
-package controllers
-
-import (
-	"net/http"
-	"strconv"
-
-	"github.com/mm-api/mm-api/database"
-	"github.com/mm-api/mm-api/middleware"
-	"github.com/mm-api/mm-api/models"
-	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
-	"gorm.io/gorm"
-)
-
-// NotificationController обрабатывает запросы уведомлений
-type NotificationController struct{}
-
-// GetNotifications возвращает уведомления пользователя с пагинацией
-func (nc *NotificationController) GetNotifications(c *gin.Context) {
-	user, exists := middleware.GetCurrentUser(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
-			models.ErrAuthRequired,
-			"User not found",
-		))
-		return
-	}
-
-	query := database.DB.Model(&models.Notification{}).Where("user_id = ?", user.ID)
-
-	// Фильтрация по типу
-	if notificationType := c.Query("type"); notificationType != "" {
-		query = query.Where("type = ?", notificationType)
-	}
-
-	// Фильтрация по прочитанности
-	if isReadStr := c.Query("isRead"); isReadStr != "" {
-		if isRead, err := strconv.ParseBool(isReadStr); err == nil {
-			query = query.Where("is_read = ?", isRead)
-		}
-	}
-
-	// Пагинация
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 20
-	}
-
-	offset := (page - 1) * limit
-
-	// Получаем общее количество
-	var total int64
-	query.Count(&total)
-
-	// Получаем уведомления
-	var notifications []models.Notification
-	if err := query.Order("timestamp DESC").Offset(offset).Limit(limit).Find(&notifications).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to fetch notifications",
-		))
-		return
-	}
-
-	// Преобразуем в response
-	notificationResponses := make([]models.NotificationResponse, len(notifications))
-	for i, notification := range notifications {
-		notificationResponses[i] = notification.ToResponse()
-	}
-
-	// Вычисляем пагинацию
-	totalPages := (int(total) + limit - 1) / limit
-	pagination := models.PaginationInfo{
-		Page:       page,
-		Limit:      limit,
-		Total:      int(total),
-		TotalPages: totalPages,
-	}
-
-	c.JSON(http.StatusOK, models.PaginatedSuccessResponse(notificationResponses, pagination))
-}
-
-// MarkAsRead отмечает уведомление как прочитанное
-func (nc *NotificationController) MarkAsRead(c *gin.Context) {
-	user, exists := middleware.GetCurrentUser(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
-			models.ErrAuthRequired,
-			"User not found",
-		))
-		return
-	}
-
-	id := c.Param("id")
-	notificationID, err := uuid.Parse(id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid notification ID",
-		))
-		return
-	}
-
-	var notification models.Notification
-	if err := database.DB.Where("id = ? AND user_id = ?", notificationID, user.ID).First(&notification).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
-				models.ErrNotFound,
-				"Notification not found",
-			))
-		} else {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-				models.ErrInternalError,
-				"Database error",
-			))
-		}
-		return
-	}
-
-	if !notification.IsRead {
-		notification.IsRead = true
-		if err := database.DB.Save(&notification).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-				models.ErrInternalError,
-				"Failed to mark notification as read",
-			))
-			return
-		}
-	}
-
-	c.JSON(http.StatusOK, models.SuccessResponse(
-		notification.ToResponse(),
-		"Notification marked as read",
-	))
-}
-
-// MarkAllAsRead отмечает все уведомления как прочитанные
-func (nc *NotificationController) MarkAllAsRead(c *gin.Context) {
-	user, exists := middleware.GetCurrentUser(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
-			models.ErrAuthRequired,
-			"User not found",
-		))
-		return
-	}
-
-	if err := database.DB.Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", user.ID, false).Update("is_read", true).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to mark notifications as read",
-		))
-		return
-	}
-
-	c.JSON(http.StatusOK, models.SuccessResponse(
-		nil,
-		"All notifications marked as read",
-	))
-}
-
-// DeleteNotification удаляет уведомление
-func (nc *NotificationController) DeleteNotification(c *gin.Context) {
-	user, exists := middleware.GetCurrentUser(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
-			models.ErrAuthRequired,
-			"User not found",
-		))
-		return
-	}
-
-	id := c.Param("id")
-	notificationID, err := uuid.Parse(id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid notification ID",
-		))
-		return
-	}
-
-	result := database.DB.Where("id = ? AND user_id = ?", notificationID, user.ID).Delete(&models.Notification{})
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to delete notification",
-		))
-		return
-	}
-
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
-			models.ErrNotFound,
-			"Notification not found",
-		))
-		return
-	}
-
-	c.JSON(http.StatusOK, models.SuccessResponse(
-		nil,
-		"Notification deleted successfully",
-	))
-}
-
-// CreateNotification создает новое уведомление (для системы/админов)
-func (nc *NotificationController) CreateNotification(c *gin.Context) {
-	var req models.NotificationRequest
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid request data",
-			err.Error(),
-		))
-		return
-	}
-
-	// Проверяем, существует ли пользователь
-	var user models.User
-	if err := database.DB.First(&user, "id = ?", req.UserID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
-				models.ErrNotFound,
-				"User not found",
-			))
-		} else {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-				models.ErrInternalError,
-				"Database error",
-			))
-		}
-		return
-	}
-
-	notification := models.Notification{
-		UserID:    req.UserID,
-		Title:     req.Title,
-		Body:      req.Body,
-		Type:      req.Type,
-		ImageURL:  req.ImageURL,
-		ActionURL: req.ActionURL,
-	}
-
-	// Сериализуем дополнительные данные в JSON если есть
-	if req.Data != nil {
-		// Здесь можно добавить JSON маршаллинг, пока оставляем пустым
-		notification.Data = ""
-	}
-
-	if err := database.DB.Create(&notification).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to create notification",
-		))
-		return
-	}
-
-	c.JSON(http.StatusCreated, models.SuccessResponse(
-		notification.ToResponse(),
-		"Notification created successfully",
-	))
-}
-
-// GetUnreadCount возвращает количество непрочитанных уведомлений
-func (nc *NotificationController) GetUnreadCount(c *gin.Context) {
-	user, exists := middleware.GetCurrentUser(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
-			models.ErrAuthRequired,
-			"User not found",
-		))
-		return
-	}
-
-	var count int64
-	if err := database.DB.Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", user.ID, false).Count(&count).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Failed to get unread count",
-		))
-		return
-	}
-
-	c.JSON(http.StatusOK, models.SuccessResponse(map[string]int64{
-		"unreadCount": count,
-	}))
-}
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/middleware"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NotificationController обрабатывает запросы уведомлений
+type NotificationController struct{}
+
+// GetNotifications возвращает уведомления пользователя с пагинацией
+func (nc *NotificationController) GetNotifications(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+
+	query := database.DB.Model(&models.Notification{}).Where("user_id = ?", user.ID)
+
+	// Фильтрация по типу
+	if notificationType := c.Query("type"); notificationType != "" {
+		query = query.Where("type = ?", notificationType)
+	}
+
+	// Фильтрация по прочитанности
+	if isReadStr := c.Query("isRead"); isReadStr != "" {
+		if isRead, err := strconv.ParseBool(isReadStr); err == nil {
+			query = query.Where("is_read = ?", isRead)
+		}
+	}
+
+	// Пагинация: курсор (after) или страница
+	list, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	// Получаем общее количество (только для постраничного режима)
+	var total int64
+	if !list.CursorMode {
+		query.Count(&total)
+		query = query.Order("timestamp DESC")
+	}
+
+	// Получаем уведомления
+	var notifications []models.Notification
+	if err := list.Paginate(query, "timestamp", "id").Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to fetch notifications",
+		))
+		return
+	}
+
+	if list.CursorMode {
+		var cursor models.CursorInfo
+		notifications, cursor = models.CursorPage(notifications, list.Limit, func(n *models.Notification) models.Cursor {
+			return models.Cursor{Time: n.Timestamp, ID: n.ID}
+		})
+		c.JSON(http.StatusOK, models.CursorSuccessResponse(notificationResponsesOf(notifications), cursor))
+		return
+	}
+
+	// Вычисляем пагинацию
+	totalPages := (int(total) + list.Limit - 1) / list.Limit
+	pagination := models.PaginationInfo{
+		Page:       list.Page,
+		Limit:      list.Limit,
+		Total:      int(total),
+		TotalPages: totalPages,
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedSuccessResponse(notificationResponsesOf(notifications), pagination))
+}
+
+// notificationResponsesOf преобразует уведомления в response
+func notificationResponsesOf(notifications []models.Notification) []models.NotificationResponse {
+	responses := make([]models.NotificationResponse, len(notifications))
+	for i, notification := range notifications {
+		responses[i] = notification.ToResponse()
+	}
+	return responses
+}
+
+// MarkAsRead отмечает уведомление как прочитанное
+func (nc *NotificationController) MarkAsRead(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+
+	id := c.Param("id")
+	notificationID, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid notification ID",
+		))
+		return
+	}
+
+	var notification models.Notification
+	if err := database.DB.Where("id = ? AND user_id = ?", notificationID, user.ID).First(&notification).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+				models.ErrNotFound,
+				"Notification not found",
+			))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Database error",
+			))
+		}
+		return
+	}
+
+	if !notification.IsRead {
+		notification.IsRead = true
+		if err := database.DB.Save(&notification).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Failed to mark notification as read",
+			))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		notification.ToResponse(),
+		"Notification marked as read",
+	))
+}
+
+// MarkAllAsRead отмечает все уведомления как прочитанные
+func (nc *NotificationController) MarkAllAsRead(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+
+	if err := database.DB.Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", user.ID, false).Update("is_read", true).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to mark notifications as read",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		nil,
+		"All notifications marked as read",
+	))
+}
+
+// DeleteNotification удаляет уведомление
+func (nc *NotificationController) DeleteNotification(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+
+	id := c.Param("id")
+	notificationID, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid notification ID",
+		))
+		return
+	}
+
+	result := database.DB.Where("id = ? AND user_id = ?", notificationID, user.ID).Delete(&models.Notification{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to delete notification",
+		))
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+			models.ErrNotFound,
+			"Notification not found",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		nil,
+		"Notification deleted successfully",
+	))
+}
+
+// CreateNotification создает новое уведомление (для системы/админов)
+func (nc *NotificationController) CreateNotification(c *gin.Context) {
+	var req models.NotificationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	// Проверяем, существует ли пользователь
+	var user models.User
+	if err := database.DB.First(&user, "id = ?", req.UserID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+				models.ErrNotFound,
+				"User not found",
+			))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Database error",
+			))
+		}
+		return
+	}
+
+	notification := models.Notification{
+		UserID:    req.UserID,
+		Title:     req.Title,
+		Body:      req.Body,
+		Type:      req.Type,
+		ImageURL:  req.ImageURL,
+		ActionURL: req.ActionURL,
+	}
+
+	// Сериализуем дополнительные данные в JSON если есть
+	if req.Data != nil {
+		// Здесь можно добавить JSON маршаллинг, пока оставляем пустым
+		notification.Data = ""
+	}
+
+	// Уведомление отправляется через диспетчер с учетом настроек пользователя
+	var result services.DispatchResult
+	if err := services.WithRealtimeEvents(database.DB, func(tx *gorm.DB) error {
+		var err error
+		result, err = services.DispatchNotification(tx, &notification)
+		return err
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to create notification",
+		))
+		return
+	}
+
+	// Канал in_app отключен пользователем - уведомление не сохранено, ID нет
+	if !result.Stored {
+		status, message := http.StatusOK, "Notification suppressed by user settings"
+		if result.Queued {
+			status, message = http.StatusAccepted, "Notification not stored (in-app disabled by user settings), push queued"
+		}
+		c.JSON(status, models.SuccessResponse(gin.H{
+			"stored":     false,
+			"queued":     result.Queued,
+			"deferUntil": result.DeferUntil,
+		}, message))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse(
+		notification.ToResponse(),
+		"Notification created successfully",
+	))
+}
+
+// GetUnreadCount возвращает количество непрочитанных уведомлений
+func (nc *NotificationController) GetUnreadCount(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+
+	var count int64
+	if err := database.DB.Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", user.ID, false).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to get unread count",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(map[string]int64{
+		"unreadCount": count,
+	}))
+}
+
+// GetOutbox возвращает записи очереди push-уведомлений с историей доставки (для админов).
+// По умолчанию показывает неудачные доставки (status=failed).
+func (nc *NotificationController) GetOutbox(c *gin.Context) {
+	status := c.DefaultQuery("status", string(models.OutboxStatusFailed))
+
+	query := database.DB.Model(&models.NotificationOutbox{})
+	if status != "all" {
+		query = query.Where("status = ?", status)
+	}
+	if userIDStr := c.Query("userId"); userIDStr != "" {
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+				models.ErrValidationError,
+				"Invalid user ID",
+			))
+			return
+		}
+		query = query.Where("user_id = ?", userID)
+	}
+
+	// Пагинация
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	var total int64
+	query.Count(&total)
+
+	var entries []models.NotificationOutbox
+	if err := query.Preload("User").
+		Preload("Deliveries", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Order("updated_at DESC").Offset(offset).Limit(limit).
+		Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to fetch notification outbox",
+		))
+		return
+	}
+
+	responses := make([]models.NotificationOutboxResponse, len(entries))
+	for i, entry := range entries {
+		responses[i] = entry.ToResponse()
+	}
+
+	totalPages := (int(total) + limit - 1) / limit
+	pagination := models.PaginationInfo{
+		Page:       page,
+		Limit:      limit,
+		Total:      int(total),
+		TotalPages: totalPages,
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedSuccessResponse(responses, pagination))
+}
+
+// RetryOutboxEntry возвращает неудачную запись очереди в обработку (для админов)
+func (nc *NotificationController) RetryOutboxEntry(c *gin.Context) {
+	entryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid outbox entry ID",
+		))
+		return
+	}
+
+	if err := services.RetryOutboxEntry(entryID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+				models.ErrNotFound,
+				"Failed or skipped outbox entry not found",
+			))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Failed to retry outbox entry",
+			))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		nil,
+		"Outbox entry queued for retry",
+	))
+}
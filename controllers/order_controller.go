@@ -96,7 +96,8 @@ func (oc *OrderController) CreateOrder(c *gin.Context) {
 	}
 
 	var createdOrder models.Order
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
+	err := services.WithRealtimeEvents(database.DB, func(tx *gorm.DB) error {
+		var items []models.OrderItem
 		// Создаём адрес (гостевой, простой, из одной строки shipping_addr)
 		addr := models.Address{
 			UserID:    currentUserID,
@@ -172,6 +173,12 @@ func (oc *OrderController) CreateOrder(c *gin.Context) {
 			if err := tx.Create(&item).Error; err != nil {
 				return err
 			}
+			items = append(items, item)
+		}
+
+		// Уведомления владельцам магазинов ставятся в очередь в той же транзакции
+		if err := oc.notifyShopOwnersAboutNewOrder(tx, order, items); err != nil {
+			return err
 		}
 
 		createdOrder = order
@@ -214,9 +221,6 @@ func (oc *OrderController) CreateOrder(c *gin.Context) {
 
 	log.Printf("✅ Заказ успешно загружен с %d позициями", len(orderItems))
 
-	// Создаем уведомления для владельцев магазинов
-	go oc.notifyShopOwnersAboutNewOrder(createdOrder, orderItems)
-
 	c.JSON(http.StatusOK, models.StandardResponse{
 		Success: true,
 		Data:    createdOrder.ToResponse(),
@@ -225,14 +229,15 @@ func (oc *OrderController) CreateOrder(c *gin.Context) {
 }
 
 // notifyShopOwnersAboutNewOrder создает уведомления для владельцев магазинов о новом заказе
-func (oc *OrderController) notifyShopOwnersAboutNewOrder(order models.Order, orderItems []models.OrderItem) {
+// и ставит push-уведомления в очередь в рамках транзакции заказа
+func (oc *OrderController) notifyShopOwnersAboutNewOrder(tx *gorm.DB, order models.Order, orderItems []models.OrderItem) error {
 	// Собираем уникальные ID владельцев магазинов (из shop_id или shop_owner_id для обратной совместимости)
 	shopOwnerIDs := make(map[uuid.UUID]bool)
 	for _, item := range orderItems {
 		if item.ShopID != nil {
 			// Находим owner_id магазина
 			var shop models.Shop
-			if err := database.DB.Where("id = ?", item.ShopID).First(&shop).Error; err == nil {
+			if err := tx.Where("id = ?", item.ShopID).First(&shop).Error; err == nil {
 				shopOwnerIDs[shop.OwnerID] = true
 			}
 		} else if item.ShopOwnerID != nil {
@@ -245,7 +250,7 @@ func (oc *OrderController) notifyShopOwnersAboutNewOrder(order models.Order, ord
 	for shopOwnerID := range shopOwnerIDs {
 		// Проверяем, что владелец магазина существует и имеет роль shop_owner
 		var shopOwner models.User
-		if err := database.DB.Preload("Role").First(&shopOwner, "id = ?", shopOwnerID).Error; err != nil {
+		if err := tx.Preload("Role").First(&shopOwner, "id = ?", shopOwnerID).Error; err != nil {
 			log.Printf("⚠️ Владелец магазина %s не найден: %v", shopOwnerID, err)
 			continue
 		}
@@ -256,7 +261,7 @@ func (oc *OrderController) notifyShopOwnersAboutNewOrder(order models.Order, ord
 			continue
 		}
 
-		// Создаем уведомление в БД
+		// Создаем уведомление через диспетчер (учитывает настройки уведомлений владельца)
 		// ActionURL будет работать как deep link - при клике откроется страница заказа
 		// Если токен истек, пользователь будет перенаправлен на логин
 		notification := models.Notification{
@@ -267,56 +272,34 @@ func (oc *OrderController) notifyShopOwnersAboutNewOrder(order models.Order, ord
 			ActionURL: fmt.Sprintf("/admin#orders?orderId=%s", order.ID.String()), // Deep link с параметром
 		}
 
-		if err := database.DB.Create(&notification).Error; err != nil {
+		if _, err := services.DispatchNotification(tx, &notification); err != nil {
 			log.Printf("❌ Ошибка создания уведомления для владельца магазина %s: %v", shopOwnerID, err)
-		} else {
-			log.Printf("✅ Уведомление создано для владельца магазина %s о заказе %s", shopOwnerID, order.ID)
-			
-			// Отправляем push-уведомление на все активные устройства пользователя
-			go oc.sendPushNotification(shopOwnerID, notification.Title, notification.Body, notification.ActionURL)
-		}
-	}
-}
-
-// sendPushNotification отправляет push-уведомление на все активные устройства пользователя
-func (oc *OrderController) sendPushNotification(userID uuid.UUID, title, body, actionURL string) {
-	// Получаем все активные токены устройств пользователя
-	var deviceTokens []models.DeviceToken
-	if err := database.DB.Where("user_id = ? AND is_active = ?", userID, true).Find(&deviceTokens).Error; err != nil {
-		log.Printf("⚠️ Ошибка получения токенов устройств для пользователя %s: %v", userID, err)
-		return
-	}
-
-	if len(deviceTokens) == 0 {
-		log.Printf("ℹ️ Нет активных токенов устройств для пользователя %s", userID)
-		return
-	}
-
-	// Группируем токены по платформам
-	var fcmTokens []string
-	for _, token := range deviceTokens {
-		// FCM работает для Android, Web и iOS (через FCM)
-		if token.Platform == "android" || token.Platform == "web" || token.Platform == "ios" {
-			fcmTokens = append(fcmTokens, token.Token)
+			return err
 		}
+		log.Printf("✅ Уведомление для владельца магазина %s о заказе %s обработано", shopOwnerID, order.ID)
+
+		// Событие для открытой панели владельца (отправится после фиксации транзакции)
+		services.PublishRealtime(tx, shopOwnerID, services.RealtimeEventShopOrderCreated, gin.H{
+			"orderId":     order.ID,
+			"status":      order.Status,
+			"totalAmount": order.TotalAmount,
+			"itemsCount":  len(orderItems),
+			"createdAt":   order.CreatedAt,
+		})
 	}
 
-	if len(fcmTokens) == 0 {
-		log.Printf("ℹ️ Нет FCM токенов для отправки push-уведомления пользователю %s", userID)
-		return
-	}
+	return nil
+}
 
-	// Отправляем через FCM
-	fcmService := services.GetFCMService()
-	if fcmService != nil {
-		if err := fcmService.SendPushNotificationToMultiple(fcmTokens, title, body, actionURL); err != nil {
-			log.Printf("❌ Ошибка отправки push-уведомления: %v", err)
-		} else {
-			log.Printf("✅ Push-уведомление отправлено на %d устройств пользователя %s", len(fcmTokens), userID)
-		}
-	} else {
-		log.Printf("⚠️ FCM Service не инициализирован, push-уведомление не отправлено")
-	}
+// publishOrderStatus отправляет покупателю событие об изменении статуса заказа
+func publishOrderStatus(order *models.Order) {
+	services.PublishRealtime(nil, order.UserID, services.RealtimeEventOrderStatus, gin.H{
+		"orderId":     order.ID,
+		"status":      order.Status,
+		"confirmedAt": order.ConfirmedAt,
+		"cancelledAt": order.CancelledAt,
+		"updatedAt":   order.UpdatedAt,
+	})
 }
 
 // GetMyOrders - список заказов текущего пользователя
@@ -333,9 +316,10 @@ func (oc *OrderController) GetMyOrders(c *gin.Context) {
 
 	status := c.Query("status")
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset := (page - 1) * limit
+	list, ok := parseListQuery(c, "items")
+	if !ok {
+		return
+	}
 
 	query := database.DB.Model(&models.Order{}).Where("user_id = ?", currentUserID)
 	if status != "" {
@@ -349,10 +333,17 @@ func (oc *OrderController) GetMyOrders(c *gin.Context) {
 	}
 
 	var total int64
-	query.Count(&total)
+	if !list.CursorMode {
+		query.Count(&total)
+		query = query.Order("created_at DESC")
+	}
+
+	if list.Expands("items", true) {
+		query = query.Preload("OrderItems").Preload("OrderItems.Variation")
+	}
 
 	var orders []models.Order
-	if err := query.Preload("OrderItems").Preload("OrderItems.Variation").Order("created_at DESC").Offset(offset).Limit(limit).Find(&orders).Error; err != nil {
+	if err := list.Paginate(query, "created_at", "id").Find(&orders).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
 			models.ErrInternalError,
 			"Ошибка при получении заказов",
@@ -360,26 +351,42 @@ func (oc *OrderController) GetMyOrders(c *gin.Context) {
 		return
 	}
 
-	var orderResponses []models.OrderResponse
-	for _, order := range orders {
-		orderResponses = append(orderResponses, order.ToResponse())
+	if list.CursorMode {
+		var cursor models.CursorInfo
+		orders, cursor = models.CursorPage(orders, list.Limit, orderCursor)
+		c.JSON(http.StatusOK, models.CursorSuccessResponse(orderResponsesOf(orders), cursor, "Заказы получены успешно"))
+		return
 	}
 
 	c.JSON(http.StatusOK, models.StandardResponse{
 		Success: true,
 		Data: gin.H{
-			"orders": orderResponses,
+			"orders": orderResponsesOf(orders),
 			"pagination": models.PaginationInfo{
-				Page:       page,
-				Limit:      limit,
+				Page:       list.Page,
+				Limit:      list.Limit,
 				Total:      int(total),
-				TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+				TotalPages: int((total + int64(list.Limit) - 1) / int64(list.Limit)),
 			},
 		},
 		Message: "Заказы получены успешно",
 	})
 }
 
+// orderCursor курсор списка заказов (created_at, id)
+func orderCursor(order *models.Order) models.Cursor {
+	return models.Cursor{Time: order.CreatedAt, ID: order.ID}
+}
+
+// orderResponsesOf преобразует заказы в ответы
+func orderResponsesOf(orders []models.Order) []models.OrderResponse {
+	responses := make([]models.OrderResponse, len(orders))
+	for i, order := range orders {
+		responses[i] = order.ToResponse()
+	}
+	return responses
+}
+
 // GetMyOrder - детали заказа текущего пользователя
 func (oc *OrderController) GetMyOrder(c *gin.Context) {
 	userIDValue, ok := c.Get("userID")
@@ -459,6 +466,8 @@ func (oc *OrderController) CancelMyOrder(c *gin.Context) {
 		return
 	}
 
+	publishOrderStatus(&order)
+
 	c.JSON(http.StatusOK, models.StandardResponse{
 		Success: true,
 		Data:    order.ToResponse(),
@@ -632,9 +641,11 @@ func (oc *OrderController) CreateGuestOrder(c *gin.Context) {
 		currency = "TJS"
 	}
 
-	// Создаем или находим пользователя по номеру телефона
+	// Создаем или находим гостя по номеру телефона.
+	// Зарегистрированные аккаунты с тем же телефоном не используются:
+	// заказ попадет в них только после подтверждения телефона и объединения.
 	var user models.User
-	err := database.DB.Where("phone = ?", req.GuestPhone).First(&user).Error
+	err := database.DB.Where("phone IN ? AND is_guest = ? AND is_active = ?", phoneVariants(req.GuestPhone), true, true).First(&user).Error
 	if err == gorm.ErrRecordNotFound {
 		// Создаем нового пользователя автоматически
 		user = models.User{
@@ -673,7 +684,8 @@ func (oc *OrderController) CreateGuestOrder(c *gin.Context) {
 	}
 
 	var createdOrder models.Order
-	err = database.DB.Transaction(func(tx *gorm.DB) error {
+	err = services.WithRealtimeEvents(database.DB, func(tx *gorm.DB) error {
+		var items []models.OrderItem
 		// Создаём адрес для гостя из строки shipping_addr
 		addr := models.Address{
 			UserID:    user.ID,
@@ -754,6 +766,12 @@ func (oc *OrderController) CreateGuestOrder(c *gin.Context) {
 			if err := tx.Create(&item).Error; err != nil {
 				return err
 			}
+			items = append(items, item)
+		}
+
+		// Уведомления владельцам магазинов ставятся в очередь в той же транзакции
+		if err := oc.notifyShopOwnersAboutNewOrder(tx, order, items); err != nil {
+			return err
 		}
 
 		createdOrder = order
@@ -796,9 +814,6 @@ func (oc *OrderController) CreateGuestOrder(c *gin.Context) {
 
 	log.Printf("✅ Заказ успешно загружен с %d позициями", len(orderItems))
 
-	// Создаем уведомления для владельцев магазинов
-	go oc.notifyShopOwnersAboutNewOrder(createdOrder, orderItems)
-
 	c.JSON(http.StatusOK, models.StandardResponse{
 		Success: true,
 		Data:    createdOrder.ToResponse(),
@@ -1077,6 +1092,8 @@ func (oc *OrderController) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
+	publishOrderStatus(&order)
+
 	c.JSON(http.StatusOK, models.StandardResponse{
 		Success: true,
 		Data:    order.ToAdminResponse(),
@@ -1117,6 +1134,8 @@ func (oc *OrderController) ConfirmOrder(c *gin.Context) {
 		return
 	}
 
+	publishOrderStatus(&order)
+
 	c.JSON(http.StatusOK, models.StandardResponse{
 		Success: true,
 		Data:    order.ToAdminResponse(),
@@ -1157,6 +1176,8 @@ func (oc *OrderController) RejectOrder(c *gin.Context) {
 		return
 	}
 
+	publishOrderStatus(&order)
+
 	c.JSON(http.StatusOK, models.StandardResponse{
 		Success: true,
 		Data:    order.ToAdminResponse(),
@@ -1220,10 +1241,11 @@ func (oc *OrderController) GetShopOrders(c *gin.Context) {
 	// Логируем информацию о пользователе
 	log.Printf("🔍 GetShopOrders вызван пользователем: %s (Role: %s)", currentUser.ID, currentUser.Role.Name)
 
-	// Параметры пагинации
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset := (page - 1) * limit
+	// Параметры пагинации: курсор (after) или страница
+	list, ok := parseListQuery(c, "items", "user")
+	if !ok {
+		return
+	}
 
 	// Если админ - показываем все заказы, если владелец магазина - только заказы с его товарами
 	query := database.DB.Model(&models.Order{})
@@ -1248,15 +1270,22 @@ func (oc *OrderController) GetShopOrders(c *gin.Context) {
 		log.Printf("👑 Админ/супер-админ - показываем все заказы")
 	}
 
-	// Подсчет общего количества
-	query.Count(&total)
-	log.Printf("📊 Найдено заказов: %d", total)
+	// Подсчет общего количества (только для постраничного режима)
+	if !list.CursorMode {
+		query.Count(&total)
+		log.Printf("📊 Найдено заказов: %d", total)
+	}
 
 	// Получение заказов с пагинацией
-	preloadQuery := query.Preload("User")
+	preloadQuery := query
+	if list.Expands("user", true) {
+		preloadQuery = preloadQuery.Preload("User")
+	}
 
 	// Для владельца магазина загружаем только его товары в заказах
-	if currentUser.Role != nil && currentUser.Role.Name == "shop_owner" {
+	if !list.Expands("items", true) {
+		log.Printf("📦 OrderItems не запрошены (expand)")
+	} else if currentUser.Role != nil && currentUser.Role.Name == "shop_owner" {
 		// Пробуем найти shop для этого пользователя
 		var shop models.Shop
 		if err := database.DB.Where("owner_id = ?", currentUser.ID).First(&shop).Error; err == nil {
@@ -1272,12 +1301,13 @@ func (oc *OrderController) GetShopOrders(c *gin.Context) {
 		preloadQuery = preloadQuery.Preload("OrderItems")
 	}
 
-	result := preloadQuery.
-		Preload("OrderItems.Variation").
-		Offset(offset).
-		Limit(limit).
-		Order("created_at DESC").
-		Find(&orders)
+	if list.Expands("items", true) {
+		preloadQuery = preloadQuery.Preload("OrderItems.Variation")
+	}
+	if !list.CursorMode {
+		preloadQuery = preloadQuery.Order("created_at DESC")
+	}
+	result := list.Paginate(preloadQuery, "orders.created_at", "orders.id").Find(&orders)
 
 	if result.Error != nil {
 		log.Printf("❌ Ошибка при получении заказов: %v", result.Error)
@@ -1295,21 +1325,22 @@ func (oc *OrderController) GetShopOrders(c *gin.Context) {
 		log.Printf("  📋 Заказ %d: ID=%s, Items=%d", i+1, order.ID, len(order.OrderItems))
 	}
 
-	// Преобразование в ответы
-	var orderResponses []models.OrderResponse
-	for _, order := range orders {
-		orderResponses = append(orderResponses, order.ToResponse())
+	if list.CursorMode {
+		var cursor models.CursorInfo
+		orders, cursor = models.CursorPage(orders, list.Limit, orderCursor)
+		c.JSON(http.StatusOK, models.CursorSuccessResponse(orderResponsesOf(orders), cursor, "Заказы получены успешно"))
+		return
 	}
 
 	c.JSON(http.StatusOK, models.StandardResponse{
 		Success: true,
 		Data: gin.H{
-			"orders": orderResponses,
+			"orders": orderResponsesOf(orders),
 			"pagination": models.PaginationInfo{
-				Page:       page,
-				Limit:      limit,
+				Page:       list.Page,
+				Limit:      list.Limit,
 				Total:      int(total),
-				TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+				TotalPages: int((total + int64(list.Limit) - 1) / int64(list.Limit)),
 			},
 		},
 		Message: "Заказы получены успешно",
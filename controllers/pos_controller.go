@@ -10,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/mm-api/mm-api/database"
 	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
 	"gorm.io/gorm"
 )
 
@@ -28,6 +29,7 @@ type BulkProductItem struct {
 	Brand       string                 `json:"brand"`
 	CategoryID  uuid.UUID              `json:"categoryId" binding:"required"`
 	Gender      string                 `json:"gender" binding:"required"` // 'male', 'female', 'unisex'
+	Attributes  map[string]interface{} `json:"attributes"`                // Характеристики по схеме категории
 	Variations  []BulkVariationItem    `json:"variations" binding:"required,min=1"`
 }
 
@@ -153,6 +155,9 @@ func (pc *PosController) BulkUploadProducts(c *gin.Context) {
 	// Начинаем транзакцию
 	tx := database.DB.Begin()
 
+	// Схемы характеристик по категориям (загружаются один раз на запрос)
+	schemas := make(map[uuid.UUID][]models.CategoryAttribute)
+
 	for i, productItem := range req.Products {
 		// Проверяем существование категории
 		var category models.Category
@@ -162,6 +167,17 @@ func (pc *PosController) BulkUploadProducts(c *gin.Context) {
 			continue
 		}
 
+		schema, ok := schemas[category.ID]
+		if !ok {
+			var err error
+			if schema, err = services.GetCategoryAttributeSchema(tx, category.ID); err != nil {
+				response.Failed++
+				response.Errors = append(response.Errors, fmt.Sprintf("Product %d: %v", i+1, err))
+				continue
+			}
+			schemas[category.ID] = schema
+		}
+
 		// Ищем существующий товар по названию и бренду (или по штрих-коду)
 		var existingProduct models.Product
 		query := tx.Where("shop_id = ? AND name = ?", shop.ID, productItem.Name)
@@ -171,6 +187,18 @@ func (pc *PosController) BulkUploadProducts(c *gin.Context) {
 
 		err := query.First(&existingProduct).Error
 
+		// Характеристики проверяем всегда, кроме обновления без attributes в той же категории
+		var attributes models.ProductAttributes
+		keepAttributes := err == nil && productItem.Attributes == nil && existingProduct.CategoryID == productItem.CategoryID
+		if !keepAttributes && (err == nil || err == gorm.ErrRecordNotFound) {
+			var validationErr error
+			if attributes, validationErr = services.ValidateProductAttributes(schema, productItem.Attributes); validationErr != nil {
+				response.Failed++
+				response.Errors = append(response.Errors, fmt.Sprintf("Product %d: %v", i+1, validationErr))
+				continue
+			}
+		}
+
 		if err == gorm.ErrRecordNotFound {
 			// Создаем новый товар
 			product := models.Product{
@@ -179,6 +207,7 @@ func (pc *PosController) BulkUploadProducts(c *gin.Context) {
 				Brand:       productItem.Brand,
 				CategoryID:  productItem.CategoryID,
 				Gender:      productItem.Gender,
+				Attributes:  attributes,
 				ShopID:      &shop.ID,
 				CityID:      shop.CityID,
 				IsAvailable: true,
@@ -234,6 +263,9 @@ func (pc *PosController) BulkUploadProducts(c *gin.Context) {
 			existingProduct.Brand = productItem.Brand
 			existingProduct.CategoryID = productItem.CategoryID
 			existingProduct.Gender = productItem.Gender
+			if !keepAttributes {
+				existingProduct.Attributes = attributes
+			}
 
 			if err := tx.Save(&existingProduct).Error; err != nil {
 				response.Failed++
@@ -337,6 +369,8 @@ func (pc *PosController) BulkUploadProducts(c *gin.Context) {
 		return
 	}
 
+	refreshCategoryCounts()
+
 	response.Success = true
 	c.JSON(http.StatusOK, response)
 }
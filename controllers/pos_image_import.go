@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/config"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"github.com/mm-api/mm-api/storage"
+	"github.com/mm-api/mm-api/utils"
+	"gorm.io/gorm"
+)
+
+// imageImportFolder папка для фото, загруженных из архивов
+const imageImportFolder = "variations"
+
+// imageImportFormOverhead запас на заголовки multipart и поле mode сверх размера архива
+const imageImportFormOverhead = 1 << 20
+
+func imageImportCursor(j *models.ImageImportJob) models.Cursor {
+	return models.Cursor{Time: j.CreatedAt, ID: j.ID}
+}
+
+// posShop возвращает магазин текущего пользователя (ответ с ошибкой уже отправлен, если false)
+func posShop(c *gin.Context) (*models.Shop, *models.User, bool) {
+	value, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not authenticated"))
+		return nil, nil, false
+	}
+	user := value.(models.User)
+
+	var shop models.Shop
+	if err := database.DB.Where("owner_id = ?", user.ID).First(&shop).Error; err != nil {
+		c.JSON(http.StatusForbidden, models.ErrorResponseWithCode(models.ErrForbidden, "Shop not found for this user"))
+		return nil, nil, false
+	}
+	return &shop, &user, true
+}
+
+// saveImportedImage сохраняет фото из архива так же, как /upload/image?folder=variations:
+// Cloudinary, если он включен, иначе локальная обработка IMAGE_PIPELINES или сжатие
+func saveImportedImage(uploadedBy *uuid.UUID) services.ImageImportSaver {
+	uc := &UploadController{}
+	return func(ctx context.Context, name string, data []byte) (string, error) {
+		cfg := config.GetConfig()
+
+		if cfg.UseCloudinary && cfg.CloudinaryCloudName != "" && cfg.CloudinaryUploadPreset != "" {
+			cloudinaryProcessor := utils.NewCloudinaryProcessor(
+				cfg.CloudinaryCloudName,
+				cfg.CloudinaryAPIKey,
+				cfg.CloudinaryAPISecret,
+				cfg.CloudinaryUploadPreset,
+			)
+			result, err := cloudinaryProcessor.ProcessProductImage(bytes.NewReader(data), imageImportFolder, cfg.CloudinaryRemoveBackground)
+			if err != nil {
+				return "", fmt.Errorf("ошибка загрузки в Cloudinary: %v", err)
+			}
+			services.RegisterMediaUpload(database.DB, result.SecureURL, int64(result.Bytes), "image/"+result.Format, uploadedBy)
+			return result.SecureURL, nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(name))
+		filename := uuid.New().String() + ext
+		key := storage.JoinKey("images", imageImportFolder, filename)
+
+		var size int64
+		var err error
+		if processor, ok := imageProcessorFor(cfg, imageImportFolder); ok {
+			filename = strings.TrimSuffix(filename, ext) + ".jpg"
+			size, err = uc.processProductImage(processor, bytes.NewReader(data), strings.TrimSuffix(key, ext)+".jpg")
+		} else {
+			filename, size, err = uc.compressAndSaveImage(bytes.NewReader(data), key, ext, mime.TypeByExtension(ext))
+		}
+		if err != nil {
+			return "", fmt.Errorf("ошибка обработки изображения: %v", err)
+		}
+
+		fileURL := uc.GetImageURL(filename, imageImportFolder)
+		services.RegisterMediaUpload(database.DB, fileURL, size, mime.TypeByExtension(path.Ext(filename)), uploadedBy)
+		return fileURL, nil
+	}
+}
+
+// ImportImages принимает ZIP архив с фото товаров и запускает импорт в фоне.
+// Имена файлов: SKU, штрих-код или SKU_цвет_N (N - порядок фото цвета).
+// mode=replace (по умолчанию) заменяет фото цвета, mode=append добавляет к ним.
+// POST /api/v1/pos/products/images/import
+func (pc *PosController) ImportImages(c *gin.Context) {
+	shop, user, ok := posShop(c)
+	if !ok {
+		return
+	}
+	cfg := config.GetConfig()
+
+	// Тело ограничивается до разбора формы: иначе архив любого размера
+	// сначала целиком записывается во временные файлы multipart
+	maxSize := int64(cfg.ImageImportMaxSizeMB) * 1024 * 1024
+	if maxSize > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+imageImportFormOverhead)
+	}
+	if _, err := c.MultipartForm(); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponseWithCode(models.ErrValidationError,
+				fmt.Sprintf("Archive is too large (max %dMB)", cfg.ImageImportMaxSizeMB)))
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "ZIP archive is required (field file)"))
+		return
+	}
+
+	mode := c.DefaultPostForm("mode", models.ImageImportModeReplace)
+	if mode != models.ImageImportModeReplace && mode != models.ImageImportModeAppend {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Mode must be replace or append"))
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "ZIP archive is required (field file)"))
+		return
+	}
+	if !strings.EqualFold(filepath.Ext(header.Filename), ".zip") {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "File must be a ZIP archive"))
+		return
+	}
+	if maxSize > 0 && header.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponseWithCode(models.ErrValidationError,
+			fmt.Sprintf("Archive is too large (max %dMB)", cfg.ImageImportMaxSizeMB)))
+		return
+	}
+
+	// Архив хранится во временном файле до окончания обработки
+	tmp, err := os.CreateTemp("", "image-import-*.zip")
+	if err != nil {
+		log.Printf("❌ Не удалось создать временный файл для импорта фото: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to save archive"))
+		return
+	}
+	tmp.Close()
+	if err := c.SaveUploadedFile(header, tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		log.Printf("❌ Не удалось сохранить архив импорта фото: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to save archive"))
+		return
+	}
+
+	job := models.ImageImportJob{
+		ShopID:    shop.ID,
+		CreatedBy: user.ID,
+		FileName:  header.Filename,
+		Mode:      mode,
+		Status:    models.ImageImportStatusPending,
+	}
+	if err := services.CreateImageImportJob(database.DB, &job); err != nil {
+		os.Remove(tmp.Name())
+		if errors.Is(err, services.ErrImageImportInProgress) {
+			c.JSON(http.StatusConflict, models.ErrorResponseWithCode(models.ErrConflict, "Previous image import is still running"))
+			return
+		}
+		log.Printf("❌ Не удалось создать задачу импорта фото: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to create import job"))
+		return
+	}
+
+	log.Printf("📦 Импорт фото %s: магазин %s, архив %s (%d байт)", job.ID, shop.ID, header.Filename, header.Size)
+	go services.RunImageImport(context.Background(), database.DB, job.ID, tmp.Name(), services.ImageImportConfig{
+		MaxImages:   cfg.MaxImagesPerVariation,
+		MaxFiles:    cfg.ImageImportMaxFiles,
+		MaxFileSize: cfg.GetUploadMaxSize(),
+		Save:        saveImportedImage(&user.ID),
+	})
+
+	c.JSON(http.StatusAccepted, models.SuccessResponse(job.ToResponse(), "Import started"))
+}
+
+// GetImageImport возвращает прогресс и отчет об ошибках импорта (для опроса из POS)
+// GET /api/v1/pos/products/images/import/:id
+func (pc *PosController) GetImageImport(c *gin.Context) {
+	shop, _, ok := posShop(c)
+	if !ok {
+		return
+	}
+
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid import ID"))
+		return
+	}
+
+	var job models.ImageImportJob
+	if err := database.DB.First(&job, "id = ? AND shop_id = ?", jobID, shop.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(models.ErrNotFound, "Import not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to fetch import"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(job.ToResponse()))
+}
+
+// GetImageImports возвращает импорты фото магазина, новые первыми
+// GET /api/v1/pos/products/images/imports
+func (pc *PosController) GetImageImports(c *gin.Context) {
+	shop, _, ok := posShop(c)
+	if !ok {
+		return
+	}
+	list, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&models.ImageImportJob{}).Where("shop_id = ?", shop.ID)
+	var total int64
+	if !list.CursorMode {
+		query.Count(&total)
+		query = query.Order("image_import_jobs.created_at DESC, image_import_jobs.id DESC")
+	}
+
+	var jobs []models.ImageImportJob
+	if err := list.Paginate(query, "image_import_jobs.created_at", "image_import_jobs.id").Find(&jobs).Error; err != nil {
+		log.Printf("❌ Ошибка получения импортов фото: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to fetch imports"))
+		return
+	}
+
+	if list.CursorMode {
+		jobs, cursor := models.CursorPage(jobs, list.Limit, imageImportCursor)
+		c.JSON(http.StatusOK, models.CursorSuccessResponse(imageImportResponses(jobs), cursor))
+		return
+	}
+	c.JSON(http.StatusOK, models.PaginatedSuccessResponse(imageImportResponses(jobs), list.Pagination(total)))
+}
+
+func imageImportResponses(jobs []models.ImageImportJob) []models.ImageImportJobResponse {
+	responses := make([]models.ImageImportJobResponse, len(jobs))
+	for i := range jobs {
+		responses[i] = jobs[i].ToResponse()
+	}
+	return responses
+}
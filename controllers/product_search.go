@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+)
+
+// SearchProducts ищет товары по всему каталогу (публичный endpoint).
+// Учитывает морфологию русского языка, таджикские буквы и опечатки,
+// сортирует по релевантности и подсвечивает совпадения.
+func (pc *ProductController) SearchProducts(c *gin.Context) {
+	search := strings.TrimSpace(c.Query("q"))
+	if search == "" {
+		search = strings.TrimSpace(c.Query("search"))
+	}
+	if search == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Search query is required",
+		})
+		return
+	}
+
+	index := services.GetSearchIndex()
+	query := database.DB.Model(&models.Product{}).Where("products.is_available = ?", true)
+
+	// Фильтрация по категории (вместе с подкатегориями)
+	if categoryID := c.Query("category"); categoryID != "" {
+		if categoryUUID, err := uuid.Parse(categoryID); err == nil {
+			query = query.Where(services.CategorySubtreeCondition("products.category_id"), categoryUUID)
+		}
+	}
+
+	// Фильтрация по полу
+	if gender := c.Query("gender"); gender != "" {
+		query = query.Where("products.gender = ?", gender)
+	}
+
+	// Фильтрация по магазину
+	if shopID := c.Query("shop_id"); shopID != "" {
+		if shopUUID, err := uuid.Parse(shopID); err == nil {
+			query = query.Where("products.shop_id = ?", shopUUID)
+		}
+	}
+
+	// Фильтрация по городу
+	if cityID := c.Query("city_id"); cityID != "" {
+		if cityUUID, err := uuid.Parse(cityID); err == nil {
+			query = query.Where("products.city_id = ? OR products.shop_id IN (SELECT id FROM shops WHERE city_id = ?)", cityUUID, cityUUID)
+		}
+	}
+
+	query = index.Apply(query, search, true).Order("products.created_at DESC")
+
+	// Пагинация
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("❌ Ошибка подсчета результатов поиска: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to search products",
+		})
+		return
+	}
+
+	var products []models.Product
+	if err := query.Offset(offset).Limit(limit).Preload("Variations").Preload("Category").Preload("Shop").Find(&products).Error; err != nil {
+		log.Printf("❌ Ошибка поиска товаров: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to search products",
+		})
+		return
+	}
+
+	productIDs := make([]uuid.UUID, len(products))
+	productResponses := make([]models.ProductResponse, len(products))
+	for i, product := range products {
+		productIDs[i] = product.ID
+		productResponses[i] = product.ToResponse()
+	}
+
+	// Подсветка не критична: при ошибке отдаем результаты без нее
+	highlights, err := index.Highlight(productIDs, search)
+	if err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+
+	log.Printf("🔍 Поиск %q (%s): найдено %d товаров", search, index.Name(), total)
+
+	c.JSON(http.StatusOK, gin.H{
+		"products":   productResponses,
+		"highlights": highlights,
+		"query":      search,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// SuggestProducts возвращает подсказки автодополнения для строки поиска
+func (pc *ProductController) SuggestProducts(c *gin.Context) {
+	search := strings.TrimSpace(c.Query("q"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 20 {
+		limit = 10
+	}
+
+	// Подсказки имеют смысл начиная со второго символа
+	if len([]rune(search)) < 2 {
+		c.JSON(http.StatusOK, gin.H{
+			"suggestions": []services.SearchSuggestion{},
+		})
+		return
+	}
+
+	suggestions, err := services.GetSearchIndex().Suggest(search, limit)
+	if err != nil {
+		log.Printf("❌ Ошибка подсказок поиска: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get suggestions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suggestions": suggestions,
+	})
+}
@@ -0,0 +1,457 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/middleware"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"gorm.io/gorm"
+)
+
+// QuestionController отвечает за вопросы покупателей о товарах и ответы магазинов
+type QuestionController struct{}
+
+// questionResponsesOf преобразует вопросы в ответы
+func questionResponsesOf(questions []models.ProductQuestion, public bool) []models.ProductQuestionResponse {
+	responses := make([]models.ProductQuestionResponse, len(questions))
+	for i := range questions {
+		if public {
+			responses[i] = questions[i].ToPublicResponse()
+		} else {
+			responses[i] = questions[i].ToResponse()
+		}
+	}
+	return responses
+}
+
+// questionCursor курсор списка вопросов
+func questionCursor(q *models.ProductQuestion) models.Cursor {
+	return models.Cursor{Time: q.CreatedAt, ID: q.ID}
+}
+
+// respondQuestionList выполняет запрос списка вопросов (страница или курсор) и отвечает
+func respondQuestionList(c *gin.Context, list listQuery, query *gorm.DB, public bool) {
+	var total int64
+	if !list.CursorMode {
+		query.Count(&total)
+		query = query.Order("product_questions.created_at DESC, product_questions.id DESC")
+	}
+
+	var questions []models.ProductQuestion
+	if err := list.Paginate(query, "product_questions.created_at", "product_questions.id").Find(&questions).Error; err != nil {
+		log.Printf("❌ Ошибка получения вопросов: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to fetch questions"))
+		return
+	}
+
+	if list.CursorMode {
+		questions, cursor := models.CursorPage(questions, list.Limit, questionCursor)
+		c.JSON(http.StatusOK, models.CursorSuccessResponse(questionResponsesOf(questions, public), cursor))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedSuccessResponse(questionResponsesOf(questions, public), list.Pagination(total)))
+}
+
+// loadQuestion загружает вопрос по :id
+func loadQuestion(c *gin.Context) (*models.ProductQuestion, bool) {
+	questionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid question ID"))
+		return nil, false
+	}
+
+	var question models.ProductQuestion
+	if err := database.DB.Preload("User").Preload("Product").First(&question, "id = ?", questionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(models.ErrNotFound, "Question not found"))
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to fetch question"))
+		return nil, false
+	}
+	return &question, true
+}
+
+// GetProductQuestions возвращает отвеченные вопросы о товаре (публично)
+func (qc *QuestionController) GetProductQuestions(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid product ID"))
+		return
+	}
+
+	list, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&models.ProductQuestion{}).
+		Where("product_questions.product_id = ?", productID).
+		Where(services.PublicQuestionsCondition).
+		Preload("User")
+	respondQuestionList(c, list, query, true)
+}
+
+// AskQuestion создает вопрос о товаре и уведомляет владельца магазина
+func (qc *QuestionController) AskQuestion(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return
+	}
+
+	var req models.ProductQuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid request data", err.Error()))
+		return
+	}
+	req.Text = strings.TrimSpace(req.Text)
+
+	var product models.Product
+	if err := database.DB.First(&product, "id = ?", req.ProductID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(models.ErrProductNotFound, "Product not found"))
+		return
+	}
+
+	var recent int64
+	database.DB.Model(&models.ProductQuestion{}).
+		Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-24*time.Hour)).
+		Count(&recent)
+	if recent >= models.MaxQuestionsPerDay {
+		c.JSON(http.StatusTooManyRequests, models.ErrorResponseWithCode(
+			models.ErrRateLimitExceeded,
+			"Too many questions, try again tomorrow",
+		))
+		return
+	}
+
+	question := models.ProductQuestion{
+		ProductID: product.ID,
+		ShopID:    product.ShopID,
+		UserID:    user.ID,
+		Text:      req.Text,
+		Status:    models.QuestionStatusPublished,
+	}
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&question).Error; err != nil {
+			return err
+		}
+		return services.NotifyShopAboutQuestion(tx, &question, &product)
+	}); err != nil {
+		log.Printf("❌ Ошибка создания вопроса о товаре %s: %v", product.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to create question"))
+		return
+	}
+
+	log.Printf("❓ Вопрос %s о товаре %s", question.ID, product.ID)
+	question.User = &user
+	question.Product = &product
+	c.JSON(http.StatusCreated, models.SuccessResponse(question.ToResponse()))
+}
+
+// GetMyQuestions возвращает вопросы текущего пользователя (включая неотвеченные)
+func (qc *QuestionController) GetMyQuestions(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return
+	}
+
+	list, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&models.ProductQuestion{}).
+		Where("product_questions.user_id = ?", user.ID).
+		Preload("Product")
+	respondQuestionList(c, list, query, false)
+}
+
+// DeleteQuestion удаляет свой вопрос (пока на него не ответили)
+func (qc *QuestionController) DeleteQuestion(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return
+	}
+
+	question, ok := loadQuestion(c)
+	if !ok {
+		return
+	}
+	if !isAdminUser(user) {
+		if question.UserID != user.ID {
+			c.JSON(http.StatusForbidden, models.ErrorResponseWithCode(models.ErrForbidden, "You can delete only your own questions"))
+			return
+		}
+		if question.AnsweredAt != nil {
+			c.JSON(http.StatusConflict, models.ErrorResponseWithCode(models.ErrConflict, "Answered questions cannot be deleted"))
+			return
+		}
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("question_id = ?", question.ID).Delete(&models.QuestionReport{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(question).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to delete question"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"message": "Question deleted"}))
+}
+
+// ReportQuestion принимает жалобу на вопрос или ответ магазина
+func (qc *QuestionController) ReportQuestion(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return
+	}
+
+	question, ok := loadQuestion(c)
+	if !ok {
+		return
+	}
+
+	var req models.QuestionReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid request data", err.Error()))
+		return
+	}
+	if req.Target == "answer" && question.AnsweredAt == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Question has no answer"))
+		return
+	}
+	if question.UserID == user.ID && req.Target == "question" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "You cannot report your own question"))
+		return
+	}
+
+	report := models.QuestionReport{
+		UserID:  user.ID,
+		Target:  req.Target,
+		Reason:  req.Reason,
+		Comment: strings.TrimSpace(req.Comment),
+	}
+	hidden, err := services.ReportQuestion(database.DB, question, &report)
+	if err != nil {
+		if errors.Is(err, services.ErrQuestionAlreadyReported) {
+			c.JSON(http.StatusConflict, models.ErrorResponseWithCode(models.ErrConflict, "You have already reported this "+req.Target))
+			return
+		}
+		log.Printf("❌ Ошибка жалобы на вопрос %s: %v", question.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to report question"))
+		return
+	}
+
+	if hidden {
+		log.Printf("🚩 Вопрос %s скрыт после %d жалоб", question.ID, question.ReportsCount)
+	}
+	c.JSON(http.StatusCreated, models.SuccessResponse(gin.H{"message": "Report received"}))
+}
+
+// GetShopQuestions возвращает вопросы о товарах магазинов владельца.
+// Админ видит все вопросы или вопросы магазина ?shopId=. Фильтры: unanswered=true, product_id.
+func (qc *QuestionController) GetShopQuestions(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return
+	}
+
+	list, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&models.ProductQuestion{}).
+		Where("product_questions.status = ?", models.QuestionStatusPublished)
+	if isAdminUser(user) {
+		shopID, ok := catalogQueryUUID(c, "shopId")
+		if !ok {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid shopId"))
+			return
+		}
+		if shopID != nil {
+			query = query.Where("product_questions.shop_id = ?", *shopID)
+		}
+	} else {
+		// Вопросы о товарах своих магазинов и старых товарах владельца без магазина
+		query = query.Where(
+			"product_questions.shop_id IN (SELECT id FROM shops WHERE owner_id = ?) OR "+
+				"(product_questions.shop_id IS NULL AND product_questions.product_id IN (SELECT id FROM products WHERE owner_id = ?))",
+			user.ID, user.ID,
+		)
+	}
+
+	productID, ok := catalogQueryUUID(c, "product_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid product_id"))
+		return
+	}
+	if productID != nil {
+		query = query.Where("product_questions.product_id = ?", *productID)
+	}
+	if c.Query("unanswered") == "true" {
+		query = query.Where("product_questions.answered_at IS NULL")
+	}
+
+	respondQuestionList(c, list, query.Preload("User").Preload("Product"), false)
+}
+
+// AnswerQuestion сохраняет ответ магазина и уведомляет автора вопроса
+func (qc *QuestionController) AnswerQuestion(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return
+	}
+
+	question, ok := loadQuestion(c)
+	if !ok {
+		return
+	}
+	if question.Product == nil || question.Product.ID == uuid.Nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(models.ErrProductNotFound, "Product not found"))
+		return
+	}
+
+	if !isAdminUser(user) {
+		ownerID, err := services.ProductOwnerID(database.DB, question.Product)
+		if err != nil {
+			log.Printf("❌ %v", err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to check shop"))
+			return
+		}
+		if ownerID != user.ID {
+			c.JSON(http.StatusForbidden, models.ErrorResponseWithCode(models.ErrForbidden, "Question belongs to another shop"))
+			return
+		}
+	}
+	if question.Status != models.QuestionStatusPublished {
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(models.ErrConflict, "Question is hidden by moderation"))
+		return
+	}
+
+	var req models.QuestionAnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid request data", err.Error()))
+		return
+	}
+
+	now := time.Now()
+	firstAnswer := question.AnsweredAt == nil
+	question.Answer = strings.TrimSpace(req.Text)
+	question.AnsweredBy = &user.ID
+	question.AnsweredAt = &now
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(question).Select("answer", "answered_by", "answered_at").Updates(question).Error; err != nil {
+			return err
+		}
+		if !firstAnswer {
+			return nil
+		}
+		return services.NotifyAskerAboutAnswer(tx, question, question.Product.Name)
+	}); err != nil {
+		log.Printf("❌ Ошибка ответа на вопрос %s: %v", question.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to save answer"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(question.ToResponse()))
+}
+
+// GetQuestionsForModeration возвращает вопросы для модерации (админ).
+// Фильтры: status, reported=true (есть нерассмотренные жалобы), shop_id, product_id.
+func (qc *QuestionController) GetQuestionsForModeration(c *gin.Context) {
+	list, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&models.ProductQuestion{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("product_questions.status = ?", status)
+	}
+	if c.Query("reported") == "true" {
+		query = query.Where("product_questions.reports_count > 0")
+	}
+	for _, filter := range []struct{ param, column string }{
+		{"shop_id", "product_questions.shop_id"},
+		{"product_id", "product_questions.product_id"},
+	} {
+		id, ok := catalogQueryUUID(c, filter.param)
+		if !ok {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid "+filter.param))
+			return
+		}
+		if id != nil {
+			query = query.Where(filter.column+" = ?", *id)
+		}
+	}
+
+	respondQuestionList(c, list, query.Preload("User").Preload("Product"), false)
+}
+
+// GetQuestionReports возвращает жалобы на вопрос (админ)
+func (qc *QuestionController) GetQuestionReports(c *gin.Context) {
+	question, ok := loadQuestion(c)
+	if !ok {
+		return
+	}
+
+	var reports []models.QuestionReport
+	if err := database.DB.Where("question_id = ?", question.ID).Order("created_at DESC").Find(&reports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to fetch reports"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"question": question.ToResponse(),
+		"reports":  reports,
+	}))
+}
+
+// ModerateQuestion публикует или скрывает вопрос и закрывает жалобы (админ)
+func (qc *QuestionController) ModerateQuestion(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return
+	}
+
+	question, ok := loadQuestion(c)
+	if !ok {
+		return
+	}
+
+	var req models.QuestionModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid request data", err.Error()))
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+
+	if err := services.ModerateQuestion(database.DB, question, user.ID, req); err != nil {
+		log.Printf("❌ Ошибка модерации вопроса %s: %v", question.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to moderate question"))
+		return
+	}
+
+	log.Printf("🛡️ Вопрос %s: статус %s (модератор %s)", question.ID, question.Status, user.ID)
+	c.JSON(http.StatusOK, models.SuccessResponse(question.ToResponse()))
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"github.com/mm-api/mm-api/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// realtimeHeartbeatInterval интервал keep-alive комментариев, чтобы прокси не закрывали поток
+const realtimeHeartbeatInterval = 25 * time.Second
+
+// RealtimeController обрабатывает поток событий реального времени (SSE)
+type RealtimeController struct{}
+
+// Stream отдает события пользователя через Server-Sent Events:
+// новые уведомления, изменения статусов заказов и новые заказы магазина владельца
+// GET /api/v1/events/stream
+func (rc *RealtimeController) Stream(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"Пользователь не найден",
+		))
+		return
+	}
+	currentUser := user.(models.User)
+
+	hub := services.GetRealtimeHub()
+	sub := hub.Subscribe(currentUser.ID)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Отключаем буферизацию nginx
+
+	// Начальное состояние, чтобы клиенту не нужно было отдельно запрашивать счетчик
+	var unreadCount int64
+	database.DB.Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", currentUser.ID, false).Count(&unreadCount)
+	c.SSEvent(string(services.RealtimeEventConnected), gin.H{
+		"userId":      currentUser.ID,
+		"unreadCount": unreadCount,
+	})
+	c.Writer.Flush()
+
+	log.Printf("📡 Realtime: пользователь %s подключен (подключений: %d)", currentUser.ID, hub.ConnectionsCount(currentUser.ID))
+
+	heartbeat := time.NewTicker(realtimeHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-sub.Events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(string(event.Type), event)
+			return true
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return false
+			}
+			return true
+		}
+	})
+
+	log.Printf("📡 Realtime: пользователь %s отключен", currentUser.ID)
+}
+
+// IssueStreamToken выдает короткоживущий токен подключения к потоку событий.
+// Токен действует только для GET /events/stream?stream_token=..., поэтому токен сессии
+// не попадает в URL и логи прокси. Клиент запрашивает новый токен перед каждым переподключением.
+// POST /api/v1/events/token
+func (rc *RealtimeController) IssueStreamToken(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"Пользователь не найден",
+		))
+		return
+	}
+	currentUser := user.(models.User)
+
+	token, expiresAt, err := utils.GenerateStreamToken(currentUser.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Не удалось выдать токен потока",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"streamToken": token,
+		"expiresAt":   expiresAt,
+	}))
+}
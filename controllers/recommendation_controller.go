@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/config"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/middleware"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+)
+
+// RecommendationController отвечает за рекомендации товаров и закрепления администраторов
+type RecommendationController struct{}
+
+// recommendationLimit читает limit (по умолчанию 20, максимум 50)
+func recommendationLimit(c *gin.Context) int {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 50 {
+		limit = 20
+	}
+	return limit
+}
+
+// respondRecommendedProducts загружает товары в порядке рекомендаций и отвечает списком
+func respondRecommendedProducts(c *gin.Context, ids []uuid.UUID) {
+	products, err := services.LoadRecommendedProducts(database.DB, ids)
+	if err != nil {
+		log.Printf("❌ Ошибка загрузки рекомендованных товаров: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to fetch recommendations",
+		))
+		return
+	}
+
+	productResponses := make([]models.ProductResponse, len(products))
+	for i, product := range products {
+		productResponses[i] = product.ToResponse()
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(productResponses))
+}
+
+// GetFeatured возвращает рекомендуемые товары: закрепленные администратором,
+// затем популярные в городе и по всем городам
+func (rc *RecommendationController) GetFeatured(c *gin.Context) {
+	cityID, ok := catalogQueryUUID(c, "city_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid city_id"))
+		return
+	}
+
+	ids, err := services.GetFeaturedProductIDs(database.DB, cityID, recommendationLimit(c))
+	if err != nil {
+		log.Printf("❌ %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to fetch featured products",
+		))
+		return
+	}
+	respondRecommendedProducts(c, ids)
+}
+
+// getProductRecommendations общий обработчик похожих товаров и "покупают вместе"
+func (rc *RecommendationController) getProductRecommendations(c *gin.Context, kind models.RecommendationKind) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid product ID"))
+		return
+	}
+
+	ids, err := services.GetProductRecommendationIDs(database.DB, kind, productID, recommendationLimit(c))
+	if err != nil {
+		log.Printf("❌ %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to fetch recommendations",
+		))
+		return
+	}
+	respondRecommendedProducts(c, ids)
+}
+
+// GetSimilar возвращает товары, похожие на указанный
+func (rc *RecommendationController) GetSimilar(c *gin.Context) {
+	rc.getProductRecommendations(c, models.RecommendationSimilar)
+}
+
+// GetBoughtTogether возвращает товары, которые часто покупают вместе с указанным
+func (rc *RecommendationController) GetBoughtTogether(c *gin.Context) {
+	rc.getProductRecommendations(c, models.RecommendationBoughtTogether)
+}
+
+// GetForYou возвращает персональные рекомендации текущего пользователя
+func (rc *RecommendationController) GetForYou(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+
+	cityID, ok := catalogQueryUUID(c, "city_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid city_id"))
+		return
+	}
+
+	ids, err := services.GetForYouProductIDs(database.DB, user.ID, cityID, recommendationLimit(c))
+	if err != nil {
+		log.Printf("❌ %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to fetch recommendations",
+		))
+		return
+	}
+	respondRecommendedProducts(c, ids)
+}
+
+// GetPins возвращает закрепленные товары (админ)
+func (rc *RecommendationController) GetPins(c *gin.Context) {
+	query := database.DB.Preload("Product").Preload("Product.Variations")
+	if c.Query("active") == "true" {
+		query = query.Where("(starts_at IS NULL OR starts_at <= NOW()) AND (ends_at IS NULL OR ends_at > NOW())")
+	}
+
+	var pins []models.ProductPin
+	if err := query.Order("position ASC, created_at DESC").Find(&pins).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to fetch pins",
+		))
+		return
+	}
+
+	pinResponses := make([]models.ProductPinResponse, len(pins))
+	for i := range pins {
+		pinResponses[i] = pins[i].ToResponse()
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(pinResponses))
+}
+
+// CreatePin закрепляет товар в рекомендуемых (админ)
+func (rc *RecommendationController) CreatePin(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+
+	var req models.ProductPinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"endsAt must be after startsAt",
+		))
+		return
+	}
+
+	var product models.Product
+	if err := database.DB.First(&product, "id = ?", req.ProductID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+			models.ErrProductNotFound,
+			"Product not found",
+		))
+		return
+	}
+
+	pin := models.ProductPin{
+		ProductID: req.ProductID,
+		CityID:    req.CityID,
+		Position:  req.Position,
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		CreatedBy: user.ID,
+	}
+	if err := database.DB.Create(&pin).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to create pin",
+		))
+		return
+	}
+
+	pin.Product = &product
+	log.Printf("📌 Товар %s закреплен в рекомендуемых (позиция %d)", product.ID, pin.Position)
+	c.JSON(http.StatusCreated, models.SuccessResponse(pin.ToResponse()))
+}
+
+// DeletePin снимает закрепление товара (админ)
+func (rc *RecommendationController) DeletePin(c *gin.Context) {
+	pinID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid pin ID"))
+		return
+	}
+
+	result := database.DB.Delete(&models.ProductPin{}, "id = ?", pinID)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to delete pin",
+		))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+			models.ErrNotFound,
+			"Pin not found",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"message": "Pin deleted"}))
+}
+
+// Recompute запускает пересчет рекомендаций немедленно (админ)
+func (rc *RecommendationController) Recompute(c *gin.Context) {
+	cfg := config.GetConfig()
+	if err := services.RecomputeRecommendations(database.DB, services.RecommendationConfig{
+		HistoryDays: cfg.RecommendationHistoryDays,
+		PerProduct:  cfg.RecommendationsPerProduct,
+		PerUser:     cfg.RecommendationsPerUser,
+	}); err != nil {
+		log.Printf("❌ %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to recompute recommendations",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"message": "Recommendations recomputed"}))
+}
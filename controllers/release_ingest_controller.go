@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+)
+
+// ReleaseIngestController состояние приема обновлений и установщиков POS из папки входящих
+type ReleaseIngestController struct{}
+
+// GetStatus папки, файлы во входящих, итоги за 30 дней и последние записи (админ)
+func (rc *ReleaseIngestController) GetStatus(c *gin.Context) {
+	status, err := services.GetReleaseIngestStatus(database.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to fetch ingest status",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    status,
+	})
+}
+
+// ListIngestions журнал приема (админ). Фильтры: kind, status, source.
+func (rc *ReleaseIngestController) ListIngestions(c *gin.Context) {
+	query := database.DB.Model(&models.ReleaseIngestion{}).Order("created_at DESC")
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if source := c.Query("source"); source != "" {
+		query = query.Where("source = ?", source)
+	}
+
+	var ingestions []models.ReleaseIngestion
+	if err := query.Limit(500).Find(&ingestions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to fetch ingestions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    ingestions,
+	})
+}
+
+// Scan проверяет папку входящих, не дожидаясь интервала (админ)
+func (rc *ReleaseIngestController) Scan(c *gin.Context) {
+	if !services.ReleaseIngestEnabled() {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "release ingest is disabled (RELEASE_INGEST_ENABLED)",
+		})
+		return
+	}
+
+	services.NudgeReleaseIngest()
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Inbox scan started",
+	})
+}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/config"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"github.com/mm-api/mm-api/utils"
+	"gorm.io/gorm"
+)
+
+// ReleasePatchController выдает бинарные патчи между версиями обновлений и установщиков POS
+type ReleasePatchController struct{}
+
+// releasePatchResponse ответ клиенту: патч от версии from или полный файл
+func releasePatchResponse(c *gin.Context, kind models.ReleaseKind, full models.ReleaseFullFile, from string) models.ReleasePatchResponse {
+	response := models.ReleasePatchResponse{Mode: "full", Full: full}
+	if utils.CompareVersions(from, full.Version) >= 0 {
+		response.Reason = "client is already on this version"
+		return response
+	}
+
+	patch, reason := services.FindReleasePatch(database.DB, kind, full.ID, from)
+	if patch == nil {
+		response.Reason = reason
+		return response
+	}
+	patch.DownloadURL = releaseDownloadURL(c, patch.FilePath, path.Base(patch.FilePath))
+	response.Mode = "patch"
+	response.Patch = patch
+	return response
+}
+
+// attachUpdatePatch добавляет к решению об обновлении патч от текущей версии клиента
+func attachUpdatePatch(c *gin.Context, decision *models.UpdateDecision) {
+	if decision.Release == nil || decision.CurrentVersion == "" {
+		return
+	}
+	if patch, _ := services.FindReleasePatch(database.DB, models.ReleaseKindUpdate, decision.Release.ID, decision.CurrentVersion); patch != nil {
+		patch.DownloadURL = releaseDownloadURL(c, patch.FilePath, path.Base(patch.FilePath))
+		decision.Patch = patch
+	}
+}
+
+// GetUpdatePatch патч обновления от версии клиента.
+// GET /updates/patch?platform=windows&from=1.1.0[&to=1.2.0][&channel=beta&deviceId=...]
+// Без to - к последней версии, выданной устройству (как /updates/latest).
+func (rc *ReleasePatchController) GetUpdatePatch(c *gin.Context) {
+	platform := models.UpdatePlatform(c.Query("platform"))
+	from := strings.TrimSpace(c.Query("from"))
+	if platform == "" || from == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "platform and from are required",
+		})
+		return
+	}
+
+	// С to и без него релиз выбирается с учетом канала и доли выпуска:
+	// версия, еще не выданная устройству, недоступна и по прямому запросу
+	req := models.UpdateCheckRequest{
+		Platform:       platform,
+		Channel:        models.UpdateChannel(c.Query("channel")),
+		CurrentVersion: from,
+		DeviceID:       c.Query("deviceId"),
+	}
+	var target *models.UpdateRelease
+	var err error
+	if to := strings.TrimSpace(c.Query("to")); to != "" {
+		target, err = services.UpdateReleaseVersion(database.DB, req, to)
+	} else {
+		target, err = services.LatestUpdateRelease(database.DB, req)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	if target == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "update not found",
+		})
+		return
+	}
+
+	full := models.ReleaseFullFile{
+		ID:             target.ID,
+		Version:        target.Version,
+		FileSize:       target.FileSize,
+		ChecksumSHA256: target.ChecksumSHA256,
+		DownloadURL:    releaseDownloadURL(c, target.FilePath, target.FileName),
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    releasePatchResponse(c, models.ReleaseKindUpdate, full, from),
+	})
+}
+
+// GetLibissPosPatch патч установщика POS от версии клиента.
+// GET /libiss-pos/patch?type=full&platform=windows&from=1.1.0[&to=1.2.0][&deviceId=...]
+// Без to - к последней версии типа и платформы. С deviceId версия кассы учитывается
+// политикой хранения.
+func (rc *ReleasePatchController) GetLibissPosPatch(c *gin.Context) {
+	fileType := c.Query("type")
+	platform := c.Query("platform")
+	from := strings.TrimSpace(c.Query("from"))
+	if fileType == "" || platform == "" || from == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "type, platform and from are required",
+		})
+		return
+	}
+
+	var files []models.LibissPosFile
+	if err := database.DB.Where("type = ? AND platform = ? AND is_active = ? AND is_public = ?", fileType, platform, true, true).
+		Order("created_at DESC").
+		Find(&files).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to fetch files",
+		})
+		return
+	}
+
+	var target *models.LibissPosFile
+	to := strings.TrimSpace(c.Query("to"))
+	for i := range files {
+		if to != "" && utils.CompareVersions(files[i].Version, to) != 0 {
+			continue
+		}
+		if target == nil || utils.CompareVersions(files[i].Version, target.Version) > 0 {
+			target = &files[i]
+		}
+	}
+	if target == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "file not found",
+		})
+		return
+	}
+
+	services.RecordLibissPosCheckIn(database.DB, fileType, platform, c.Query("deviceId"), from, target.Version,
+		c.ClientIP(), config.GetConfig().UpdateCheckInLimit)
+
+	full := models.ReleaseFullFile{
+		ID:             target.ID,
+		Version:        target.Version,
+		FileSize:       target.FileSize,
+		ChecksumSHA256: target.ChecksumSHA256,
+		DownloadURL:    target.PublicURL,
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    releasePatchResponse(c, models.ReleaseKindLibissPos, full, from),
+	})
+}
+
+// ListPatches список патчей (админ). Фильтры: kind, status, targetId.
+func (rc *ReleasePatchController) ListPatches(c *gin.Context) {
+	query := database.DB.Model(&models.ReleasePatch{}).Order("created_at DESC")
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if targetID := c.Query("targetId"); targetID != "" {
+		if _, err := uuid.Parse(targetID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "invalid targetId",
+			})
+			return
+		}
+		query = query.Where("target_id = ?", targetID)
+	}
+
+	var patches []models.ReleasePatch
+	if err := query.Limit(500).Find(&patches).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to fetch patches",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    patches,
+	})
+}
+
+// RetryPatch возвращает неудачный или пропущенный патч в очередь построения (админ)
+func (rc *ReleasePatchController) RetryPatch(c *gin.Context) {
+	patchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "invalid patch id",
+		})
+		return
+	}
+
+	if err := services.RetryReleasePatch(database.DB, patchID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   "failed or skipped patch not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to retry patch",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Patch queued for rebuild",
+	})
+}
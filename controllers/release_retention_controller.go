@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/config"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+)
+
+// ReleaseRetentionController политика хранения обновлений и установщиков POS
+type ReleaseRetentionController struct{}
+
+// releaseRetentionConfig настройки политики из конфигурации; keep_last, min_age,
+// install_window и dry_run в запросе переопределяют их для одного запуска
+func releaseRetentionConfig(c *gin.Context) (services.ReleaseRetentionConfig, error) {
+	cfg := config.GetConfig()
+	retention := services.ReleaseRetentionConfig{
+		KeepLast:      cfg.GetReleaseRetentionKeepLast(),
+		MinAge:        cfg.GetReleaseRetentionMinAge(),
+		InstallWindow: cfg.GetReleaseRetentionInstallWindow(),
+		DryRun:        cfg.ReleaseRetentionDryRun,
+	}
+
+	if raw := c.Query("keep_last"); raw != "" {
+		keepLast, err := strconv.Atoi(raw)
+		if err != nil || keepLast < 1 {
+			return retention, errors.New("keep_last must be a positive number")
+		}
+		retention.KeepLast = keepLast
+	}
+	if raw := c.Query("min_age"); raw != "" {
+		minAge, err := time.ParseDuration(raw)
+		if err != nil || minAge < 0 {
+			return retention, errors.New("invalid min_age, use a duration like 240h")
+		}
+		retention.MinAge = minAge
+	}
+	if raw := c.Query("install_window"); raw != "" {
+		window, err := time.ParseDuration(raw)
+		if err != nil || window <= 0 {
+			return retention, errors.New("invalid install_window, use a duration like 720h")
+		}
+		retention.InstallWindow = window
+	}
+	if raw := c.Query("dry_run"); raw != "" {
+		dryRun, err := strconv.ParseBool(raw)
+		if err != nil {
+			return retention, errors.New("dry_run must be true or false")
+		}
+		retention.DryRun = dryRun
+	}
+	return retention, nil
+}
+
+// releaseActorID ID администратора, выполняющего запрос
+func releaseActorID(c *gin.Context) *uuid.UUID {
+	if value, exists := c.Get("user"); exists {
+		if user, ok := value.(models.User); ok {
+			return &user.ID
+		}
+	}
+	return nil
+}
+
+// runReleaseRetention применяет политику и отвечает отчетом. preview - только отчет
+// независимо от настроек и dry_run.
+func runReleaseRetention(c *gin.Context, preview bool) {
+	retention, err := releaseRetentionConfig(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	retention.DryRun = retention.DryRun || preview
+
+	report, err := services.ApplyReleaseRetention(c.Request.Context(), database.DB, retention, releaseActorID(c))
+	if errors.Is(err, services.ErrReleaseRetentionInProgress) {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "release retention is already running",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to apply release retention",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// Preview показывает, какие релизы будут удалены и почему остальные хранятся (админ).
+// Ничего не удаляет.
+func (rc *ReleaseRetentionController) Preview(c *gin.Context) {
+	runReleaseRetention(c, true)
+}
+
+// Run применяет политику хранения, не дожидаясь интервала (админ). Режим по умолчанию -
+// RELEASE_RETENTION_DRY_RUN; ?dry_run=false удаляет релизы.
+func (rc *ReleaseRetentionController) Run(c *gin.Context) {
+	runReleaseRetention(c, false)
+}
+
+// ListDeletions журнал удаления релизов (админ). Фильтры: kind, trigger, platform.
+func (rc *ReleaseRetentionController) ListDeletions(c *gin.Context) {
+	query := database.DB.Model(&models.ReleaseDeletion{}).Order("created_at DESC")
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if trigger := c.Query("trigger"); trigger != "" {
+		query = query.Where("trigger = ?", trigger)
+	}
+	if platform := c.Query("platform"); platform != "" {
+		query = query.Where("platform = ?", platform)
+	}
+
+	var deletions []models.ReleaseDeletion
+	if err := query.Limit(500).Find(&deletions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to fetch release deletions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    deletions,
+	})
+}
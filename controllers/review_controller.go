@@ -0,0 +1,632 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/middleware"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReviewController отвечает за отзывы о товарах, ответы магазинов и модерацию
+type ReviewController struct{}
+
+// isAdminUser проверяет роль администратора
+func isAdminUser(user models.User) bool {
+	return user.Role != nil && (user.Role.Name == "admin" || user.Role.Name == "super_admin")
+}
+
+// reviewResponsesOf преобразует отзывы в ответы
+func reviewResponsesOf(reviews []models.ProductReview) []models.ProductReviewResponse {
+	responses := make([]models.ProductReviewResponse, len(reviews))
+	for i := range reviews {
+		responses[i] = reviews[i].ToResponse()
+	}
+	return responses
+}
+
+// applyReviewFilters применяет общие фильтры списков: rating, with_photos, product_id
+func applyReviewFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, bool) {
+	if raw := c.Query("rating"); raw != "" {
+		rating, err := strconv.Atoi(raw)
+		if err != nil || rating < 1 || rating > 5 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid rating"))
+			return query, false
+		}
+		query = query.Where("product_reviews.rating = ?", rating)
+	}
+	if c.Query("with_photos") == "true" {
+		query = query.Where("product_reviews.photos IS NOT NULL AND product_reviews.photos NOT IN ('', 'null', '[]')")
+	}
+	productID, ok := catalogQueryUUID(c, "product_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid product_id"))
+		return query, false
+	}
+	if productID != nil {
+		query = query.Where("product_reviews.product_id = ?", *productID)
+	}
+	return query, true
+}
+
+// loadReview загружает отзыв по :id
+func loadReview(c *gin.Context) (*models.ProductReview, bool) {
+	reviewID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid review ID"))
+		return nil, false
+	}
+
+	var review models.ProductReview
+	if err := database.DB.Preload("User").Preload("Product").First(&review, "id = ?", reviewID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(models.ErrNotFound, "Review not found"))
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to fetch review"))
+		return nil, false
+	}
+	return &review, true
+}
+
+// saveReview сохраняет отзыв и пересчитывает рейтинги товара и магазина
+func saveReview(review *models.ProductReview, save func(tx *gorm.DB) error) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := save(tx); err != nil {
+			return err
+		}
+		return services.RecalculateRatings(tx, review.ProductID, review.ShopID)
+	})
+}
+
+// GetProductReviews возвращает опубликованные отзывы о товаре.
+// Сортировка: created_at (по умолчанию) или rating; фильтры rating, with_photos.
+func (rc *ReviewController) GetProductReviews(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid product ID"))
+		return
+	}
+
+	list, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&models.ProductReview{}).
+		Where("product_reviews.product_id = ? AND product_reviews.status = ?", productID, models.ReviewStatusPublished)
+	if query, ok = applyReviewFilters(c, query); !ok {
+		return
+	}
+
+	sortBy := c.DefaultQuery("sort_by", "created_at")
+	if list.CursorMode && sortBy != "created_at" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Cursor pagination supports only sort_by=created_at",
+		))
+		return
+	}
+
+	var total int64
+	if !list.CursorMode {
+		query.Count(&total)
+		switch sortBy {
+		case "rating":
+			direction := "DESC"
+			if c.Query("sort_order") == "asc" {
+				direction = "ASC"
+			}
+			query = query.Order("product_reviews.rating " + direction + ", product_reviews.created_at DESC")
+		default:
+			query = query.Order("product_reviews.created_at DESC, product_reviews.id DESC")
+		}
+	}
+
+	var reviews []models.ProductReview
+	if err := list.Paginate(query.Preload("User"), "product_reviews.created_at", "product_reviews.id").Find(&reviews).Error; err != nil {
+		log.Printf("❌ Ошибка получения отзывов товара %s: %v", productID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to fetch reviews"))
+		return
+	}
+
+	if list.CursorMode {
+		reviews, cursor := models.CursorPage(reviews, list.Limit, func(r *models.ProductReview) models.Cursor {
+			return models.Cursor{Time: r.CreatedAt, ID: r.ID}
+		})
+		c.JSON(http.StatusOK, models.CursorSuccessResponse(reviewResponsesOf(reviews), cursor))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedSuccessResponse(reviewResponsesOf(reviews), list.Pagination(total)))
+}
+
+// GetProductRatingSummary возвращает среднюю оценку и распределение оценок товара
+func (rc *ReviewController) GetProductRatingSummary(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid product ID"))
+		return
+	}
+
+	summary, err := services.GetRatingSummary(database.DB, productID)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to fetch rating"))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(summary))
+}
+
+// CanReview сообщает, может ли пользователь оставить отзыв о товаре,
+// и возвращает его отзыв, если он уже есть
+func (rc *ReviewController) CanReview(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return
+	}
+
+	productID, err := uuid.Parse(c.Param("productId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid product ID"))
+		return
+	}
+
+	var existing models.ProductReview
+	if err := database.DB.Where("user_id = ? AND product_id = ?", user.ID, productID).First(&existing).Error; err == nil {
+		response := existing.ToResponse()
+		c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"canReview": false, "review": response}))
+		return
+	}
+
+	_, err = services.FindReviewableOrderItem(database.DB, user.ID, productID)
+	if err != nil && !errors.Is(err, services.ErrReviewNotEligible) {
+		log.Printf("❌ %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to check purchase"))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"canReview": err == nil}))
+}
+
+// GetMyReviews возвращает отзывы текущего пользователя (во всех статусах)
+func (rc *ReviewController) GetMyReviews(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return
+	}
+
+	list, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&models.ProductReview{}).Where("product_reviews.user_id = ?", user.ID)
+	var total int64
+	if !list.CursorMode {
+		query.Count(&total)
+		query = query.Order("product_reviews.created_at DESC, product_reviews.id DESC")
+	}
+
+	var reviews []models.ProductReview
+	if err := list.Paginate(query.Preload("Product"), "product_reviews.created_at", "product_reviews.id").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to fetch reviews"))
+		return
+	}
+
+	if list.CursorMode {
+		reviews, cursor := models.CursorPage(reviews, list.Limit, func(r *models.ProductReview) models.Cursor {
+			return models.Cursor{Time: r.CreatedAt, ID: r.ID}
+		})
+		c.JSON(http.StatusOK, models.CursorSuccessResponse(reviewResponsesOf(reviews), cursor))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedSuccessResponse(reviewResponsesOf(reviews), list.Pagination(total)))
+}
+
+// CreateReview создает отзыв о товаре из доставленного заказа
+func (rc *ReviewController) CreateReview(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return
+	}
+
+	var req models.ProductReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid request data", err.Error()))
+		return
+	}
+	if req.ProductID == uuid.Nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "productId is required"))
+		return
+	}
+	if err := services.ValidateReviewPhotos(req.Photos); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, err.Error()))
+		return
+	}
+
+	var product models.Product
+	if err := database.DB.First(&product, "id = ?", req.ProductID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(models.ErrProductNotFound, "Product not found"))
+		return
+	}
+
+	item, err := services.FindReviewableOrderItem(database.DB, user.ID, product.ID)
+	if err != nil {
+		if errors.Is(err, services.ErrReviewNotEligible) {
+			c.JSON(http.StatusForbidden, models.ErrorResponseWithCode(
+				models.ErrForbidden,
+				"Only customers who received this product can review it",
+			))
+			return
+		}
+		log.Printf("❌ %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to check purchase"))
+		return
+	}
+
+	shopID := product.ShopID
+	if shopID == nil {
+		shopID = item.ShopID
+	}
+	review := models.ProductReview{
+		ProductID:   product.ID,
+		ShopID:      shopID,
+		UserID:      user.ID,
+		OrderItemID: item.ID,
+		Rating:      req.Rating,
+		Text:        strings.TrimSpace(req.Text),
+		Photos:      req.Photos,
+		Status:      models.ReviewStatusPublished,
+	}
+	// Уникальный индекс (user_id, product_id) решает гонку двух одновременных запросов
+	err = saveReview(&review, func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&review)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return services.ErrReviewExists
+		}
+		return result.Error
+	})
+	if errors.Is(err, services.ErrReviewExists) {
+		c.JSON(http.StatusConflict, models.ErrorResponseWithCode(
+			models.ErrConflict,
+			"You have already reviewed this product, use PUT /reviews/:id to change it",
+		))
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Ошибка создания отзыва: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to create review"))
+		return
+	}
+
+	log.Printf("⭐ Отзыв %s на товар %s: оценка %d", review.ID, product.ID, review.Rating)
+	review.User = &user
+	review.Product = &product
+	c.JSON(http.StatusCreated, models.SuccessResponse(review.ToResponse()))
+}
+
+// UpdateReview изменяет свой отзыв. Отклоненный модератором отзыв
+// после изменения возвращается на проверку.
+func (rc *ReviewController) UpdateReview(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return
+	}
+
+	review, ok := loadReview(c)
+	if !ok {
+		return
+	}
+	if review.UserID != user.ID {
+		c.JSON(http.StatusForbidden, models.ErrorResponseWithCode(models.ErrForbidden, "You can edit only your own reviews"))
+		return
+	}
+
+	var req models.ProductReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid request data", err.Error()))
+		return
+	}
+	if err := services.ValidateReviewPhotos(req.Photos); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, err.Error()))
+		return
+	}
+
+	review.Rating = req.Rating
+	review.Text = strings.TrimSpace(req.Text)
+	review.Photos = req.Photos
+	if review.Status == models.ReviewStatusRejected {
+		review.Status = models.ReviewStatusPending
+	}
+	if err := saveReview(review, func(tx *gorm.DB) error {
+		return tx.Model(review).Select("rating", "text", "photos", "status").Updates(review).Error
+	}); err != nil {
+		log.Printf("❌ Ошибка изменения отзыва %s: %v", review.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to update review"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(review.ToResponse()))
+}
+
+// DeleteReview удаляет свой отзыв
+func (rc *ReviewController) DeleteReview(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return
+	}
+
+	review, ok := loadReview(c)
+	if !ok {
+		return
+	}
+	if review.UserID != user.ID && !isAdminUser(user) {
+		c.JSON(http.StatusForbidden, models.ErrorResponseWithCode(models.ErrForbidden, "You can delete only your own reviews"))
+		return
+	}
+
+	if err := saveReview(review, func(tx *gorm.DB) error { return tx.Delete(review).Error }); err != nil {
+		log.Printf("❌ Ошибка удаления отзыва %s: %v", review.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to delete review"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"message": "Review deleted"}))
+}
+
+// GetShopReviews возвращает отзывы о товарах магазинов владельца.
+// Админ видит все отзывы или отзывы магазина ?shopId=.
+// Фильтры: rating, with_photos, product_id, unanswered=true.
+func (rc *ReviewController) GetShopReviews(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return
+	}
+
+	list, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	// Владелец видит опубликованные и ожидающие проверки отзывы своих магазинов
+	query := database.DB.Model(&models.ProductReview{}).
+		Where("product_reviews.status <> ?", models.ReviewStatusRejected)
+	if isAdminUser(user) {
+		shopID, ok := catalogQueryUUID(c, "shopId")
+		if !ok {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid shopId"))
+			return
+		}
+		if shopID != nil {
+			query = query.Where("product_reviews.shop_id = ?", *shopID)
+		}
+	} else {
+		query = query.Where("product_reviews.shop_id IN (SELECT id FROM shops WHERE owner_id = ?)", user.ID)
+	}
+	if query, ok = applyReviewFilters(c, query); !ok {
+		return
+	}
+	if c.Query("unanswered") == "true" {
+		query = query.Where("product_reviews.replied_at IS NULL")
+	}
+
+	var total int64
+	if !list.CursorMode {
+		query.Count(&total)
+		query = query.Order("product_reviews.created_at DESC, product_reviews.id DESC")
+	}
+
+	var reviews []models.ProductReview
+	if err := list.Paginate(query.Preload("User").Preload("Product"), "product_reviews.created_at", "product_reviews.id").
+		Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to fetch reviews"))
+		return
+	}
+
+	if list.CursorMode {
+		reviews, cursor := models.CursorPage(reviews, list.Limit, func(r *models.ProductReview) models.Cursor {
+			return models.Cursor{Time: r.CreatedAt, ID: r.ID}
+		})
+		c.JSON(http.StatusOK, models.CursorSuccessResponse(reviewResponsesOf(reviews), cursor))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedSuccessResponse(reviewResponsesOf(reviews), list.Pagination(total)))
+}
+
+// loadShopReview загружает отзыв о товаре магазина текущего владельца (или любой - для админа)
+func loadShopReview(c *gin.Context) (*models.ProductReview, models.User, bool) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return nil, user, false
+	}
+
+	review, ok := loadReview(c)
+	if !ok {
+		return nil, user, false
+	}
+	if isAdminUser(user) {
+		return review, user, true
+	}
+
+	var count int64
+	if review.ShopID != nil {
+		database.DB.Model(&models.Shop{}).Where("id = ? AND owner_id = ?", *review.ShopID, user.ID).Count(&count)
+	}
+	if count == 0 {
+		c.JSON(http.StatusForbidden, models.ErrorResponseWithCode(models.ErrForbidden, "Review belongs to another shop"))
+		return nil, user, false
+	}
+	return review, user, true
+}
+
+// ReplyToReview сохраняет ответ магазина на отзыв и уведомляет автора
+func (rc *ReviewController) ReplyToReview(c *gin.Context) {
+	review, user, ok := loadShopReview(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReviewReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid request data", err.Error()))
+		return
+	}
+
+	now := time.Now()
+	firstReply := review.RepliedAt == nil
+	review.Reply = strings.TrimSpace(req.Text)
+	review.RepliedBy = &user.ID
+	review.RepliedAt = &now
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(review).Select("reply", "replied_by", "replied_at").Updates(review).Error; err != nil {
+			return err
+		}
+		if !firstReply || review.Status != models.ReviewStatusPublished {
+			return nil
+		}
+
+		productName := ""
+		if review.Product != nil {
+			productName = review.Product.Name
+		}
+		notification := models.Notification{
+			UserID:    review.UserID,
+			Title:     "Магазин ответил на ваш отзыв",
+			Body:      fmt.Sprintf("Ответ на отзыв о товаре «%s»", productName),
+			Type:      models.NotificationTypeSystem,
+			ActionURL: fmt.Sprintf("/products/%s#reviews", review.ProductID),
+		}
+		_, err := services.DispatchNotification(tx, &notification)
+		return err
+	})
+	if err != nil {
+		log.Printf("❌ Ошибка ответа на отзыв %s: %v", review.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to save reply"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(review.ToResponse()))
+}
+
+// DeleteReviewReply удаляет ответ магазина
+func (rc *ReviewController) DeleteReviewReply(c *gin.Context) {
+	review, _, ok := loadShopReview(c)
+	if !ok {
+		return
+	}
+
+	review.Reply = ""
+	review.RepliedBy = nil
+	review.RepliedAt = nil
+	if err := database.DB.Model(review).Select("reply", "replied_by", "replied_at").Updates(review).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to delete reply"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(review.ToResponse()))
+}
+
+// GetReviewsForModeration возвращает отзывы для модерации (админ).
+// По умолчанию - все статусы; фильтры status, shop_id, product_id, rating, with_photos.
+func (rc *ReviewController) GetReviewsForModeration(c *gin.Context) {
+	list, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&models.ProductReview{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("product_reviews.status = ?", status)
+	}
+	shopID, ok := catalogQueryUUID(c, "shop_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid shop_id"))
+		return
+	}
+	if shopID != nil {
+		query = query.Where("product_reviews.shop_id = ?", *shopID)
+	}
+	if query, ok = applyReviewFilters(c, query); !ok {
+		return
+	}
+
+	var total int64
+	if !list.CursorMode {
+		query.Count(&total)
+		query = query.Order("product_reviews.created_at DESC, product_reviews.id DESC")
+	}
+
+	var reviews []models.ProductReview
+	if err := list.Paginate(query.Preload("User").Preload("Product"), "product_reviews.created_at", "product_reviews.id").
+		Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to fetch reviews"))
+		return
+	}
+
+	if list.CursorMode {
+		reviews, cursor := models.CursorPage(reviews, list.Limit, func(r *models.ProductReview) models.Cursor {
+			return models.Cursor{Time: r.CreatedAt, ID: r.ID}
+		})
+		c.JSON(http.StatusOK, models.CursorSuccessResponse(reviewResponsesOf(reviews), cursor))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedSuccessResponse(reviewResponsesOf(reviews), list.Pagination(total)))
+}
+
+// ModerateReview публикует или скрывает отзыв (админ) и пересчитывает рейтинги
+func (rc *ReviewController) ModerateReview(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return
+	}
+
+	review, ok := loadReview(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReviewModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(models.ErrValidationError, "Invalid request data", err.Error()))
+		return
+	}
+
+	now := time.Now()
+	review.Status = req.Status
+	review.ModerationNote = strings.TrimSpace(req.Note)
+	review.ModeratedBy = &user.ID
+	review.ModeratedAt = &now
+	if err := saveReview(review, func(tx *gorm.DB) error {
+		return tx.Model(review).Select("status", "moderation_note", "moderated_by", "moderated_at").Updates(review).Error
+	}); err != nil {
+		log.Printf("❌ Ошибка модерации отзыва %s: %v", review.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(models.ErrInternalError, "Failed to moderate review"))
+		return
+	}
+
+	log.Printf("🛡️ Отзыв %s: статус %s (модератор %s)", review.ID, review.Status, user.ID)
+	c.JSON(http.StatusOK, models.SuccessResponse(review.ToResponse()))
+}
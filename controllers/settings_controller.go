@@ -1,227 +1,394 @@
-package controllers
-
-import (
-	"net/http"
-
-	"github.com/mm-api/mm-api/database"
-	"github.com/mm-api/mm-api/middleware"
-	"github.com/mm-api/mm-api/models"
-	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
-	"gorm.io/gorm"
-)
-
-// SettingsController обрабатывает запросы настроек пользователя
-type SettingsController struct{}
-
-// GetSettings возвращает настройки пользователя
-func (sc *SettingsController) GetSettings(c *gin.Context) {
-	user, exists := middleware.GetCurrentUser(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
-			models.ErrAuthRequired,
-			"User not found",
-		))
-		return
-	}
-
-	var settings models.UserSettings
-	err := database.DB.Preload("City").Where("user_id = ?", user.ID).First(&settings).Error
-
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// Создаем настройки по умолчанию, если их нет
-			settings = models.UserSettings{
-				UserID:               user.ID,
-				Language:             "ru",
-				Theme:                "system",
-				NotificationsEnabled: true,
-				EmailNotifications:   true,
-				PushNotifications:    true,
-			}
-
-			if err := database.DB.Create(&settings).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-					models.ErrInternalError,
-					"Failed to create default settings",
-				))
-				return
-			}
-		} else {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-				models.ErrInternalError,
-				"Failed to fetch settings",
-			))
-			return
-		}
-	}
-
-	// Загружаем City если настройки были только что созданы
-	if settings.City == nil && settings.SelectedCityID != nil {
-		database.DB.Preload("City").First(&settings, settings.ID)
-	}
-
-	c.JSON(http.StatusOK, models.SuccessResponse(settings.ToResponse()))
-}
-
-// UpdateSettings обновляет настройки пользователя
-func (sc *SettingsController) UpdateSettings(c *gin.Context) {
-	user, exists := middleware.GetCurrentUser(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
-			models.ErrAuthRequired,
-			"User not found",
-		))
-		return
-	}
-
-	var req models.SettingsRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
-			models.ErrValidationError,
-			"Invalid request data",
-			err.Error(),
-		))
-		return
-	}
-
-	var settings models.UserSettings
-	err := database.DB.Preload("City").Where("user_id = ?", user.ID).First(&settings).Error
-
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// Создаем новые настройки
-			settings = models.UserSettings{
-				UserID: user.ID,
-			}
-		} else {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-				models.ErrInternalError,
-				"Database error",
-			))
-			return
-		}
-	}
-
-	// Обновляем только переданные поля
-	if req.Language != "" {
-		settings.Language = req.Language
-	}
-
-	if req.Theme != "" {
-		settings.Theme = req.Theme
-	}
-
-	if req.NotificationsEnabled != nil {
-		settings.NotificationsEnabled = *req.NotificationsEnabled
-	}
-
-	if req.EmailNotifications != nil {
-		settings.EmailNotifications = *req.EmailNotifications
-	}
-
-	if req.PushNotifications != nil {
-		settings.PushNotifications = *req.PushNotifications
-	}
-
-	// Обновляем выбранный город
-	if req.SelectedCityID != nil {
-		if cityUUID, err := uuid.Parse(*req.SelectedCityID); err == nil {
-			settings.SelectedCityID = &cityUUID
-		}
-	}
-
-	// Сохраняем настройки
-	if settings.ID == uuid.Nil {
-		// Создаем новые настройки
-		if err := database.DB.Create(&settings).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-				models.ErrInternalError,
-				"Failed to create settings",
-			))
-			return
-		}
-	} else {
-		// Обновляем существующие
-		if err := database.DB.Save(&settings).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-				models.ErrInternalError,
-				"Failed to update settings",
-			))
-			return
-		}
-	}
-
-	c.JSON(http.StatusOK, models.SuccessResponse(
-		settings.ToResponse(),
-		"Settings updated successfully",
-	))
-}
-
-// ResetSettings сбрасывает настройки к значениям по умолчанию
-func (sc *SettingsController) ResetSettings(c *gin.Context) {
-	user, exists := middleware.GetCurrentUser(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
-			models.ErrAuthRequired,
-			"User not found",
-		))
-		return
-	}
-
-	// Устанавливаем настройки по умолчанию
-	updates := map[string]interface{}{
-		"language":              "ru",
-		"theme":                 "system",
-		"notifications_enabled": true,
-		"email_notifications":   true,
-		"push_notifications":    true,
-	}
-
-	// Обновляем или создаем настройки
-	var settings models.UserSettings
-	err := database.DB.Where("user_id = ?", user.ID).First(&settings).Error
-
-	if err == gorm.ErrRecordNotFound {
-		// Создаем новые настройки с умолчаниями
-		settings = models.UserSettings{
-			UserID:               user.ID,
-			Language:             "ru",
-			Theme:                "system",
-			NotificationsEnabled: true,
-			EmailNotifications:   true,
-			PushNotifications:    true,
-		}
-
-		if err := database.DB.Create(&settings).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-				models.ErrInternalError,
-				"Failed to reset settings",
-			))
-			return
-		}
-	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Database error",
-		))
-		return
-	} else {
-		// Обновляем существующие настройки
-		if err := database.DB.Model(&settings).Updates(updates).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-				models.ErrInternalError,
-				"Failed to reset settings",
-			))
-			return
-		}
-
-		// Перезагружаем обновленные настройки
-		database.DB.First(&settings, settings.ID)
-	}
-
-	c.JSON(http.StatusOK, models.SuccessResponse(
-		settings.ToResponse(),
-		"Settings reset to defaults",
-	))
-}
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/middleware"
+	"github.com/mm-api/mm-api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SettingsController обрабатывает запросы настроек пользователя
+type SettingsController struct{}
+
+// GetSettings возвращает настройки пользователя
+func (sc *SettingsController) GetSettings(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+
+	var settings models.UserSettings
+	err := database.DB.Preload("City").Where("user_id = ?", user.ID).First(&settings).Error
+
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			// Создаем настройки по умолчанию, если их нет
+			settings = models.UserSettings{
+				UserID:               user.ID,
+				Language:             "ru",
+				Theme:                "system",
+				NotificationsEnabled: true,
+				EmailNotifications:   true,
+				PushNotifications:    true,
+			}
+
+			if err := database.DB.Create(&settings).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+					models.ErrInternalError,
+					"Failed to create default settings",
+				))
+				return
+			}
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Failed to fetch settings",
+			))
+			return
+		}
+	}
+
+	// Загружаем City если настройки были только что созданы
+	if settings.City == nil && settings.SelectedCityID != nil {
+		database.DB.Preload("City").First(&settings, settings.ID)
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(settings.ToResponse()))
+}
+
+// UpdateSettings обновляет настройки пользователя
+func (sc *SettingsController) UpdateSettings(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+
+	var req models.SettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	var settings models.UserSettings
+	err := database.DB.Preload("City").Where("user_id = ?", user.ID).First(&settings).Error
+
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			// Создаем новые настройки
+			settings = models.UserSettings{
+				UserID: user.ID,
+			}
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Database error",
+			))
+			return
+		}
+	}
+
+	// Обновляем только переданные поля
+	if req.Language != "" {
+		settings.Language = req.Language
+	}
+
+	if req.Theme != "" {
+		settings.Theme = req.Theme
+	}
+
+	if req.NotificationsEnabled != nil {
+		settings.NotificationsEnabled = *req.NotificationsEnabled
+	}
+
+	if req.EmailNotifications != nil {
+		settings.EmailNotifications = *req.EmailNotifications
+	}
+
+	if req.PushNotifications != nil {
+		settings.PushNotifications = *req.PushNotifications
+	}
+
+	// Тихие часы
+	if req.QuietHoursEnabled != nil {
+		settings.QuietHoursEnabled = *req.QuietHoursEnabled
+	}
+
+	if req.QuietHoursStart != nil {
+		if _, err := models.ParseClock(*req.QuietHoursStart); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+				models.ErrValidationError,
+				"Invalid quietHoursStart, expected HH:MM",
+			))
+			return
+		}
+		settings.QuietHoursStart = *req.QuietHoursStart
+	}
+
+	if req.QuietHoursEnd != nil {
+		if _, err := models.ParseClock(*req.QuietHoursEnd); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+				models.ErrValidationError,
+				"Invalid quietHoursEnd, expected HH:MM",
+			))
+			return
+		}
+		settings.QuietHoursEnd = *req.QuietHoursEnd
+	}
+
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+				models.ErrValidationError,
+				"Invalid timezone, expected IANA name (e.g. Asia/Dushanbe)",
+			))
+			return
+		}
+		settings.Timezone = *req.Timezone
+	}
+
+	// Обновляем выбранный город
+	if req.SelectedCityID != nil {
+		if cityUUID, err := uuid.Parse(*req.SelectedCityID); err == nil {
+			settings.SelectedCityID = &cityUUID
+		}
+	}
+
+	// Сохраняем настройки
+	if settings.ID == uuid.Nil {
+		// Создаем новые настройки
+		if err := database.DB.Create(&settings).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Failed to create settings",
+			))
+			return
+		}
+	} else {
+		// Обновляем существующие
+		if err := database.DB.Save(&settings).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Failed to update settings",
+			))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		settings.ToResponse(),
+		"Settings updated successfully",
+	))
+}
+
+// ResetSettings сбрасывает настройки к значениям по умолчанию
+func (sc *SettingsController) ResetSettings(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+
+	// Устанавливаем настройки по умолчанию
+	updates := map[string]interface{}{
+		"language":              "ru",
+		"theme":                 "system",
+		"notifications_enabled": true,
+		"email_notifications":   true,
+		"push_notifications":    true,
+		"quiet_hours_enabled":   false,
+		"quiet_hours_start":     "22:00",
+		"quiet_hours_end":       "08:00",
+		"timezone":              models.DefaultTimezone,
+	}
+
+	// Обновляем или создаем настройки
+	var settings models.UserSettings
+	err := database.DB.Where("user_id = ?", user.ID).First(&settings).Error
+
+	if err == gorm.ErrRecordNotFound {
+		// Создаем новые настройки с умолчаниями
+		settings = models.UserSettings{
+			UserID:               user.ID,
+			Language:             "ru",
+			Theme:                "system",
+			NotificationsEnabled: true,
+			EmailNotifications:   true,
+			PushNotifications:    true,
+		}
+
+		if err := database.DB.Create(&settings).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Failed to reset settings",
+			))
+			return
+		}
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Database error",
+		))
+		return
+	} else {
+		// Обновляем существующие настройки
+		if err := database.DB.Model(&settings).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Failed to reset settings",
+			))
+			return
+		}
+
+		// Перезагружаем обновленные настройки
+		database.DB.First(&settings, settings.ID)
+	}
+
+	// Предпочтения по типам уведомлений также возвращаются к значениям по умолчанию
+	if err := database.DB.Where("user_id = ?", user.ID).Delete(&models.NotificationPreference{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to reset notification preferences",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		settings.ToResponse(),
+		"Settings reset to defaults",
+	))
+}
+
+// GetNotificationPreferences возвращает матрицу предпочтений уведомлений (тип x канал) и тихие часы
+func (sc *SettingsController) GetNotificationPreferences(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+
+	response, err := buildNotificationPreferencesResponse(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to fetch notification preferences",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(response))
+}
+
+// UpdateNotificationPreferences обновляет предпочтения уведомлений по типам и каналам.
+// Переданные пары тип/канал перезаписываются, остальные не меняются.
+func (sc *SettingsController) UpdateNotificationPreferences(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(
+			models.ErrAuthRequired,
+			"User not found",
+		))
+		return
+	}
+
+	var req models.NotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, item := range req.Preferences {
+			pref := models.NotificationPreference{
+				UserID:  user.ID,
+				Type:    item.Type,
+				Channel: item.Channel,
+				Enabled: item.Enabled,
+			}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "channel"}},
+				DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+			}).Create(&pref).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to update notification preferences",
+		))
+		return
+	}
+
+	response, err := buildNotificationPreferencesResponse(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to fetch notification preferences",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(
+		response,
+		"Notification preferences updated successfully",
+	))
+}
+
+// buildNotificationPreferencesResponse собирает предпочтения пользователя с учетом значений по умолчанию
+func buildNotificationPreferencesResponse(userID uuid.UUID) (models.NotificationPreferencesResponse, error) {
+	var prefs []models.NotificationPreference
+	if err := database.DB.Where("user_id = ?", userID).Find(&prefs).Error; err != nil {
+		return models.NotificationPreferencesResponse{}, err
+	}
+
+	response := models.NotificationPreferencesResponse{
+		Preferences:     models.BuildNotificationPreferences(prefs),
+		QuietHoursStart: "22:00",
+		QuietHoursEnd:   "08:00",
+		Timezone:        models.DefaultTimezone,
+	}
+
+	var settings models.UserSettings
+	err := database.DB.Where("user_id = ?", userID).First(&settings).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return response, err
+	}
+	if err == nil {
+		response.QuietHoursEnabled = settings.QuietHoursEnabled
+		response.QuietHoursStart = settings.QuietHoursStart
+		response.QuietHoursEnd = settings.QuietHoursEnd
+		response.Timezone = settings.Timezone
+	}
+
+	return response, nil
+}
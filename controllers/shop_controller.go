@@ -13,6 +13,8 @@ import (
 
 	"github.com/mm-api/mm-api/database"
 	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"github.com/mm-api/mm-api/storage"
 	"github.com/mm-api/mm-api/utils"
 
 	"github.com/gin-gonic/gin"
@@ -109,6 +111,8 @@ func (sc *ShopController) GetShopInfo(c *gin.Context) {
 				"avatar":          avatar,
 				"productsCount":   productsCount,
 				"subscribersCount": subscribersCount,
+				"rating":          shop.Rating,        // 0 для legacy магазинов
+				"reviewsCount":    shop.ReviewsCount,
 				"createdAt":       createdAt,
 			},
 		},
@@ -174,23 +178,21 @@ func (sc *ShopController) GetShopProducts(c *gin.Context) {
 		query = database.DB.Model(&models.Product{}).Where("shop_id = ? OR owner_id = ?", shopID, shop.OwnerID)
 	}
 
-	// Фильтрация по категории
+	// Фильтрация по категории (вместе с подкатегориями)
 	if categoryID := c.Query("category"); categoryID != "" {
 		if categoryUUID, err := uuid.Parse(categoryID); err == nil {
-			query = query.Where("category_id = ?", categoryUUID)
+			query = query.Where(services.CategorySubtreeCondition("category_id"), categoryUUID)
 		}
 	}
 
-	// Поиск по названию
+	// Полнотекстовый поиск (морфология, опечатки; без sort_by - по релевантности)
 	if search := c.Query("search"); search != "" {
-		query = query.Where("name ILIKE ? OR description ILIKE ?", "%"+search+"%", "%"+search+"%")
+		query = services.GetSearchIndex().Apply(query, search, c.Query("sort_by") == "")
 	}
 
 	// Фильтрация по наличию на складе
 	if inStock := c.Query("in_stock"); inStock == "true" {
-		query = query.Joins("JOIN product_variations ON products.id = product_variations.product_id").
-			Where("product_variations.stock_quantity > 0").
-			Distinct("products.id")
+		query = query.Where(services.CatalogInStockCondition)
 	}
 
 	// Фильтрация по полу
@@ -214,18 +216,9 @@ func (sc *ShopController) GetShopProducts(c *gin.Context) {
 	// Сортировка
 	sortBy := c.DefaultQuery("sort_by", "created_at")
 	sortOrder := c.DefaultQuery("sort_order", "desc")
-	allowedSorts := map[string]bool{
-		"name":       true,
-		"created_at": true,
-		"price":      false, // Для цены нужен JOIN с вариациями
-	}
-
-	if allowedSorts[sortBy] {
-		if sortOrder == "asc" {
-			query = query.Order("products." + sortBy + " ASC")
-		} else {
-			query = query.Order("products." + sortBy + " DESC")
-		}
+	// Цена и скидка считаются по вариациям товара
+	if orderBy, ok := services.CatalogOrder(sortBy, sortOrder == "asc"); ok {
+		query = query.Order(orderBy)
 	}
 
 	// Пагинация
@@ -692,6 +685,24 @@ func (sc *ShopController) CheckSubscription(c *gin.Context) {
 	})
 }
 
+// shopListOrder сортировка списков магазинов: sort_by=created_at (по умолчанию), rating или name
+func shopListOrder(c *gin.Context) string {
+	direction := " DESC"
+	if c.Query("sort_order") == "asc" {
+		direction = " ASC"
+	}
+	switch c.Query("sort_by") {
+	case "rating":
+		return "shops.rating" + direction + ", shops.reviews_count DESC, shops.created_at DESC"
+	case "name":
+		if c.Query("sort_order") == "" {
+			direction = " ASC"
+		}
+		return "shops.name" + direction
+	}
+	return "shops.created_at" + direction
+}
+
 // GetShops возвращает список всех магазинов с информацией о подписке пользователя
 func (sc *ShopController) GetShops(c *gin.Context) {
 	log.Printf("🛍️ [GetShops] Начало получения списка магазинов")
@@ -778,7 +789,7 @@ func (sc *ShopController) GetShops(c *gin.Context) {
 	// Получаем магазины с загрузкой связей
 	if err := query.Preload("Owner.Role").
 		Preload("City").
-		Order("created_at DESC").
+		Order(shopListOrder(c)).
 		Offset(offset).
 		Limit(limit).
 		Find(&shops).Error; err != nil {
@@ -890,6 +901,7 @@ func (sc *ShopController) GetShops(c *gin.Context) {
 			"phone":            shop.Phone,
 			"address":          shop.Address,
 			"rating":           shop.Rating,
+			"reviewsCount":     shop.ReviewsCount,
 			"isActive":         shop.IsActive,
 			"ownerId":          shop.OwnerID,
 			"productsCount":    productsCount,
@@ -1075,7 +1087,7 @@ func (sc *ShopController) GetShopsWithLicenses(c *gin.Context) {
 	// Получаем магазины с загрузкой связей
 	if err := query.Preload("Owner.Role").
 		Preload("City").
-		Order("created_at DESC").
+		Order(shopListOrder(c)).
 		Offset(offset).
 		Limit(limit).
 		Find(&shops).Error; err != nil {
@@ -1189,6 +1201,7 @@ func (sc *ShopController) GetShopsWithLicenses(c *gin.Context) {
 			"phone":            shop.Phone,
 			"logo":             shop.Logo,
 			"rating":           shop.Rating,
+			"reviewsCount":     shop.ReviewsCount,
 			"isActive":         shop.IsActive,
 			"productsCount":    productsCount,
 			"subscribersCount": subscribersCount,
@@ -1421,13 +1434,6 @@ func (sc *ShopController) UploadLogo(c *gin.Context) {
 	// Загружаем изображение
 	folder := "shop-logos"
 	uploadDir := fmt.Sprintf("images/%s", folder)
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
-			models.ErrInternalError,
-			"Ошибка создания папки",
-		))
-		return
-	}
 
 	// Генерируем уникальное имя файла
 	ext := filepath.Ext(header.Filename)
@@ -1436,7 +1442,7 @@ func (sc *ShopController) UploadLogo(c *gin.Context) {
 	}
 	ext = strings.ToLower(ext)
 	filename := fmt.Sprintf("%s%s", uuid.New().String(), ext)
-	filePath := filepath.Join(uploadDir, filename)
+	filePath := storage.JoinKey(uploadDir, filename)
 
 	// Сохраняем файл через метод UploadController
 	contentType := header.Header.Get("Content-Type")
@@ -1458,16 +1464,10 @@ func (sc *ShopController) UploadLogo(c *gin.Context) {
 	// Формируем URL
 	logoURL := uploadController.GetImageURL(filename, folder)
 
-	// Удаляем старый логотип, если он есть
-	if shop.Logo != "" {
-		oldFilename := filepath.Base(shop.Logo)
-		oldPath := filepath.Join(uploadDir, oldFilename)
-		if _, err := os.Stat(oldPath); err == nil {
-			os.Remove(oldPath)
-		}
-	}
+	services.RegisterMediaUpload(database.DB, logoURL, 0, "", uploaderID(c))
 
-	// Обновляем логотип магазина
+	// Обновляем логотип магазина; старый файл удалит сборщик мусора медиа
+	oldLogo := shop.Logo
 	shop.Logo = logoURL
 	if err := database.DB.Save(&shop).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
@@ -1476,6 +1476,7 @@ func (sc *ShopController) UploadLogo(c *gin.Context) {
 		))
 		return
 	}
+	services.ReleaseMedia(database.DB, oldLogo)
 
 	// Загружаем обновленные данные
 	database.DB.Preload("Owner").Preload("City").First(&shop, shop.ID)
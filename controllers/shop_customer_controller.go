@@ -3,7 +3,6 @@ package controllers
 import (
 	"log"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/mm-api/mm-api/database"
@@ -423,30 +422,25 @@ func (scc *ShopCustomerController) GetBonusHistory(c *gin.Context) {
 		return
 	}
 
-	// Параметры пагинации
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 20
+	// Параметры пагинации: курсор (after) или страница
+	list, ok := parseListQuery(c)
+	if !ok {
+		return
 	}
-	offset := (page - 1) * limit
 
 	// Получаем историю бонусов
 	var history []models.BonusHistory
 	var total int64
 
-	database.DB.Model(&models.BonusHistory{}).
-		Where("shop_client_id = ?", shopClient.ID).
-		Count(&total)
+	query := database.DB.Where("shop_client_id = ?", shopClient.ID)
+	if !list.CursorMode {
+		database.DB.Model(&models.BonusHistory{}).
+			Where("shop_client_id = ?", shopClient.ID).
+			Count(&total)
+		query = query.Order("created_at DESC")
+	}
 
-	if err := database.DB.Where("shop_client_id = ?", shopClient.ID).
-		Order("created_at DESC").
-		Offset(offset).
-		Limit(limit).
-		Find(&history).Error; err != nil {
+	if err := list.Paginate(query, "created_at", "id").Find(&history).Error; err != nil {
 		log.Printf("❌ [GetBonusHistory] Ошибка получения истории: %v", err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
 			models.ErrInternalError,
@@ -455,18 +449,30 @@ func (scc *ShopCustomerController) GetBonusHistory(c *gin.Context) {
 		return
 	}
 
+	var cursor models.CursorInfo
+	if list.CursorMode {
+		history, cursor = models.CursorPage(history, list.Limit, func(h *models.BonusHistory) models.Cursor {
+			return models.Cursor{Time: h.CreatedAt, ID: h.ID}
+		})
+	}
+
 	// Преобразуем в ответы
 	responses := make([]models.BonusHistoryResponse, len(history))
 	for i, h := range history {
 		responses[i] = h.ToResponse()
 	}
 
+	if list.CursorMode {
+		c.JSON(http.StatusOK, models.CursorSuccessResponse(responses, cursor, "История бонусов получена"))
+		return
+	}
+
 	c.JSON(http.StatusOK, models.SuccessResponse(
 		gin.H{
 			"history": responses,
 			"total":   total,
-			"page":    page,
-			"limit":   limit,
+			"page":    list.Page,
+			"limit":   list.Limit,
 		},
 		"История бонусов получена",
 	))
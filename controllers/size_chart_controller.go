@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"gorm.io/gorm"
+)
+
+// SizeChartController управляет размерными сетками категорий и брендов
+type SizeChartController struct{}
+
+// GetProductSizeChart возвращает размерную сетку, подходящую товару
+func (sc *SizeChartController) GetProductSizeChart(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid product ID",
+		))
+		return
+	}
+
+	var product models.Product
+	if err := database.DB.Select("id", "category_id", "brand").First(&product, "id = ?", productID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+				models.ErrProductNotFound,
+				"Product not found",
+			))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Database error",
+			))
+		}
+		return
+	}
+
+	chart, err := services.FindSizeChart(database.DB, product.CategoryID, product.Brand)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to fetch size chart",
+		))
+		return
+	}
+	if chart == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+			models.ErrNotFound,
+			"Size chart not found",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(chart))
+}
+
+// GetSizeCharts возвращает размерные сетки с фильтром по категории и бренду (админка)
+func (sc *SizeChartController) GetSizeCharts(c *gin.Context) {
+	list, ok := parseListQuery(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&models.SizeChart{})
+	categoryID, ok := catalogQueryUUID(c, "category_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid category_id",
+		))
+		return
+	}
+	if categoryID != nil {
+		query = query.Where("category_id = ?", *categoryID)
+	}
+	if brand := strings.TrimSpace(c.Query("brand")); brand != "" {
+		query = query.Where("lower(brand) = lower(?)", brand)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Database error",
+		))
+		return
+	}
+
+	var charts []models.SizeChart
+	if err := query.Preload("Category").Order("brand ASC, name ASC").
+		Offset((list.Page - 1) * list.Limit).Limit(list.Limit).Find(&charts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to fetch size charts",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedSuccessResponse(charts, list.Pagination(total)))
+}
+
+// bindSizeChartRequest читает и проверяет размерную сетку
+func bindSizeChartRequest(c *gin.Context) (models.SizeChartRequest, bool) {
+	var req models.SizeChartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return req, false
+	}
+	if err := services.ValidateSizeChart(&req); err != nil {
+		var validationErr *services.AttributeValidationError
+		errors.As(err, &validationErr)
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid size chart",
+			validationErr.Problems,
+		))
+		return req, false
+	}
+	if req.CategoryID != nil {
+		var category models.Category
+		if err := database.DB.Select("id").First(&category, "id = ?", *req.CategoryID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+				models.ErrValidationError,
+				"Category not found",
+			))
+			return req, false
+		}
+	}
+	return req, true
+}
+
+// CreateSizeChart создает размерную сетку (только для админов)
+func (sc *SizeChartController) CreateSizeChart(c *gin.Context) {
+	req, ok := bindSizeChartRequest(c)
+	if !ok {
+		return
+	}
+
+	chart := models.SizeChart{
+		Name:       req.Name,
+		CategoryID: req.CategoryID,
+		Brand:      req.Brand,
+		Columns:    req.Columns,
+		Rows:       req.Rows,
+		Note:       req.Note,
+	}
+	if err := database.DB.Create(&chart).Error; err != nil {
+		log.Printf("❌ Ошибка создания размерной сетки: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to create size chart",
+		))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse(chart, "Size chart created successfully"))
+}
+
+// UpdateSizeChart заменяет размерную сетку (только для админов)
+func (sc *SizeChartController) UpdateSizeChart(c *gin.Context) {
+	chartID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid size chart ID",
+		))
+		return
+	}
+
+	req, ok := bindSizeChartRequest(c)
+	if !ok {
+		return
+	}
+
+	var chart models.SizeChart
+	if err := database.DB.First(&chart, "id = ?", chartID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+				models.ErrNotFound,
+				"Size chart not found",
+			))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+				models.ErrInternalError,
+				"Database error",
+			))
+		}
+		return
+	}
+
+	chart.Name = req.Name
+	chart.CategoryID = req.CategoryID
+	chart.Brand = req.Brand
+	chart.Columns = req.Columns
+	chart.Rows = req.Rows
+	chart.Note = req.Note
+	if err := database.DB.Save(&chart).Error; err != nil {
+		log.Printf("❌ Ошибка обновления размерной сетки: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to update size chart",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(chart, "Size chart updated successfully"))
+}
+
+// DeleteSizeChart удаляет размерную сетку (только для админов)
+func (sc *SizeChartController) DeleteSizeChart(c *gin.Context) {
+	chartID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid size chart ID",
+		))
+		return
+	}
+
+	result := database.DB.Delete(&models.SizeChart{}, "id = ?", chartID)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to delete size chart",
+		))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+			models.ErrNotFound,
+			"Size chart not found",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(nil, "Size chart deleted successfully"))
+}
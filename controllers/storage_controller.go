@@ -0,0 +1,379 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/config"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/middleware"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"github.com/mm-api/mm-api/storage"
+)
+
+// StorageController раздает файлы хранилища и выдает временные ссылки
+type StorageController struct{}
+
+// countingReader считает прочитанные байты (размер загруженного файла)
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// storagePrefixAllowed проверяет, что ключ лежит в одной из папок приложения
+func storagePrefixAllowed(key string) bool {
+	for _, prefix := range storage.DefaultPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// serveStorageObject отдает объект клиенту: локальный файл напрямую (с поддержкой Range,
+// If-Range и If-None-Match), S3 - перенаправлением на временную ссылку (Range и ETag
+// поддерживает само хранилище). downloadName задает имя скачиваемого файла.
+func serveStorageObject(c *gin.Context, key, downloadName, contentType string) {
+	store := storage.Default()
+
+	if _, ok := store.(*storage.LocalStorage); !ok {
+		url, err := store.PresignGet(c.Request.Context(), key, config.GetConfig().GetStoragePresignTTL(), downloadName)
+		if err != nil {
+			log.Printf("❌ Ошибка создания ссылки на %s: %v", key, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "failed to create download link",
+			})
+			return
+		}
+		c.Redirect(http.StatusFound, url)
+		return
+	}
+
+	reader, obj, err := store.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   "file not found",
+			})
+			return
+		}
+		log.Printf("❌ Ошибка чтения %s из хранилища: %v", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to read file",
+		})
+		return
+	}
+	defer reader.Close()
+
+	if downloadName != "" {
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": downloadName}))
+	}
+	if contentType == "" {
+		contentType = obj.ContentType
+	}
+	if contentType != "" {
+		c.Header("Content-Type", contentType)
+	}
+
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		// ETag нужен для If-None-Match и If-Range (докачка); файлы релизов задают свой по SHA256
+		if c.Writer.Header().Get("ETag") == "" {
+			c.Header("ETag", fmt.Sprintf("\"%x-%x\"", obj.ModTime.UnixNano(), obj.Size))
+		}
+		http.ServeContent(c.Writer, c.Request, path.Base(obj.Key), obj.ModTime, seeker)
+		return
+	}
+	c.DataFromReader(http.StatusOK, obj.Size, contentType, reader, nil)
+}
+
+// releaseDownloadURL ссылка на скачивание релиза: для S3 временная ссылка прямо в бакет,
+// для локального хранилища - обычный адрес файла
+func releaseDownloadURL(c *gin.Context, key, downloadName string) string {
+	store := storage.Default()
+	if _, ok := store.(*storage.LocalStorage); ok {
+		return store.URL(key)
+	}
+	url, err := store.PresignGet(c.Request.Context(), key, config.GetConfig().GetStoragePresignTTL(), downloadName)
+	if err != nil {
+		log.Printf("⚠️ Не удалось создать ссылку на %s: %v", key, err)
+		return store.URL(key)
+	}
+	return url
+}
+
+// ServeFiles раздает папку хранилища (/images, /updates, /libiss_pos).
+// Заменяет статическую раздачу: ссылки в БД не зависят от бэкенда.
+func (sc *StorageController) ServeFiles(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := storage.JoinKey(prefix, c.Param("filepath"))
+		if _, err := storage.CleanKey(key); err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+
+		store := storage.Default()
+		if _, ok := store.(*storage.LocalStorage); !ok {
+			// Публичный бакет или CDN - постоянная ссылка, иначе временная
+			if url := store.URL(key); strings.HasPrefix(url, "http") {
+				c.Redirect(http.StatusFound, url)
+				return
+			}
+		}
+		serveStorageObject(c, key, "", "")
+	}
+}
+
+// parseSignedRequest проверяет подпись ссылки локального хранилища. Возвращает
+// лимит размера загрузки из ссылки (0 - без лимита).
+func parseSignedRequest(c *gin.Context, method string) (*storage.LocalStorage, string, int64, bool) {
+	local, ok := storage.Default().(*storage.LocalStorage)
+	if !ok {
+		c.JSON(http.StatusNotFound, models.ErrorResponseWithCode(
+			models.ErrNotFound,
+			"Signed URLs are served by the object storage",
+		))
+		return nil, "", 0, false
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	var maxSize int64
+	if raw := c.Query("max"); err == nil && raw != "" {
+		maxSize, err = strconv.ParseInt(raw, 10, 64)
+	}
+	if err != nil || !local.VerifySignature(method, key, expires, c.Query("name"), maxSize, c.Query("signature")) {
+		c.JSON(http.StatusForbidden, models.ErrorResponseWithCode(
+			models.ErrForbidden,
+			"Invalid or expired signature",
+		))
+		return nil, "", 0, false
+	}
+	return local, key, maxSize, true
+}
+
+// GetSignedObject скачивание по временной ссылке локального хранилища
+func (sc *StorageController) GetSignedObject(c *gin.Context) {
+	_, key, _, ok := parseSignedRequest(c, http.MethodGet)
+	if !ok {
+		return
+	}
+	serveStorageObject(c, key, c.Query("name"), "")
+}
+
+// PutSignedObject загрузка по временной ссылке локального хранилища.
+// Тело больше лимита из ссылки обрывается, файл не сохраняется.
+func (sc *StorageController) PutSignedObject(c *gin.Context) {
+	local, key, maxSize, ok := parseSignedRequest(c, http.MethodPut)
+	if !ok {
+		return
+	}
+
+	if maxSize > 0 {
+		if c.Request.ContentLength > maxSize {
+			c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponseWithCode(models.ErrValidationError,
+				fmt.Sprintf("File is too large (max %d bytes)", maxSize)))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+	}
+
+	if err := local.Put(c.Request.Context(), key, c.Request.Body, c.Request.ContentLength, c.ContentType()); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponseWithCode(models.ErrValidationError,
+				fmt.Sprintf("File is too large (max %d bytes)", maxSize)))
+			return
+		}
+		log.Printf("❌ Ошибка загрузки %s по временной ссылке: %v", key, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to store object",
+		))
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// presignResponse ответ с временной ссылкой
+func presignResponse(method, key, url string, ttl time.Duration) gin.H {
+	return gin.H{
+		"method":    method,
+		"key":       key,
+		"url":       url,
+		"publicUrl": "/" + key,
+		"expiresAt": time.Now().Add(ttl),
+	}
+}
+
+// PresignObject выдает временную ссылку на любой объект хранилища (только для админов)
+func (sc *StorageController) PresignObject(c *gin.Context) {
+	var req struct {
+		Key         string `json:"key" binding:"required"`
+		Method      string `json:"method"`
+		ContentType string `json:"contentType"`
+		FileName    string `json:"fileName"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	key, err := storage.CleanKey(req.Key)
+	if err != nil || !storagePrefixAllowed(key) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			fmt.Sprintf("Key must be inside %s", strings.Join(storage.DefaultPrefixes, ", ")),
+		))
+		return
+	}
+
+	ttl := config.GetConfig().GetStoragePresignTTL()
+	store := storage.Default()
+	method := strings.ToUpper(req.Method)
+	var url string
+	switch method {
+	case "", http.MethodGet:
+		method = http.MethodGet
+		url, err = store.PresignGet(c.Request.Context(), key, ttl, req.FileName)
+	case http.MethodPut:
+		url, err = store.PresignPut(c.Request.Context(), key, ttl, req.ContentType)
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Method must be GET or PUT",
+		))
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Ошибка создания временной ссылки: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to create presigned URL",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(presignResponse(method, key, url, ttl)))
+}
+
+// imageUploadContentType тип изображения по расширению, если он разрешен UPLOAD_ALLOWED_TYPES.
+// SVG не принимается никогда: он может содержать скрипты.
+func imageUploadContentType(ext string) (string, bool) {
+	contentType := mime.TypeByExtension(ext)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	if !strings.HasPrefix(contentType, "image/") || ext == ".svg" || contentType == "image/svg+xml" {
+		return "", false
+	}
+	for _, allowed := range config.GetConfig().GetUploadAllowedTypes() {
+		if strings.EqualFold(strings.TrimSpace(allowed), contentType) {
+			return contentType, true
+		}
+	}
+	return "", false
+}
+
+// PresignImageUpload выдает временную ссылку для прямой загрузки изображения в хранилище.
+// Файл сохраняется без сжатия; публичный адрес возвращается в publicUrl. Тип файла
+// и лимит UPLOAD_MAX_SIZE проверяет хранилище (подпись ссылки или POST policy S3).
+func (sc *StorageController) PresignImageUpload(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponseWithCode(models.ErrAuthRequired, "User not found"))
+		return
+	}
+	if user.IsGuest {
+		c.JSON(http.StatusForbidden, models.ErrorResponseWithCode(
+			models.ErrForbidden,
+			"Guest accounts cannot upload files, register first",
+		))
+		return
+	}
+
+	var req struct {
+		Folder   string `json:"folder"`
+		FileName string `json:"fileName" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid request data",
+			err.Error(),
+		))
+		return
+	}
+
+	ext := strings.ToLower(filepath.Ext(req.FileName))
+	contentType, ok := imageUploadContentType(ext)
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Unsupported file extension",
+			fmt.Sprintf("allowed types: %s", config.GetConfig().UploadAllowedTypes),
+		))
+		return
+	}
+
+	folder := req.Folder
+	if folder == "" {
+		folder = "uploads"
+	}
+	key, err := storage.CleanKey(storage.JoinKey("images", folder, uuid.NewString()+ext))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponseWithCode(
+			models.ErrValidationError,
+			"Invalid folder",
+		))
+		return
+	}
+
+	cfg := config.GetConfig()
+	ttl := cfg.GetStoragePresignTTL()
+	maxSize := cfg.GetUploadMaxSize()
+	upload, err := storage.Default().PresignUpload(c.Request.Context(), key, ttl, contentType, maxSize)
+	if err != nil {
+		log.Printf("❌ Ошибка создания временной ссылки: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponseWithCode(
+			models.ErrInternalError,
+			"Failed to create presigned URL",
+		))
+		return
+	}
+
+	// Файл попадает в реестр сразу: если загрузка не состоится, запись удалит сборщик мусора
+	services.RegisterMediaUpload(database.DB, "/"+key, 0, contentType, &user.ID)
+
+	response := presignResponse(upload.Method, key, upload.URL, ttl)
+	response["contentType"] = contentType
+	response["maxSize"] = maxSize
+	if len(upload.Fields) > 0 {
+		response["fields"] = upload.Fields
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(response))
+}
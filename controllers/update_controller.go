@@ -3,24 +3,48 @@ package controllers
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/config"
 	"github.com/mm-api/mm-api/database"
 	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"github.com/mm-api/mm-api/storage"
 )
 
 // UpdateController обрабатывает загрузку и выдачу обновлений
 type UpdateController struct{}
 
+// updateAllowedExts допустимые расширения файлов обновлений
+var updateAllowedExts = []string{".zip", ".exe", ".apk"}
+
+// updateExtAllowed проверяет расширение файла обновления
+func updateExtAllowed(ext string) bool {
+	for _, e := range updateAllowedExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// updatePlatformValid проверяет платформу обновления
+func updatePlatformValid(platform models.UpdatePlatform) bool {
+	return platform == models.UpdatePlatformServer ||
+		platform == models.UpdatePlatformWindows ||
+		platform == models.UpdatePlatformAndroid
+}
+
 // UploadUpdate загружает файл обновления (только для админов)
 func (uc *UpdateController) UploadUpdate(c *gin.Context) {
 	log.Println("📤 [UploadUpdate] Начало загрузки обновления")
@@ -66,6 +90,26 @@ func (uc *UpdateController) UploadUpdate(c *gin.Context) {
 	if values := c.Request.MultipartForm.Value["releaseNotes"]; len(values) > 0 {
 		releaseNotes = values[0]
 	}
+
+	// Параметры поэтапного выпуска (по умолчанию stable, 100%)
+	var channel models.UpdateChannel
+	if values := c.Request.MultipartForm.Value["channel"]; len(values) > 0 {
+		channel = models.UpdateChannel(strings.TrimSpace(values[0]))
+	}
+	rollout := 100
+	if values := c.Request.MultipartForm.Value["rolloutPercentage"]; len(values) > 0 && strings.TrimSpace(values[0]) != "" {
+		if _, err := fmt.Sscanf(strings.TrimSpace(values[0]), "%d", &rollout); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "rolloutPercentage must be a number",
+			})
+			return
+		}
+	}
+	minSupportedVersion := ""
+	if values := c.Request.MultipartForm.Value["minSupportedVersion"]; len(values) > 0 {
+		minSupportedVersion = strings.TrimSpace(values[0])
+	}
 	
 	// Логируем все доступные поля формы для отладки
 	log.Printf("🔍 [UploadUpdate] Все поля формы: %v", c.Request.MultipartForm.Value)
@@ -81,9 +125,7 @@ func (uc *UpdateController) UploadUpdate(c *gin.Context) {
 	}
 
 	platform := models.UpdatePlatform(platformStr)
-	if platform != models.UpdatePlatformServer &&
-		platform != models.UpdatePlatformWindows &&
-		platform != models.UpdatePlatformAndroid {
+	if !updatePlatformValid(platform) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"error":   "invalid platform (allowed: server, windows, android)",
@@ -91,6 +133,18 @@ func (uc *UpdateController) UploadUpdate(c *gin.Context) {
 		return
 	}
 
+	channel, err := services.NormalizeUpdateChannel(channel)
+	if err == nil {
+		err = services.ValidateReleaseVersions(version, minSupportedVersion, rollout)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
 	log.Println("📁 [UploadUpdate] Получение файла из запроса...")
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
@@ -115,55 +169,24 @@ func (uc *UpdateController) UploadUpdate(c *gin.Context) {
 		return
 	}
 
-	allowedExts := []string{".zip", ".exe", ".apk"}
-	isAllowed := false
-	for _, e := range allowedExts {
-		if ext == e {
-			isAllowed = true
-			break
-		}
-	}
-	if !isAllowed {
+	if !updateExtAllowed(ext) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"error":   fmt.Sprintf("unsupported extension %s (allowed: %v)", ext, allowedExts),
-		})
-		return
-	}
-
-	dir := filepath.Join("updates", string(platform))
-	log.Printf("📂 [UploadUpdate] Создание директории: %s", dir)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		log.Printf("❌ [UploadUpdate] Ошибка создания директории: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"error":   "failed to create updates directory",
-			"details": err.Error(),
+			"error":   fmt.Sprintf("unsupported extension %s (allowed: %v)", ext, updateAllowedExts),
 		})
 		return
 	}
 
+	dir := storage.JoinKey("updates", string(platform))
 	filename := fmt.Sprintf("%s_%s_%s%s", platform, version, uuid.NewString(), ext)
-	filePath := filepath.Join(dir, filename)
-	log.Printf("💾 [UploadUpdate] Сохранение файла: %s", filePath)
-
-	dst, err := os.Create(filePath)
-	if err != nil {
-		log.Printf("❌ [UploadUpdate] Ошибка создания файла: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"error":   "failed to create file",
-			"details": err.Error(),
-		})
-		return
-	}
-	defer dst.Close()
+	filePath := storage.JoinKey(dir, filename)
+	log.Printf("💾 [UploadUpdate] Сохранение файла: %s (хранилище: %s)", filePath, storage.Default().Name())
 
 	log.Println("📥 [UploadUpdate] Начало копирования файла и вычисления SHA256...")
 	hasher := sha256.New()
-	size, err := io.Copy(io.MultiWriter(dst, hasher), file)
-	if err != nil {
-		log.Printf("❌ [UploadUpdate] Ошибка копирования файла: %v", err)
+	counter := &countingReader{r: io.TeeReader(file, hasher)}
+	if err := storage.Default().Put(c.Request.Context(), filePath, counter, header.Size, ""); err != nil {
+		log.Printf("❌ [UploadUpdate] Ошибка сохранения файла: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   "failed to save file",
@@ -171,13 +194,14 @@ func (uc *UpdateController) UploadUpdate(c *gin.Context) {
 		})
 		return
 	}
+	size := counter.n
 	log.Printf("✅ [UploadUpdate] Файл скопирован: %d байт", size)
 
 	log.Println("🔐 [UploadUpdate] Вычисление SHA256...")
 	checksum := hex.EncodeToString(hasher.Sum(nil))
 	log.Printf("✅ [UploadUpdate] SHA256 вычислен: %s", checksum[:16]+"...")
 	
-	fileURL := fmt.Sprintf("/updates/%s/%s", platform, filename)
+	fileURL := "/" + filePath
 
 	log.Println("💾 [UploadUpdate] Сохранение метаданных в БД...")
 	update := models.UpdateRelease{
@@ -192,6 +216,10 @@ func (uc *UpdateController) UploadUpdate(c *gin.Context) {
 		IsActive:       true,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
+
+		Channel:             channel,
+		RolloutPercentage:   rollout,
+		MinSupportedVersion: minSupportedVersion,
 	}
 
 	if err := database.DB.Create(&update).Error; err != nil {
@@ -204,6 +232,7 @@ func (uc *UpdateController) UploadUpdate(c *gin.Context) {
 		return
 	}
 	log.Printf("✅ [UploadUpdate] Метаданные сохранены в БД, ID: %s", update.ID)
+	services.NudgeReleasePatches()
 
 	log.Println("🎉 [UploadUpdate] Загрузка завершена успешно!")
 	c.JSON(http.StatusCreated, gin.H{
@@ -237,7 +266,9 @@ func (uc *UpdateController) ListUpdates(c *gin.Context) {
 	})
 }
 
-// GetLatestUpdate возвращает последнее активное обновление по платформе
+// GetLatestUpdate возвращает последнее активное обновление по платформе.
+// Необязательные channel и deviceId учитывают канал и поэтапный выпуск:
+// без deviceId выдаются только релизы, выпущенные на 100%.
 func (uc *UpdateController) GetLatestUpdate(c *gin.Context) {
 	platform := c.Query("platform")
 	if platform == "" {
@@ -248,10 +279,91 @@ func (uc *UpdateController) GetLatestUpdate(c *gin.Context) {
 		return
 	}
 
+	update, err := services.LatestUpdateRelease(database.DB, models.UpdateCheckRequest{
+		Platform:       models.UpdatePlatform(platform),
+		Channel:        models.UpdateChannel(c.Query("channel")),
+		CurrentVersion: c.Query("version"),
+		DeviceID:       c.Query("deviceId"),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	if update == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "update not found",
+		})
+		return
+	}
+
+	update.DownloadURL = releaseDownloadURL(c, update.FilePath, update.FileName)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    update,
+	})
+}
+
+// CheckUpdate решение об обновлении для клиента: POS и Android передают платформу,
+// канал, текущую версию и deviceId и получают action (none, update, required, rollback)
+func (uc *UpdateController) CheckUpdate(c *gin.Context) {
+	var req models.UpdateCheckRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "platform is required",
+		})
+		return
+	}
+	if !updatePlatformValid(req.Platform) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "invalid platform (allowed: server, windows, android)",
+		})
+		return
+	}
+
+	decision, err := services.CheckForUpdate(database.DB, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	services.RecordUpdateCheckIn(database.DB, req, decision, c.ClientIP(), config.GetConfig().UpdateCheckInLimit)
+
+	if decision.Release != nil {
+		decision.Release.DownloadURL = releaseDownloadURL(c, decision.Release.FilePath, decision.Release.FileName)
+		attachUpdatePatch(c, decision)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    decision,
+	})
+}
+
+// UpdateRelease меняет канал, долю выпуска, минимальную версию или описание релиза (админ).
+// isActive=false отзывает релиз: клиенты получают предыдущую активную версию,
+// а установившие отозванную - указание откатиться.
+func (uc *UpdateController) UpdateRelease(c *gin.Context) {
+	var req models.UpdateReleaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
 	var update models.UpdateRelease
-	if err := database.DB.Where("platform = ? AND is_active = ?", platform, true).
-		Order("created_at DESC").
-		First(&update).Error; err != nil {
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&update).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"error":   "update not found",
@@ -259,12 +371,108 @@ func (uc *UpdateController) GetLatestUpdate(c *gin.Context) {
 		return
 	}
 
+	if req.Channel != nil {
+		channel, err := services.NormalizeUpdateChannel(*req.Channel)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		update.Channel = channel
+	}
+	if req.RolloutPercentage != nil {
+		update.RolloutPercentage = *req.RolloutPercentage
+	}
+	if req.MinSupportedVersion != nil {
+		update.MinSupportedVersion = strings.TrimSpace(*req.MinSupportedVersion)
+	}
+	if req.ReleaseNotes != nil {
+		update.ReleaseNotes = *req.ReleaseNotes
+	}
+	if req.IsActive != nil {
+		update.IsActive = *req.IsActive
+	}
+
+	// Старые релизы могли быть загружены с версией не в формате semver - проверяем только изменяемые поля
+	if req.RolloutPercentage != nil || req.MinSupportedVersion != nil {
+		if err := services.ValidateReleaseVersions(update.Version, update.MinSupportedVersion, update.RolloutPercentage); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := database.DB.Model(&update).
+		Select("Channel", "RolloutPercentage", "MinSupportedVersion", "ReleaseNotes", "IsActive").
+		Updates(&update).Error; err != nil {
+		log.Printf("❌ [UpdateRelease] Ошибка сохранения %s: %v", update.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to update release",
+		})
+		return
+	}
+
+	log.Printf("✅ [UpdateRelease] %s %s: канал=%s, выпуск=%d%%, мин. версия=%s, активен=%v",
+		update.Platform, update.Version, update.Channel, update.RolloutPercentage, update.MinSupportedVersion, update.IsActive)
+	if update.IsActive {
+		services.NudgeReleasePatches()
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
+		"message": "Update release saved",
 		"data":    update,
 	})
 }
 
+// GetInstallStats распределение устройств по версиям по данным проверок обновлений (админ).
+// days - за сколько дней учитывать проверки (по умолчанию 30).
+func (uc *UpdateController) GetInstallStats(c *gin.Context) {
+	days := 30
+	if raw := c.Query("days"); raw != "" {
+		if _, err := fmt.Sscanf(raw, "%d", &days); err != nil || days <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "days must be a positive number",
+			})
+			return
+		}
+	}
+
+	type versionStat struct {
+		Platform models.UpdatePlatform `json:"platform"`
+		Channel  models.UpdateChannel  `json:"channel"`
+		Version  string                `json:"version"`
+		Devices  int64                 `json:"devices"`
+	}
+	query := database.DB.Model(&models.UpdateCheckIn{}).
+		Select("platform, channel, version, COUNT(*) AS devices").
+		Where("last_checked_at >= ?", time.Now().AddDate(0, 0, -days)).
+		Group("platform, channel, version").
+		Order("platform, channel, devices DESC")
+	if platform := c.Query("platform"); platform != "" {
+		query = query.Where("platform = ?", platform)
+	}
+
+	var stats []versionStat
+	if err := query.Scan(&stats).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to fetch install stats",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
+
 // DeleteUpdate удаляет обновление (только для админов)
 func (uc *UpdateController) DeleteUpdate(c *gin.Context) {
 	updateID := c.Param("id")
@@ -289,16 +497,22 @@ func (uc *UpdateController) DeleteUpdate(c *gin.Context) {
 		return
 	}
 
-	// Удаляем файл с диска
+	deletion := services.NewUpdateReleaseDeletion(update, models.ReleaseDeletionTriggerManual, "deleted by admin", releaseActorID(c))
+
+	// Удаляем файл из хранилища
 	if update.FilePath != "" {
-		if err := os.Remove(update.FilePath); err != nil {
+		if err := storage.Default().Delete(c.Request.Context(), update.FilePath); err != nil {
 			// Логируем ошибку, но продолжаем удаление из БД
 			log.Printf("⚠️ [DeleteUpdate] Ошибка удаления файла %s: %v", update.FilePath, err)
+			deletion.FileError = err.Error()
 		} else {
 			log.Printf("✅ [DeleteUpdate] Файл удален: %s", update.FilePath)
 		}
 	}
 
+	// Патчи от этой версии и к ней больше не нужны
+	services.DeleteReleasePatches(c.Request.Context(), database.DB, update.ID)
+
 	// Удаляем запись из БД
 	if err := database.DB.Delete(&update).Error; err != nil {
 		log.Printf("❌ [DeleteUpdate] Ошибка удаления из БД: %v", err)
@@ -309,6 +523,7 @@ func (uc *UpdateController) DeleteUpdate(c *gin.Context) {
 		})
 		return
 	}
+	services.RecordReleaseDeletion(database.DB, deletion)
 
 	log.Printf("✅ [DeleteUpdate] Обновление удалено: ID=%s, Platform=%s, Version=%s", updateID, update.Platform, update.Version)
 
@@ -317,3 +532,228 @@ func (uc *UpdateController) DeleteUpdate(c *gin.Context) {
 		"message": "Update deleted successfully",
 	})
 }
+
+// PresignUpload выдает временную ссылку для загрузки файла обновления напрямую в хранилище,
+// минуя API (большие установщики). После загрузки вызывается CompleteUpload.
+func (uc *UpdateController) PresignUpload(c *gin.Context) {
+	var req models.UpdatePresignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ext := strings.ToLower(filepath.Ext(req.FileName))
+	if !updatePlatformValid(req.Platform) || !updateExtAllowed(ext) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   fmt.Sprintf("invalid platform or extension (allowed: %v)", updateAllowedExts),
+		})
+		return
+	}
+
+	version := strings.TrimSpace(req.Version)
+	filename := fmt.Sprintf("%s_%s_%s%s", req.Platform, version, uuid.NewString(), ext)
+	key, err := storage.CleanKey(storage.JoinKey("updates", string(req.Platform), filename))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "invalid version",
+		})
+		return
+	}
+
+	ttl := config.GetConfig().GetStoragePresignTTL()
+	url, err := storage.Default().PresignPut(c.Request.Context(), key, ttl, "application/octet-stream")
+	if err != nil {
+		log.Printf("❌ [PresignUpload] Ошибка создания ссылки: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to create upload link",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    presignResponse(http.MethodPut, key, url, ttl),
+	})
+}
+
+// CompleteUpload регистрирует обновление, загруженное по временной ссылке:
+// проверяет наличие файла и считает SHA256 по содержимому из хранилища
+func (uc *UpdateController) CompleteUpload(c *gin.Context) {
+	var req models.UpdateCompleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	channel, err := services.NormalizeUpdateChannel(req.Channel)
+	rollout := 100
+	if req.RolloutPercentage != nil {
+		rollout = *req.RolloutPercentage
+	}
+	if err == nil {
+		err = services.ValidateReleaseVersions(strings.TrimSpace(req.Version), strings.TrimSpace(req.MinSupportedVersion), rollout)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	key, err := storage.CleanKey(req.Key)
+	if err != nil || !updatePlatformValid(req.Platform) ||
+		!strings.HasPrefix(key, storage.JoinKey("updates", string(req.Platform))+"/") ||
+		!updateExtAllowed(strings.ToLower(filepath.Ext(key))) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "key does not belong to the platform updates folder",
+		})
+		return
+	}
+
+	var existing int64
+	database.DB.Model(&models.UpdateRelease{}).Where("file_path = ?", key).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "update for this file is already registered",
+		})
+		return
+	}
+
+	reader, _, err := storage.Default().Get(c.Request.Context(), key)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"error":   "uploaded file not found",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer reader.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, reader)
+	if err != nil {
+		log.Printf("❌ [CompleteUpload] Ошибка чтения %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to read uploaded file",
+		})
+		return
+	}
+
+	update := models.UpdateRelease{
+		Platform:       req.Platform,
+		Version:        strings.TrimSpace(req.Version),
+		FileName:       path.Base(key),
+		FilePath:       key,
+		FileURL:        "/" + key,
+		FileSize:       size,
+		ChecksumSHA256: hex.EncodeToString(hasher.Sum(nil)),
+		ReleaseNotes:   req.ReleaseNotes,
+		IsActive:       true,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+
+		Channel:             channel,
+		RolloutPercentage:   rollout,
+		MinSupportedVersion: strings.TrimSpace(req.MinSupportedVersion),
+	}
+	if err := database.DB.Create(&update).Error; err != nil {
+		log.Printf("❌ [CompleteUpload] Ошибка сохранения в БД: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to save update metadata",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("✅ [CompleteUpload] Обновление зарегистрировано: %s (%d байт)", key, size)
+	services.NudgeReleasePatches()
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Update uploaded successfully",
+		"data":    update,
+	})
+}
+
+// GetManifest подписанный манифест активных обновлений платформы в канале клиента.
+// GET /updates/manifest?platform=windows[&channel=beta]
+// Клиент проверяет подпись ключом из /updates/signing-key, затем сверяет SHA256
+// скачанного файла с манифестом перед установкой.
+func (uc *UpdateController) GetManifest(c *gin.Context) {
+	platform := models.UpdatePlatform(c.Query("platform"))
+	if platform == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "platform is required",
+		})
+		return
+	}
+
+	manifest, err := services.BuildUpdateManifest(database.DB, platform, models.UpdateChannel(c.Query("channel")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	signed, err := services.SignReleaseManifest(manifest)
+	if errors.Is(err, services.ErrReleaseSigningDisabled) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "release signing is not configured",
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ [GetManifest] Ошибка подписи манифеста: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to sign manifest",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    signed,
+	})
+}
+
+// GetSigningKey публичный ключ проверки подписи манифестов (обновлений и установщиков POS).
+// Клиент должен встроить ключ в сборку: ключ, полученный по сети, защищает только
+// от повреждения файла, но не от подмены сервера. Без RELEASE_SIGNING_KEY - 503.
+func (uc *UpdateController) GetSigningKey(c *gin.Context) {
+	key, err := services.ReleaseSigningPublicKey()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "release signing is not configured",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    key,
+	})
+}
@@ -1,404 +1,467 @@
-package controllers
-
-import (
-	"fmt"
-	"image"
-	"image/jpeg"
-	"image/png"
-	"io"
-	"log"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
-
-	"github.com/mm-api/mm-api/config"
-	"github.com/mm-api/mm-api/utils"
-	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
-	"golang.org/x/image/webp"
-)
-
-type UploadController struct{}
-
-// GetImageURL возвращает правильный URL для изображения
-func (uc *UploadController) GetImageURL(filename, folder string) string {
-	// Всегда возвращаем относительный путь для same-origin запросов
-	// nginx будет проксировать /images/ к API
-	return fmt.Sprintf("/images/%s/%s", folder, filename)
-}
-
-// UploadImage загружает изображение
-func (uc *UploadController) UploadImage(c *gin.Context) {
-	log.Printf("📸 Начало загрузки изображения...")
-
-	// Проверяем, что это POST запрос
-	if c.Request.Method != "POST" {
-		log.Printf("❌ Неверный метод: %s", c.Request.Method)
-		c.JSON(http.StatusMethodNotAllowed, gin.H{
-			"error": "Method not allowed",
-		})
-		return
-	}
-
-	// Получаем файл из формы
-	file, header, err := c.Request.FormFile("image")
-	if err != nil {
-		log.Printf("❌ Ошибка получения файла: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "No image file provided",
-			"details": err.Error(),
-		})
-		return
-	}
-	defer file.Close()
-
-	log.Printf("📁 Получен файл: %s, размер: %d байт", header.Filename, header.Size)
-
-	// Проверяем тип файла
-	contentType := header.Header.Get("Content-Type")
-	log.Printf("📋 Content-Type: %s", contentType)
-
-	if !strings.HasPrefix(contentType, "image/") {
-		log.Printf("❌ Неверный тип файла: %s", contentType)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":       "File is not an image",
-			"contentType": contentType,
-		})
-		return
-	}
-
-	// Получаем конфигурацию для максимального размера файла
-	// Лимит увеличен для фото с телефонов (могут быть 5-15MB)
-	cfg := config.GetConfig()
-	maxSizeStr := cfg.UploadMaxSize
-	maxSize := int64(20 * 1024 * 1024) // По умолчанию 20MB (достаточно для фото с телефонов)
-
-	// Парсим размер из конфигурации
-	if strings.HasSuffix(maxSizeStr, "MB") {
-		var mb int
-		fmt.Sscanf(maxSizeStr, "%dMB", &mb)
-		maxSize = int64(mb) * 1024 * 1024
-	} else if strings.HasSuffix(maxSizeStr, "KB") {
-		var kb int
-		fmt.Sscanf(maxSizeStr, "%dKB", &kb)
-		maxSize = int64(kb) * 1024
-	}
-
-	// Проверяем размер файла
-	if header.Size > maxSize {
-		log.Printf("❌ Файл слишком большой: %d байт (максимум %d байт)", header.Size, maxSize)
-		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
-			"error":   fmt.Sprintf("File size too large (max %s)", maxSizeStr),
-			"size":    header.Size,
-			"maxSize": maxSize,
-		})
-		return
-	}
-
-	// Получаем расширение файла
-	ext := filepath.Ext(header.Filename)
-	if ext == "" {
-		ext = ".jpg" // По умолчанию
-	}
-	
-	// Нормализуем расширение к нижнему регистру для Linux
-	ext = strings.ToLower(ext)
-
-	// Проверяем допустимые расширения
-	allowedExts := []string{".jpg", ".jpeg", ".png", ".gif", ".webp"}
-	isAllowed := false
-	for _, allowedExt := range allowedExts {
-		if ext == allowedExt {
-			isAllowed = true
-			break
-		}
-	}
-
-	if !isAllowed {
-		log.Printf("❌ Неподдерживаемое расширение: %s", ext)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":     "Unsupported file extension",
-			"extension": ext,
-			"allowed":   allowedExts,
-		})
-		return
-	}
-
-	// Генерируем уникальное имя файла
-	filename := fmt.Sprintf("%s%s", uuid.New().String(), ext)
-	log.Printf("🆔 Сгенерировано имя файла: %s", filename)
-
-	// Определяем папку для сохранения
-	folder := c.DefaultQuery("folder", "uploads")
-	uploadDir := fmt.Sprintf("images/%s", folder)
-	log.Printf("📂 Папка для сохранения: %s", uploadDir)
-
-	// Создаем папку, если её нет
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		log.Printf("❌ Ошибка создания папки: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to create upload directory",
-			"details": err.Error(),
-		})
-		return
-	}
-
-	// Путь для сохранения файла
-	filePath := filepath.Join(uploadDir, filename)
-	log.Printf("💾 Путь сохранения: %s", filePath)
-
-	// Используем уже полученную конфигурацию (cfg объявлен выше)
-	originalSize := header.Size
-	var bytesWritten int64
-	var finalFilename string
-
-	// Если это изображение товара (variations или products), обрабатываем специально
-	// Оптимизировано для фото с телефонов: автоматическая обработка EXIF ориентации,
-	// изменение размера, добавление фона, сжатие
-	if folder == "variations" || folder == "products" {
-		// Проверяем, используется ли Cloudinary
-		if cfg.UseCloudinary && cfg.CloudinaryCloudName != "" && cfg.CloudinaryUploadPreset != "" {
-			log.Printf("☁️ Обработка изображения товара через Cloudinary...")
-			log.Printf("   ✅ Cloudinary включен")
-			log.Printf("   ☁️  Cloud Name: %s", cfg.CloudinaryCloudName)
-			log.Printf("   ⚙️  Upload Preset: %s", cfg.CloudinaryUploadPreset)
-			log.Printf("   🎨 Remove Background: %v", cfg.CloudinaryRemoveBackground)
-			
-			// Создаем процессор Cloudinary
-			cloudinaryProcessor := utils.NewCloudinaryProcessor(
-				cfg.CloudinaryCloudName,
-				cfg.CloudinaryAPIKey,
-				cfg.CloudinaryAPISecret,
-				cfg.CloudinaryUploadPreset,
-			)
-			
-			// Загружаем в Cloudinary с обработкой
-			result, err := cloudinaryProcessor.ProcessProductImage(
-				file,
-				folder,
-				cfg.CloudinaryRemoveBackground,
-			)
-			if err != nil {
-				log.Printf("❌ Ошибка загрузки в Cloudinary: %v", err)
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error":   "Failed to upload to Cloudinary",
-					"details": err.Error(),
-				})
-				return
-			}
-			
-			// Возвращаем URL от Cloudinary
-			log.Printf("✅ Изображение успешно загружено в Cloudinary:")
-			log.Printf("   🔗 URL: %s", result.SecureURL)
-			log.Printf("   📏 Размер: %dx%d", result.Width, result.Height)
-			log.Printf("   📦 Размер файла: %d байт", result.Bytes)
-			log.Printf("   🆔 Public ID: %s", result.PublicID)
-			
-			c.JSON(http.StatusOK, gin.H{
-				"url":      result.SecureURL,
-				"public_id": result.PublicID,
-				"width":    result.Width,
-				"height":   result.Height,
-				"format":   result.Format,
-				"bytes":    result.Bytes,
-				"provider": "cloudinary",
-			})
-			return
-		}
-		
-		// Локальная обработка (если Cloudinary не используется)
-		log.Printf("🎨 Обработка изображения товара локально (оптимизировано для фото с телефонов)...")
-		if !cfg.UseCloudinary {
-			log.Printf("   ⚠️ Cloudinary отключен (USE_CLOUDINARY=false)")
-		} else if cfg.CloudinaryCloudName == "" {
-			log.Printf("   ⚠️ Cloudinary Cloud Name не настроен (CLOUDINARY_CLOUD_NAME пустой)")
-		} else if cfg.CloudinaryUploadPreset == "" {
-			log.Printf("   ⚠️ Cloudinary Upload Preset не настроен (CLOUDINARY_UPLOAD_PRESET пустой)")
-		}
-		
-		// Создаем процессор изображений
-		processor := utils.NewImageProcessor(
-			cfg.ProductImageWidth,
-			cfg.ProductImageHeight,
-			cfg.ProductImageBG,
-		)
-		processor.JPEGQuality = cfg.ProductImageQuality
-
-		// Всегда сохраняем как .jpg для товаров
-		finalPath := strings.TrimSuffix(filePath, ext) + ".jpg"
-		
-		// Обрабатываем изображение (изменение размера + фон + сжатие)
-		bytesWritten, err = processor.ProcessProductImage(file, finalPath)
-		if err != nil {
-			log.Printf("❌ Ошибка обработки изображения товара: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Failed to process product image",
-				"details": err.Error(),
-			})
-			return
-		}
-
-		// Обновляем имя файла на .jpg
-		finalFilename = strings.TrimSuffix(filename, ext) + ".jpg"
-		log.Printf("✅ Изображение товара обработано: размер=%dx%d, фон=%s", 
-			cfg.ProductImageWidth, cfg.ProductImageHeight, cfg.ProductImageBG)
-	} else {
-		// Для других изображений используем стандартную обработку
-		finalFilename, bytesWritten, err = uc.compressAndSaveImage(file, filePath, ext, contentType)
-		if err != nil {
-			log.Printf("❌ Ошибка сохранения файла: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Failed to save file",
-				"details": err.Error(),
-			})
-			return
-		}
-
-		// Обновляем filename, если формат изменился (PNG/WebP -> JPEG)
-		if finalFilename != filename {
-			filename = finalFilename
-			log.Printf("🔄 Формат изменен, новое имя файла: %s", filename)
-		}
-	}
-
-	// Вычисляем процент сжатия
-	compressionRatio := float64(bytesWritten) / float64(originalSize) * 100
-	savedBytes := originalSize - bytesWritten
-	log.Printf("✅ Файл успешно сохранен: %d байт записано (было %d байт, сжато на %.1f%%, сэкономлено %d байт)", 
-		bytesWritten, originalSize, 100-compressionRatio, savedBytes)
-	
-	// Обновляем filename для ответа
-	if finalFilename != "" {
-		filename = finalFilename
-	}
-
-	// Формируем URL для доступа к файлу
-	fileURL := uc.GetImageURL(filename, folder)
-	log.Printf("🔗 URL файла: %s", fileURL)
-
-	c.JSON(http.StatusOK, gin.H{
-		"success":  true,
-		"url":      fileURL,
-		"filename": filename,
-		"size":     bytesWritten,
-		"folder":   folder,
-	})
-}
-
-// CompressAndSaveImage сжимает и сохраняет изображение (публичный метод для использования в других контроллерах)
-func (uc *UploadController) CompressAndSaveImage(file io.Reader, filePath string, ext string, contentType string) (string, int64, error) {
-	return uc.compressAndSaveImage(file, filePath, ext, contentType)
-}
-
-// compressAndSaveImage сжимает и сохраняет изображение
-// Поддерживает JPEG, PNG, WebP
-// Для JPEG: качество 85% (хороший баланс между размером и качеством)
-// Для PNG: конвертирует в JPEG для лучшего сжатия (если возможно)
-// Возвращает: (finalFilename, bytesWritten, error)
-func (uc *UploadController) compressAndSaveImage(file io.Reader, filePath string, ext string, contentType string) (string, int64, error) {
-	// Декодируем изображение
-	var img image.Image
-	var err error
-	finalPath := filePath
-
-	switch {
-	case ext == ".jpg" || ext == ".jpeg" || strings.Contains(contentType, "jpeg"):
-		img, err = jpeg.Decode(file)
-		if err != nil {
-			return "", 0, fmt.Errorf("ошибка декодирования JPEG: %v", err)
-		}
-	case ext == ".png" || strings.Contains(contentType, "png"):
-		img, err = png.Decode(file)
-		if err != nil {
-			return "", 0, fmt.Errorf("ошибка декодирования PNG: %v", err)
-		}
-		// PNG конвертируем в JPEG для лучшего сжатия
-		finalPath = strings.TrimSuffix(filePath, ".png") + ".jpg"
-	case ext == ".webp" || strings.Contains(contentType, "webp"):
-		img, err = webp.Decode(file)
-		if err != nil {
-			// Если не удалось декодировать WebP, пробуем сохранить как есть
-			log.Printf("⚠️ Не удалось декодировать WebP, сохраняем без сжатия: %v", err)
-			dst, err := os.Create(filePath)
-			if err != nil {
-				return "", 0, err
-			}
-			defer dst.Close()
-			bytesWritten, err := io.Copy(dst, file)
-			return filepath.Base(filePath), bytesWritten, err
-		}
-		// WebP конвертируем в JPEG для лучшего сжатия
-		finalPath = strings.TrimSuffix(filePath, ".webp") + ".jpg"
-	default:
-		// Для других форматов (GIF и т.д.) просто копируем без сжатия
-		dst, err := os.Create(filePath)
-		if err != nil {
-			return "", 0, err
-		}
-		defer dst.Close()
-		bytesWritten, err := io.Copy(dst, file)
-		return filepath.Base(filePath), bytesWritten, err
-	}
-
-	// Создаем файл для сохранения
-	dst, err := os.Create(finalPath)
-	if err != nil {
-		return "", 0, err
-	}
-	defer dst.Close()
-
-	// Сохраняем с сжатием
-	// JPEG качество 85% - хороший баланс между размером и качеством
-	// Можно уменьшить до 75% для большего сжатия, но качество будет хуже
-	quality := 85
-
-	err = jpeg.Encode(dst, img, &jpeg.Options{Quality: quality})
-	if err != nil {
-		return "", 0, fmt.Errorf("ошибка кодирования JPEG: %v", err)
-	}
-
-	// Получаем размер файла
-	info, err := dst.Stat()
-	if err != nil {
-		return "", 0, err
-	}
-	bytesWritten := info.Size()
-
-	return filepath.Base(finalPath), bytesWritten, nil
-}
-
-// DeleteImage удаляет изображение
-func (uc *UploadController) DeleteImage(c *gin.Context) {
-	filename := c.Param("filename")
-	if filename == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Filename is required",
-		})
-		return
-	}
-
-	folder := c.DefaultQuery("folder", "uploads")
-	filePath := filepath.Join("images", folder, filename)
-
-	// Проверяем, существует ли файл
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "File not found",
-		})
-		return
-	}
-
-	// Удаляем файл
-	if err := os.Remove(filePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to delete file",
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "File deleted successfully",
-	})
-}
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mm-api/mm-api/config"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"github.com/mm-api/mm-api/storage"
+	"github.com/mm-api/mm-api/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/image/webp"
+)
+
+type UploadController struct{}
+
+// GetImageURL возвращает правильный URL для изображения
+func (uc *UploadController) GetImageURL(filename, folder string) string {
+	// Всегда возвращаем относительный путь для same-origin запросов
+	// nginx будет проксировать /images/ к API
+	return fmt.Sprintf("/images/%s/%s", folder, filename)
+}
+
+// UploadImage загружает изображение
+func (uc *UploadController) UploadImage(c *gin.Context) {
+	log.Printf("📸 Начало загрузки изображения...")
+
+	// Проверяем, что это POST запрос
+	if c.Request.Method != "POST" {
+		log.Printf("❌ Неверный метод: %s", c.Request.Method)
+		c.JSON(http.StatusMethodNotAllowed, gin.H{
+			"error": "Method not allowed",
+		})
+		return
+	}
+
+	// Получаем файл из формы
+	file, header, err := c.Request.FormFile("image")
+	if err != nil {
+		log.Printf("❌ Ошибка получения файла: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "No image file provided",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	log.Printf("📁 Получен файл: %s, размер: %d байт", header.Filename, header.Size)
+
+	// Проверяем тип файла
+	contentType := header.Header.Get("Content-Type")
+	log.Printf("📋 Content-Type: %s", contentType)
+
+	if !strings.HasPrefix(contentType, "image/") {
+		log.Printf("❌ Неверный тип файла: %s", contentType)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":       "File is not an image",
+			"contentType": contentType,
+		})
+		return
+	}
+
+	// Получаем конфигурацию для максимального размера файла
+	// Лимит увеличен для фото с телефонов (могут быть 5-15MB)
+	cfg := config.GetConfig()
+	maxSizeStr := cfg.UploadMaxSize
+	maxSize := cfg.GetUploadMaxSize()
+
+	// Проверяем размер файла
+	if header.Size > maxSize {
+		log.Printf("❌ Файл слишком большой: %d байт (максимум %d байт)", header.Size, maxSize)
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":   fmt.Sprintf("File size too large (max %s)", maxSizeStr),
+			"size":    header.Size,
+			"maxSize": maxSize,
+		})
+		return
+	}
+
+	// Получаем расширение файла
+	ext := filepath.Ext(header.Filename)
+	if ext == "" {
+		ext = ".jpg" // По умолчанию
+	}
+	
+	// Нормализуем расширение к нижнему регистру для Linux
+	ext = strings.ToLower(ext)
+
+	// Проверяем допустимые расширения
+	allowedExts := []string{".jpg", ".jpeg", ".png", ".gif", ".webp"}
+	isAllowed := false
+	for _, allowedExt := range allowedExts {
+		if ext == allowedExt {
+			isAllowed = true
+			break
+		}
+	}
+
+	if !isAllowed {
+		log.Printf("❌ Неподдерживаемое расширение: %s", ext)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     "Unsupported file extension",
+			"extension": ext,
+			"allowed":   allowedExts,
+		})
+		return
+	}
+
+	// Генерируем уникальное имя файла
+	filename := fmt.Sprintf("%s%s", uuid.New().String(), ext)
+	log.Printf("🆔 Сгенерировано имя файла: %s", filename)
+
+	// Определяем папку для сохранения
+	folder := c.DefaultQuery("folder", "uploads")
+	uploadDir := fmt.Sprintf("images/%s", folder)
+	log.Printf("📂 Папка для сохранения: %s", uploadDir)
+
+	// Ключ файла в хранилище (локальная папка или S3)
+	filePath := storage.JoinKey(uploadDir, filename)
+	if _, err := storage.CleanKey(filePath); err != nil {
+		log.Printf("❌ Недопустимая папка: %s", folder)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid folder",
+			"folder": folder,
+		})
+		return
+	}
+	log.Printf("💾 Путь сохранения: %s", filePath)
+
+	// Используем уже полученную конфигурацию (cfg объявлен выше)
+	originalSize := header.Size
+	var bytesWritten int64
+	var finalFilename string
+
+	// Если для папки настроена локальная обработка (IMAGE_PIPELINES, по умолчанию
+	// variations и products), обрабатываем специально
+	// Оптимизировано для фото с телефонов: автоматическая обработка EXIF ориентации,
+	// изменение размера, добавление фона, сжатие
+	processor, usePipeline := imageProcessorFor(cfg, folder)
+	if usePipeline {
+		// Проверяем, используется ли Cloudinary (только для фото товаров)
+		if (folder == "variations" || folder == "products") && cfg.UseCloudinary && cfg.CloudinaryCloudName != "" && cfg.CloudinaryUploadPreset != "" {
+			log.Printf("☁️ Обработка изображения товара через Cloudinary...")
+			log.Printf("   ✅ Cloudinary включен")
+			log.Printf("   ☁️  Cloud Name: %s", cfg.CloudinaryCloudName)
+			log.Printf("   ⚙️  Upload Preset: %s", cfg.CloudinaryUploadPreset)
+			log.Printf("   🎨 Remove Background: %v", cfg.CloudinaryRemoveBackground)
+			
+			// Создаем процессор Cloudinary
+			cloudinaryProcessor := utils.NewCloudinaryProcessor(
+				cfg.CloudinaryCloudName,
+				cfg.CloudinaryAPIKey,
+				cfg.CloudinaryAPISecret,
+				cfg.CloudinaryUploadPreset,
+			)
+			
+			// Загружаем в Cloudinary с обработкой
+			result, err := cloudinaryProcessor.ProcessProductImage(
+				file,
+				folder,
+				cfg.CloudinaryRemoveBackground,
+			)
+			if err != nil {
+				log.Printf("❌ Ошибка загрузки в Cloudinary: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to upload to Cloudinary",
+					"details": err.Error(),
+				})
+				return
+			}
+			
+			// Возвращаем URL от Cloudinary
+			log.Printf("✅ Изображение успешно загружено в Cloudinary:")
+			log.Printf("   🔗 URL: %s", result.SecureURL)
+			log.Printf("   📏 Размер: %dx%d", result.Width, result.Height)
+			log.Printf("   📦 Размер файла: %d байт", result.Bytes)
+			log.Printf("   🆔 Public ID: %s", result.PublicID)
+			services.RegisterMediaUpload(database.DB, result.SecureURL, int64(result.Bytes), "image/"+result.Format, uploaderID(c))
+			
+			c.JSON(http.StatusOK, gin.H{
+				"url":      result.SecureURL,
+				"public_id": result.PublicID,
+				"width":    result.Width,
+				"height":   result.Height,
+				"format":   result.Format,
+				"bytes":    result.Bytes,
+				"provider": "cloudinary",
+				"images":   models.NewResponsiveImage(result.SecureURL),
+			})
+			return
+		}
+		
+		// Локальная обработка (если Cloudinary не используется)
+		log.Printf("🎨 Обработка изображения товара локально (оптимизировано для фото с телефонов)...")
+		if !cfg.UseCloudinary {
+			log.Printf("   ⚠️ Cloudinary отключен (USE_CLOUDINARY=false)")
+		} else if cfg.CloudinaryCloudName == "" {
+			log.Printf("   ⚠️ Cloudinary Cloud Name не настроен (CLOUDINARY_CLOUD_NAME пустой)")
+		} else if cfg.CloudinaryUploadPreset == "" {
+			log.Printf("   ⚠️ Cloudinary Upload Preset не настроен (CLOUDINARY_UPLOAD_PRESET пустой)")
+		}
+		
+		// Всегда сохраняем как .jpg для товаров
+		finalPath := strings.TrimSuffix(filePath, ext) + ".jpg"
+		
+		// Обрабатываем изображение (изменение размера + фон + сжатие)
+		bytesWritten, err = uc.processProductImage(processor, file, finalPath)
+		if err != nil {
+			log.Printf("❌ Ошибка обработки изображения товара: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to process product image",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		// Обновляем имя файла на .jpg
+		finalFilename = strings.TrimSuffix(filename, ext) + ".jpg"
+		log.Printf("✅ Изображение товара обработано: размер=%dx%d, фон=%s, шаги=%s", 
+			cfg.ProductImageWidth, cfg.ProductImageHeight, cfg.ProductImageBG, strings.Join(processor.Steps, ","))
+	} else {
+		// Для других изображений используем стандартную обработку
+		finalFilename, bytesWritten, err = uc.compressAndSaveImage(file, filePath, ext, contentType)
+		if err != nil {
+			log.Printf("❌ Ошибка сохранения файла: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to save file",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		// Обновляем filename, если формат изменился (PNG/WebP -> JPEG)
+		if finalFilename != filename {
+			filename = finalFilename
+			log.Printf("🔄 Формат изменен, новое имя файла: %s", filename)
+		}
+	}
+
+	// Вычисляем процент сжатия
+	compressionRatio := float64(bytesWritten) / float64(originalSize) * 100
+	savedBytes := originalSize - bytesWritten
+	log.Printf("✅ Файл успешно сохранен: %d байт записано (было %d байт, сжато на %.1f%%, сэкономлено %d байт)", 
+		bytesWritten, originalSize, 100-compressionRatio, savedBytes)
+	
+	// Обновляем filename для ответа
+	if finalFilename != "" {
+		filename = finalFilename
+	}
+
+	// Формируем URL для доступа к файлу
+	fileURL := uc.GetImageURL(filename, folder)
+	log.Printf("🔗 URL файла: %s", fileURL)
+	services.RegisterMediaUpload(database.DB, fileURL, bytesWritten, mime.TypeByExtension(path.Ext(filename)), uploaderID(c))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"url":      fileURL,
+		"filename": filename,
+		"size":     bytesWritten,
+		"folder":   folder,
+		"images":   models.NewResponsiveImage(fileURL),
+	})
+}
+
+// CompressAndSaveImage сжимает и сохраняет изображение (публичный метод для использования в других контроллерах)
+func (uc *UploadController) CompressAndSaveImage(file io.Reader, filePath string, ext string, contentType string) (string, int64, error) {
+	return uc.compressAndSaveImage(file, filePath, ext, contentType)
+}
+
+// compressAndSaveImage сжимает и сохраняет изображение в хранилище
+// Поддерживает JPEG, PNG, WebP
+// Для JPEG: качество 85% (хороший баланс между размером и качеством)
+// Для PNG: конвертирует в JPEG для лучшего сжатия (если возможно)
+// filePath - ключ в хранилище (images/<папка>/<файл>)
+// Возвращает: (finalFilename, bytesWritten, error)
+func (uc *UploadController) compressAndSaveImage(file io.Reader, filePath string, ext string, contentType string) (string, int64, error) {
+	// Декодируем изображение
+	var img image.Image
+	var err error
+	finalPath := filePath
+
+	switch {
+	case ext == ".jpg" || ext == ".jpeg" || strings.Contains(contentType, "jpeg"):
+		img, err = jpeg.Decode(file)
+		if err != nil {
+			return "", 0, fmt.Errorf("ошибка декодирования JPEG: %v", err)
+		}
+	case ext == ".png" || strings.Contains(contentType, "png"):
+		img, err = png.Decode(file)
+		if err != nil {
+			return "", 0, fmt.Errorf("ошибка декодирования PNG: %v", err)
+		}
+		// PNG конвертируем в JPEG для лучшего сжатия
+		finalPath = strings.TrimSuffix(filePath, ".png") + ".jpg"
+	case ext == ".webp" || strings.Contains(contentType, "webp"):
+		// Читаем целиком, чтобы при ошибке декодирования сохранить оригинал
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return "", 0, err
+		}
+		img, err = webp.Decode(bytes.NewReader(data))
+		if err != nil {
+			// Если не удалось декодировать WebP, пробуем сохранить как есть
+			log.Printf("⚠️ Не удалось декодировать WebP, сохраняем без сжатия: %v", err)
+			return uc.saveOriginalImage(bytes.NewReader(data), filePath, contentType)
+		}
+		// WebP конвертируем в JPEG для лучшего сжатия
+		finalPath = strings.TrimSuffix(filePath, ".webp") + ".jpg"
+	default:
+		// Для других форматов (GIF и т.д.) просто копируем без сжатия
+		return uc.saveOriginalImage(file, filePath, contentType)
+	}
+
+	// Сохраняем с сжатием
+	// JPEG качество 85% - хороший баланс между размером и качеством
+	// Можно уменьшить до 75% для большего сжатия, но качество будет хуже
+	quality := 85
+
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	if err != nil {
+		return "", 0, fmt.Errorf("ошибка кодирования JPEG: %v", err)
+	}
+	bytesWritten := int64(buf.Len())
+
+	if err := storage.Default().Put(context.Background(), finalPath, &buf, bytesWritten, "image/jpeg"); err != nil {
+		return "", 0, fmt.Errorf("ошибка сохранения в хранилище: %v", err)
+	}
+
+	return path.Base(finalPath), bytesWritten, nil
+}
+
+// saveOriginalImage сохраняет изображение в хранилище без изменений
+func (uc *UploadController) saveOriginalImage(file io.Reader, filePath string, contentType string) (string, int64, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := storage.Default().Put(context.Background(), filePath, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return "", 0, fmt.Errorf("ошибка сохранения в хранилище: %v", err)
+	}
+	return path.Base(filePath), int64(len(data)), nil
+}
+
+// imageProcessorFor возвращает процессор изображений для папки загрузки, если для нее
+// настроена локальная обработка (IMAGE_PIPELINES, разбирается при загрузке конфигурации)
+func imageProcessorFor(cfg *config.Config, folder string) (*utils.ImageProcessor, bool) {
+	steps, ok := cfg.ImagePipelineSteps[folder]
+	if !ok {
+		return nil, false
+	}
+
+	processor := utils.NewImageProcessor(
+		cfg.ProductImageWidth,
+		cfg.ProductImageHeight,
+		cfg.ProductImageBG,
+	)
+	processor.JPEGQuality = cfg.ProductImageQuality
+	processor.Steps = steps
+	if cfg.ImageChromaTolerance > 0 {
+		processor.ChromaTolerance = float64(cfg.ImageChromaTolerance)
+	}
+	if cfg.ImageTrimPadding >= 0 {
+		processor.TrimPadding = float64(cfg.ImageTrimPadding) / 100
+	}
+	if cfg.ImageChromaKey != "" && cfg.ImageChromaKey != "auto" {
+		key, err := utils.ParseColor(cfg.ImageChromaKey)
+		if err != nil {
+			log.Printf("⚠️ Неверный IMAGE_CHROMA_KEY: %v, фон определяется по краям снимка", err)
+		} else {
+			processor.ChromaKey = key
+		}
+	}
+	return processor, true
+}
+
+// processProductImage обрабатывает фото товара во временный файл и переносит его в хранилище
+func (uc *UploadController) processProductImage(processor *utils.ImageProcessor, file io.Reader, key string) (int64, error) {
+	tmp, err := os.CreateTemp("", "product-*.jpg")
+	if err != nil {
+		return 0, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	bytesWritten, err := processor.ProcessProductImage(file, tmp.Name())
+	if err != nil {
+		return 0, err
+	}
+
+	processed, err := os.Open(tmp.Name())
+	if err != nil {
+		return 0, err
+	}
+	defer processed.Close()
+
+	if err := storage.Default().Put(context.Background(), key, processed, bytesWritten, "image/jpeg"); err != nil {
+		return 0, fmt.Errorf("ошибка сохранения в хранилище: %v", err)
+	}
+
+	// Уменьшенные копии для каталога создаются в фоне; до их готовности
+	// адреса вариантов обслуживает ResizeImage
+	if utils.ImageVariantSettings().OnUpload {
+		enqueueImageVariants(key)
+	}
+	return bytesWritten, nil
+}
+
+// DeleteImage удаляет изображение
+func (uc *UploadController) DeleteImage(c *gin.Context) {
+	filename := c.Param("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Filename is required",
+		})
+		return
+	}
+
+	folder := c.DefaultQuery("folder", "uploads")
+	filePath := storage.JoinKey("images", folder, filename)
+	store := storage.Default()
+
+	// Проверяем, существует ли файл
+	if _, err := store.Stat(c.Request.Context(), filePath); err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "File not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to access storage",
+			})
+		}
+		return
+	}
+
+	// Удаляем файл
+	if err := store.Delete(c.Request.Context(), filePath); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete file",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "File deleted successfully",
+	})
+}
@@ -0,0 +1,16 @@
+-- Индексы для фильтров каталога по вариациям товаров
+-- Условия на вариации выполняются через EXISTS по product_id
+
+-- Вариации товара (EXISTS и JOIN по product_id)
+CREATE INDEX IF NOT EXISTS idx_product_variations_product_price
+ON product_variations(product_id, price)
+WHERE is_available = true;
+
+-- Фильтр и фасет по бренду (без учета регистра)
+CREATE INDEX IF NOT EXISTS idx_products_brand_lower
+ON products(lower(trim(brand)));
+
+-- Фильтр по полу и категории в каталоге
+CREATE INDEX IF NOT EXISTS idx_products_catalog
+ON products(category_id, gender)
+WHERE is_available = true;
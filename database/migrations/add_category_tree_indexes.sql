@@ -0,0 +1,10 @@
+-- Индексы для дерева категорий
+
+-- Обход подкатегорий (рекурсивные запросы по parent_id)
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id
+ON categories(parent_id, sort_order);
+
+-- Подсчет доступных товаров по категориям
+CREATE INDEX IF NOT EXISTS idx_products_available_category
+ON products(category_id)
+WHERE is_available = true;
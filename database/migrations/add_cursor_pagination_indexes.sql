@@ -0,0 +1,26 @@
+-- Индексы для курсорной пагинации списков (after=)
+-- Keyset условие (created_at, id) < (?, ?) с сортировкой по тем же колонкам
+
+-- Каталог и списки товаров (новые первыми)
+CREATE INDEX IF NOT EXISTS idx_products_created_id
+ON products(created_at DESC, id DESC);
+
+-- Заказы покупателя
+CREATE INDEX IF NOT EXISTS idx_orders_user_created_id
+ON orders(user_id, created_at DESC, id DESC);
+
+-- Все заказы (админ, владелец магазина)
+CREATE INDEX IF NOT EXISTS idx_orders_created_id
+ON orders(created_at DESC, id DESC);
+
+-- Уведомления пользователя
+CREATE INDEX IF NOT EXISTS idx_notifications_user_timestamp_id
+ON notifications(user_id, timestamp DESC, id DESC);
+
+-- История бонусов клиента магазина
+CREATE INDEX IF NOT EXISTS idx_bonus_histories_client_created_id
+ON bonus_histories(shop_client_id, created_at DESC, id DESC);
+
+-- Лицензии (админ)
+CREATE INDEX IF NOT EXISTS idx_licenses_created_id
+ON licenses(created_at DESC, id DESC);
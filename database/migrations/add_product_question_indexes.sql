@@ -0,0 +1,20 @@
+-- Индексы для вопросов о товарах
+
+-- Отвеченные вопросы на странице товара
+CREATE INDEX IF NOT EXISTS idx_product_questions_public
+ON product_questions(product_id, answered_at DESC)
+WHERE status = 'published' AND answered_at IS NOT NULL;
+
+-- Неотвеченные вопросы магазина
+CREATE INDEX IF NOT EXISTS idx_product_questions_shop_unanswered
+ON product_questions(shop_id, created_at DESC)
+WHERE answered_at IS NULL;
+
+-- Очередь модерации: вопросы с жалобами
+CREATE INDEX IF NOT EXISTS idx_product_questions_reported
+ON product_questions(created_at DESC)
+WHERE reports_count > 0;
+
+-- Жалобы уникальны по (question_id, user_id, target): старый индекс без target
+-- запрещал пожаловаться и на вопрос, и на ответ
+DROP INDEX IF EXISTS idx_question_reports_question_user;
@@ -0,0 +1,19 @@
+-- Индексы для отзывов и сортировки по рейтингу
+
+-- Список опубликованных отзывов товара (новые первыми)
+CREATE INDEX IF NOT EXISTS idx_product_reviews_product_status_created
+ON product_reviews(product_id, status, created_at DESC, id DESC);
+
+-- Отзывы магазина для владельца и пересчет рейтинга магазина
+CREATE INDEX IF NOT EXISTS idx_product_reviews_shop_status
+ON product_reviews(shop_id, status, created_at DESC);
+
+-- Сортировка каталога по рейтингу
+CREATE INDEX IF NOT EXISTS idx_products_rating
+ON products(rating DESC, reviews_count DESC)
+WHERE is_available = true;
+
+-- Сортировка списка магазинов по рейтингу
+CREATE INDEX IF NOT EXISTS idx_shops_rating
+ON shops(rating DESC, reviews_count DESC)
+WHERE is_active = true;
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"fmt"
+	"log"
+)
+
+// Полнотекстовый поиск товаров.
+// Выполняется из Go, а не из database/migrations: тела plpgsql функций
+// содержат ";" и не переживают разбиение SQL файлов на команды.
+
+// productSearchStatements создают колонку search_vector, функции и триггеры
+var productSearchStatements = []string{
+	// Приведение таджикских букв к русским: запрос, набранный на русской раскладке,
+	// находит таджикские слова, и наоборот
+	`CREATE OR REPLACE FUNCTION mm_search_normalize(value text) RETURNS text
+	LANGUAGE sql IMMUTABLE PARALLEL SAFE AS
+	$$ SELECT translate(lower(coalesce(value, '')), 'ғӣқӯҳҷё', 'гикухче') $$`,
+
+	`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+
+	// Документ товара: название (A), бренд и категория (B), описание (C).
+	// Конфигурация russian дает морфологию, simple - точные формы (таджикский, бренды)
+	`CREATE OR REPLACE FUNCTION mm_product_search_document(p_name text, p_brand text, p_description text, p_category_id uuid)
+	RETURNS tsvector LANGUAGE sql STABLE AS
+	$$
+		SELECT
+			setweight(to_tsvector('russian', mm_search_normalize(p_name)), 'A') ||
+			setweight(to_tsvector('simple', mm_search_normalize(p_name)), 'A') ||
+			setweight(to_tsvector('simple', mm_search_normalize(p_brand)), 'B') ||
+			setweight(to_tsvector('russian', mm_search_normalize(
+				(SELECT name FROM categories WHERE id = p_category_id))), 'B') ||
+			setweight(to_tsvector('russian', mm_search_normalize(p_description)), 'C')
+	$$`,
+
+	`CREATE OR REPLACE FUNCTION mm_products_search_vector_trigger() RETURNS trigger
+	LANGUAGE plpgsql AS
+	$$
+	BEGIN
+		NEW.search_vector := mm_product_search_document(NEW.name, NEW.brand, NEW.description, NEW.category_id);
+		RETURN NEW;
+	END
+	$$`,
+
+	`DROP TRIGGER IF EXISTS trg_products_search_vector ON products`,
+
+	`CREATE TRIGGER trg_products_search_vector
+	BEFORE INSERT OR UPDATE OF name, brand, description, category_id ON products
+	FOR EACH ROW EXECUTE FUNCTION mm_products_search_vector_trigger()`,
+
+	// Переименование категории пересчитывает документы ее товаров
+	`CREATE OR REPLACE FUNCTION mm_categories_search_vector_trigger() RETURNS trigger
+	LANGUAGE plpgsql AS
+	$$
+	BEGIN
+		IF NEW.name IS DISTINCT FROM OLD.name THEN
+			UPDATE products
+			SET search_vector = mm_product_search_document(name, brand, description, category_id)
+			WHERE category_id = NEW.id;
+		END IF;
+		RETURN NEW;
+	END
+	$$`,
+
+	`DROP TRIGGER IF EXISTS trg_categories_search_vector ON categories`,
+
+	`CREATE TRIGGER trg_categories_search_vector
+	AFTER UPDATE OF name ON categories
+	FOR EACH ROW EXECUTE FUNCTION mm_categories_search_vector_trigger()`,
+
+	`CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)`,
+
+	// Заполнение для товаров, созданных до появления колонки
+	`UPDATE products
+	SET search_vector = mm_product_search_document(name, brand, description, category_id)
+	WHERE search_vector IS NULL`,
+}
+
+// productTrigramStatements включают нечеткий поиск с опечатками (pg_trgm)
+var productTrigramStatements = []string{
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (mm_search_normalize(name) gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_products_brand_trgm ON products USING GIN (mm_search_normalize(brand) gin_trgm_ops)`,
+}
+
+// setupProductSearch подготавливает полнотекстовый поиск товаров.
+// Без pg_trgm поиск работает, но без исправления опечаток.
+func setupProductSearch() error {
+	for _, statement := range productSearchStatements {
+		if err := DB.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to set up product search: %w", err)
+		}
+	}
+
+	for _, statement := range productTrigramStatements {
+		if err := DB.Exec(statement).Error; err != nil {
+			log.Printf("⚠️ Trigram search disabled (pg_trgm unavailable): %v", err)
+			return nil
+		}
+	}
+
+	log.Println("✅ Product full-text search ready")
+	return nil
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
 	"runtime/debug"
 
 	"github.com/gin-gonic/gin"
@@ -9,6 +11,8 @@ import (
 	"github.com/mm-api/mm-api/database"
 	"github.com/mm-api/mm-api/routes"
 	"github.com/mm-api/mm-api/services"
+	"github.com/mm-api/mm-api/storage"
+	"github.com/mm-api/mm-api/utils"
 )
 
 func main() {
@@ -19,6 +23,18 @@ func main() {
 		}
 	}()
 
+	// Перенос файлов между хранилищами (отдельная команда, сервер не запускается)
+	if len(os.Args) > 1 && os.Args[1] == "migrate-storage" {
+		runStorageMigration(os.Args[2:])
+		return
+	}
+
+	// Импорт релизов по манифесту (отдельная команда, сервер не запускается)
+	if len(os.Args) > 1 && os.Args[1] == "import-releases" {
+		runReleaseImport(os.Args[2:])
+		return
+	}
+
 	log.Println("🚀 Starting MM API Server...")
 
 	// Загрузка конфигурации
@@ -30,6 +46,24 @@ func main() {
 		log.Fatal("❌ Configuration validation failed:", err)
 	}
 
+	// Хранилище файлов (изображения, обновления, установщики POS)
+	store, err := newStorage(cfg, cfg.StorageBackend)
+	if err != nil {
+		log.Fatal("❌ Storage initialization failed:", err)
+	}
+	storage.SetDefault(store)
+	log.Printf("✅ Storage backend: %s", store.Name())
+
+	// Уменьшенные копии фото товаров (srcset)
+	utils.ConfigureImageVariants(utils.ImageVariantConfig{
+		SigningKey:  cfg.GetStorageSigningKey(),
+		WebPEncoder: cfg.ImageWebPEncoder,
+		JPEGQuality: cfg.ImageVariantQuality,
+		WebPQuality: cfg.ImageWebPQuality,
+		OnUpload:    cfg.ImageVariantsOnUpload,
+		Workers:     cfg.ImageVariantWorkers,
+	})
+
 	// Настройка режима Gin
 	gin.SetMode(cfg.GinMode)
 	log.Printf("✅ Gin mode set to: %s", cfg.GinMode)
@@ -41,12 +75,109 @@ func main() {
 	}
 	log.Println("✅ Database connected successfully")
 
+	// Выбор реализации поиска товаров (tsvector + pg_trgm или ILIKE)
+	services.InitSearchIndex(database.DB)
+
 	// Инициализация FCM сервиса для push-уведомлений
-	if cfg.FCMServerKey != "" {
-		services.InitFCMService(cfg.FCMServerKey)
-		log.Println("✅ FCM Service initialized")
+	if cfg.FCMCredentialsFile != "" {
+		if err := services.InitFCMService(services.FCMConfig{
+			CredentialsFile: cfg.FCMCredentialsFile,
+			ProjectID:       cfg.FCMProjectID,
+			APIBaseURL:      cfg.FCMAPIBaseURL,
+			TokenURL:        cfg.FCMTokenURL,
+		}); err != nil {
+			log.Printf("⚠️ FCM Service initialization failed, push notifications will be disabled: %v", err)
+		} else {
+			log.Println("✅ FCM Service initialized")
+		}
 	} else {
-		log.Println("⚠️ FCM Server Key not configured, push notifications will be disabled")
+		log.Println("⚠️ FCM credentials not configured, push notifications will be disabled")
+	}
+
+	// Провайдер SMS для кодов подтверждения телефона
+	if cfg.SMSProvider != "" {
+		sender, err := services.NewSMSSender(services.SMSConfig{
+			Provider: cfg.SMSProvider,
+			APIKey:   cfg.SMSAPIKey,
+			From:     cfg.SMSFrom,
+			APIURL:   cfg.SMSAPIURL,
+		})
+		if err != nil {
+			log.Fatal("❌ SMS provider initialization failed:", err)
+		}
+		services.SetSMSSender(sender)
+		log.Printf("✅ SMS provider initialized (%s)", cfg.SMSProvider)
+	} else if cfg.GinMode == "release" {
+		log.Println("⚠️ SMS provider not configured, phone verification codes will be disabled")
+	}
+
+	// Запуск воркеров очереди push-уведомлений
+	services.StartOutboxWorker(context.Background(), services.OutboxWorkerConfig{
+		Workers:      cfg.NotificationWorkers,
+		PollInterval: cfg.GetNotificationPollInterval(),
+		MaxAttempts:  cfg.NotificationMaxAttempts,
+	})
+
+	// Запуск отправки маркетинговых рассылок магазинов
+	services.StartCampaignWorker(context.Background(), services.CampaignWorkerConfig{
+		Interval:  cfg.GetCampaignSendInterval(),
+		BatchSize: cfg.CampaignBatchSize,
+	})
+
+	// Фоновый пересчет количества товаров в категориях
+	services.StartCategoryCountWorker(context.Background())
+
+	// Запуск пересчета рекомендаций товаров
+	services.StartRecommendationWorker(context.Background(), services.RecommendationConfig{
+		Interval:    cfg.GetRecommendationInterval(),
+		HistoryDays: cfg.RecommendationHistoryDays,
+		PerProduct:  cfg.RecommendationsPerProduct,
+		PerUser:     cfg.RecommendationsPerUser,
+	})
+
+	// Построение бинарных патчей между версиями (Windows установщики)
+	if cfg.ReleasePatchEnabled {
+		services.StartReleasePatchWorker(context.Background(), services.ReleasePatchConfig{
+			Interval:  cfg.GetReleasePatchInterval(),
+			Depth:     cfg.ReleasePatchDepth,
+			Platforms: cfg.GetReleasePatchPlatforms(),
+		})
+	}
+
+	// Прием обновлений и установщиков POS из папки входящих (FTP)
+	if cfg.ReleaseIngestEnabled {
+		services.StartReleaseIngestWorker(context.Background(), services.ReleaseIngestConfig{
+			InboxDir:      cfg.ReleaseIngestInbox,
+			QuarantineDir: cfg.ReleaseIngestQuarantine,
+			ProcessedDir:  cfg.ReleaseIngestProcessed,
+			Interval:      cfg.GetReleaseIngestInterval(),
+			SettleTime:    cfg.GetReleaseIngestSettle(),
+		})
+	}
+
+	// Политика хранения релизов (вместо скрипта cleanup_old_updates)
+	if cfg.ReleaseRetentionEnabled {
+		services.StartReleaseRetentionWorker(context.Background(), services.ReleaseRetentionConfig{
+			Interval:      cfg.GetReleaseRetentionInterval(),
+			KeepLast:      cfg.GetReleaseRetentionKeepLast(),
+			MinAge:        cfg.GetReleaseRetentionMinAge(),
+			InstallWindow: cfg.GetReleaseRetentionInstallWindow(),
+			DryRun:        cfg.ReleaseRetentionDryRun,
+		})
+	}
+
+	// Импорты фото, прерванные перезапуском (архивы хранились во временных файлах)
+	services.FailInterruptedImageImports(database.DB)
+
+	// Запуск сборки мусора медиа (файлы удаленных товаров, замененные аватары и логотипы)
+	if cfg.MediaGCEnabled {
+		services.StartMediaSweeper(context.Background(), services.MediaSweepConfig{
+			Interval:    cfg.GetMediaGCInterval(),
+			GracePeriod: cfg.GetMediaGCGracePeriod(),
+			DryRun:      cfg.MediaGCDryRun,
+			Discover:    cfg.MediaGCDiscover,
+			Folders:     cfg.GetMediaGCFolders(),
+		})
 	}
 
 	// Настройка маршрутов
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AttributeType тип значения характеристики товара
+type AttributeType string
+
+const (
+	AttributeTypeString    AttributeType = "string"     // Произвольный текст
+	AttributeTypeNumber    AttributeType = "number"     // Число (например, высота каблука)
+	AttributeTypeBoolean   AttributeType = "boolean"    // Да/нет
+	AttributeTypeEnum      AttributeType = "enum"       // Одно значение из Options
+	AttributeTypeMultiEnum AttributeType = "multi_enum" // Несколько значений из Options
+)
+
+// IsFilterable можно ли фильтровать каталог по характеристике этого типа
+func (t AttributeType) IsFilterable() bool {
+	return t == AttributeTypeEnum || t == AttributeTypeMultiEnum || t == AttributeTypeBoolean
+}
+
+// MaxAttributeStringLength максимальная длина текстовой характеристики
+const MaxAttributeStringLength = 200
+
+// CategoryAttribute описывает характеристику товаров категории (материал, сезон,
+// посадка, полнота обуви...). Действует для категории и всех ее подкатегорий;
+// характеристика подкатегории с тем же кодом перекрывает родительскую.
+type CategoryAttribute struct {
+	ID         uuid.UUID     `json:"id" gorm:"type:uuid;primary_key;"`
+	CategoryID uuid.UUID     `json:"categoryId" gorm:"type:uuid;not null;uniqueIndex:idx_category_attributes_category_code,priority:1"`
+	Code       string        `json:"code" gorm:"type:varchar(50);not null;uniqueIndex:idx_category_attributes_category_code,priority:2"` // Ключ в attributes товара
+	Name       string        `json:"name" gorm:"not null"`
+	Type       AttributeType `json:"type" gorm:"type:varchar(20);not null"`
+	Options    []string      `json:"options" gorm:"serializer:json"` // Допустимые значения для enum/multi_enum
+	Unit       string        `json:"unit"`                           // Единица измерения для number (см, мм)
+	Required   bool          `json:"required" gorm:"default:false"`
+	Filterable bool          `json:"filterable" gorm:"default:false"` // Показывать в фильтрах каталога
+	SortOrder  int           `json:"sortOrder" gorm:"default:0"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	UpdatedAt  time.Time     `json:"updatedAt"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (a *CategoryAttribute) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// CategoryAttributeRequest запрос на создание/обновление характеристики
+type CategoryAttributeRequest struct {
+	Code       string        `json:"code" binding:"required,max=50"`
+	Name       string        `json:"name" binding:"required"`
+	Type       AttributeType `json:"type" binding:"required,oneof=string number boolean enum multi_enum"`
+	Options    []string      `json:"options"`
+	Unit       string        `json:"unit"`
+	Required   bool          `json:"required"`
+	Filterable bool          `json:"filterable"`
+	SortOrder  int           `json:"sortOrder"`
+}
+
+// ProductAttributes значения характеристик товара: код -> значение
+// (string, float64, bool или []string для multi_enum)
+type ProductAttributes map[string]interface{}
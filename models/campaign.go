@@ -0,0 +1,216 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CampaignStatus представляет статус маркетинговой рассылки
+type CampaignStatus string
+
+const (
+	CampaignStatusDraft     CampaignStatus = "draft"     // Черновик
+	CampaignStatusScheduled CampaignStatus = "scheduled" // Запланирована
+	CampaignStatusSending   CampaignStatus = "sending"   // Идет отправка
+	CampaignStatusSent      CampaignStatus = "sent"      // Отправлена
+	CampaignStatusCancelled CampaignStatus = "cancelled" // Отменена
+	CampaignStatusFailed    CampaignStatus = "failed"    // Не удалось отправить (например, нет лицензии)
+)
+
+// CampaignAudience представляет базовую аудиторию рассылки
+type CampaignAudience string
+
+const (
+	CampaignAudienceSubscribers CampaignAudience = "subscribers" // Подписчики магазина (ShopSubscription)
+	CampaignAudienceClients     CampaignAudience = "clients"     // Клиенты магазина с бонусной картой (ShopClient)
+)
+
+// CampaignRecipientStatus представляет статус отправки конкретному получателю
+type CampaignRecipientStatus string
+
+const (
+	CampaignRecipientPending CampaignRecipientStatus = "pending"
+	CampaignRecipientSent    CampaignRecipientStatus = "sent"
+	CampaignRecipientSkipped CampaignRecipientStatus = "skipped" // Пользователь отключил акции
+)
+
+// CampaignMonthlyQuota количество сообщений в месяц по типу подписки лицензии магазина
+var CampaignMonthlyQuota = map[SubscriptionType]int{
+	SubscriptionTypeTrial:    100,
+	SubscriptionTypeMonthly:  2000,
+	SubscriptionTypeYearly:   5000,
+	SubscriptionTypeLifetime: 5000,
+}
+
+// Campaign представляет маркетинговую рассылку магазина своим подписчикам или клиентам
+type Campaign struct {
+	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;"`
+	ShopID    uuid.UUID      `json:"shopId" gorm:"type:uuid;not null;index"`
+	CreatedBy uuid.UUID      `json:"createdBy" gorm:"type:uuid;not null"`
+	Title     string         `json:"title" gorm:"not null"`
+	Body      string         `json:"body" gorm:"type:text;not null"`
+	ImageURL  string         `json:"imageUrl"`
+	ProductID *uuid.UUID     `json:"productId" gorm:"type:uuid"` // Товар, на который ведет рассылка
+	DeepLink  string         `json:"deepLink"`                   // Произвольный deep link (если нет товара)
+	Status    CampaignStatus `json:"status" gorm:"type:varchar(20);not null;default:draft;index"`
+
+	// Аудитория и фильтры
+	Audience            CampaignAudience `json:"audience" gorm:"type:varchar(20);not null;default:subscribers"`
+	CityID              *uuid.UUID       `json:"cityId" gorm:"type:uuid"` // Город из настроек пользователя
+	MinBonusAmount      *int             `json:"minBonusAmount"`          // Нижняя граница бонусов (в дирамах)
+	MaxBonusAmount      *int             `json:"maxBonusAmount"`          // Верхняя граница бонусов (в дирамах)
+	PurchasedWithinDays *int             `json:"purchasedWithinDays"`     // Покупали в магазине за последние N дней
+	NotPurchasedForDays *int             `json:"notPurchasedForDays"`     // Не покупали в магазине N дней (включая никогда)
+
+	// Планирование и статистика
+	ScheduledAt     *time.Time `json:"scheduledAt" gorm:"index"`
+	StartedAt       *time.Time `json:"startedAt"`
+	CompletedAt     *time.Time `json:"completedAt"`
+	RecipientsCount int        `json:"recipientsCount" gorm:"default:0"`
+	SentCount       int        `json:"sentCount" gorm:"default:0"`
+	SkippedCount    int        `json:"skippedCount" gorm:"default:0"`
+	OpenCount       int        `json:"openCount" gorm:"default:0"`
+	ClickCount      int        `json:"clickCount" gorm:"default:0"`
+	LastError       string     `json:"lastError" gorm:"type:text"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+
+	// Связи
+	Shop Shop `json:"shop,omitempty" gorm:"foreignKey:ShopID"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (c *Campaign) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsEditable сообщает, можно ли еще менять рассылку
+func (c *Campaign) IsEditable() bool {
+	return c.Status == CampaignStatusDraft || c.Status == CampaignStatusScheduled
+}
+
+// CampaignRecipient получатель рассылки, фиксируется при старте отправки
+type CampaignRecipient struct {
+	ID         uuid.UUID               `json:"id" gorm:"type:uuid;primary_key;"`
+	CampaignID uuid.UUID               `json:"campaignId" gorm:"type:uuid;not null;uniqueIndex:idx_campaign_recipient_user"`
+	UserID     uuid.UUID               `json:"userId" gorm:"type:uuid;not null;uniqueIndex:idx_campaign_recipient_user;index"`
+	Status     CampaignRecipientStatus `json:"status" gorm:"type:varchar(20);not null;default:pending;index"`
+	SentAt     *time.Time              `json:"sentAt"`
+	OpenedAt   *time.Time              `json:"openedAt"`
+	ClickedAt  *time.Time              `json:"clickedAt"`
+	CreatedAt  time.Time               `json:"createdAt"`
+	UpdatedAt  time.Time               `json:"updatedAt"`
+
+	// Связи
+	Campaign Campaign `json:"campaign,omitempty" gorm:"foreignKey:CampaignID"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (r *CampaignRecipient) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// CampaignRequest представляет запрос на создание или обновление рассылки
+type CampaignRequest struct {
+	Title               string           `json:"title" binding:"required,max=100"`
+	Body                string           `json:"body" binding:"required,max=500"`
+	ImageURL            string           `json:"imageUrl"`
+	ProductID           *uuid.UUID       `json:"productId"`
+	DeepLink            string           `json:"deepLink"`
+	Audience            CampaignAudience `json:"audience" binding:"omitempty,oneof=subscribers clients"`
+	CityID              *uuid.UUID       `json:"cityId"`
+	MinBonusAmount      *int             `json:"minBonusAmount" binding:"omitempty,min=0"`
+	MaxBonusAmount      *int             `json:"maxBonusAmount" binding:"omitempty,min=0"`
+	PurchasedWithinDays *int             `json:"purchasedWithinDays" binding:"omitempty,min=1"`
+	NotPurchasedForDays *int             `json:"notPurchasedForDays" binding:"omitempty,min=1"`
+	ScheduledAt         *time.Time       `json:"scheduledAt"` // Если указано - рассылка сразу планируется
+}
+
+// CampaignScheduleRequest представляет запрос на планирование рассылки
+type CampaignScheduleRequest struct {
+	ScheduledAt *time.Time `json:"scheduledAt"` // Пусто - отправить сейчас
+}
+
+// CampaignQuotaInfo представляет месячную квоту сообщений магазина
+type CampaignQuotaInfo struct {
+	SubscriptionType SubscriptionType `json:"subscriptionType,omitempty"`
+	MonthlyLimit     int              `json:"monthlyLimit"`
+	Used             int              `json:"used"`
+	Remaining        int              `json:"remaining"`
+	PeriodStart      time.Time        `json:"periodStart"`
+}
+
+// CampaignResponse представляет рассылку со статистикой
+type CampaignResponse struct {
+	ID                  uuid.UUID        `json:"id"`
+	ShopID              uuid.UUID        `json:"shopId"`
+	Title               string           `json:"title"`
+	Body                string           `json:"body"`
+	ImageURL            string           `json:"imageUrl,omitempty"`
+	ProductID           *uuid.UUID       `json:"productId,omitempty"`
+	DeepLink            string           `json:"deepLink,omitempty"`
+	Status              CampaignStatus   `json:"status"`
+	Audience            CampaignAudience `json:"audience"`
+	CityID              *uuid.UUID       `json:"cityId,omitempty"`
+	MinBonusAmount      *int             `json:"minBonusAmount,omitempty"`
+	MaxBonusAmount      *int             `json:"maxBonusAmount,omitempty"`
+	PurchasedWithinDays *int             `json:"purchasedWithinDays,omitempty"`
+	NotPurchasedForDays *int             `json:"notPurchasedForDays,omitempty"`
+	ScheduledAt         *time.Time       `json:"scheduledAt,omitempty"`
+	StartedAt           *time.Time       `json:"startedAt,omitempty"`
+	CompletedAt         *time.Time       `json:"completedAt,omitempty"`
+	RecipientsCount     int              `json:"recipientsCount"`
+	SentCount           int              `json:"sentCount"`
+	SkippedCount        int              `json:"skippedCount"`
+	OpenCount           int              `json:"openCount"`
+	ClickCount          int              `json:"clickCount"`
+	OpenRate            float64          `json:"openRate"`  // Доля открытий от отправленных
+	ClickRate           float64          `json:"clickRate"` // Доля переходов от отправленных
+	LastError           string           `json:"lastError,omitempty"`
+	CreatedAt           time.Time        `json:"createdAt"`
+	UpdatedAt           time.Time        `json:"updatedAt"`
+}
+
+// ToResponse преобразует Campaign в CampaignResponse
+func (c *Campaign) ToResponse() CampaignResponse {
+	response := CampaignResponse{
+		ID:                  c.ID,
+		ShopID:              c.ShopID,
+		Title:               c.Title,
+		Body:                c.Body,
+		ImageURL:            c.ImageURL,
+		ProductID:           c.ProductID,
+		DeepLink:            c.DeepLink,
+		Status:              c.Status,
+		Audience:            c.Audience,
+		CityID:              c.CityID,
+		MinBonusAmount:      c.MinBonusAmount,
+		MaxBonusAmount:      c.MaxBonusAmount,
+		PurchasedWithinDays: c.PurchasedWithinDays,
+		NotPurchasedForDays: c.NotPurchasedForDays,
+		ScheduledAt:         c.ScheduledAt,
+		StartedAt:           c.StartedAt,
+		CompletedAt:         c.CompletedAt,
+		RecipientsCount:     c.RecipientsCount,
+		SentCount:           c.SentCount,
+		SkippedCount:        c.SkippedCount,
+		OpenCount:           c.OpenCount,
+		ClickCount:          c.ClickCount,
+		LastError:           c.LastError,
+		CreatedAt:           c.CreatedAt,
+		UpdatedAt:           c.UpdatedAt,
+	}
+	if c.SentCount > 0 {
+		response.OpenRate = float64(c.OpenCount) / float64(c.SentCount)
+		response.ClickRate = float64(c.ClickCount) / float64(c.SentCount)
+	}
+	return response
+}
@@ -0,0 +1,94 @@
+package models
+
+import "github.com/google/uuid"
+
+// CatalogSort представляет сортировку каталога
+type CatalogSort string
+
+const (
+	CatalogSortRelevance CatalogSort = "relevance"  // По релевантности (только с поиском)
+	CatalogSortNewest    CatalogSort = "created_at" // Сначала новые
+	CatalogSortName      CatalogSort = "name"       // По названию
+	CatalogSortPrice     CatalogSort = "price"      // По минимальной цене подходящих вариаций
+	CatalogSortDiscount  CatalogSort = "discount"   // По максимальной скидке
+	CatalogSortRating    CatalogSort = "rating"     // По средней оценке отзывов
+)
+
+// CatalogFacet названия фасетов (совпадают с параметрами фильтра)
+const (
+	CatalogFacetCategory = "category"
+	CatalogFacetGender   = "gender"
+	CatalogFacetBrand    = "brand"
+	CatalogFacetSize     = "size"
+	CatalogFacetColor    = "color"
+	CatalogFacetPrice    = "price"
+	CatalogFacetOnSale   = "on_sale"
+	CatalogFacetInStock  = "in_stock"
+	CatalogFacetShop     = "shop"
+)
+
+// CatalogAttributeParamPrefix префикс параметров фильтра по характеристикам:
+// attr.material=cotton,wool (он же - имя фасета характеристики)
+const CatalogAttributeParamPrefix = "attr."
+
+// CatalogFilter представляет фильтры и сортировку каталога.
+// Условия на вариации (цена, размеры, цвета, скидка, наличие) должны
+// выполняться для одной и той же вариации товара.
+type CatalogFilter struct {
+	Search     string
+	CategoryID *uuid.UUID
+	Gender     string
+	Brands     []string
+	Sizes      []string
+	Colors     []string
+	MinPrice   *float64
+	MaxPrice   *float64
+	OnSale     bool
+	InStock    bool
+	CityID     *uuid.UUID
+	ShopID     *uuid.UUID
+	// Attributes значения характеристик: код -> любое из значений
+	Attributes map[string][]string
+
+	SortBy   CatalogSort
+	SortDesc bool
+	Page     int
+	Limit    int
+
+	// Курсорная пагинация (только для сортировки по дате создания): без COUNT
+	CursorMode bool
+	After      *Cursor
+
+	// Relations связи товара для загрузки (Preload)
+	Relations []string
+}
+
+// FacetValue значение фасета с количеством товаров
+type FacetValue struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"` // Название (для категорий и магазинов)
+	Count int64  `json:"count"`
+}
+
+// PriceRange диапазон цен подходящих вариаций
+type PriceRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// CatalogFacets количество товаров по значениям каждого фильтра.
+// Счетчики фасета учитывают все фильтры, кроме его собственного,
+// чтобы в шторке фильтров были видны альтернативы выбранному значению.
+type CatalogFacets struct {
+	Categories []FacetValue `json:"categories"`
+	Genders    []FacetValue `json:"genders"`
+	Brands     []FacetValue `json:"brands"`
+	Sizes      []FacetValue `json:"sizes"`
+	Colors     []FacetValue `json:"colors"`
+	Shops      []FacetValue `json:"shops"`
+	// Attributes фасеты фильтруемых характеристик выбранной категории (код -> значения)
+	Attributes map[string][]FacetValue `json:"attributes,omitempty"`
+	Price      PriceRange              `json:"price"`
+	OnSale     int64                   `json:"onSale"`
+	InStock    int64                   `json:"inStock"`
+}
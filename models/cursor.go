@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor курсор поврежден или создан для другого списка
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor позиция в списке, отсортированном по (время DESC, id DESC).
+// Клиент получает его закодированным и передает обратно без изменений.
+type Cursor struct {
+	Time time.Time `json:"t"`
+	ID   uuid.UUID `json:"id"`
+}
+
+// Encode кодирует курсор в непрозрачную строку
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor разбирает курсор из параметра after. Пустая строка - первая страница.
+func DecodeCursor(raw string) (*Cursor, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// ApplyCursor добавляет к запросу keyset-условие, сортировку и лимит.
+// Выбирается limit+1 запись, чтобы узнать, есть ли следующая страница, без COUNT.
+func ApplyCursor(query *gorm.DB, cursor *Cursor, timeColumn, idColumn string, limit int) *gorm.DB {
+	if cursor != nil {
+		query = query.Where("("+timeColumn+", "+idColumn+") < (?, ?)", cursor.Time, cursor.ID)
+	}
+	return query.Order(timeColumn + " DESC").Order(idColumn + " DESC").Limit(limit + 1)
+}
+
+// CursorPage обрезает выборку ApplyCursor до limit и строит курсор следующей страницы
+func CursorPage[T any](items []T, limit int, key func(*T) Cursor) ([]T, CursorInfo) {
+	info := CursorInfo{Limit: limit}
+	if len(items) > limit {
+		items = items[:limit]
+		info.HasMore = true
+	}
+	if info.HasMore && len(items) > 0 {
+		info.NextCursor = key(&items[len(items)-1]).Encode()
+	}
+	return items, info
+}
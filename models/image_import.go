@@ -0,0 +1,107 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ImageImportStatus статус импорта фото из ZIP архива
+type ImageImportStatus string
+
+const (
+	ImageImportStatusPending    ImageImportStatus = "pending"    // Архив принят, ждет обработки
+	ImageImportStatusProcessing ImageImportStatus = "processing" // Идет обработка
+	ImageImportStatusCompleted  ImageImportStatus = "completed"  // Обработан (возможно, с ошибками по отдельным файлам)
+	ImageImportStatusFailed     ImageImportStatus = "failed"     // Архив не удалось обработать
+)
+
+// Режим импорта: что делать с фото, которые уже есть у цвета вариации
+const (
+	ImageImportModeReplace = "replace" // Фото из архива заменяют фото цвета
+	ImageImportModeAppend  = "append"  // Фото из архива добавляются к существующим (в пределах лимита)
+)
+
+// ImageImportMaxErrors сколько ошибок по файлам хранится в отчете
+const ImageImportMaxErrors = 500
+
+// ImageImportError ошибка обработки файла из архива
+type ImageImportError struct {
+	File   string `json:"file"`
+	Reason string `json:"reason"`
+}
+
+// ImageImportJob задача импорта фото товаров из ZIP архива. Файлы называются
+// по SKU, штрих-коду или SKU_цвет_N; фото попадают в ImageURLsByColor вариаций.
+// POS опрашивает задачу, пока статус pending или processing.
+type ImageImportJob struct {
+	ID        uuid.UUID         `json:"id" gorm:"type:uuid;primary_key;"`
+	ShopID    uuid.UUID         `json:"shopId" gorm:"type:uuid;not null;index"`
+	CreatedBy uuid.UUID         `json:"createdBy" gorm:"type:uuid;not null"`
+	FileName  string            `json:"fileName"`
+	Mode      string            `json:"mode" gorm:"type:varchar(20);not null;default:replace"`
+	Status    ImageImportStatus `json:"status" gorm:"type:varchar(20);not null;default:pending;index"`
+
+	// Прогресс
+	TotalFiles        int `json:"totalFiles"`     // Фото в архиве
+	ProcessedFiles    int `json:"processedFiles"` // Обработано (загружено, пропущено или с ошибкой)
+	ImportedFiles     int `json:"importedFiles"`  // Загружено и добавлено к вариациям
+	SkippedFiles      int `json:"skippedFiles"`   // Не найдена вариация, превышен лимит и т.п.
+	FailedFiles       int `json:"failedFiles"`    // Ошибки обработки и сохранения
+	UpdatedVariations int `json:"updatedVariations"`
+
+	Errors     []ImageImportError `json:"errors" gorm:"serializer:json"`
+	Error      string             `json:"error,omitempty"` // Причина, если статус failed
+	StartedAt  *time.Time         `json:"startedAt"`
+	FinishedAt *time.Time         `json:"finishedAt"`
+	CreatedAt  time.Time          `json:"createdAt"`
+	UpdatedAt  time.Time          `json:"updatedAt"`
+
+	// Экземпляр API, который обрабатывает архив (архив лежит в его временном файле),
+	// и время его последнего сигнала. Задачу с устаревшим сигналом никто не обработает.
+	Instance    string     `json:"-" gorm:"type:varchar(255);index"`
+	HeartbeatAt *time.Time `json:"-"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (j *ImageImportJob) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	return nil
+}
+
+// Progress процент обработанных файлов
+func (j *ImageImportJob) Progress() int {
+	if j.TotalFiles == 0 {
+		if j.Status == ImageImportStatusCompleted {
+			return 100
+		}
+		return 0
+	}
+	return j.ProcessedFiles * 100 / j.TotalFiles
+}
+
+// AddError добавляет ошибку в отчет (не больше ImageImportMaxErrors)
+func (j *ImageImportJob) AddError(file, reason string) {
+	if len(j.Errors) < ImageImportMaxErrors {
+		j.Errors = append(j.Errors, ImageImportError{File: file, Reason: reason})
+	}
+}
+
+// ImageImportJobResponse задача импорта с процентом выполнения для POS
+type ImageImportJobResponse struct {
+	ImageImportJob
+	Progress int  `json:"progress"`
+	Done     bool `json:"done"` // Можно прекратить опрос
+}
+
+// ToResponse преобразует ImageImportJob в ответ API
+func (j *ImageImportJob) ToResponse() ImageImportJobResponse {
+	return ImageImportJobResponse{
+		ImageImportJob: *j,
+		Progress:       j.Progress(),
+		Done:           j.Status == ImageImportStatusCompleted || j.Status == ImageImportStatusFailed,
+	}
+}
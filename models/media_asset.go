@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Где хранится файл
+const (
+	MediaProviderStorage    = "storage"    // Хранилище приложения (локальная папка или S3)
+	MediaProviderCloudinary = "cloudinary" // Cloudinary (фото товаров)
+)
+
+// MediaCloudinaryKeyPrefix префикс ключа реестра для файлов Cloudinary (cloudinary:<public_id>)
+const MediaCloudinaryKeyPrefix = "cloudinary:"
+
+// Типы сущностей, ссылающихся на файлы
+const (
+	MediaEntityProductVariation   = "product_variation"
+	MediaEntityUser               = "user"
+	MediaEntityShop               = "shop"
+	MediaEntityCategory           = "category"
+	MediaEntityReview             = "review"
+	MediaEntityNotification       = "notification"
+	MediaEntityNotificationOutbox = "notification_outbox"
+	MediaEntityCampaign           = "campaign"
+	MediaEntityOrderItem          = "order_item"
+)
+
+// MediaAsset загруженный файл в реестре медиа. Файлы без ссылок удаляются
+// сборщиком мусора после периода ожидания (OrphanedAt + MEDIA_GC_GRACE_PERIOD).
+type MediaAsset struct {
+	ID               uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;"`
+	Key              string     `json:"key" gorm:"not null;uniqueIndex"` // Ключ в хранилище (images/...) или cloudinary:<public_id>
+	URL              string     `json:"url" gorm:"not null"`
+	Provider         string     `json:"provider" gorm:"type:varchar(20);not null;default:storage"`
+	Folder           string     `json:"folder" gorm:"type:varchar(100);index"` // products, variations, users, shop-logos...
+	Size             int64      `json:"size" gorm:"not null;default:0"`
+	ContentType      string     `json:"contentType"`
+	UploadedBy       *uuid.UUID `json:"uploadedBy" gorm:"type:uuid;index"`
+	ReferenceCount   int        `json:"referenceCount" gorm:"not null;default:0"`
+	LastReferencedAt *time.Time `json:"lastReferencedAt"`
+	OrphanedAt       *time.Time `json:"orphanedAt" gorm:"index"` // С какого момента на файл никто не ссылается
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+
+	// Связи
+	References []MediaReference `json:"references,omitempty" gorm:"foreignKey:AssetID"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (a *MediaAsset) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// MediaReference ссылка сущности на файл. Таблица пересобирается сборщиком
+// мусора по данным сущностей (фото вариаций, аватары, логотипы и т.д.).
+type MediaReference struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primary_key;"`
+	AssetID    uuid.UUID `json:"assetId" gorm:"type:uuid;not null;index"`
+	EntityType string    `json:"entityType" gorm:"type:varchar(30);not null;index:idx_media_references_entity,priority:1"`
+	EntityID   uuid.UUID `json:"entityId" gorm:"type:uuid;not null;index:idx_media_references_entity,priority:2"`
+	Field      string    `json:"field" gorm:"type:varchar(100);not null"` // image_urls, image_urls_by_color.<цвет>, avatar...
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (r *MediaReference) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// MediaSweepReport результат прохода сборщика мусора
+type MediaSweepReport struct {
+	DryRun      bool         `json:"dryRun"`
+	GracePeriod string       `json:"gracePeriod"`
+	Discovered  int          `json:"discovered"` // Файлы хранилища, добавленные в реестр при этом проходе
+	Assets      int64        `json:"assets"`     // Всего файлов в реестре
+	References  int          `json:"references"`
+	Orphaned    int64        `json:"orphaned"` // Файлы без ссылок (включая ожидающие)
+	Expired     int          `json:"expired"`  // Без ссылок дольше периода ожидания
+	Deleted     int          `json:"deleted"`
+	Failed      int          `json:"failed"`
+	FreedBytes  int64        `json:"freedBytes"`
+	Candidates  []MediaAsset `json:"candidates"` // Удаленные (или, в режиме dry-run, подлежащие удалению) файлы
+	StartedAt   time.Time    `json:"startedAt"`
+	FinishedAt  time.Time    `json:"finishedAt"`
+}
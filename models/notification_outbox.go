@@ -0,0 +1,134 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OutboxStatus представляет статус записи в очереди push-уведомлений
+type OutboxStatus string
+
+const (
+	OutboxStatusPending    OutboxStatus = "pending"    // Ожидает отправки (или повторной попытки)
+	OutboxStatusProcessing OutboxStatus = "processing" // Захвачена воркером
+	OutboxStatusSent       OutboxStatus = "sent"       // Доставлена хотя бы на одно устройство
+	OutboxStatusFailed     OutboxStatus = "failed"     // Не доставлена, попытки исчерпаны
+	OutboxStatusSkipped    OutboxStatus = "skipped"    // Нет устройств или push отключен
+)
+
+// DeliveryStatus представляет результат одной попытки доставки на устройство
+type DeliveryStatus string
+
+const (
+	DeliveryStatusSent         DeliveryStatus = "sent"
+	DeliveryStatusFailed       DeliveryStatus = "failed"
+	DeliveryStatusUnregistered DeliveryStatus = "unregistered" // FCM сообщил, что токен недействителен
+)
+
+// NotificationOutbox запись очереди push-уведомлений.
+// Создается в той же транзакции, что и бизнес-изменение, и доставляется воркером.
+type NotificationOutbox struct {
+	ID             uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;"`
+	NotificationID *uuid.UUID   `json:"notificationId" gorm:"type:uuid;index"` // Уведомление в БД (если есть)
+	UserID         uuid.UUID    `json:"userId" gorm:"type:uuid;not null;index"`
+	Title          string       `json:"title" gorm:"not null"`
+	Body           string       `json:"body" gorm:"not null"`
+	ActionURL      string       `json:"actionUrl"`
+	ImageURL       string       `json:"imageUrl"`              // Абсолютный URL картинки для push
+	Data           string       `json:"data" gorm:"type:text"` // JSON объект со строковыми значениями
+	Status         OutboxStatus `json:"status" gorm:"type:varchar(20);not null;default:pending;index:idx_outbox_status_next"`
+	Attempts       int          `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts    int          `json:"maxAttempts" gorm:"not null;default:5"`
+	NextAttemptAt  time.Time    `json:"nextAttemptAt" gorm:"index:idx_outbox_status_next"`
+	LockedAt       *time.Time   `json:"lockedAt"`
+	SentAt         *time.Time   `json:"sentAt"`
+	LastError      string       `json:"lastError" gorm:"type:text"`
+	CreatedAt      time.Time    `json:"createdAt"`
+	UpdatedAt      time.Time    `json:"updatedAt"`
+
+	// Связи
+	User       User                   `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Deliveries []NotificationDelivery `json:"deliveries,omitempty" gorm:"foreignKey:OutboxID"`
+}
+
+// BeforeCreate устанавливает UUID и время первой попытки перед созданием
+func (o *NotificationOutbox) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	if o.NextAttemptAt.IsZero() {
+		o.NextAttemptAt = time.Now()
+	}
+	if o.Status == "" {
+		o.Status = OutboxStatusPending
+	}
+	return nil
+}
+
+// NotificationDelivery фиксирует одну попытку доставки записи очереди на устройство
+type NotificationDelivery struct {
+	ID            uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;"`
+	OutboxID      uuid.UUID      `json:"outboxId" gorm:"type:uuid;not null;index"`
+	DeviceTokenID uuid.UUID      `json:"deviceTokenId" gorm:"type:uuid;not null;index"`
+	Platform      string         `json:"platform"`
+	Attempt       int            `json:"attempt" gorm:"not null"`
+	Status        DeliveryStatus `json:"status" gorm:"type:varchar(20);not null"`
+	Error         string         `json:"error" gorm:"type:text"`
+	CreatedAt     time.Time      `json:"createdAt"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (d *NotificationDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+// NotificationOutboxResponse представляет запись очереди для админ панели
+type NotificationOutboxResponse struct {
+	ID             uuid.UUID              `json:"id"`
+	NotificationID *uuid.UUID             `json:"notificationId,omitempty"`
+	UserID         uuid.UUID              `json:"userId"`
+	UserName       string                 `json:"userName,omitempty"`
+	Title          string                 `json:"title"`
+	Body           string                 `json:"body"`
+	ActionURL      string                 `json:"actionUrl,omitempty"`
+	Status         OutboxStatus           `json:"status"`
+	Attempts       int                    `json:"attempts"`
+	MaxAttempts    int                    `json:"maxAttempts"`
+	NextAttemptAt  time.Time              `json:"nextAttemptAt"`
+	SentAt         *time.Time             `json:"sentAt,omitempty"`
+	LastError      string                 `json:"lastError,omitempty"`
+	Deliveries     []NotificationDelivery `json:"deliveries"`
+	CreatedAt      time.Time              `json:"createdAt"`
+	UpdatedAt      time.Time              `json:"updatedAt"`
+}
+
+// ToResponse преобразует NotificationOutbox в NotificationOutboxResponse
+func (o *NotificationOutbox) ToResponse() NotificationOutboxResponse {
+	deliveries := o.Deliveries
+	if deliveries == nil {
+		deliveries = []NotificationDelivery{}
+	}
+	return NotificationOutboxResponse{
+		ID:             o.ID,
+		NotificationID: o.NotificationID,
+		UserID:         o.UserID,
+		UserName:       o.User.Name,
+		Title:          o.Title,
+		Body:           o.Body,
+		ActionURL:      o.ActionURL,
+		Status:         o.Status,
+		Attempts:       o.Attempts,
+		MaxAttempts:    o.MaxAttempts,
+		NextAttemptAt:  o.NextAttemptAt,
+		SentAt:         o.SentAt,
+		LastError:      o.LastError,
+		Deliveries:     deliveries,
+		CreatedAt:      o.CreatedAt,
+		UpdatedAt:      o.UpdatedAt,
+	}
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NotificationChannel представляет канал доставки уведомлений
+type NotificationChannel string
+
+const (
+	NotificationChannelInApp NotificationChannel = "in_app" // Список уведомлений в приложении
+	NotificationChannelPush  NotificationChannel = "push"   // Push-уведомление через FCM
+)
+
+// NotificationTypes все типы уведомлений, для которых настраиваются предпочтения
+var NotificationTypes = []NotificationType{
+	NotificationTypeOrder,
+	NotificationTypePromotion,
+	NotificationTypeSystem,
+	NotificationTypeReminder,
+}
+
+// NotificationChannels все каналы доставки, для которых настраиваются предпочтения
+var NotificationChannels = []NotificationChannel{
+	NotificationChannelInApp,
+	NotificationChannelPush,
+}
+
+// BypassesQuietHours сообщает, доставляется ли уведомление этого типа в тихие часы.
+// Заказы и системные уведомления отправляются сразу, акции и напоминания откладываются.
+func (t NotificationType) BypassesQuietHours() bool {
+	return t == NotificationTypeOrder || t == NotificationTypeSystem
+}
+
+// NotificationPreference хранит согласие пользователя на тип уведомлений в конкретном канале.
+// Отсутствие записи означает, что канал включен.
+type NotificationPreference struct {
+	ID        uuid.UUID           `json:"id" gorm:"type:uuid;primary_key;"`
+	UserID    uuid.UUID           `json:"userId" gorm:"type:uuid;not null;uniqueIndex:idx_notification_pref_user_type_channel"`
+	Type      NotificationType    `json:"type" gorm:"type:varchar(20);not null;uniqueIndex:idx_notification_pref_user_type_channel"`
+	Channel   NotificationChannel `json:"channel" gorm:"type:varchar(20);not null;uniqueIndex:idx_notification_pref_user_type_channel"`
+	Enabled   bool                `json:"enabled" gorm:"not null;default:true"`
+	CreatedAt time.Time           `json:"createdAt"`
+	UpdatedAt time.Time           `json:"updatedAt"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (p *NotificationPreference) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// NotificationPreferenceItem представляет одну настройку типа/канала в запросе и ответе
+type NotificationPreferenceItem struct {
+	Type    NotificationType    `json:"type" binding:"required,oneof=order promotion system reminder"`
+	Channel NotificationChannel `json:"channel" binding:"required,oneof=in_app push"`
+	Enabled bool                `json:"enabled"`
+}
+
+// NotificationPreferencesRequest представляет запрос на обновление предпочтений уведомлений
+type NotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceItem `json:"preferences" binding:"required,dive"`
+}
+
+// NotificationPreferencesResponse представляет полную матрицу предпочтений и тихие часы
+type NotificationPreferencesResponse struct {
+	Preferences       []NotificationPreferenceItem `json:"preferences"`
+	QuietHoursEnabled bool                         `json:"quietHoursEnabled"`
+	QuietHoursStart   string                       `json:"quietHoursStart"`
+	QuietHoursEnd     string                       `json:"quietHoursEnd"`
+	Timezone          string                       `json:"timezone"`
+}
+
+// BuildNotificationPreferences дополняет сохраненные предпочтения значениями по умолчанию
+// и возвращает полную матрицу тип x канал
+func BuildNotificationPreferences(saved []NotificationPreference) []NotificationPreferenceItem {
+	enabled := make(map[NotificationType]map[NotificationChannel]bool)
+	for _, pref := range saved {
+		if enabled[pref.Type] == nil {
+			enabled[pref.Type] = make(map[NotificationChannel]bool)
+		}
+		enabled[pref.Type][pref.Channel] = pref.Enabled
+	}
+
+	items := make([]NotificationPreferenceItem, 0, len(NotificationTypes)*len(NotificationChannels))
+	for _, notificationType := range NotificationTypes {
+		for _, channel := range NotificationChannels {
+			value, ok := enabled[notificationType][channel]
+			if !ok {
+				value = true
+			}
+			items = append(items, NotificationPreferenceItem{
+				Type:    notificationType,
+				Channel: channel,
+				Enabled: value,
+			})
+		}
+	}
+	return items
+}
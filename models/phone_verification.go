@@ -0,0 +1,103 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PhoneVerificationPurpose назначение одноразового кода
+type PhoneVerificationPurpose string
+
+const (
+	PhoneVerificationLogin        PhoneVerificationPurpose = "login"         // Вход в зарегистрированный аккаунт по коду
+	PhoneVerificationGuestMerge   PhoneVerificationPurpose = "guest_merge"   // Перенос данных гостя в аккаунт
+	PhoneVerificationRegister     PhoneVerificationPurpose = "register"      // Превращение гостя в зарегистрированного пользователя
+	PhoneVerificationAccountMerge PhoneVerificationPurpose = "account_merge" // Объединение двух зарегистрированных аккаунтов
+)
+
+// PhoneVerification одноразовый код подтверждения телефона (хранится только хеш)
+type PhoneVerification struct {
+	ID         uuid.UUID                `json:"id" gorm:"type:uuid;primary_key;"`
+	Phone      string                   `json:"phone" gorm:"not null;index:idx_phone_verification_lookup"` // Нормализованный номер
+	Purpose    PhoneVerificationPurpose `json:"purpose" gorm:"type:varchar(20);not null;index:idx_phone_verification_lookup"`
+	CodeHash   string                   `json:"-" gorm:"not null"`
+	Attempts   int                      `json:"attempts" gorm:"not null;default:0"`
+	ExpiresAt  time.Time                `json:"expiresAt"`
+	ConsumedAt *time.Time               `json:"consumedAt"`
+	CreatedAt  time.Time                `json:"createdAt"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (v *PhoneVerification) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+// PhoneCodeRequest запрос на отправку кода подтверждения
+type PhoneCodeRequest struct {
+	Phone   string                   `json:"phone" binding:"required"`
+	Purpose PhoneVerificationPurpose `json:"purpose" binding:"omitempty,oneof=login guest_merge register account_merge"`
+}
+
+// PhoneCodeLoginRequest запрос на вход по коду из SMS
+type PhoneCodeLoginRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// GuestMergeRequest запрос на перенос гостевых данных в зарегистрированный аккаунт.
+// Для гостя телефон берется из его аккаунта; зарегистрированный пользователь
+// может указать телефон, с которого оформлял гостевые заказы.
+type GuestMergeRequest struct {
+	Phone string `json:"phone"`
+	Code  string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// GuestUpgradeRequest запрос на превращение гостя в зарегистрированного пользователя.
+// Телефон (по умолчанию - телефон гостя) подтверждается кодом с purpose=register.
+type GuestUpgradeRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email" binding:"omitempty,email"`
+	Phone    string `json:"phone"`
+	Password string `json:"password" binding:"required,min=8"`
+	Code     string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// CartMergeStrategy правило объединения одинаковых позиций корзины
+type CartMergeStrategy string
+
+const (
+	CartMergeSum        CartMergeStrategy = "sum"         // Сложить количество
+	CartMergeMax        CartMergeStrategy = "max"         // Оставить большее количество
+	CartMergeKeepTarget CartMergeStrategy = "keep_target" // Оставить позицию целевого аккаунта
+)
+
+// AccountMergeRequest запрос на присоединение второго зарегистрированного аккаунта.
+// Владение вторым аккаунтом подтверждается кодом на его телефон (purpose=account_merge).
+type AccountMergeRequest struct {
+	Phone        string            `json:"phone" binding:"required"`
+	Code         string            `json:"code" binding:"required,len=6,numeric"`
+	CartStrategy CartMergeStrategy `json:"cartStrategy" binding:"omitempty,oneof=sum max keep_target"`
+}
+
+// AccountMergeSummary итог переноса данных между аккаунтами
+type AccountMergeSummary struct {
+	SourceUserIDs        []uuid.UUID `json:"sourceUserIds"`
+	TargetUserID         uuid.UUID   `json:"targetUserId"`
+	Orders               int64       `json:"orders"`
+	Addresses            int64       `json:"addresses"`
+	AddressesMerged      int64       `json:"addressesMerged"` // Совпавшие адреса (заказы перенаправлены на адрес целевого аккаунта)
+	CartItems            int64       `json:"cartItems"`
+	CartItemsMerged      int64       `json:"cartItemsMerged"` // Совпавшие позиции корзины (по cartStrategy)
+	Favorites            int64       `json:"favorites"`
+	FavoritesSkipped     int64       `json:"favoritesSkipped"`  // Уже были в избранном
+	ShopClients          int64       `json:"shopClients"`       // Бонусные карты магазинов, привязанные к аккаунту
+	ShopClientsMerged    int64       `json:"shopClientsMerged"` // Карты в магазинах, где у аккаунта уже была карта (бонусы и история перенесены)
+	Subscriptions        int64       `json:"subscriptions"`
+	SubscriptionsSkipped int64       `json:"subscriptionsSkipped"` // Уже был подписан
+	DeviceTokens         int64       `json:"deviceTokens"`
+}
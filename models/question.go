@@ -0,0 +1,163 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// QuestionStatus представляет статус модерации вопроса
+type QuestionStatus string
+
+const (
+	QuestionStatusPublished QuestionStatus = "published" // Виден на странице товара после ответа магазина
+	QuestionStatusHidden    QuestionStatus = "hidden"    // Скрыт модератором или по жалобам
+)
+
+// QuestionReportsToHide количество жалоб, после которого вопрос скрывается до проверки модератором
+const QuestionReportsToHide = 3
+
+// MaxQuestionsPerDay сколько вопросов пользователь может задать за сутки
+const MaxQuestionsPerDay = 20
+
+// ProductQuestion представляет вопрос покупателя о товаре и ответ магазина
+type ProductQuestion struct {
+	ID             uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;"`
+	ProductID      uuid.UUID      `json:"productId" gorm:"type:uuid;not null;index"`
+	ShopID         *uuid.UUID     `json:"shopId" gorm:"type:uuid;index"` // Магазин товара на момент вопроса
+	UserID         uuid.UUID      `json:"userId" gorm:"type:uuid;not null;index"`
+	Text           string         `json:"text" gorm:"type:text;not null"`
+	Answer         string         `json:"answer" gorm:"type:text"`
+	AnsweredBy     *uuid.UUID     `json:"answeredBy" gorm:"type:uuid"`
+	AnsweredAt     *time.Time     `json:"answeredAt"`
+	Status         QuestionStatus `json:"status" gorm:"type:varchar(20);not null;default:published;index"`
+	ReportsCount   int            `json:"reportsCount" gorm:"not null;default:0"` // Нерассмотренные жалобы
+	ModerationNote string         `json:"moderationNote"`
+	ModeratedBy    *uuid.UUID     `json:"moderatedBy" gorm:"type:uuid"`
+	ModeratedAt    *time.Time     `json:"moderatedAt"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+
+	// Связи
+	User    *User    `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Product *Product `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (q *ProductQuestion) BeforeCreate(tx *gorm.DB) error {
+	if q.ID == uuid.Nil {
+		q.ID = uuid.New()
+	}
+	return nil
+}
+
+// QuestionReportReason причина жалобы
+type QuestionReportReason string
+
+const (
+	QuestionReportSpam     QuestionReportReason = "spam"
+	QuestionReportAbuse    QuestionReportReason = "abuse"     // Оскорбления
+	QuestionReportOffTopic QuestionReportReason = "off_topic" // Не относится к товару
+	QuestionReportOther    QuestionReportReason = "other"
+)
+
+// QuestionReport жалоба на вопрос или ответ магазина
+type QuestionReport struct {
+	ID         uuid.UUID            `json:"id" gorm:"type:uuid;primary_key;"`
+	QuestionID uuid.UUID            `json:"questionId" gorm:"type:uuid;not null;uniqueIndex:idx_question_reports_question_user_target,priority:1"`
+	UserID     uuid.UUID            `json:"userId" gorm:"type:uuid;not null;uniqueIndex:idx_question_reports_question_user_target,priority:2"`
+	Target     string               `json:"target" gorm:"type:varchar(20);not null;uniqueIndex:idx_question_reports_question_user_target,priority:3"` // question или answer
+	Reason     QuestionReportReason `json:"reason" gorm:"type:varchar(20);not null"`
+	Comment    string               `json:"comment"`
+	ResolvedAt *time.Time           `json:"resolvedAt"` // Заполняется при решении модератора
+	CreatedAt  time.Time            `json:"createdAt"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (r *QuestionReport) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// ProductQuestionRequest запрос на создание вопроса
+type ProductQuestionRequest struct {
+	ProductID uuid.UUID `json:"productId" binding:"required"`
+	Text      string    `json:"text" binding:"required,min=3,max=1000"`
+}
+
+// QuestionAnswerRequest ответ магазина на вопрос
+type QuestionAnswerRequest struct {
+	Text string `json:"text" binding:"required,max=2000"`
+}
+
+// QuestionReportRequest жалоба на вопрос или ответ
+type QuestionReportRequest struct {
+	Target  string               `json:"target" binding:"required,oneof=question answer"`
+	Reason  QuestionReportReason `json:"reason" binding:"required,oneof=spam abuse off_topic other"`
+	Comment string               `json:"comment" binding:"max=500"`
+}
+
+// QuestionModerationRequest решение модератора: published закрывает жалобы
+// и возвращает вопрос, hidden скрывает его
+type QuestionModerationRequest struct {
+	Status      QuestionStatus `json:"status" binding:"required,oneof=published hidden"`
+	Note        string         `json:"note"`
+	ClearAnswer bool           `json:"clearAnswer"` // Удалить ответ магазина (если жалоба на ответ)
+}
+
+// ProductQuestionResponse ответ с вопросом
+type ProductQuestionResponse struct {
+	ID             uuid.UUID      `json:"id"`
+	ProductID      uuid.UUID      `json:"productId"`
+	ShopID         *uuid.UUID     `json:"shopId"`
+	Text           string         `json:"text"`
+	Answer         string         `json:"answer,omitempty"`
+	AnsweredAt     *time.Time     `json:"answeredAt,omitempty"`
+	Status         QuestionStatus `json:"status"`
+	ReportsCount   int            `json:"reportsCount,omitempty"`
+	ModerationNote string         `json:"moderationNote,omitempty"`
+	Author         *ReviewAuthor  `json:"author,omitempty"`
+	Product        *ReviewProduct `json:"product,omitempty"`
+	CreatedAt      time.Time      `json:"createdAt"`
+}
+
+// ToResponse преобразует ProductQuestion в ProductQuestionResponse
+func (q *ProductQuestion) ToResponse() ProductQuestionResponse {
+	response := ProductQuestionResponse{
+		ID:             q.ID,
+		ProductID:      q.ProductID,
+		ShopID:         q.ShopID,
+		Text:           q.Text,
+		Answer:         q.Answer,
+		AnsweredAt:     q.AnsweredAt,
+		Status:         q.Status,
+		ReportsCount:   q.ReportsCount,
+		ModerationNote: q.ModerationNote,
+		CreatedAt:      q.CreatedAt,
+	}
+	if q.User != nil && q.User.ID != uuid.Nil {
+		response.Author = &ReviewAuthor{
+			ID:     q.User.ID,
+			Name:   q.User.Name,
+			Avatar: q.User.Avatar,
+		}
+	}
+	if q.Product != nil && q.Product.ID != uuid.Nil {
+		response.Product = &ReviewProduct{
+			ID:   q.Product.ID,
+			Name: q.Product.Name,
+		}
+	}
+	return response
+}
+
+// ToPublicResponse ответ для страницы товара (без служебных полей модерации)
+func (q *ProductQuestion) ToPublicResponse() ProductQuestionResponse {
+	response := q.ToResponse()
+	response.ReportsCount = 0
+	response.ModerationNote = ""
+	return response
+}
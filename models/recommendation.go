@@ -0,0 +1,111 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecommendationKind представляет вид рекомендации
+type RecommendationKind string
+
+const (
+	RecommendationFeatured       RecommendationKind = "featured"        // Популярное: SubjectID - город (NULL - все города)
+	RecommendationSimilar        RecommendationKind = "similar"         // Похожие: SubjectID - товар
+	RecommendationBoughtTogether RecommendationKind = "bought_together" // Покупают вместе: SubjectID - товар
+	RecommendationForYou         RecommendationKind = "for_you"         // Для вас: SubjectID - пользователь
+)
+
+// ProductRecommendation предрассчитанная рекомендация товара.
+// Таблица полностью пересчитывается фоновым заданием.
+type ProductRecommendation struct {
+	ID         uuid.UUID          `json:"id" gorm:"type:uuid;primary_key;"`
+	Kind       RecommendationKind `json:"kind" gorm:"type:varchar(20);not null;index:idx_product_recommendations_lookup,priority:1"`
+	SubjectID  *uuid.UUID         `json:"subjectId" gorm:"type:uuid;index:idx_product_recommendations_lookup,priority:2"`
+	ProductID  uuid.UUID          `json:"productId" gorm:"type:uuid;not null"`
+	Score      float64            `json:"score" gorm:"not null;default:0"`
+	Reason     string             `json:"reason" gorm:"type:varchar(30)"` // popular, category, brand, shop, together
+	ComputedAt time.Time          `json:"computedAt"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (r *ProductRecommendation) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// ProductPin товар, закрепленный администратором в рекомендуемых
+type ProductPin struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;"`
+	ProductID uuid.UUID  `json:"productId" gorm:"type:uuid;not null;index"`
+	CityID    *uuid.UUID `json:"cityId" gorm:"type:uuid;index"` // NULL - во всех городах
+	Position  int        `json:"position" gorm:"not null;default:0"`
+	StartsAt  *time.Time `json:"startsAt"`
+	EndsAt    *time.Time `json:"endsAt"`
+	CreatedBy uuid.UUID  `json:"createdBy" gorm:"type:uuid;not null"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+
+	// Связи
+	Product *Product `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (p *ProductPin) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// ProductPinRequest запрос на закрепление товара
+type ProductPinRequest struct {
+	ProductID uuid.UUID  `json:"productId" binding:"required"`
+	CityID    *uuid.UUID `json:"cityId"`
+	Position  int        `json:"position" binding:"gte=0"`
+	StartsAt  *time.Time `json:"startsAt"`
+	EndsAt    *time.Time `json:"endsAt"`
+}
+
+// ProductPinResponse ответ с закрепленным товаром
+type ProductPinResponse struct {
+	ID        uuid.UUID        `json:"id"`
+	ProductID uuid.UUID        `json:"productId"`
+	CityID    *uuid.UUID       `json:"cityId"`
+	Position  int              `json:"position"`
+	StartsAt  *time.Time       `json:"startsAt"`
+	EndsAt    *time.Time       `json:"endsAt"`
+	IsActive  bool             `json:"isActive"` // Закрепление действует сейчас
+	CreatedAt time.Time        `json:"createdAt"`
+	Product   *ProductResponse `json:"product,omitempty"`
+}
+
+// IsActiveAt проверяет, действует ли закрепление в момент now
+func (p *ProductPin) IsActiveAt(now time.Time) bool {
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	return p.EndsAt == nil || now.Before(*p.EndsAt)
+}
+
+// ToResponse преобразует ProductPin в ProductPinResponse
+func (p *ProductPin) ToResponse() ProductPinResponse {
+	response := ProductPinResponse{
+		ID:        p.ID,
+		ProductID: p.ProductID,
+		CityID:    p.CityID,
+		Position:  p.Position,
+		StartsAt:  p.StartsAt,
+		EndsAt:    p.EndsAt,
+		IsActive:  p.IsActiveAt(time.Now()),
+		CreatedAt: p.CreatedAt,
+	}
+	if p.Product != nil && p.Product.ID != uuid.Nil {
+		product := p.Product.ToResponse()
+		response.Product = &product
+	}
+	return response
+}
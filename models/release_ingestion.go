@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReleaseIngestSource откуда пришел файл релиза
+type ReleaseIngestSource string
+
+const (
+	ReleaseIngestSourceInbox    ReleaseIngestSource = "inbox"    // Папка входящих (FTP)
+	ReleaseIngestSourceManifest ReleaseIngestSource = "manifest" // Команда import-releases
+)
+
+// ReleaseIngestStatus результат приема файла
+type ReleaseIngestStatus string
+
+const (
+	ReleaseIngestStatusImported  ReleaseIngestStatus = "imported"  // Релиз создан
+	ReleaseIngestStatusDuplicate ReleaseIngestStatus = "duplicate" // Та же версия с тем же содержимым уже есть
+	ReleaseIngestStatusRejected  ReleaseIngestStatus = "rejected"  // Файл не прошел проверку (из папки входящих - в карантине)
+)
+
+// ReleaseIngestion запись журнала приема файлов релизов (обновлений и установщиков POS)
+type ReleaseIngestion struct {
+	ID             uuid.UUID           `json:"id" gorm:"type:uuid;primary_key;"`
+	Source         ReleaseIngestSource `json:"source" gorm:"type:varchar(20);not null"`
+	Kind           ReleaseKind         `json:"kind" gorm:"type:varchar(20);index"`
+	FileName       string              `json:"fileName" gorm:"type:varchar(255);not null"` // Имя файла во входящих или в манифесте
+	Platform       string              `json:"platform" gorm:"type:varchar(20)"`
+	Type           string              `json:"type,omitempty" gorm:"type:varchar(20)"` // Тип LibissPosFile
+	Version        string              `json:"version" gorm:"type:varchar(50)"`
+	Channel        string              `json:"channel,omitempty" gorm:"type:varchar(20)"`
+	FileSize       int64               `json:"fileSize"`
+	ChecksumSHA256 string              `json:"checksumSha256" gorm:"type:varchar(128)"`
+	Status         ReleaseIngestStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	Error          string              `json:"error,omitempty" gorm:"type:text"`
+	QuarantinePath string              `json:"quarantinePath,omitempty" gorm:"type:varchar(500)"` // Куда перемещен отклоненный файл
+	ReleaseID      *uuid.UUID          `json:"releaseId,omitempty" gorm:"type:uuid"`              // Созданный или совпавший UpdateRelease / LibissPosFile
+	CreatedAt      time.Time           `json:"createdAt" gorm:"index"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием записи
+func (r *ReleaseIngestion) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// ReleaseIngestEntry описание файла релиза: запись манифеста import-releases или
+// файл <имя файла>.json рядом с файлом во входящих. Пустые поля определяются по имени
+// и папке файла.
+type ReleaseIngestEntry struct {
+	Kind                ReleaseKind `json:"kind"`     // update (по умолчанию) или libiss_pos
+	File                string      `json:"file"`     // Путь к файлу (относительно манифеста)
+	Platform            string      `json:"platform"` // По умолчанию по расширению файла
+	Type                string      `json:"type"`     // Тип LibissPosFile: full, cassa2, server_only
+	Version             string      `json:"version"`  // По умолчанию из имени файла
+	Channel             string      `json:"channel"`
+	RolloutPercentage   *int        `json:"rolloutPercentage"`
+	MinSupportedVersion string      `json:"minSupportedVersion"`
+	ReleaseNotes        string      `json:"releaseNotes"`     // Описание изменений (для установщиков POS - description)
+	ReleaseNotesFile    string      `json:"releaseNotesFile"` // Файл с описанием изменений
+	IsPublic            *bool       `json:"isPublic"`         // Установщики POS: публичное скачивание (по умолчанию true)
+	SHA256              string      `json:"sha256"`           // Ожидаемая контрольная сумма
+}
+
+// ReleaseIngestManifest манифест команды import-releases
+type ReleaseIngestManifest struct {
+	Releases []ReleaseIngestEntry `json:"releases"`
+}
+
+// ReleaseInboxFile файл, ожидающий обработки во входящих
+type ReleaseInboxFile struct {
+	Path       string    `json:"path"` // Относительно папки входящих
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+	Settled    bool      `json:"settled"` // Загрузка завершена (файл не менялся дольше периода ожидания)
+}
+
+// ReleaseIngestStatusResponse состояние приема релизов для админки
+type ReleaseIngestStatusResponse struct {
+	Enabled       bool                          `json:"enabled"`
+	InboxDir      string                        `json:"inboxDir"`
+	QuarantineDir string                        `json:"quarantineDir"`
+	ProcessedDir  string                        `json:"processedDir,omitempty"`
+	LastScanAt    *time.Time                    `json:"lastScanAt,omitempty"`
+	LastError     string                        `json:"lastError,omitempty"`
+	Pending       []ReleaseInboxFile            `json:"pending"`
+	Counts        map[ReleaseIngestStatus]int64 `json:"counts"` // За последние 30 дней
+	Recent        []ReleaseIngestion            `json:"recent"`
+}
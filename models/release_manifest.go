@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReleaseManifestEntry файл в манифесте релизов
+type ReleaseManifestEntry struct {
+	ID             uuid.UUID `json:"id"`
+	Version        string    `json:"version"`
+	Type           string    `json:"type,omitempty"`    // Тип LibissPosFile
+	Channel        string    `json:"channel,omitempty"` // Канал UpdateRelease
+	FileName       string    `json:"fileName"`
+	FileSize       int64     `json:"fileSize"`
+	ChecksumSHA256 string    `json:"checksumSha256"`
+}
+
+// ReleaseManifest список активных файлов и их контрольных сумм. Подписываются
+// ровно те байты JSON, которые выдаются клиенту.
+type ReleaseManifest struct {
+	Kind      ReleaseKind            `json:"kind"`
+	Platform  string                 `json:"platform"`
+	Type      string                 `json:"type,omitempty"`
+	Channel   string                 `json:"channel,omitempty"`
+	UpdatedAt time.Time              `json:"updatedAt"` // Последнее изменение файлов манифеста
+	Entries   []ReleaseManifestEntry `json:"entries"`
+}
+
+// SignedReleaseManifest манифест с отделенной подписью
+type SignedReleaseManifest struct {
+	Manifest  string          `json:"manifest"`  // Base64 подписанных байтов JSON манифеста
+	Signature string          `json:"signature"` // Base64 подписи ed25519 над байтами манифеста
+	Algorithm string          `json:"algorithm"`
+	KeyID     string          `json:"keyId"`
+	Data      ReleaseManifest `json:"data"` // Тот же манифест в разобранном виде (не доверять без проверки подписи)
+}
+
+// ReleaseSigningKeyResponse публичный ключ проверки манифестов
+type ReleaseSigningKeyResponse struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"keyId"`
+	PublicKey string `json:"publicKey"` // Base64 публичного ключа ed25519 (32 байта)
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReleaseKind вид выпускаемых файлов
+type ReleaseKind string
+
+const (
+	ReleaseKindUpdate    ReleaseKind = "update"     // UpdateRelease (обновления приложений)
+	ReleaseKindLibissPos ReleaseKind = "libiss_pos" // LibissPosFile (установщики POS)
+)
+
+// ReleasePatchStatus статус построения патча
+type ReleasePatchStatus string
+
+const (
+	ReleasePatchStatusPending    ReleasePatchStatus = "pending"    // Ожидает построения
+	ReleasePatchStatusProcessing ReleasePatchStatus = "processing" // Строится
+	ReleasePatchStatusReady      ReleasePatchStatus = "ready"      // Построен и проверен, выдается клиентам
+	ReleasePatchStatusSkipped    ReleasePatchStatus = "skipped"    // Патч почти не меньше полного файла
+	ReleasePatchStatusFailed     ReleasePatchStatus = "failed"     // Ошибка построения или проверки
+)
+
+// ReleasePatch бинарный патч (формат MMDELTA1) между двумя версиями одного
+// выпуска: платформы обновлений или типа и платформы установщиков POS
+type ReleasePatch struct {
+	ID             uuid.UUID          `json:"id" gorm:"type:uuid;primary_key;"`
+	Kind           ReleaseKind        `json:"kind" gorm:"type:varchar(20);not null;index"`
+	Platform       string             `json:"platform" gorm:"type:varchar(20);not null"`
+	Type           string             `json:"type,omitempty" gorm:"type:varchar(20)"` // Тип LibissPosFile (full, cassa2, server_only)
+	SourceID       uuid.UUID          `json:"sourceId" gorm:"type:uuid;not null;uniqueIndex:idx_release_patch_pair,priority:2"`
+	TargetID       uuid.UUID          `json:"targetId" gorm:"type:uuid;not null;uniqueIndex:idx_release_patch_pair,priority:1"`
+	SourceVersion  string             `json:"sourceVersion" gorm:"type:varchar(50);not null"`
+	TargetVersion  string             `json:"targetVersion" gorm:"type:varchar(50);not null"`
+	SourceSHA256   string             `json:"sourceSha256" gorm:"type:varchar(128)"` // Клиент проверяет установленный файл перед применением
+	TargetSHA256   string             `json:"targetSha256" gorm:"type:varchar(128)"` // и результат после
+	TargetSize     int64              `json:"targetSize"`
+	FilePath       string             `json:"filePath" gorm:"type:varchar(500)"`
+	FileSize       int64              `json:"fileSize"`
+	ChecksumSHA256 string             `json:"checksumSha256" gorm:"type:varchar(128)"` // SHA256 самого патча
+	Status         ReleasePatchStatus `json:"status" gorm:"type:varchar(20);not null;default:pending;index"`
+	Error          string             `json:"error,omitempty" gorm:"type:text"`
+	CreatedAt      time.Time          `json:"createdAt"`
+	UpdatedAt      time.Time          `json:"updatedAt"`
+
+	// DownloadURL ссылка на скачивание патча (не хранится в БД)
+	DownloadURL string `json:"downloadUrl,omitempty" gorm:"-"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием записи
+func (p *ReleasePatch) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// ReleaseFullFile полный файл версии (запасной вариант, если патч не подходит)
+type ReleaseFullFile struct {
+	ID             uuid.UUID `json:"id"`
+	Version        string    `json:"version"`
+	FileSize       int64     `json:"fileSize"`
+	ChecksumSHA256 string    `json:"checksumSha256"`
+	DownloadURL    string    `json:"downloadUrl"`
+}
+
+// ReleasePatchResponse ответ клиенту: патч от его версии или полный файл
+type ReleasePatchResponse struct {
+	Mode   string          `json:"mode"`             // patch или full
+	Reason string          `json:"reason,omitempty"` // Почему патч недоступен
+	Patch  *ReleasePatch   `json:"patch,omitempty"`
+	Full   ReleaseFullFile `json:"full"` // Всегда: если патч не прошел проверку, скачивается полный файл
+}
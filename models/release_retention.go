@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReleaseDeletionTrigger причина удаления релиза
+type ReleaseDeletionTrigger string
+
+const (
+	ReleaseDeletionTriggerRetention ReleaseDeletionTrigger = "retention" // Политика хранения
+	ReleaseDeletionTriggerManual    ReleaseDeletionTrigger = "manual"    // Удален администратором
+)
+
+// ReleaseDeletion журнал удаления обновлений и установщиков POS
+type ReleaseDeletion struct {
+	ID             uuid.UUID              `json:"id" gorm:"type:uuid;primary_key;"`
+	Kind           ReleaseKind            `json:"kind" gorm:"type:varchar(20);not null;index"`
+	ReleaseID      uuid.UUID              `json:"releaseId" gorm:"type:uuid;not null"`
+	Platform       string                 `json:"platform" gorm:"type:varchar(20)"`
+	Type           string                 `json:"type,omitempty" gorm:"type:varchar(20)"`
+	Channel        string                 `json:"channel,omitempty" gorm:"type:varchar(20)"`
+	Version        string                 `json:"version" gorm:"type:varchar(50)"`
+	FilePath       string                 `json:"filePath" gorm:"type:varchar(500)"`
+	FileSize       int64                  `json:"fileSize"`
+	ChecksumSHA256 string                 `json:"checksumSha256" gorm:"type:varchar(128)"`
+	Trigger        ReleaseDeletionTrigger `json:"trigger" gorm:"type:varchar(20);not null;index"`
+	Reason         string                 `json:"reason" gorm:"type:text"`
+	FileError      string                 `json:"fileError,omitempty" gorm:"type:text"` // Файл не удалось удалить из хранилища
+	DeletedBy      *uuid.UUID             `json:"deletedBy,omitempty" gorm:"type:uuid"` // Пусто - удален по расписанию
+	CreatedAt      time.Time              `json:"createdAt" gorm:"index"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием записи
+func (d *ReleaseDeletion) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+// LibissPosCheckIn последняя проверка версии установщиком POS (/libiss-pos/latest и
+// /libiss-pos/patch с deviceId). Установленные версии не удаляются политикой хранения.
+type LibissPosCheckIn struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key;"`
+	Type           string    `json:"type" gorm:"type:varchar(20);not null;uniqueIndex:idx_libiss_pos_check_in_device,priority:1"`
+	Platform       string    `json:"platform" gorm:"type:varchar(20);not null;uniqueIndex:idx_libiss_pos_check_in_device,priority:2"`
+	DeviceID       string    `json:"deviceId" gorm:"type:varchar(255);not null;uniqueIndex:idx_libiss_pos_check_in_device,priority:3"`
+	Version        string    `json:"version" gorm:"type:varchar(50)"`
+	OfferedVersion string    `json:"offeredVersion" gorm:"type:varchar(50)"` // Какую версию предложили при последней проверке
+	LastCheckedAt  time.Time `json:"lastCheckedAt" gorm:"index"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием записи
+func (l *LibissPosCheckIn) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
+// ReleaseRetentionDecision решение политики хранения по одному релизу
+type ReleaseRetentionDecision struct {
+	Kind      ReleaseKind `json:"kind"`
+	ID        uuid.UUID   `json:"id"`
+	Platform  string      `json:"platform"`
+	Type      string      `json:"type,omitempty"`
+	Channel   string      `json:"channel,omitempty"`
+	Version   string      `json:"version"`
+	IsActive  bool        `json:"isActive"`
+	FileSize  int64       `json:"fileSize"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Delete    bool        `json:"delete"`
+	Reason    string      `json:"reason"`            // Почему релиз хранится или удаляется
+	Devices   int64       `json:"devices,omitempty"` // Устройства и кассы с этой версией (установлена или предложена)
+}
+
+// ReleaseRetentionReport результат прохода политики хранения
+type ReleaseRetentionReport struct {
+	DryRun        bool                       `json:"dryRun"`
+	KeepLast      int                        `json:"keepLast"`
+	MinAge        string                     `json:"minAge"`
+	InstallWindow string                     `json:"installWindow"`
+	Releases      []ReleaseRetentionDecision `json:"releases"`   // Все релизы с решением
+	Candidates    int                        `json:"candidates"` // Подлежат удалению
+	Deleted       int                        `json:"deleted"`
+	Failed        int                        `json:"failed"`
+	FreedBytes    int64                      `json:"freedBytes"`
+	StartedAt     time.Time                  `json:"startedAt"`
+	FinishedAt    time.Time                  `json:"finishedAt"`
+}
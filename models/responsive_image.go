@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mm-api/mm-api/utils"
+)
+
+// ResponsiveImage фото товара с адресами уменьшенных копий
+type ResponsiveImage struct {
+	URL       string            `json:"url"`                 // Оригинал
+	Thumbnail string            `json:"thumbnail,omitempty"` // 160px, JPEG
+	List      string            `json:"list,omitempty"`      // 400px, JPEG
+	Detail    string            `json:"detail,omitempty"`    // 800px, JPEG
+	Zoom      string            `json:"zoom,omitempty"`      // 1600px, JPEG
+	SrcSet    map[string]string `json:"srcset,omitempty"`    // Формат (webp, jpeg) -> "url 160w, url 400w, ..."
+}
+
+// NewResponsiveImage собирает адреса вариантов изображения. Для адресов, которые
+// нельзя уменьшить (внешние ссылки), заполняется только URL.
+func NewResponsiveImage(imageURL string) ResponsiveImage {
+	image := ResponsiveImage{URL: imageURL}
+	if utils.ImageVariantURL(imageURL, utils.ImageRenditions[0].Width, utils.ImageFormatJPEG) == "" {
+		return image
+	}
+
+	for _, rendition := range utils.ImageRenditions {
+		url := utils.ImageVariantURL(imageURL, rendition.Width, utils.ImageFormatJPEG)
+		switch rendition.Name {
+		case "thumbnail":
+			image.Thumbnail = url
+		case "list":
+			image.List = url
+		case "detail":
+			image.Detail = url
+		case "zoom":
+			image.Zoom = url
+		}
+	}
+
+	image.SrcSet = make(map[string]string)
+	for _, format := range utils.ImageVariantFormats() {
+		candidates := make([]string, 0, len(utils.ImageRenditions))
+		for _, rendition := range utils.ImageRenditions {
+			candidates = append(candidates, fmt.Sprintf("%s %dw", utils.ImageVariantURL(imageURL, rendition.Width, format), rendition.Width))
+		}
+		image.SrcSet[format] = strings.Join(candidates, ", ")
+	}
+	return image
+}
+
+// NewResponsiveImages собирает варианты для списка фото
+func NewResponsiveImages(imageURLs []string) []ResponsiveImage {
+	if len(imageURLs) == 0 {
+		return nil
+	}
+	images := make([]ResponsiveImage, 0, len(imageURLs))
+	for _, imageURL := range imageURLs {
+		if imageURL != "" {
+			images = append(images, NewResponsiveImage(imageURL))
+		}
+	}
+	return images
+}
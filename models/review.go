@@ -0,0 +1,148 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReviewStatus представляет статус модерации отзыва
+type ReviewStatus string
+
+const (
+	ReviewStatusPublished ReviewStatus = "published" // Опубликован (учитывается в рейтинге)
+	ReviewStatusPending   ReviewStatus = "pending"   // Отклоненный отзыв исправлен автором и ждет проверки
+	ReviewStatusRejected  ReviewStatus = "rejected"  // Скрыт модератором
+)
+
+// MaxReviewPhotos максимальное количество фото в отзыве
+const MaxReviewPhotos = 5
+
+// ProductReview представляет отзыв покупателя о товаре.
+// Оставить отзыв можно только на товар из доставленного заказа, один на товар.
+type ProductReview struct {
+	ID             uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;"`
+	ProductID      uuid.UUID    `json:"productId" gorm:"type:uuid;not null;uniqueIndex:idx_product_reviews_user_product,priority:2;index"`
+	ShopID         *uuid.UUID   `json:"shopId" gorm:"type:uuid;index"` // Магазин товара на момент отзыва
+	UserID         uuid.UUID    `json:"userId" gorm:"type:uuid;not null;uniqueIndex:idx_product_reviews_user_product,priority:1"`
+	OrderItemID    uuid.UUID    `json:"orderItemId" gorm:"type:uuid;not null"` // Доставленная позиция заказа
+	Rating         int          `json:"rating" gorm:"not null"`                // 1-5
+	Text           string       `json:"text" gorm:"type:text"`
+	Photos         []string     `json:"photos" gorm:"serializer:json"`
+	Status         ReviewStatus `json:"status" gorm:"type:varchar(20);not null;default:published;index"`
+	ModerationNote string       `json:"moderationNote"`
+	ModeratedBy    *uuid.UUID   `json:"moderatedBy" gorm:"type:uuid"`
+	ModeratedAt    *time.Time   `json:"moderatedAt"`
+	Reply          string       `json:"reply" gorm:"type:text"` // Ответ магазина
+	RepliedBy      *uuid.UUID   `json:"repliedBy" gorm:"type:uuid"`
+	RepliedAt      *time.Time   `json:"repliedAt"`
+	CreatedAt      time.Time    `json:"createdAt"`
+	UpdatedAt      time.Time    `json:"updatedAt"`
+
+	// Связи
+	User    *User    `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Product *Product `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (r *ProductReview) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// ProductReviewRequest запрос на создание или изменение отзыва
+type ProductReviewRequest struct {
+	ProductID uuid.UUID `json:"productId"` // Обязателен при создании, при изменении игнорируется
+	Rating    int       `json:"rating" binding:"required,min=1,max=5"`
+	Text      string    `json:"text" binding:"max=5000"`
+	Photos    []string  `json:"photos"` // URL из POST /upload/image?folder=reviews
+}
+
+// ReviewReplyRequest ответ магазина на отзыв
+type ReviewReplyRequest struct {
+	Text string `json:"text" binding:"required,max=2000"`
+}
+
+// ReviewModerationRequest решение модератора
+type ReviewModerationRequest struct {
+	Status ReviewStatus `json:"status" binding:"required,oneof=published rejected"`
+	Note   string       `json:"note"`
+}
+
+// ReviewAuthor публичная информация об авторе отзыва
+type ReviewAuthor struct {
+	ID     uuid.UUID `json:"id"`
+	Name   string    `json:"name"`
+	Avatar string    `json:"avatar"`
+}
+
+// ReviewProduct краткая информация о товаре отзыва
+type ReviewProduct struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// ProductReviewResponse ответ с отзывом
+type ProductReviewResponse struct {
+	ID             uuid.UUID      `json:"id"`
+	ProductID      uuid.UUID      `json:"productId"`
+	ShopID         *uuid.UUID     `json:"shopId"`
+	Rating         int            `json:"rating"`
+	Text           string         `json:"text"`
+	Photos         []string       `json:"photos"`
+	Status         ReviewStatus   `json:"status"`
+	ModerationNote string         `json:"moderationNote,omitempty"`
+	Reply          string         `json:"reply,omitempty"`
+	RepliedAt      *time.Time     `json:"repliedAt,omitempty"`
+	Author         *ReviewAuthor  `json:"author,omitempty"`
+	Product        *ReviewProduct `json:"product,omitempty"` // Товар (для списков магазина и модерации)
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+}
+
+// ToResponse преобразует ProductReview в ProductReviewResponse
+func (r *ProductReview) ToResponse() ProductReviewResponse {
+	photos := r.Photos
+	if photos == nil {
+		photos = []string{}
+	}
+
+	response := ProductReviewResponse{
+		ID:             r.ID,
+		ProductID:      r.ProductID,
+		ShopID:         r.ShopID,
+		Rating:         r.Rating,
+		Text:           r.Text,
+		Photos:         photos,
+		Status:         r.Status,
+		ModerationNote: r.ModerationNote,
+		Reply:          r.Reply,
+		RepliedAt:      r.RepliedAt,
+		CreatedAt:      r.CreatedAt,
+		UpdatedAt:      r.UpdatedAt,
+	}
+	if r.User != nil && r.User.ID != uuid.Nil {
+		response.Author = &ReviewAuthor{
+			ID:     r.User.ID,
+			Name:   r.User.Name,
+			Avatar: r.User.Avatar,
+		}
+	}
+	if r.Product != nil && r.Product.ID != uuid.Nil {
+		response.Product = &ReviewProduct{
+			ID:   r.Product.ID,
+			Name: r.Product.Name,
+		}
+	}
+	return response
+}
+
+// RatingSummary сводка оценок товара
+type RatingSummary struct {
+	Average      float64       `json:"average"`
+	Count        int64         `json:"count"`
+	Distribution map[int]int64 `json:"distribution"` // Оценка (1-5) -> количество
+}
@@ -1,77 +1,154 @@
-package models
-
-import (
-	"time"
-
-	"github.com/google/uuid"
-	"gorm.io/gorm"
-)
-
-// UserSettings представляет настройки пользователя
-type UserSettings struct {
-	ID                   uuid.UUID `json:"id" gorm:"type:uuid;primary_key;"`
-	UserID               uuid.UUID `json:"userId" gorm:"type:uuid;not null;uniqueIndex"`
-	Language             string    `json:"language" gorm:"default:'ru'"`  // ru/en
-	Theme                string    `json:"theme" gorm:"default:'system'"` // system/light/dark
-	NotificationsEnabled bool      `json:"notificationsEnabled" gorm:"default:true"`
-	EmailNotifications   bool      `json:"emailNotifications" gorm:"default:true"`
-	PushNotifications    bool       `json:"pushNotifications" gorm:"default:true"`
-	SelectedCityID       *uuid.UUID `json:"selectedCityId" gorm:"type:uuid;index"` // Выбранный город пользователя
-	CreatedAt            time.Time  `json:"createdAt"`
-	UpdatedAt            time.Time  `json:"updatedAt"`
-
-	// Связи
-	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	City *City `json:"city,omitempty" gorm:"foreignKey:SelectedCityID"`
-}
-
-// BeforeCreate устанавливает UUID перед созданием
-func (s *UserSettings) BeforeCreate(tx *gorm.DB) error {
-	if s.ID == uuid.Nil {
-		s.ID = uuid.New()
-	}
-	return nil
-}
-
-// SettingsRequest представляет запрос на обновление настроек
-type SettingsRequest struct {
-	Language             string  `json:"language" binding:"oneof=ru en"`
-	Theme                string  `json:"theme" binding:"oneof=system light dark"`
-	NotificationsEnabled *bool   `json:"notificationsEnabled"`
-	EmailNotifications   *bool   `json:"emailNotifications"`
-	PushNotifications    *bool   `json:"pushNotifications"`
-	SelectedCityID       *string `json:"selectedCityId"` // ID выбранного города
-}
-
-// SettingsResponse представляет ответ с настройками
-type SettingsResponse struct {
-	Language             string        `json:"language"`
-	Theme                string        `json:"theme"`
-	NotificationsEnabled bool          `json:"notificationsEnabled"`
-	EmailNotifications   bool          `json:"emailNotifications"`
-	PushNotifications    bool          `json:"pushNotifications"`
-	SelectedCityID       *uuid.UUID    `json:"selectedCityId"`
-	City                 *CityResponse `json:"city,omitempty"` // Информация о выбранном городе
-	UpdatedAt            time.Time     `json:"updatedAt"`
-}
-
-// ToResponse преобразует UserSettings в SettingsResponse
-func (s *UserSettings) ToResponse() SettingsResponse {
-	response := SettingsResponse{
-		Language:             s.Language,
-		Theme:                s.Theme,
-		NotificationsEnabled: s.NotificationsEnabled,
-		EmailNotifications:   s.EmailNotifications,
-		PushNotifications:    s.PushNotifications,
-		SelectedCityID:       s.SelectedCityID,
-		UpdatedAt:            s.UpdatedAt,
-	}
-	
-	// Если есть информация о городе, добавляем её
-	if s.City != nil {
-		cityResp := s.City.ToResponse()
-		response.City = &cityResp
-	}
-	
-	return response
-}
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserSettings представляет настройки пользователя
+type UserSettings struct {
+	ID                   uuid.UUID `json:"id" gorm:"type:uuid;primary_key;"`
+	UserID               uuid.UUID `json:"userId" gorm:"type:uuid;not null;uniqueIndex"`
+	Language             string    `json:"language" gorm:"default:'ru'"`  // ru/en
+	Theme                string    `json:"theme" gorm:"default:'system'"` // system/light/dark
+	NotificationsEnabled bool      `json:"notificationsEnabled" gorm:"default:true"`
+	EmailNotifications   bool      `json:"emailNotifications" gorm:"default:true"`
+	PushNotifications    bool       `json:"pushNotifications" gorm:"default:true"`
+	SelectedCityID       *uuid.UUID `json:"selectedCityId" gorm:"type:uuid;index"` // Выбранный город пользователя
+	QuietHoursEnabled    bool       `json:"quietHoursEnabled" gorm:"default:false"`           // Тихие часы для push-уведомлений
+	QuietHoursStart      string     `json:"quietHoursStart" gorm:"size:5;default:'22:00'"`    // Начало тихих часов (HH:MM)
+	QuietHoursEnd        string     `json:"quietHoursEnd" gorm:"size:5;default:'08:00'"`      // Конец тихих часов (HH:MM)
+	Timezone             string     `json:"timezone" gorm:"size:64;default:'Asia/Dushanbe'"` // Часовой пояс пользователя (IANA)
+	CreatedAt            time.Time  `json:"createdAt"`
+	UpdatedAt            time.Time  `json:"updatedAt"`
+
+	// Связи
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	City *City `json:"city,omitempty" gorm:"foreignKey:SelectedCityID"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (s *UserSettings) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// DefaultTimezone часовой пояс по умолчанию для тихих часов
+const DefaultTimezone = "Asia/Dushanbe"
+
+// ParseClock разбирает время суток в формате HH:MM и возвращает минуты от полуночи
+func ParseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Location возвращает часовой пояс пользователя (или DefaultTimezone)
+func (s *UserSettings) Location() *time.Location {
+	name := s.Timezone
+	if name == "" {
+		name = DefaultTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// QuietHoursUntil возвращает момент окончания тихих часов, если now попадает в них.
+// Интервал может переходить через полночь (например, 22:00-08:00).
+func (s *UserSettings) QuietHoursUntil(now time.Time) (time.Time, bool) {
+	if !s.QuietHoursEnabled {
+		return time.Time{}, false
+	}
+	start, err := ParseClock(s.QuietHoursStart)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := ParseClock(s.QuietHoursEnd)
+	if err != nil || start == end {
+		return time.Time{}, false
+	}
+
+	local := now.In(s.Location())
+	current := local.Hour()*60 + local.Minute()
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+
+	if start < end {
+		if current >= start && current < end {
+			return midnight.Add(time.Duration(end) * time.Minute), true
+		}
+		return time.Time{}, false
+	}
+
+	// Интервал через полночь
+	if current >= start {
+		return midnight.AddDate(0, 0, 1).Add(time.Duration(end) * time.Minute), true
+	}
+	if current < end {
+		return midnight.Add(time.Duration(end) * time.Minute), true
+	}
+	return time.Time{}, false
+}
+
+// SettingsRequest представляет запрос на обновление настроек
+type SettingsRequest struct {
+	Language             string  `json:"language" binding:"oneof=ru en"`
+	Theme                string  `json:"theme" binding:"oneof=system light dark"`
+	NotificationsEnabled *bool   `json:"notificationsEnabled"`
+	EmailNotifications   *bool   `json:"emailNotifications"`
+	PushNotifications    *bool   `json:"pushNotifications"`
+	SelectedCityID       *string `json:"selectedCityId"` // ID выбранного города
+	QuietHoursEnabled    *bool   `json:"quietHoursEnabled"`
+	QuietHoursStart      *string `json:"quietHoursStart"` // HH:MM
+	QuietHoursEnd        *string `json:"quietHoursEnd"`   // HH:MM
+	Timezone             *string `json:"timezone"`        // IANA, например Asia/Dushanbe
+}
+
+// SettingsResponse представляет ответ с настройками
+type SettingsResponse struct {
+	Language             string        `json:"language"`
+	Theme                string        `json:"theme"`
+	NotificationsEnabled bool          `json:"notificationsEnabled"`
+	EmailNotifications   bool          `json:"emailNotifications"`
+	PushNotifications    bool          `json:"pushNotifications"`
+	SelectedCityID       *uuid.UUID    `json:"selectedCityId"`
+	City                 *CityResponse `json:"city,omitempty"` // Информация о выбранном городе
+	QuietHoursEnabled    bool          `json:"quietHoursEnabled"`
+	QuietHoursStart      string        `json:"quietHoursStart"`
+	QuietHoursEnd        string        `json:"quietHoursEnd"`
+	Timezone             string        `json:"timezone"`
+	UpdatedAt            time.Time     `json:"updatedAt"`
+}
+
+// ToResponse преобразует UserSettings в SettingsResponse
+func (s *UserSettings) ToResponse() SettingsResponse {
+	response := SettingsResponse{
+		Language:             s.Language,
+		Theme:                s.Theme,
+		NotificationsEnabled: s.NotificationsEnabled,
+		EmailNotifications:   s.EmailNotifications,
+		PushNotifications:    s.PushNotifications,
+		SelectedCityID:       s.SelectedCityID,
+		QuietHoursEnabled:    s.QuietHoursEnabled,
+		QuietHoursStart:      s.QuietHoursStart,
+		QuietHoursEnd:        s.QuietHoursEnd,
+		Timezone:             s.Timezone,
+		UpdatedAt:            s.UpdatedAt,
+	}
+	
+	// Если есть информация о городе, добавляем её
+	if s.City != nil {
+		cityResp := s.City.ToResponse()
+		response.City = &cityResp
+	}
+	
+	return response
+}
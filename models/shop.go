@@ -17,7 +17,8 @@ type Shop struct {
 	Email       string     `json:"email"`       // Email магазина
 	Phone       string     `json:"phone"`       // Телефон магазина
 	Address     string     `json:"address"`     // Адрес магазина
-	Rating      float64    `json:"rating" gorm:"default:0"` // Рейтинг магазина
+	Rating      float64    `json:"rating" gorm:"default:0"` // Рейтинг магазина (средняя оценка отзывов о товарах)
+	ReviewsCount int       `json:"reviewsCount" gorm:"not null;default:0"` // Количество опубликованных отзывов
 	IsActive    bool       `json:"isActive" gorm:"default:true"`
 	OwnerID     uuid.UUID  `json:"ownerId" gorm:"type:uuid;not null;index"` // ID владельца (User с ролью shop_owner)
 	CityID      *uuid.UUID `json:"cityId" gorm:"type:uuid;index"` // ID города
@@ -59,6 +60,7 @@ type ShopResponse struct {
 	Phone           string    `json:"phone"`
 	Address         string    `json:"address"`
 	Rating          float64   `json:"rating"`
+	ReviewsCount    int       `json:"reviewsCount"`
 	IsActive        bool      `json:"isActive"`
 	OwnerID         uuid.UUID `json:"ownerId"`
 	ProductsCount   int64     `json:"productsCount"`
@@ -79,6 +81,7 @@ func (s *Shop) ToResponse() ShopResponse {
 		Phone:     s.Phone,
 		Address:   s.Address,
 		Rating:    s.Rating,
+		ReviewsCount: s.ReviewsCount,
 		IsActive:  s.IsActive,
 		OwnerID:   s.OwnerID,
 		CreatedAt: s.CreatedAt,
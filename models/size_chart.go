@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SizeChartRow строка размерной сетки: размер и его мерки по колонкам
+type SizeChartRow struct {
+	Size   string   `json:"size"`
+	Values []string `json:"values"`
+}
+
+// SizeChart размерная сетка категории и/или бренда.
+// Для товара выбирается самая точная: бренд + категория, затем бренд, затем категория
+// (ближайшая из родительских).
+type SizeChart struct {
+	ID         uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;"`
+	Name       string         `json:"name" gorm:"not null"`
+	CategoryID *uuid.UUID     `json:"categoryId" gorm:"type:uuid;index"`
+	Brand      string         `json:"brand" gorm:"index"`
+	Columns    []string       `json:"columns" gorm:"serializer:json"` // Названия мерок: "Обхват груди, см"
+	Rows       []SizeChartRow `json:"rows" gorm:"serializer:json"`
+	Note       string         `json:"note"` // Как снимать мерки
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
+
+	// Связи
+	Category *Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием
+func (s *SizeChart) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// SizeChartRequest запрос на создание/обновление размерной сетки
+type SizeChartRequest struct {
+	Name       string         `json:"name" binding:"required"`
+	CategoryID *uuid.UUID     `json:"categoryId"`
+	Brand      string         `json:"brand"`
+	Columns    []string       `json:"columns" binding:"required,min=1"`
+	Rows       []SizeChartRow `json:"rows" binding:"required,min=1"`
+	Note       string         `json:"note"`
+}
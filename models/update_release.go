@@ -16,6 +16,24 @@ const (
 	UpdatePlatformAndroid UpdatePlatform = "android" // Flutter Android (.apk)
 )
 
+// UpdateChannel канал обновлений
+type UpdateChannel string
+
+const (
+	UpdateChannelStable UpdateChannel = "stable" // Все клиенты
+	UpdateChannelBeta   UpdateChannel = "beta"   // Тестировщики: получают beta и stable
+)
+
+// UpdateAction решение для клиента, сообщившего свою версию
+type UpdateAction string
+
+const (
+	UpdateActionNone     UpdateAction = "none"     // Обновление не требуется
+	UpdateActionUpdate   UpdateAction = "update"   // Доступно обновление
+	UpdateActionRequired UpdateAction = "required" // Версия клиента ниже минимальной поддерживаемой
+	UpdateActionRollback UpdateAction = "rollback" // Версия клиента отозвана, нужно вернуться на предыдущую
+)
+
 // UpdateRelease хранит информацию об опубликованных обновлениях
 type UpdateRelease struct {
 	ID             uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;"`
@@ -27,9 +45,89 @@ type UpdateRelease struct {
 	FileSize       int64          `json:"fileSize" gorm:"not null"`
 	ChecksumSHA256 string         `json:"checksumSha256" gorm:"type:varchar(128);not null"`
 	ReleaseNotes   string         `json:"releaseNotes" gorm:"type:text"`
-	IsActive       bool           `json:"isActive" gorm:"default:true"`
+	IsActive       bool           `json:"isActive" gorm:"default:true"` // false - релиз отозван (откат на предыдущий)
 	CreatedAt      time.Time      `json:"createdAt"`
 	UpdatedAt      time.Time      `json:"updatedAt"`
+
+	// Поэтапный выпуск
+	Channel             UpdateChannel `json:"channel" gorm:"type:varchar(20);not null;default:stable;index"`
+	RolloutPercentage   int           `json:"rolloutPercentage" gorm:"not null;default:100"` // Доля устройств (по deviceId), получающих релиз
+	MinSupportedVersion string        `json:"minSupportedVersion" gorm:"type:varchar(50)"`   // Клиенты ниже этой версии обязаны обновиться
+
+	// DownloadURL временная ссылка на скачивание из хранилища (не хранится в БД)
+	DownloadURL string `json:"downloadUrl,omitempty" gorm:"-"`
+}
+
+// UpdatePresignRequest запрос временной ссылки для прямой загрузки обновления в хранилище
+type UpdatePresignRequest struct {
+	Platform UpdatePlatform `json:"platform" binding:"required"`
+	Version  string         `json:"version" binding:"required"`
+	FileName string         `json:"fileName" binding:"required"`
+}
+
+// UpdateCompleteRequest регистрация обновления, загруженного по временной ссылке
+type UpdateCompleteRequest struct {
+	Key                 string         `json:"key" binding:"required"`
+	Platform            UpdatePlatform `json:"platform" binding:"required"`
+	Version             string         `json:"version" binding:"required"`
+	ReleaseNotes        string         `json:"releaseNotes"`
+	Channel             UpdateChannel  `json:"channel"`
+	RolloutPercentage   *int           `json:"rolloutPercentage"`
+	MinSupportedVersion string         `json:"minSupportedVersion"`
+}
+
+// UpdateReleaseRequest изменение параметров выпуска (админ). Пустые поля не меняются.
+type UpdateReleaseRequest struct {
+	Channel             *UpdateChannel `json:"channel"`
+	RolloutPercentage   *int           `json:"rolloutPercentage"`
+	MinSupportedVersion *string        `json:"minSupportedVersion"`
+	ReleaseNotes        *string        `json:"releaseNotes"`
+	IsActive            *bool          `json:"isActive"`
+}
+
+// UpdateCheckRequest запрос клиента на проверку обновлений
+type UpdateCheckRequest struct {
+	Platform       UpdatePlatform `form:"platform" json:"platform" binding:"required"`
+	Channel        UpdateChannel  `form:"channel" json:"channel"`
+	CurrentVersion string         `form:"version" json:"version"`
+	DeviceID       string         `form:"deviceId" json:"deviceId"`
+}
+
+// UpdateDecision ответ на проверку обновлений
+type UpdateDecision struct {
+	Action              UpdateAction   `json:"action"`
+	UpdateAvailable     bool           `json:"updateAvailable"`
+	Mandatory           bool           `json:"mandatory"` // Клиент не должен продолжать работу без обновления
+	Outdated            bool           `json:"outdated"`  // Есть более новая версия в канале (даже если устройству она еще не выдана)
+	Platform            UpdatePlatform `json:"platform"`
+	Channel             UpdateChannel  `json:"channel"`
+	CurrentVersion      string         `json:"currentVersion,omitempty"`
+	LatestVersion       string         `json:"latestVersion,omitempty"`
+	MinSupportedVersion string         `json:"minSupportedVersion,omitempty"`
+	Release             *UpdateRelease `json:"release,omitempty"` // Версия, которую нужно установить
+	Patch               *ReleasePatch  `json:"patch,omitempty"`   // Патч от текущей версии к release, если построен
+}
+
+// UpdateCheckIn последняя проверка обновлений устройством: версия и канал
+// установленного приложения (статистика выпуска)
+type UpdateCheckIn struct {
+	ID             uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;"`
+	Platform       UpdatePlatform `json:"platform" gorm:"type:varchar(20);not null;uniqueIndex:idx_update_check_in_device,priority:1"`
+	DeviceID       string         `json:"deviceId" gorm:"type:varchar(255);not null;uniqueIndex:idx_update_check_in_device,priority:2"`
+	Channel        UpdateChannel  `json:"channel" gorm:"type:varchar(20);not null;default:stable"`
+	Version        string         `json:"version" gorm:"type:varchar(50);index"`
+	LastAction     UpdateAction   `json:"lastAction" gorm:"type:varchar(20)"`
+	OfferedVersion string         `json:"offeredVersion" gorm:"type:varchar(50)"` // Какую версию предложили при последней проверке
+	LastCheckedAt  time.Time      `json:"lastCheckedAt" gorm:"index"`
+	CreatedAt      time.Time      `json:"createdAt"`
+}
+
+// BeforeCreate устанавливает UUID перед созданием записи
+func (u *UpdateCheckIn) BeforeCreate(tx *gorm.DB) error {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return nil
 }
 
 // BeforeCreate устанавливает UUID перед созданием записи
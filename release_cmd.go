@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/mm-api/mm-api/config"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/services"
+	"github.com/mm-api/mm-api/storage"
+)
+
+// runReleaseImport принимает обновления и установщики POS по манифесту:
+//
+//	./mm_shop import-releases -manifest /path/releases.json [-dry-run]
+//
+// Файлы проходят те же проверки, что и загруженные во входящие (версия выше
+// предыдущих, контрольная сумма); отклоненные файлы остаются на месте.
+func runReleaseImport(args []string) {
+	flags := flag.NewFlagSet("import-releases", flag.ExitOnError)
+	manifest := flags.String("manifest", "", "path to the releases manifest (JSON)")
+	dryRun := flags.Bool("dry-run", false, "only validate files, do not import")
+	flags.Parse(args)
+
+	if *manifest == "" {
+		log.Fatal("❌ Укажите манифест: import-releases -manifest releases.json")
+	}
+
+	cfg := config.Load()
+	store, err := newStorage(cfg, cfg.StorageBackend)
+	if err != nil {
+		log.Fatalf("❌ Хранилище: %v", err)
+	}
+	storage.SetDefault(store)
+	if err := database.Connect(); err != nil {
+		log.Fatalf("❌ База данных: %v", err)
+	}
+
+	records, err := services.ImportReleaseManifest(context.Background(), database.DB, *manifest, *dryRun)
+	counts := map[models.ReleaseIngestStatus]int{}
+	for _, record := range records {
+		counts[record.Status]++
+		switch record.Status {
+		case models.ReleaseIngestStatusRejected:
+			log.Printf("❌ %s: %s", record.FileName, record.Error)
+		case models.ReleaseIngestStatusDuplicate:
+			log.Printf("ℹ️ %s: версия %s уже принята", record.FileName, record.Version)
+		default:
+			log.Printf("✅ %s: %s %s/%s %s", record.FileName, record.Kind, record.Platform, record.Type, record.Version)
+		}
+	}
+	log.Printf("📊 Принято: %d, повторы: %d, отклонено: %d, dry-run %v",
+		counts[models.ReleaseIngestStatusImported], counts[models.ReleaseIngestStatusDuplicate],
+		counts[models.ReleaseIngestStatusRejected], *dryRun)
+	if err != nil {
+		log.Fatalf("❌ Импорт прерван: %v", err)
+	}
+	if counts[models.ReleaseIngestStatusRejected] > 0 {
+		log.Fatalf("⚠️ Часть файлов отклонена")
+	}
+	log.Println("✅ Импорт завершен")
+}
@@ -25,6 +25,7 @@ func SetupRoutes() *gin.Engine {
 	// Инициализация контроллеров
 	authController := &controllers.AuthController{}
 	productController := &controllers.ProductController{}
+	catalogController := &controllers.CatalogController{}
 	cartController := &controllers.CartController{}
 	categoryController := &controllers.CategoryController{}
 	favoriteController := &controllers.FavoriteController{}
@@ -46,8 +47,20 @@ func SetupRoutes() *gin.Engine {
 	paymentController := &controllers.PaymentController{}
 	updateController := &controllers.UpdateController{}
 	libissPosController := &controllers.LibissPosController{}
+	releasePatchController := &controllers.ReleasePatchController{}
+	releaseIngestController := &controllers.ReleaseIngestController{}
+	releaseRetentionController := &controllers.ReleaseRetentionController{}
 	shopCustomerController := &controllers.ShopCustomerController{}
 	posController := &controllers.PosController{}
+	campaignController := &controllers.CampaignController{}
+	realtimeController := &controllers.RealtimeController{}
+	recommendationController := &controllers.RecommendationController{}
+	reviewController := &controllers.ReviewController{}
+	questionController := &controllers.QuestionController{}
+	attributeController := &controllers.AttributeController{}
+	sizeChartController := &controllers.SizeChartController{}
+	storageController := &controllers.StorageController{}
+	mediaController := &controllers.MediaController{}
 
 	// API группа
 	api := r.Group("/api/v1")
@@ -68,6 +81,8 @@ func SetupRoutes() *gin.Engine {
 			auth.POST("/login", authController.Login)
 			auth.POST("/refresh", authController.RefreshToken)
 			auth.POST("/guest-token", authController.CreateGuestToken) // Новый эндпоинт для гостевого токена
+			auth.POST("/phone/send-code", authController.SendPhoneCode)   // Код подтверждения телефона по SMS
+			auth.POST("/phone/login", authController.LoginWithPhoneCode)  // Вход в аккаунт по коду из SMS
 			auth.POST("/forgot-password", authController.ForgotPassword)
 			// auth.POST("/logout", authController.Logout) // TODO: Реализовать
 		}
@@ -78,6 +93,22 @@ func SetupRoutes() *gin.Engine {
 			// Публичный endpoint для deep links (БЕЗ аутентификации, БЕЗ фильтрации по владельцу)
 			// Должен быть ПЕРЕД /:id, чтобы не перехватывался общим маршрутом
 			products.GET("/:id/public", productController.GetProductPublic)
+			products.GET("/featured", recommendationController.GetFeatured)                 // Рекомендуемые: закрепленные и популярные
+			products.GET("/:id/similar", recommendationController.GetSimilar)               // Похожие товары
+			products.GET("/:id/bought-together", recommendationController.GetBoughtTogether) // Часто покупают вместе
+			products.GET("/:id/reviews", reviewController.GetProductReviews)                // Опубликованные отзывы
+			products.GET("/:id/rating", reviewController.GetProductRatingSummary)           // Средняя оценка и распределение
+			products.GET("/:id/questions", questionController.GetProductQuestions)          // Отвеченные вопросы покупателей
+			products.GET("/:id/size-chart", sizeChartController.GetProductSizeChart)       // Размерная сетка товара
+		}
+
+		// Каталог с фильтрами по вариациям и фасетами (публичный)
+		catalog := public.Group("catalog")
+		{
+			catalog.GET("/products", catalogController.GetProducts)
+			catalog.GET("/facets", catalogController.GetFacets)
+			catalog.GET("/search", productController.SearchProducts)   // Полнотекстовый поиск по каталогу
+			catalog.GET("/suggest", productController.SuggestProducts) // Автодополнение строки поиска
 		}
 
 		// Продукты (требуют аутентификации для изоляции данных)
@@ -86,7 +117,6 @@ func SetupRoutes() *gin.Engine {
 		{
 			productsAuth.GET("/", productController.GetProducts)
 			productsAuth.GET("/:id", productController.GetProduct)
-			productsAuth.GET("/featured", productController.GetProducts)                      // TODO: Добавить логику для рекомендуемых
 			productsAuth.GET("/search", productController.GetProducts)                        // Используем тот же метод с параметром search
 			productsAuth.GET("/with-variations", productController.GetProductsWithVariations) // Новый endpoint с JOIN запросом
 		}
@@ -95,21 +125,40 @@ func SetupRoutes() *gin.Engine {
 		updates := public.Group("updates")
 		{
 			updates.GET("/latest", updateController.GetLatestUpdate)
+			updates.GET("/check", updateController.CheckUpdate)          // Решение об обновлении по версии клиента
+			updates.GET("/patch", releasePatchController.GetUpdatePatch) // Патч от версии клиента или полный файл
+			updates.GET("/manifest", updateController.GetManifest)       // Подписанный список файлов и SHA256
+			updates.GET("/signing-key", updateController.GetSigningKey)  // Публичный ключ проверки манифестов
 		}
 
 		// Файлы libiss_pos (публичный доступ для скачивания)
 		libissPosPublic := public.Group("libiss-pos")
 		{
 			libissPosPublic.GET("/latest", libissPosController.GetLatestFile)        // Последний файл по типу
-			libissPosPublic.GET("/public/:filename", libissPosController.PublicDownload) // Публичное скачивание
+			libissPosPublic.GET("/public/:filename", libissPosController.PublicDownload)  // Публичное скачивание
+			libissPosPublic.HEAD("/public/:filename", libissPosController.PublicDownload) // Размер и ETag перед докачкой
+			libissPosPublic.GET("/patch", releasePatchController.GetLibissPosPatch)       // Патч от версии клиента или полный файл
+			libissPosPublic.GET("/manifest", libissPosController.GetManifest)             // Подписанный список файлов и SHA256
+			libissPosPublic.GET("/signing-key", updateController.GetSigningKey)           // Публичный ключ проверки манифестов
+		}
+
+		// Временные ссылки локального хранилища (подпись проверяется в контроллере)
+		storageObjects := public.Group("storage/objects")
+		{
+			storageObjects.GET("/*key", storageController.GetSignedObject)
+			storageObjects.HEAD("/*key", storageController.GetSignedObject)
+			storageObjects.PUT("/*key", storageController.PutSignedObject)
 		}
 
 		// Категории (публичный доступ)
 		categories := public.Group("categories")
 		{
 			categories.GET("/", categoryController.GetCategories)
+			categories.GET("/tree", categoryController.GetCategories)
 			categories.GET("/:id", categoryController.GetCategory)
+			categories.GET("/:id/breadcrumbs", categoryController.GetCategoryBreadcrumbs)
 			categories.GET("/:id/products", categoryController.GetCategoryProducts)
+			categories.GET("/:id/attributes", attributeController.GetCategoryAttributes) // Характеристики с унаследованными
 		}
 
 		// Публичные endpoints для deep links магазинов (требуют аутентификации)
@@ -179,6 +228,13 @@ func SetupRoutes() *gin.Engine {
 		}
 	}
 
+	// Поток событий реального времени (SSE). Для EventSource - токен потока в ?stream_token=
+	events := api.Group("/events")
+	{
+		events.GET("/stream", middleware.StreamAuthRequired(), realtimeController.Stream)
+		events.POST("/token", middleware.AuthRequired(), realtimeController.IssueStreamToken) // Токен потока (1 минута)
+	}
+
 	// Защищенные маршруты (требуют аутентификации)
 	protected := api.Group("/")
 	protected.Use(middleware.AuthRequired())
@@ -190,6 +246,9 @@ func SetupRoutes() *gin.Engine {
 			users.PUT("/profile", authController.UpdateProfile)
 			users.DELETE("/delete-account", authController.DeleteAccount) // Удаление собственного аккаунта
 			users.POST("/avatar", authController.UploadAvatar) // Загрузка аватара пользователя
+			users.POST("/merge-guest", authController.MergeGuestAccount) // Перенос гостевых данных после подтверждения телефона
+			users.POST("/upgrade", authController.UpgradeGuestAccount)   // Превращение гостя в зарегистрированного пользователя
+			users.POST("/merge-account", authController.MergeAccount)    // Присоединение второго аккаунта того же человека
 
 			// Адреса пользователя
 			addresses := users.Group("addresses")
@@ -222,6 +281,28 @@ func SetupRoutes() *gin.Engine {
 			favorites.GET("/:productId/check", favoriteController.CheckFavorite)
 		}
 
+		// Персональные рекомендации
+		protected.GET("/recommendations/for-you", recommendationController.GetForYou)
+
+		// Отзывы о товарах (только покупатели с доставленным заказом)
+		reviews := protected.Group("reviews")
+		{
+			reviews.GET("/my", reviewController.GetMyReviews)
+			reviews.GET("/can-review/:productId", reviewController.CanReview)
+			reviews.POST("/", reviewController.CreateReview)
+			reviews.PUT("/:id", reviewController.UpdateReview)
+			reviews.DELETE("/:id", reviewController.DeleteReview)
+		}
+
+		// Вопросы о товарах
+		questions := protected.Group("questions")
+		{
+			questions.GET("/my", questionController.GetMyQuestions)
+			questions.POST("/", questionController.AskQuestion)
+			questions.DELETE("/:id", questionController.DeleteQuestion)
+			questions.POST("/:id/report", questionController.ReportQuestion) // Жалоба на вопрос или ответ
+		}
+
 		// Заказы пользователя
 		orders := protected.Group("orders")
 		{
@@ -257,12 +338,21 @@ func SetupRoutes() *gin.Engine {
 			deviceTokens.GET("/", deviceTokenController.GetUserDeviceTokens)
 		}
 
+		// Статистика маркетинговых рассылок (открытия и переходы)
+		campaigns := protected.Group("campaigns")
+		{
+			campaigns.POST("/recipients/:id/open", campaignController.TrackCampaignOpen)
+			campaigns.POST("/recipients/:id/click", campaignController.TrackCampaignClick)
+		}
+
 		// Настройки
 		settings := protected.Group("settings")
 		{
 			settings.GET("/", settingsController.GetSettings)
 			settings.PUT("/", settingsController.UpdateSettings)
 			settings.POST("/reset", settingsController.ResetSettings)
+			settings.GET("/notifications", settingsController.GetNotificationPreferences)    // Предпочтения уведомлений по типам и каналам
+			settings.PUT("/notifications", settingsController.UpdateNotificationPreferences) // Обновление предпочтений уведомлений
 		}
 
 		// Подписки на магазины
@@ -293,8 +383,12 @@ func SetupRoutes() *gin.Engine {
 		// Скачивание файлов libiss_pos (требует аутентификации)
 		libissPos := protected.Group("libiss-pos")
 		{
-			libissPos.GET("/download/:filename", libissPosController.DownloadFile) // Скачивание файла
+			libissPos.GET("/download/:filename", libissPosController.DownloadFile)  // Скачивание файла
+			libissPos.HEAD("/download/:filename", libissPosController.DownloadFile) // Размер и ETag перед докачкой
 		}
+
+		// Прямая загрузка изображений в хранилище по временной ссылке
+		protected.POST("/upload/presign", storageController.PresignImageUpload)
 	}
 
 	// Админские маршруты (для админов и супер админов)
@@ -326,6 +420,8 @@ func SetupRoutes() *gin.Engine {
 		adminNotifications := admin.Group("notifications")
 		{
 			adminNotifications.POST("/", notificationController.CreateNotification)
+			adminNotifications.GET("/outbox", notificationController.GetOutbox)                  // Очередь push-уведомлений (по умолчанию неудачные)
+			adminNotifications.POST("/outbox/:id/retry", notificationController.RetryOutboxEntry) // Повторная отправка
 		}
 
 		// Управление категориями (админы и супер админы)
@@ -333,7 +429,13 @@ func SetupRoutes() *gin.Engine {
 		{
 			// Создание категорий доступно только супер админам
 			adminCategories.POST("/", middleware.SuperAdminRequired(), categoryController.CreateCategory)
+			adminCategories.GET("/tree", categoryController.GetCategoryTreeAdmin)
+			adminCategories.PUT("/reorder", categoryController.ReorderCategories)
 			adminCategories.PUT("/:id", categoryController.UpdateCategory)
+			adminCategories.PUT("/:id/move", categoryController.MoveCategory)
+			adminCategories.POST("/:id/attributes", attributeController.CreateCategoryAttribute)
+			adminCategories.PUT("/:id/attributes/:attributeId", attributeController.UpdateCategoryAttribute)
+			adminCategories.DELETE("/:id/attributes/:attributeId", attributeController.DeleteCategoryAttribute)
 			adminCategories.DELETE("/:id", categoryController.DeleteCategory)
 		}
 
@@ -359,9 +461,48 @@ func SetupRoutes() *gin.Engine {
 		{
 			adminUpdates.GET("/", updateController.ListUpdates)
 			adminUpdates.POST("/upload", updateController.UploadUpdate)
+			adminUpdates.POST("/presign", updateController.PresignUpload)   // Ссылка для загрузки напрямую в хранилище
+			adminUpdates.POST("/complete", updateController.CompleteUpload) // Регистрация загруженного по ссылке файла
+			adminUpdates.GET("/installs", updateController.GetInstallStats) // Версии устройств по проверкам обновлений
+			adminUpdates.PUT("/:id", updateController.UpdateRelease)        // Канал, доля выпуска, мин. версия, отзыв
 			adminUpdates.DELETE("/:id", updateController.DeleteUpdate)
 		}
 
+		// Временные ссылки на файлы хранилища
+		admin.POST("/storage/presign", storageController.PresignObject)
+
+		// Реестр загруженных файлов и сборка мусора
+		adminMedia := admin.Group("media")
+		{
+			adminMedia.GET("/", mediaController.GetMediaAssets)
+			adminMedia.GET("/orphans", mediaController.GetOrphanReport) // Отчет dry-run: что будет удалено
+			adminMedia.POST("/sweep", mediaController.SweepMedia)       // Запустить сборку мусора сейчас
+			adminMedia.GET("/:id", mediaController.GetMediaAsset)
+		}
+
+		// Прием обновлений и установщиков POS из папки входящих
+		adminReleaseIngest := admin.Group("release-ingest")
+		{
+			adminReleaseIngest.GET("/", releaseIngestController.ListIngestions)
+			adminReleaseIngest.GET("/status", releaseIngestController.GetStatus)
+			adminReleaseIngest.POST("/scan", releaseIngestController.Scan)
+		}
+
+		// Политика хранения релизов (удаление старых версий)
+		adminReleaseRetention := admin.Group("release-retention")
+		{
+			adminReleaseRetention.GET("/preview", releaseRetentionController.Preview)         // Что будет удалено (без удаления)
+			adminReleaseRetention.POST("/run", releaseRetentionController.Run)                // Применить политику сейчас
+			adminReleaseRetention.GET("/deletions", releaseRetentionController.ListDeletions) // Журнал удалений
+		}
+
+		// Патчи между версиями обновлений и установщиков POS
+		adminReleasePatches := admin.Group("release-patches")
+		{
+			adminReleasePatches.GET("/", releasePatchController.ListPatches)
+			adminReleasePatches.POST("/:id/retry", releasePatchController.RetryPatch)
+		}
+
 		// Управление файлами libiss_pos (админы и супер админы)
 		adminLibissPos := admin.Group("libiss-pos")
 		{
@@ -390,6 +531,41 @@ func SetupRoutes() *gin.Engine {
 			adminLicenses.POST("/shops/:shopId/generate", licenseController.GenerateLicenseForShop) // Генерация лицензии для магазина
 		}
 
+		// Модерация отзывов
+		adminReviews := admin.Group("reviews")
+		{
+			adminReviews.GET("/", reviewController.GetReviewsForModeration)
+			adminReviews.PUT("/:id/moderate", reviewController.ModerateReview)
+			adminReviews.DELETE("/:id", reviewController.DeleteReview)
+		}
+
+		// Модерация вопросов и жалоб
+		adminQuestions := admin.Group("questions")
+		{
+			adminQuestions.GET("/", questionController.GetQuestionsForModeration)
+			adminQuestions.GET("/:id/reports", questionController.GetQuestionReports)
+			adminQuestions.PUT("/:id/moderate", questionController.ModerateQuestion)
+			adminQuestions.DELETE("/:id", questionController.DeleteQuestion)
+		}
+
+		// Размерные сетки категорий и брендов
+		adminSizeCharts := admin.Group("size-charts")
+		{
+			adminSizeCharts.GET("/", sizeChartController.GetSizeCharts)
+			adminSizeCharts.POST("/", sizeChartController.CreateSizeChart)
+			adminSizeCharts.PUT("/:id", sizeChartController.UpdateSizeChart)
+			adminSizeCharts.DELETE("/:id", sizeChartController.DeleteSizeChart)
+		}
+
+		// Рекомендации: закрепление товаров и ручной пересчет
+		adminRecommendations := admin.Group("recommendations")
+		{
+			adminRecommendations.GET("/pins", recommendationController.GetPins)
+			adminRecommendations.POST("/pins", recommendationController.CreatePin)
+			adminRecommendations.DELETE("/pins/:id", recommendationController.DeletePin)
+			adminRecommendations.POST("/recompute", recommendationController.Recompute)
+		}
+
 		// Диагностика БД для админов
 		admin.GET("/debug/db", debugController.DBInfo)
 	}
@@ -432,6 +608,34 @@ func SetupRoutes() *gin.Engine {
 			shopCustomers.GET("/:id/orders", orderController.GetCustomerOrders) // Заказы клиента
 		}
 
+		// Отзывы о товарах магазина и ответы на них
+		shopReviews := shop.Group("reviews")
+		{
+			shopReviews.GET("/", reviewController.GetShopReviews)
+			shopReviews.PUT("/:id/reply", reviewController.ReplyToReview)
+			shopReviews.DELETE("/:id/reply", reviewController.DeleteReviewReply)
+		}
+
+		// Вопросы покупателей о товарах магазина
+		shopQuestions := shop.Group("questions")
+		{
+			shopQuestions.GET("/", questionController.GetShopQuestions)
+			shopQuestions.PUT("/:id/answer", questionController.AnswerQuestion)
+		}
+
+		// Маркетинговые рассылки подписчикам и клиентам магазина
+		shopCampaigns := shop.Group("campaigns")
+		{
+			shopCampaigns.GET("/", campaignController.GetCampaigns)
+			shopCampaigns.POST("/", campaignController.CreateCampaign)
+			shopCampaigns.GET("/quota", campaignController.GetCampaignQuota)
+			shopCampaigns.GET("/:id", campaignController.GetCampaign)
+			shopCampaigns.PUT("/:id", campaignController.UpdateCampaign)
+			shopCampaigns.GET("/:id/audience", campaignController.PreviewCampaignAudience)
+			shopCampaigns.POST("/:id/schedule", campaignController.ScheduleCampaign)
+			shopCampaigns.POST("/:id/cancel", campaignController.CancelCampaign)
+		}
+
 		// Управление магазином
 		shopManagement := shop.Group("")
 		{
@@ -449,6 +653,10 @@ func SetupRoutes() *gin.Engine {
 			pos.GET("/products/stock", posController.GetStockInfo)
 			// Обновление количества конкретной вариации
 			pos.PUT("/products/:variationId/stock", posController.UpdateStock)
+			// Импорт фото из ZIP архива (файлы по SKU/штрих-коду) и опрос прогресса
+			pos.POST("/products/images/import", posController.ImportImages)
+			pos.GET("/products/images/import/:id", posController.GetImageImport)
+			pos.GET("/products/images/imports", posController.GetImageImports)
 		}
 	}
 
@@ -465,6 +673,10 @@ func SetupRoutes() *gin.Engine {
 		posAlt.GET("/products/stock", posController.GetStockInfo)
 		// Обновление количества конкретной вариации
 		posAlt.PUT("/products/:variationId/stock", posController.UpdateStock)
+		// Импорт фото из ZIP архива (файлы по SKU/штрих-коду) и опрос прогресса
+		posAlt.POST("/products/images/import", posController.ImportImages)
+		posAlt.GET("/products/images/import/:id", posController.GetImageImport)
+		posAlt.GET("/products/images/imports", posController.GetImageImports)
 	}
 
 	// Загрузка файлов
@@ -479,13 +691,14 @@ func SetupRoutes() *gin.Engine {
 	{
 		images.GET("/fix-urls", imageController.FixImageURLs)
 		images.GET("/url/:filename", imageController.GetImageURL)
+		images.GET("/resize/:width/:format/*key", imageController.ResizeImage) // Уменьшенные копии (подписанные параметры)
 	}
 
-	// Статические файлы для изображений
-	// Используем абсолютный путь для продакшена в Docker
-	r.Static("/images", "/app/images")
-	r.Static("/updates", "/app/updates")
-	r.Static("/libiss_pos", "/app/libiss_pos")
+	// Файлы из хранилища (локальная папка или S3, см. STORAGE_BACKEND)
+	for _, folder := range []string{"images", "updates", "libiss_pos"} {
+		r.GET("/"+folder+"/*filepath", storageController.ServeFiles(folder))
+		r.HEAD("/"+folder+"/*filepath", storageController.ServeFiles(folder))
+	}
 
 	// Обслуживание админ панели (если файлы присутствуют)
 	r.Static("/admin", "./admin")
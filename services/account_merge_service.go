@@ -0,0 +1,284 @@
+package services
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MergeGuestAccounts переносит данные гостевых аккаунтов в зарегистрированный
+// аккаунт и деактивирует гостей. Вызывается в транзакции после подтверждения телефона кодом.
+func MergeGuestAccounts(tx *gorm.DB, guestIDs []uuid.UUID, targetID uuid.UUID) (models.AccountMergeSummary, error) {
+	summary := models.AccountMergeSummary{
+		SourceUserIDs: guestIDs,
+		TargetUserID:  targetID,
+	}
+
+	for _, guestID := range guestIDs {
+		if guestID == targetID {
+			continue
+		}
+		var guest models.User
+		if err := tx.First(&guest, "id = ? AND is_guest = ?", guestID, true).Error; err != nil {
+			return summary, fmt.Errorf("гостевой аккаунт %s не найден: %w", guestID, err)
+		}
+		if err := mergeAccount(tx, guest.ID, targetID, models.CartMergeSum, &summary); err != nil {
+			return summary, err
+		}
+	}
+
+	log.Printf("🔀 Гостевые аккаунты %v объединены с %s: заказов %d, корзина %d (+%d), избранное %d",
+		guestIDs, targetID, summary.Orders, summary.CartItems, summary.CartItemsMerged, summary.Favorites)
+	return summary, nil
+}
+
+// MergeAccounts присоединяет второй аккаунт того же человека к целевому:
+// заказы, адреса, корзину, избранное, подписки, бонусные карты магазинов и устройства.
+// Исходный аккаунт деактивируется. Вызывается в транзакции.
+func MergeAccounts(tx *gorm.DB, sourceID, targetID uuid.UUID, cartStrategy models.CartMergeStrategy) (models.AccountMergeSummary, error) {
+	summary := models.AccountMergeSummary{
+		SourceUserIDs: []uuid.UUID{sourceID},
+		TargetUserID:  targetID,
+	}
+	if sourceID == targetID {
+		return summary, fmt.Errorf("нельзя объединить аккаунт с самим собой")
+	}
+	if cartStrategy == "" {
+		cartStrategy = models.CartMergeSum
+	}
+
+	if err := mergeAccount(tx, sourceID, targetID, cartStrategy, &summary); err != nil {
+		return summary, err
+	}
+
+	log.Printf("🔀 Аккаунт %s объединен с %s: заказов %d, адресов %d (+%d совпало), бонусных карт %d",
+		sourceID, targetID, summary.Orders, summary.Addresses, summary.AddressesMerged, summary.ShopClients)
+	return summary, nil
+}
+
+// mergeAccount переносит данные одного аккаунта и деактивирует его
+func mergeAccount(tx *gorm.DB, sourceID, targetID uuid.UUID, cartStrategy models.CartMergeStrategy, summary *models.AccountMergeSummary) error {
+	steps := []func(*gorm.DB, uuid.UUID, uuid.UUID, models.CartMergeStrategy, *models.AccountMergeSummary) error{
+		mergeAddresses, // До заказов: совпавшие адреса перенаправляют заказы
+		mergeOrders,
+		mergeCart,
+		mergeFavorites,
+		mergeSubscriptions,
+		mergeShopClients,
+		mergeDeviceTokens,
+	}
+	for _, step := range steps {
+		if err := step(tx, sourceID, targetID, cartStrategy, summary); err != nil {
+			return err
+		}
+	}
+
+	// Исходный аккаунт больше не может войти и не находится по телефону
+	return tx.Model(&models.User{}).Where("id = ?", sourceID).Update("is_active", false).Error
+}
+
+// mergeAddresses переносит адреса; совпадающие адреса не дублируются,
+// а заказы исходного адреса перенаправляются на адрес целевого аккаунта
+func mergeAddresses(tx *gorm.DB, sourceID, targetID uuid.UUID, _ models.CartMergeStrategy, summary *models.AccountMergeSummary) error {
+	var sourceAddresses, targetAddresses []models.Address
+	if err := tx.Where("user_id = ?", sourceID).Find(&sourceAddresses).Error; err != nil {
+		return fmt.Errorf("ошибка загрузки адресов: %w", err)
+	}
+	if err := tx.Where("user_id = ?", targetID).Find(&targetAddresses).Error; err != nil {
+		return fmt.Errorf("ошибка загрузки адресов: %w", err)
+	}
+
+	for _, address := range sourceAddresses {
+		var duplicate *models.Address
+		for i := range targetAddresses {
+			if sameAddress(&address, &targetAddresses[i]) {
+				duplicate = &targetAddresses[i]
+				break
+			}
+		}
+
+		if duplicate != nil {
+			if err := tx.Model(&models.Order{}).Where("address_id = ?", address.ID).Update("address_id", duplicate.ID).Error; err != nil {
+				return fmt.Errorf("ошибка перенаправления заказов на адрес: %w", err)
+			}
+			if err := tx.Delete(&models.Address{}, "id = ?", address.ID).Error; err != nil {
+				return fmt.Errorf("ошибка удаления дубликата адреса: %w", err)
+			}
+			summary.AddressesMerged++
+			continue
+		}
+
+		// Адрес по умолчанию остается у целевого аккаунта
+		if err := tx.Model(&models.Address{}).Where("id = ?", address.ID).
+			Updates(map[string]interface{}{"user_id": targetID, "is_default": false}).Error; err != nil {
+			return fmt.Errorf("ошибка переноса адреса: %w", err)
+		}
+		summary.Addresses++
+	}
+	return nil
+}
+
+// sameAddress сравнивает адреса без учета метки и признака по умолчанию
+func sameAddress(a, b *models.Address) bool {
+	return a.Street == b.Street && a.City == b.City && a.State == b.State &&
+		a.Country == b.Country && a.Building == b.Building && a.Apartment == b.Apartment
+}
+
+// mergeOrders переносит заказы целиком
+func mergeOrders(tx *gorm.DB, sourceID, targetID uuid.UUID, _ models.CartMergeStrategy, summary *models.AccountMergeSummary) error {
+	result := tx.Model(&models.Order{}).Where("user_id = ?", sourceID).Update("user_id", targetID)
+	if result.Error != nil {
+		return fmt.Errorf("ошибка переноса заказов: %w", result.Error)
+	}
+	summary.Orders += result.RowsAffected
+	return nil
+}
+
+// mergeCart переносит корзину; одинаковые вариации объединяются по стратегии
+func mergeCart(tx *gorm.DB, sourceID, targetID uuid.UUID, cartStrategy models.CartMergeStrategy, summary *models.AccountMergeSummary) error {
+	var sourceCart []models.CartItem
+	if err := tx.Where("user_id = ?", sourceID).Find(&sourceCart).Error; err != nil {
+		return fmt.Errorf("ошибка загрузки корзины: %w", err)
+	}
+
+	for _, item := range sourceCart {
+		var existing models.CartItem
+		err := tx.Where("user_id = ? AND variation_id = ?", targetID, item.VariationID).First(&existing).Error
+		switch {
+		case err == nil:
+			quantity := existing.Quantity
+			switch cartStrategy {
+			case models.CartMergeMax:
+				if item.Quantity > quantity {
+					quantity = item.Quantity
+				}
+			case models.CartMergeKeepTarget:
+			default:
+				quantity += item.Quantity
+			}
+			if quantity != existing.Quantity {
+				if err := tx.Model(&existing).Update("quantity", quantity).Error; err != nil {
+					return fmt.Errorf("ошибка объединения корзины: %w", err)
+				}
+			}
+			if err := tx.Delete(&models.CartItem{}, "id = ?", item.ID).Error; err != nil {
+				return fmt.Errorf("ошибка объединения корзины: %w", err)
+			}
+			summary.CartItemsMerged++
+		case err == gorm.ErrRecordNotFound:
+			if err := tx.Model(&models.CartItem{}).Where("id = ?", item.ID).Update("user_id", targetID).Error; err != nil {
+				return fmt.Errorf("ошибка переноса корзины: %w", err)
+			}
+			summary.CartItems++
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeFavorites переносит избранное без дубликатов
+func mergeFavorites(tx *gorm.DB, sourceID, targetID uuid.UUID, _ models.CartMergeStrategy, summary *models.AccountMergeSummary) error {
+	result := tx.Where("user_id = ? AND product_id IN (?)", sourceID,
+		tx.Model(&models.Favorite{}).Select("product_id").Where("user_id = ?", targetID)).
+		Delete(&models.Favorite{})
+	if result.Error != nil {
+		return fmt.Errorf("ошибка объединения избранного: %w", result.Error)
+	}
+	summary.FavoritesSkipped += result.RowsAffected
+
+	result = tx.Model(&models.Favorite{}).Where("user_id = ?", sourceID).Update("user_id", targetID)
+	if result.Error != nil {
+		return fmt.Errorf("ошибка переноса избранного: %w", result.Error)
+	}
+	summary.Favorites += result.RowsAffected
+	return nil
+}
+
+// mergeSubscriptions переносит подписки на магазины без дубликатов
+func mergeSubscriptions(tx *gorm.DB, sourceID, targetID uuid.UUID, _ models.CartMergeStrategy, summary *models.AccountMergeSummary) error {
+	result := tx.Where("user_id = ? AND shop_id IN (?)", sourceID,
+		tx.Model(&models.ShopSubscription{}).Select("shop_id").Where("user_id = ?", targetID)).
+		Delete(&models.ShopSubscription{})
+	if result.Error != nil {
+		return fmt.Errorf("ошибка объединения подписок: %w", result.Error)
+	}
+	summary.SubscriptionsSkipped += result.RowsAffected
+
+	result = tx.Model(&models.ShopSubscription{}).Where("user_id = ?", sourceID).Update("user_id", targetID)
+	if result.Error != nil {
+		return fmt.Errorf("ошибка переноса подписок: %w", result.Error)
+	}
+	summary.Subscriptions += result.RowsAffected
+	return nil
+}
+
+// mergeShopClients привязывает бонусные карты магазинов к целевому аккаунту.
+// Если у целевого аккаунта уже есть карта в том же магазине, бонусы и история
+// переносятся на нее (с записью в истории), а карта второго аккаунта удаляется.
+func mergeShopClients(tx *gorm.DB, sourceID, targetID uuid.UUID, _ models.CartMergeStrategy, summary *models.AccountMergeSummary) error {
+	var sourceClients []models.ShopClient
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", sourceID).Find(&sourceClients).Error; err != nil {
+		return fmt.Errorf("ошибка получения бонусных карт: %w", err)
+	}
+
+	for _, source := range sourceClients {
+		var target models.ShopClient
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("shop_id = ? AND user_id = ?", source.ShopID, targetID).
+			Order("created_at").First(&target).Error
+		if err == gorm.ErrRecordNotFound {
+			if err := tx.Model(&source).Update("user_id", targetID).Error; err != nil {
+				return fmt.Errorf("ошибка переноса бонусной карты: %w", err)
+			}
+			summary.ShopClients++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка поиска бонусной карты: %w", err)
+		}
+
+		if err := tx.Model(&models.BonusHistory{}).Where("shop_client_id = ?", source.ID).
+			Update("shop_client_id", target.ID).Error; err != nil {
+			return fmt.Errorf("ошибка переноса истории бонусов: %w", err)
+		}
+
+		updates := map[string]interface{}{"bonus_amount": target.BonusAmount + source.BonusAmount}
+		if source.FirstBonusDate != nil && (target.FirstBonusDate == nil || source.FirstBonusDate.Before(*target.FirstBonusDate)) {
+			updates["first_bonus_date"] = source.FirstBonusDate
+		}
+		if err := tx.Model(&target).Updates(updates).Error; err != nil {
+			return fmt.Errorf("ошибка объединения бонусов: %w", err)
+		}
+		if source.BonusAmount != 0 {
+			history := models.BonusHistory{
+				ShopClientID:   target.ID,
+				PreviousAmount: target.BonusAmount,
+				NewAmount:      target.BonusAmount + source.BonusAmount,
+				ChangeAmount:   source.BonusAmount,
+			}
+			if err := tx.Create(&history).Error; err != nil {
+				return fmt.Errorf("ошибка записи истории бонусов: %w", err)
+			}
+		}
+		if err := tx.Delete(&source).Error; err != nil {
+			return fmt.Errorf("ошибка удаления объединенной бонусной карты: %w", err)
+		}
+		summary.ShopClientsMerged++
+	}
+	return nil
+}
+
+// mergeDeviceTokens переносит устройства, чтобы push-уведомления приходили в новый аккаунт
+func mergeDeviceTokens(tx *gorm.DB, sourceID, targetID uuid.UUID, _ models.CartMergeStrategy, summary *models.AccountMergeSummary) error {
+	result := tx.Model(&models.DeviceToken{}).Where("user_id = ?", sourceID).Update("user_id", targetID)
+	if result.Error != nil {
+		return fmt.Errorf("ошибка переноса устройств: %w", result.Error)
+	}
+	summary.DeviceTokens += result.RowsAffected
+	return nil
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/models"
+	"gorm.io/gorm"
+)
+
+// attributeCodePattern код характеристики: латиница в нижнем регистре, цифры и "_"
+var attributeCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// AttributeValidationError ошибки проверки характеристик (по одной на поле)
+type AttributeValidationError struct {
+	Problems []string
+}
+
+func (e *AttributeValidationError) Error() string {
+	return "invalid attributes: " + strings.Join(e.Problems, "; ")
+}
+
+// ValidateAttributeDefinition проверяет описание характеристики и нормализует варианты значений
+func ValidateAttributeDefinition(req *models.CategoryAttributeRequest) error {
+	var problems []string
+	req.Code = strings.TrimSpace(req.Code)
+	if !attributeCodePattern.MatchString(req.Code) {
+		problems = append(problems, "code must contain only lowercase latin letters, digits and underscores")
+	}
+
+	options := make([]string, 0, len(req.Options))
+	seen := make(map[string]bool, len(req.Options))
+	for _, option := range req.Options {
+		option = strings.TrimSpace(option)
+		key := strings.ToLower(option)
+		if option == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		options = append(options, option)
+	}
+	req.Options = options
+
+	switch req.Type {
+	case models.AttributeTypeEnum, models.AttributeTypeMultiEnum:
+		if len(req.Options) == 0 {
+			problems = append(problems, "options are required for enum attributes")
+		}
+	default:
+		req.Options = nil
+	}
+	if req.Filterable && !req.Type.IsFilterable() {
+		problems = append(problems, "only enum, multi_enum and boolean attributes can be filterable")
+	}
+
+	if len(problems) > 0 {
+		return &AttributeValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// GetCategoryAttributeSchema возвращает характеристики категории с учетом родительских:
+// сначала унаследованные от корня, затем собственные. При совпадении кода
+// побеждает характеристика ближайшей категории.
+func GetCategoryAttributeSchema(db *gorm.DB, categoryID uuid.UUID) ([]models.CategoryAttribute, error) {
+	breadcrumbs, err := GetCategoryBreadcrumbs(db, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(breadcrumbs) == 0 {
+		return []models.CategoryAttribute{}, nil
+	}
+
+	depth := make(map[uuid.UUID]int, len(breadcrumbs))
+	ids := make([]uuid.UUID, len(breadcrumbs))
+	for i, crumb := range breadcrumbs {
+		depth[crumb.ID] = i
+		ids[i] = crumb.ID
+	}
+
+	var attributes []models.CategoryAttribute
+	if err := db.Where("category_id IN ?", ids).Find(&attributes).Error; err != nil {
+		return nil, fmt.Errorf("ошибка загрузки характеристик категории: %w", err)
+	}
+
+	byCode := make(map[string]models.CategoryAttribute, len(attributes))
+	for _, attribute := range attributes {
+		if current, ok := byCode[attribute.Code]; ok && depth[current.CategoryID] > depth[attribute.CategoryID] {
+			continue
+		}
+		byCode[attribute.Code] = attribute
+	}
+
+	schema := make([]models.CategoryAttribute, 0, len(byCode))
+	for _, attribute := range byCode {
+		schema = append(schema, attribute)
+	}
+	sort.Slice(schema, func(i, j int) bool {
+		if di, dj := depth[schema[i].CategoryID], depth[schema[j].CategoryID]; di != dj {
+			return di < dj
+		}
+		if schema[i].SortOrder != schema[j].SortOrder {
+			return schema[i].SortOrder < schema[j].SortOrder
+		}
+		return schema[i].Name < schema[j].Name
+	})
+	return schema, nil
+}
+
+// ValidateProductAttributes проверяет значения характеристик товара по схеме категории
+// и приводит их к типам схемы. Неизвестные коды и пустые обязательные поля - ошибка.
+func ValidateProductAttributes(schema []models.CategoryAttribute, input map[string]interface{}) (models.ProductAttributes, error) {
+	result := models.ProductAttributes{}
+	var problems []string
+
+	known := make(map[string]bool, len(schema))
+	for _, attribute := range schema {
+		known[attribute.Code] = true
+
+		raw, ok := input[attribute.Code]
+		if !ok || raw == nil || raw == "" {
+			if attribute.Required {
+				problems = append(problems, fmt.Sprintf("%s: required", attribute.Code))
+			}
+			continue
+		}
+
+		value, err := normalizeAttributeValue(attribute, raw)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", attribute.Code, err))
+			continue
+		}
+		if list, isList := value.([]string); isList && len(list) == 0 {
+			if attribute.Required {
+				problems = append(problems, fmt.Sprintf("%s: required", attribute.Code))
+			}
+			continue
+		}
+		result[attribute.Code] = value
+	}
+
+	unknown := make([]string, 0)
+	for code := range input {
+		if !known[code] {
+			unknown = append(unknown, code)
+		}
+	}
+	sort.Strings(unknown)
+	for _, code := range unknown {
+		problems = append(problems, fmt.Sprintf("%s: unknown attribute for this category", code))
+	}
+
+	if len(problems) > 0 {
+		return nil, &AttributeValidationError{Problems: problems}
+	}
+	return result, nil
+}
+
+// ValidateProductAttributesForCategory загружает схему категории и проверяет значения
+func ValidateProductAttributesForCategory(db *gorm.DB, categoryID uuid.UUID, input map[string]interface{}) (models.ProductAttributes, error) {
+	schema, err := GetCategoryAttributeSchema(db, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	return ValidateProductAttributes(schema, input)
+}
+
+// normalizeAttributeValue приводит значение из JSON к типу характеристики
+func normalizeAttributeValue(attribute models.CategoryAttribute, raw interface{}) (interface{}, error) {
+	switch attribute.Type {
+	case models.AttributeTypeString:
+		text, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a string")
+		}
+		text = strings.TrimSpace(text)
+		if len([]rune(text)) > models.MaxAttributeStringLength {
+			return nil, fmt.Errorf("must be at most %d characters", models.MaxAttributeStringLength)
+		}
+		return text, nil
+
+	case models.AttributeTypeNumber:
+		switch v := raw.(type) {
+		case float64:
+			return v, nil
+		case string:
+			number, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(v), ",", "."), 64)
+			if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+				return nil, fmt.Errorf("must be a number")
+			}
+			return number, nil
+		}
+		return nil, fmt.Errorf("must be a number")
+
+	case models.AttributeTypeBoolean:
+		switch v := raw.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if flag, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return flag, nil
+			}
+		}
+		return nil, fmt.Errorf("must be true or false")
+
+	case models.AttributeTypeEnum:
+		text, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be one of: %s", strings.Join(attribute.Options, ", "))
+		}
+		option, ok := matchAttributeOption(attribute.Options, text)
+		if !ok {
+			return nil, fmt.Errorf("must be one of: %s", strings.Join(attribute.Options, ", "))
+		}
+		return option, nil
+
+	case models.AttributeTypeMultiEnum:
+		var values []interface{}
+		switch v := raw.(type) {
+		case []interface{}:
+			values = v
+		case string:
+			values = []interface{}{v}
+		default:
+			return nil, fmt.Errorf("must be a list of: %s", strings.Join(attribute.Options, ", "))
+		}
+		selected := make([]string, 0, len(values))
+		seen := make(map[string]bool, len(values))
+		for _, item := range values {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("must be a list of: %s", strings.Join(attribute.Options, ", "))
+			}
+			option, ok := matchAttributeOption(attribute.Options, text)
+			if !ok {
+				return nil, fmt.Errorf("unknown value %q, allowed: %s", text, strings.Join(attribute.Options, ", "))
+			}
+			if !seen[option] {
+				seen[option] = true
+				selected = append(selected, option)
+			}
+		}
+		return selected, nil
+	}
+	return nil, fmt.Errorf("unsupported attribute type %q", attribute.Type)
+}
+
+// matchAttributeOption находит вариант без учета регистра и возвращает его каноническое написание
+func matchAttributeOption(options []string, value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, option := range options {
+		if strings.EqualFold(option, value) {
+			return option, true
+		}
+	}
+	return "", false
+}
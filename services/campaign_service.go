@@ -0,0 +1,368 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CampaignWorkerConfig параметры отправки маркетинговых рассылок
+type CampaignWorkerConfig struct {
+	Interval  time.Duration // Интервал между пачками
+	BatchSize int           // Сколько получателей одной рассылки обрабатывается за интервал
+}
+
+// CampaignWorker запускает запланированные рассылки и отправляет их пачками (throttling)
+type CampaignWorker struct {
+	config CampaignWorkerConfig
+}
+
+// ResolveCampaignAudience возвращает ID пользователей, попадающих в аудиторию рассылки
+func ResolveCampaignAudience(db *gorm.DB, campaign *models.Campaign) ([]uuid.UUID, error) {
+	var shop models.Shop
+	if err := db.First(&shop, "id = ?", campaign.ShopID).Error; err != nil {
+		return nil, fmt.Errorf("магазин рассылки не найден: %w", err)
+	}
+
+	var query *gorm.DB
+	switch campaign.Audience {
+	case models.CampaignAudienceClients:
+		query = db.Table("shop_clients AS a").Where("a.user_id IS NOT NULL")
+	default:
+		query = db.Table("shop_subscriptions AS a")
+	}
+	query = query.
+		Joins("JOIN users u ON u.id = a.user_id").
+		Where("a.shop_id = ? AND u.is_active = ?", campaign.ShopID, true)
+
+	if campaign.CityID != nil {
+		query = query.Where("a.user_id IN (SELECT user_id FROM user_settings WHERE selected_city_id = ?)", *campaign.CityID)
+	}
+
+	if campaign.MinBonusAmount != nil || campaign.MaxBonusAmount != nil {
+		bonus := db.Table("shop_clients").Select("user_id").Where("shop_id = ? AND user_id IS NOT NULL", campaign.ShopID)
+		if campaign.MinBonusAmount != nil {
+			bonus = bonus.Where("bonus_amount >= ?", *campaign.MinBonusAmount)
+		}
+		if campaign.MaxBonusAmount != nil {
+			bonus = bonus.Where("bonus_amount <= ?", *campaign.MaxBonusAmount)
+		}
+		query = query.Where("a.user_id IN (?)", bonus)
+	}
+
+	if campaign.PurchasedWithinDays != nil {
+		since := time.Now().AddDate(0, 0, -*campaign.PurchasedWithinDays)
+		query = query.Where("a.user_id IN (?)", shopBuyersSince(db, &shop, since))
+	}
+
+	if campaign.NotPurchasedForDays != nil {
+		since := time.Now().AddDate(0, 0, -*campaign.NotPurchasedForDays)
+		query = query.Where("a.user_id NOT IN (?)", shopBuyersSince(db, &shop, since))
+	}
+
+	var userIDs []uuid.UUID
+	if err := query.Distinct().Pluck("a.user_id", &userIDs).Error; err != nil {
+		return nil, fmt.Errorf("ошибка выборки аудитории: %w", err)
+	}
+	return userIDs, nil
+}
+
+// shopBuyersSince подзапрос покупателей магазина начиная с since (без отмененных заказов)
+func shopBuyersSince(db *gorm.DB, shop *models.Shop, since time.Time) *gorm.DB {
+	return db.Table("orders AS o").
+		Select("o.user_id").
+		Joins("JOIN order_items oi ON oi.order_id = o.id").
+		Where("(oi.shop_id = ? OR oi.shop_owner_id = ?) AND o.created_at >= ? AND o.status <> ?",
+			shop.ID, shop.OwnerID, since, models.OrderStatusCancelled)
+}
+
+// GetCampaignQuota возвращает месячную квоту сообщений магазина по его действующей лицензии
+func GetCampaignQuota(db *gorm.DB, shopID uuid.UUID) (models.CampaignQuotaInfo, error) {
+	now := time.Now()
+	quota := models.CampaignQuotaInfo{
+		PeriodStart: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()),
+	}
+
+	var licenses []models.License
+	if err := db.Where("shop_id = ? AND is_active = ?", shopID, true).Find(&licenses).Error; err != nil {
+		return quota, fmt.Errorf("ошибка получения лицензий магазина: %w", err)
+	}
+	for _, license := range licenses {
+		if !license.IsValid() {
+			continue
+		}
+		if limit := models.CampaignMonthlyQuota[license.SubscriptionType]; limit > quota.MonthlyLimit {
+			quota.MonthlyLimit = limit
+			quota.SubscriptionType = license.SubscriptionType
+		}
+	}
+
+	var used int64
+	if err := db.Model(&models.CampaignRecipient{}).
+		Joins("JOIN campaigns ON campaigns.id = campaign_recipients.campaign_id").
+		Where("campaigns.shop_id = ? AND campaign_recipients.created_at >= ? AND campaign_recipients.status <> ?",
+			shopID, quota.PeriodStart, models.CampaignRecipientSkipped).
+		Count(&used).Error; err != nil {
+		return quota, fmt.Errorf("ошибка подсчета использованной квоты: %w", err)
+	}
+
+	quota.Used = int(used)
+	quota.Remaining = quota.MonthlyLimit - quota.Used
+	if quota.Remaining < 0 {
+		quota.Remaining = 0
+	}
+	return quota, nil
+}
+
+// TrackCampaignEvent отмечает открытие или переход по рассылке получателем.
+// Повторные события не увеличивают статистику; переход засчитывается и как открытие.
+func TrackCampaignEvent(recipientID, userID uuid.UUID, click bool) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var recipient models.CampaignRecipient
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&recipient, "id = ? AND user_id = ?", recipientID, userID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		counters := map[string]interface{}{}
+		updates := map[string]interface{}{}
+		if recipient.OpenedAt == nil {
+			updates["opened_at"] = now
+			counters["open_count"] = gorm.Expr("open_count + 1")
+		}
+		if click && recipient.ClickedAt == nil {
+			updates["clicked_at"] = now
+			counters["click_count"] = gorm.Expr("click_count + 1")
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&recipient).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Campaign{}).Where("id = ?", recipient.CampaignID).Updates(counters).Error
+	})
+}
+
+// StartCampaignWorker запускает фоновую отправку рассылок
+func StartCampaignWorker(ctx context.Context, cfg CampaignWorkerConfig) *CampaignWorker {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 100
+	}
+
+	w := &CampaignWorker{config: cfg}
+	go w.run(ctx)
+
+	log.Printf("✅ Campaign worker запущен (пачка: %d, интервал: %s)", cfg.BatchSize, cfg.Interval)
+	return w
+}
+
+// run выполняет цикл запуска и отправки рассылок
+func (w *CampaignWorker) run(ctx context.Context) {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if database.DB != nil {
+			w.startDueCampaigns()
+			w.sendBatches()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startDueCampaigns переводит наступившие рассылки в отправку и фиксирует список получателей
+func (w *CampaignWorker) startDueCampaigns() {
+	var due []models.Campaign
+	if err := database.DB.Where("status = ? AND scheduled_at <= ?", models.CampaignStatusScheduled, time.Now()).
+		Find(&due).Error; err != nil {
+		log.Printf("⚠️ Campaign: ошибка получения запланированных рассылок: %v", err)
+		return
+	}
+
+	for i := range due {
+		if err := startCampaign(&due[i]); err != nil {
+			log.Printf("❌ Campaign: ошибка запуска рассылки %s: %v", due[i].ID, err)
+		}
+	}
+}
+
+// startCampaign в одной транзакции захватывает рассылку, применяет квоту и создает получателей
+func startCampaign(campaign *models.Campaign) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var locked models.Campaign
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			First(&locked, "id = ? AND status = ?", campaign.ID, models.CampaignStatusScheduled).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil // Уже обработана другим воркером или отменена
+			}
+			return err
+		}
+
+		// Рассылки одного магазина, запускаемые одновременно, проверяют остаток квоты по очереди
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "mm_campaign_quota:"+locked.ShopID.String()).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		quota, err := GetCampaignQuota(tx, locked.ShopID)
+		if err != nil {
+			return err
+		}
+		if quota.MonthlyLimit == 0 {
+			return tx.Model(&locked).Updates(map[string]interface{}{
+				"status":       models.CampaignStatusFailed,
+				"completed_at": now,
+				"last_error":   "у магазина нет действующей лицензии для рассылок",
+			}).Error
+		}
+
+		userIDs, err := ResolveCampaignAudience(tx, &locked)
+		if err != nil {
+			return err
+		}
+
+		lastError := ""
+		if len(userIDs) > quota.Remaining {
+			lastError = fmt.Sprintf("аудитория %d ограничена остатком месячной квоты %d", len(userIDs), quota.Remaining)
+			userIDs = userIDs[:quota.Remaining]
+		}
+
+		recipients := make([]models.CampaignRecipient, len(userIDs))
+		for i, userID := range userIDs {
+			recipients[i] = models.CampaignRecipient{
+				CampaignID: locked.ID,
+				UserID:     userID,
+				Status:     models.CampaignRecipientPending,
+			}
+		}
+		if len(recipients) > 0 {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&recipients, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		log.Printf("📣 Campaign: рассылка %s запущена, получателей: %d", locked.ID, len(recipients))
+		return tx.Model(&locked).Updates(map[string]interface{}{
+			"status":           models.CampaignStatusSending,
+			"started_at":       now,
+			"recipients_count": len(recipients),
+			"last_error":       lastError,
+		}).Error
+	})
+}
+
+// sendBatches отправляет очередную пачку получателям каждой активной рассылки
+func (w *CampaignWorker) sendBatches() {
+	var sending []models.Campaign
+	if err := database.DB.Where("status = ?", models.CampaignStatusSending).Find(&sending).Error; err != nil {
+		log.Printf("⚠️ Campaign: ошибка получения активных рассылок: %v", err)
+		return
+	}
+
+	for i := range sending {
+		if err := w.sendBatch(&sending[i]); err != nil {
+			log.Printf("❌ Campaign: ошибка отправки пачки рассылки %s: %v", sending[i].ID, err)
+		}
+	}
+}
+
+// sendBatch отправляет не более BatchSize уведомлений рассылки через общий диспетчер
+func (w *CampaignWorker) sendBatch(campaign *models.Campaign) error {
+	return WithRealtimeEvents(database.DB, func(tx *gorm.DB) error {
+		var recipients []models.CampaignRecipient
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("campaign_id = ? AND status = ?", campaign.ID, models.CampaignRecipientPending).
+			Limit(w.config.BatchSize).
+			Find(&recipients).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if len(recipients) == 0 {
+			var pending int64
+			if err := tx.Model(&models.CampaignRecipient{}).
+				Where("campaign_id = ? AND status = ?", campaign.ID, models.CampaignRecipientPending).
+				Count(&pending).Error; err != nil {
+				return err
+			}
+			if pending > 0 {
+				return nil // Пачку обрабатывает другой воркер
+			}
+			log.Printf("✅ Campaign: рассылка %s завершена", campaign.ID)
+			return tx.Model(campaign).Updates(map[string]interface{}{
+				"status":       models.CampaignStatusSent,
+				"completed_at": now,
+			}).Error
+		}
+
+		sent, skipped := 0, 0
+		for _, recipient := range recipients {
+			notification := campaignNotification(campaign, recipient)
+			result, err := DispatchNotification(tx, &notification)
+			if err != nil {
+				return err
+			}
+
+			status := models.CampaignRecipientSent
+			updates := map[string]interface{}{"status": status, "sent_at": now}
+			if !result.Stored && !result.Queued {
+				status = models.CampaignRecipientSkipped
+				updates = map[string]interface{}{"status": status}
+				skipped++
+			} else {
+				sent++
+			}
+			if err := tx.Model(&models.CampaignRecipient{}).Where("id = ?", recipient.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(campaign).Updates(map[string]interface{}{
+			"sent_count":    gorm.Expr("sent_count + ?", sent),
+			"skipped_count": gorm.Expr("skipped_count + ?", skipped),
+		}).Error
+	})
+}
+
+// campaignNotification собирает уведомление рассылки для получателя
+func campaignNotification(campaign *models.Campaign, recipient models.CampaignRecipient) models.Notification {
+	actionURL := campaign.DeepLink
+	if campaign.ProductID != nil {
+		actionURL = fmt.Sprintf("/products/%s", campaign.ProductID.String())
+	}
+
+	data, _ := json.Marshal(map[string]string{
+		"campaignId":          campaign.ID.String(),
+		"campaignRecipientId": recipient.ID.String(),
+		"shopId":              campaign.ShopID.String(),
+	})
+
+	return models.Notification{
+		UserID:    recipient.UserID,
+		Title:     campaign.Title,
+		Body:      campaign.Body,
+		Type:      models.NotificationTypePromotion,
+		Data:      string(data),
+		ImageURL:  campaign.ImageURL,
+		ActionURL: actionURL,
+	}
+}
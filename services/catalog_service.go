@@ -0,0 +1,326 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mm-api/mm-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// catalogFacetLimit максимальное количество значений в одном фасете
+const catalogFacetLimit = 50
+
+// CatalogInStockCondition условие "есть вариация в наличии" для запросов товаров
+const CatalogInStockCondition = "EXISTS (SELECT 1 FROM product_variations pv WHERE pv.product_id = products.id AND pv.is_available = true AND pv.stock_quantity > 0)"
+
+// catalogDiscountExpr скидка вариации в процентах: явная или из зачеркнутой цены
+const catalogDiscountExpr = "GREATEST(pv.discount, CASE WHEN pv.original_price > pv.price AND pv.original_price > 0 " +
+	"THEN ROUND((1 - pv.price / pv.original_price) * 100)::int ELSE 0 END)"
+
+// catalogJSONValues разворачивает JSON массив вариации (размеры, цвета).
+// Колонка хранится как текст (serializer:json), пустые строки пропускаются.
+func catalogJSONValues(column string) string {
+	return "jsonb_array_elements_text(NULLIF(pv." + column + "::text, '')::jsonb)"
+}
+
+// catalogAttributeValues разворачивает значение характеристики товара в строки:
+// массив multi_enum - поэлементно, скаляр - одной строкой. Параметры - код (трижды).
+const catalogAttributeValues = "jsonb_array_elements_text(CASE jsonb_typeof(products.attributes -> ?) " +
+	"WHEN 'array' THEN products.attributes -> ? ELSE jsonb_build_array(products.attributes -> ?) END)"
+
+// CatalogOrder возвращает ORDER BY для простых списков товаров.
+// Цена и скидка берутся из доступных вариаций, так как у товара их нет.
+func CatalogOrder(sortBy string, asc bool) (string, bool) {
+	direction := " DESC"
+	if asc {
+		direction = " ASC"
+	}
+	switch models.CatalogSort(sortBy) {
+	case models.CatalogSortName:
+		return "products.name" + direction, true
+	case models.CatalogSortNewest:
+		return "products.created_at" + direction, true
+	case models.CatalogSortPrice:
+		return "(SELECT MIN(pv.price) FROM product_variations pv WHERE pv.product_id = products.id AND pv.is_available = true)" +
+			direction + " NULLS LAST", true
+	case models.CatalogSortDiscount:
+		return "(SELECT MAX(" + catalogDiscountExpr + ") FROM product_variations pv WHERE pv.product_id = products.id AND pv.is_available = true)" +
+			direction + " NULLS LAST", true
+	case models.CatalogSortRating:
+		return "products.rating" + direction + ", products.reviews_count DESC", true
+	}
+	return "", false
+}
+
+// normalizeFacetValues приводит значения фильтра к нижнему регистру без пустых
+func normalizeFacetValues(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// catalogVariationCondition условие на одну вариацию (алиас pv) без фасета except
+func catalogVariationCondition(filter models.CatalogFilter, except string) (string, []interface{}) {
+	conditions := []string{"pv.is_available = true"}
+	var vars []interface{}
+
+	if except != models.CatalogFacetPrice {
+		if filter.MinPrice != nil {
+			conditions = append(conditions, "pv.price >= ?")
+			vars = append(vars, *filter.MinPrice)
+		}
+		if filter.MaxPrice != nil {
+			conditions = append(conditions, "pv.price <= ?")
+			vars = append(vars, *filter.MaxPrice)
+		}
+	}
+	if sizes := normalizeFacetValues(filter.Sizes); len(sizes) > 0 && except != models.CatalogFacetSize {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM "+catalogJSONValues("sizes")+" AS s(value) WHERE lower(trim(s.value)) IN ?)")
+		vars = append(vars, sizes)
+	}
+	if colors := normalizeFacetValues(filter.Colors); len(colors) > 0 && except != models.CatalogFacetColor {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM "+catalogJSONValues("colors")+" AS c(value) WHERE lower(trim(c.value)) IN ?)")
+		vars = append(vars, colors)
+	}
+	if filter.OnSale && except != models.CatalogFacetOnSale {
+		conditions = append(conditions, catalogDiscountExpr+" > 0")
+	}
+	if filter.InStock && except != models.CatalogFacetInStock {
+		conditions = append(conditions, "pv.stock_quantity > 0")
+	}
+
+	return strings.Join(conditions, " AND "), vars
+}
+
+// applyCatalogProductFilter применяет условия уровня товара без фасета except
+func applyCatalogProductFilter(query *gorm.DB, filter models.CatalogFilter, except string, ranked bool) *gorm.DB {
+	query = query.Where("products.is_available = ?", true)
+
+	if filter.CategoryID != nil && except != models.CatalogFacetCategory {
+		query = query.Where(CategorySubtreeCondition("products.category_id"), *filter.CategoryID)
+	}
+	if filter.Gender != "" && except != models.CatalogFacetGender {
+		query = query.Where("products.gender = ?", filter.Gender)
+	}
+	if brands := normalizeFacetValues(filter.Brands); len(brands) > 0 && except != models.CatalogFacetBrand {
+		query = query.Where("lower(trim(products.brand)) IN ?", brands)
+	}
+	if filter.ShopID != nil && except != models.CatalogFacetShop {
+		query = query.Where("products.shop_id = ?", *filter.ShopID)
+	}
+	if filter.CityID != nil {
+		query = query.Where("products.city_id = ? OR products.shop_id IN (SELECT id FROM shops WHERE city_id = ?)", *filter.CityID, *filter.CityID)
+	}
+	codes := make([]string, 0, len(filter.Attributes))
+	for code := range filter.Attributes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		values := normalizeFacetValues(filter.Attributes[code])
+		if len(values) == 0 || except == models.CatalogAttributeParamPrefix+code {
+			continue
+		}
+		query = query.Where("EXISTS (SELECT 1 FROM "+catalogAttributeValues+" AS attr(value) WHERE lower(attr.value) IN ?)",
+			code, code, code, values)
+	}
+	if filter.Search != "" {
+		query = GetSearchIndex().Apply(query, filter.Search, ranked)
+	}
+	return query
+}
+
+// applyCatalogFilter применяет все фильтры, кроме фасета except;
+// ranked сортирует по релевантности поиска (только для списка товаров, не для фасетов)
+func applyCatalogFilter(query *gorm.DB, filter models.CatalogFilter, except string, ranked bool) *gorm.DB {
+	query = applyCatalogProductFilter(query, filter, except, ranked)
+	condition, vars := catalogVariationCondition(filter, except)
+	return query.Where("EXISTS (SELECT 1 FROM product_variations pv WHERE pv.product_id = products.id AND "+condition+")", vars...)
+}
+
+// ApplyCatalogFilter ограничивает запрос товаров фильтрами каталога
+func ApplyCatalogFilter(query *gorm.DB, filter models.CatalogFilter) *gorm.DB {
+	return applyCatalogFilter(query, filter, "", false)
+}
+
+// QueryCatalog возвращает страницу товаров каталога и общее количество.
+// В курсорном режиме COUNT не выполняется, а выборка содержит до Limit+1
+// товаров (см. models.CursorPage).
+func QueryCatalog(db *gorm.DB, filter models.CatalogFilter) ([]models.Product, int64, error) {
+	ranked := filter.SortBy == models.CatalogSortRelevance && filter.Search != "" && !filter.CursorMode
+	query := applyCatalogFilter(db.Model(&models.Product{}), filter, "", ranked)
+
+	var total int64
+	if filter.CursorMode {
+		query = models.ApplyCursor(query, filter.After, "products.created_at", "products.id", filter.Limit)
+	} else {
+		// COUNT отбрасывает ORDER BY релевантности
+		if err := query.Count(&total).Error; err != nil {
+			return nil, 0, fmt.Errorf("ошибка подсчета товаров каталога: %w", err)
+		}
+		query = applyCatalogSort(query, filter).Offset((filter.Page - 1) * filter.Limit).Limit(filter.Limit)
+	}
+
+	for _, relation := range filter.Relations {
+		query = query.Preload(relation)
+	}
+
+	var products []models.Product
+	if err := query.Find(&products).Error; err != nil {
+		return nil, 0, fmt.Errorf("ошибка загрузки товаров каталога: %w", err)
+	}
+	return products, total, nil
+}
+
+// applyCatalogSort сортирует товары. Цена и скидка считаются по вариациям,
+// подходящим под фильтр, чтобы сортировка совпадала с тем, что видит покупатель.
+func applyCatalogSort(query *gorm.DB, filter models.CatalogFilter) *gorm.DB {
+	direction := " ASC"
+	if filter.SortDesc {
+		direction = " DESC"
+	}
+
+	switch filter.SortBy {
+	case models.CatalogSortPrice, models.CatalogSortDiscount:
+		aggregate := "MIN(pv.price)"
+		if filter.SortBy == models.CatalogSortDiscount {
+			aggregate = "MAX(" + catalogDiscountExpr + ")"
+		}
+		condition, vars := catalogVariationCondition(filter, "")
+		return query.Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL: "(SELECT " + aggregate + " FROM product_variations pv WHERE pv.product_id = products.id AND " + condition + ")" +
+				direction + " NULLS LAST, products.created_at DESC",
+			Vars: vars,
+		}})
+	case models.CatalogSortName:
+		return query.Order("products.name" + direction)
+	case models.CatalogSortRating:
+		return query.Order("products.rating" + direction + ", products.reviews_count DESC, products.created_at DESC")
+	case models.CatalogSortRelevance:
+		// Порядок по релевантности уже добавлен поиском, дата - для равных
+		if filter.Search != "" {
+			return query.Order("products.created_at DESC")
+		}
+	}
+	return query.Order("products.created_at" + direction)
+}
+
+// GetCatalogFacets считает количество товаров по значениям каждого фильтра
+func GetCatalogFacets(db *gorm.DB, filter models.CatalogFilter) (models.CatalogFacets, error) {
+	facets := models.CatalogFacets{}
+	var err error
+
+	productFacet := func(except, selectSQL, groupSQL string, scope func(*gorm.DB) *gorm.DB) ([]models.FacetValue, error) {
+		values := []models.FacetValue{}
+		query := applyCatalogFilter(db.Model(&models.Product{}), filter, except, false)
+		if scope != nil {
+			query = scope(query)
+		}
+		err := query.Select(selectSQL + ", COUNT(*) AS count").Group(groupSQL).
+			Order("count DESC").Limit(catalogFacetLimit).Scan(&values).Error
+		return values, err
+	}
+
+	if facets.Categories, err = productFacet(models.CatalogFacetCategory,
+		"products.category_id::text AS value, categories.name AS label", "products.category_id, categories.name",
+		func(query *gorm.DB) *gorm.DB {
+			return query.Joins("JOIN categories ON categories.id = products.category_id")
+		}); err != nil {
+		return facets, fmt.Errorf("ошибка фасета категорий: %w", err)
+	}
+	if facets.Genders, err = productFacet(models.CatalogFacetGender,
+		"products.gender AS value", "products.gender", nil); err != nil {
+		return facets, fmt.Errorf("ошибка фасета пола: %w", err)
+	}
+	if facets.Brands, err = productFacet(models.CatalogFacetBrand,
+		"MIN(trim(products.brand)) AS value", "lower(trim(products.brand))",
+		func(query *gorm.DB) *gorm.DB {
+			return query.Where("trim(products.brand) <> ''")
+		}); err != nil {
+		return facets, fmt.Errorf("ошибка фасета брендов: %w", err)
+	}
+	if facets.Shops, err = productFacet(models.CatalogFacetShop,
+		"products.shop_id::text AS value, shops.name AS label", "products.shop_id, shops.name",
+		func(query *gorm.DB) *gorm.DB {
+			return query.Joins("JOIN shops ON shops.id = products.shop_id")
+		}); err != nil {
+		return facets, fmt.Errorf("ошибка фасета магазинов: %w", err)
+	}
+
+	// Размеры и цвета: значения из JSON массивов вариаций, подходящих под остальные фильтры
+	variationFacet := func(except, column string) ([]models.FacetValue, error) {
+		values := []models.FacetValue{}
+		condition, vars := catalogVariationCondition(filter, except)
+		query := applyCatalogProductFilter(db.Model(&models.Product{}), filter, except, false).
+			Joins("JOIN product_variations pv ON pv.product_id = products.id AND "+condition, vars...).
+			Joins("CROSS JOIN LATERAL " + catalogJSONValues(column) + " AS facet(value)")
+		err := query.Select("MIN(trim(facet.value)) AS value, COUNT(DISTINCT products.id) AS count").
+			Where("trim(facet.value) <> ''").
+			Group("lower(trim(facet.value))").
+			Order("count DESC").Limit(catalogFacetLimit).Scan(&values).Error
+		return values, err
+	}
+
+	if facets.Sizes, err = variationFacet(models.CatalogFacetSize, "sizes"); err != nil {
+		return facets, fmt.Errorf("ошибка фасета размеров: %w", err)
+	}
+	if facets.Colors, err = variationFacet(models.CatalogFacetColor, "colors"); err != nil {
+		return facets, fmt.Errorf("ошибка фасета цветов: %w", err)
+	}
+
+	// Характеристики: только фильтруемые из схемы выбранной категории
+	if filter.CategoryID != nil {
+		schema, err := GetCategoryAttributeSchema(db, *filter.CategoryID)
+		if err != nil {
+			return facets, err
+		}
+		for _, attribute := range schema {
+			if !attribute.Filterable {
+				continue
+			}
+			values := []models.FacetValue{}
+			if err := applyCatalogFilter(db.Model(&models.Product{}), filter, models.CatalogAttributeParamPrefix+attribute.Code, false).
+				Joins("CROSS JOIN LATERAL "+catalogAttributeValues+" AS attr(value)", attribute.Code, attribute.Code, attribute.Code).
+				Select("MIN(attr.value) AS value, COUNT(DISTINCT products.id) AS count").
+				Where("attr.value IS NOT NULL AND attr.value <> ''").
+				Group("lower(attr.value)").
+				Order("count DESC").Limit(catalogFacetLimit).Scan(&values).Error; err != nil {
+				return facets, fmt.Errorf("ошибка фасета характеристики %s: %w", attribute.Code, err)
+			}
+			if facets.Attributes == nil {
+				facets.Attributes = make(map[string][]models.FacetValue)
+			}
+			facets.Attributes[attribute.Code] = values
+		}
+	}
+
+	// Диапазон цен без учета выбранного диапазона
+	condition, vars := catalogVariationCondition(filter, models.CatalogFacetPrice)
+	if err := applyCatalogProductFilter(db.Model(&models.Product{}), filter, models.CatalogFacetPrice, false).
+		Joins("JOIN product_variations pv ON pv.product_id = products.id AND "+condition, vars...).
+		Select("COALESCE(MIN(pv.price), 0) AS min, COALESCE(MAX(pv.price), 0) AS max").
+		Scan(&facets.Price).Error; err != nil {
+		return facets, fmt.Errorf("ошибка фасета цены: %w", err)
+	}
+
+	// Переключатели: сколько товаров останется, если включить
+	onSale := filter
+	onSale.OnSale = true
+	if err := ApplyCatalogFilter(db.Model(&models.Product{}), onSale).Count(&facets.OnSale).Error; err != nil {
+		return facets, fmt.Errorf("ошибка фасета скидок: %w", err)
+	}
+	inStock := filter
+	inStock.InStock = true
+	if err := ApplyCatalogFilter(db.Model(&models.Product{}), inStock).Count(&facets.InStock).Error; err != nil {
+		return facets, fmt.Errorf("ошибка фасета наличия: %w", err)
+	}
+
+	return facets, nil
+}
@@ -0,0 +1,321 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"gorm.io/gorm"
+)
+
+// categoryCountRefreshDelay сколько ждать после изменения товаров перед пересчетом:
+// изменения за это время (импорт, массовые правки) склеиваются в один пересчет
+const categoryCountRefreshDelay = 2 * time.Second
+
+// ErrCategoryCycle новый родитель является самой категорией или ее потомком
+var ErrCategoryCycle = errors.New("category cannot be moved under itself or its descendant")
+
+// ErrCategoryNotSibling категория из списка сортировки не принадлежит указанному родителю
+var ErrCategoryNotSibling = errors.New("category does not belong to the given parent")
+
+// categorySubtreeSQL рекурсивно выбирает категорию и всех ее потомков.
+// UNION (а не UNION ALL) защищает от зацикливания, если в данных уже есть цикл.
+const categorySubtreeSQL = `WITH RECURSIVE subtree AS (
+	SELECT id FROM categories WHERE id = ?
+	UNION
+	SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+) SELECT id FROM subtree`
+
+// categoryAncestorsSQL выбирает цепочку от категории до корня; depth ограничивает
+// обход на случай испорченных данных
+const categoryAncestorsSQL = `WITH RECURSIVE ancestors AS (
+	SELECT id, name, parent_id, 0 AS depth FROM categories WHERE id = ?
+	UNION ALL
+	SELECT c.id, c.name, c.parent_id, a.depth + 1
+	FROM categories c JOIN ancestors a ON c.id = a.parent_id
+	WHERE a.depth < 32
+) SELECT id, name FROM ancestors ORDER BY depth DESC`
+
+// refreshCategoryCountsSQL пересчитывает сохраненный product_count: доступные
+// товары категории вместе со всеми подкатегориями
+const refreshCategoryCountsSQL = `WITH RECURSIVE closure AS (
+	SELECT id AS ancestor_id, id FROM categories
+	UNION
+	SELECT cl.ancestor_id, c.id FROM categories c JOIN closure cl ON c.parent_id = cl.id
+), direct AS (
+	SELECT category_id, COUNT(*) AS cnt FROM products WHERE is_available = true GROUP BY category_id
+), totals AS (
+	SELECT cl.ancestor_id, COALESCE(SUM(d.cnt), 0) AS total
+	FROM closure cl LEFT JOIN direct d ON d.category_id = cl.id
+	GROUP BY cl.ancestor_id
+)
+UPDATE categories SET product_count = totals.total
+FROM totals
+WHERE categories.id = totals.ancestor_id AND categories.product_count <> totals.total`
+
+// CategorySubtreeCondition условие "column входит в поддерево категории";
+// параметр - ID категории
+func CategorySubtreeCondition(column string) string {
+	return column + " IN (" + categorySubtreeSQL + ")"
+}
+
+// CategoryDescendantIDs возвращает ID категории и всех ее потомков
+func CategoryDescendantIDs(db *gorm.DB, categoryID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := db.Raw(categorySubtreeSQL, categoryID).Scan(&ids).Error; err != nil {
+		return nil, fmt.Errorf("ошибка загрузки подкатегорий: %w", err)
+	}
+	return ids, nil
+}
+
+// GetCategoryBreadcrumbs возвращает путь от корня до категории включительно
+func GetCategoryBreadcrumbs(db *gorm.DB, categoryID uuid.UUID) ([]models.CategoryBreadcrumb, error) {
+	var breadcrumbs []models.CategoryBreadcrumb
+	if err := db.Raw(categoryAncestorsSQL, categoryID).Scan(&breadcrumbs).Error; err != nil {
+		return nil, fmt.Errorf("ошибка загрузки пути категории: %w", err)
+	}
+	return breadcrumbs, nil
+}
+
+// ValidateCategoryParent проверяет, что категорию можно поместить под parentID:
+// родитель существует и не является самой категорией или ее потомком
+func ValidateCategoryParent(db *gorm.DB, categoryID uuid.UUID, parentID *uuid.UUID) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == categoryID {
+		return ErrCategoryCycle
+	}
+
+	var parent models.Category
+	if err := db.Select("id").First(&parent, "id = ?", *parentID).Error; err != nil {
+		return err
+	}
+
+	descendants, err := CategoryDescendantIDs(db, categoryID)
+	if err != nil {
+		return err
+	}
+	for _, id := range descendants {
+		if id == *parentID {
+			return ErrCategoryCycle
+		}
+	}
+	return nil
+}
+
+// LockCategoryTree сериализует изменения иерархии в транзакции, чтобы два
+// параллельных перемещения не образовали цикл
+func LockCategoryTree(tx *gorm.DB) error {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('mm_category_tree'))").Error; err != nil {
+		return fmt.Errorf("ошибка блокировки дерева категорий: %w", err)
+	}
+	return nil
+}
+
+// MoveCategory переносит категорию под нового родителя (nil - в корень)
+// и при необходимости меняет ее позицию среди соседей
+func MoveCategory(db *gorm.DB, category *models.Category, parentID *uuid.UUID, sortOrder *int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := LockCategoryTree(tx); err != nil {
+			return err
+		}
+		if err := ValidateCategoryParent(tx, category.ID, parentID); err != nil {
+			return err
+		}
+
+		category.ParentID = parentID
+		updates := map[string]interface{}{"parent_id": parentID}
+		if sortOrder != nil {
+			category.SortOrder = *sortOrder
+			updates["sort_order"] = *sortOrder
+		}
+		return tx.Model(category).Updates(updates).Error
+	})
+}
+
+// ReorderCategories задает порядок соседних категорий: sort_order равен позиции в списке
+func ReorderCategories(db *gorm.DB, parentID *uuid.UUID, ids []uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := LockCategoryTree(tx); err != nil {
+			return err
+		}
+
+		query := tx.Model(&models.Category{}).Where("id IN ?", ids)
+		if parentID == nil {
+			query = query.Where("parent_id IS NULL")
+		} else {
+			query = query.Where("parent_id = ?", *parentID)
+		}
+		var siblings int64
+		if err := query.Count(&siblings).Error; err != nil {
+			return err
+		}
+		if int(siblings) != len(ids) {
+			return ErrCategoryNotSibling
+		}
+
+		for i, id := range ids {
+			if err := tx.Model(&models.Category{}).Where("id = ?", id).Update("sort_order", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RefreshCategoryProductCounts обновляет сохраненный product_count всех категорий
+func RefreshCategoryProductCounts(db *gorm.DB) error {
+	if err := db.Exec(refreshCategoryCountsSQL).Error; err != nil {
+		return fmt.Errorf("ошибка пересчета количества товаров в категориях: %w", err)
+	}
+	return nil
+}
+
+// categoryCountRefresh запросы на пересчет; буфер 1 склеивает повторные запросы
+var categoryCountRefresh = make(chan struct{}, 1)
+
+// ScheduleCategoryCountRefresh просит фоновый пересчет product_count и сразу
+// возвращается, чтобы запрос, изменивший товар, не ждал пересчета всего дерева
+func ScheduleCategoryCountRefresh() {
+	select {
+	case categoryCountRefresh <- struct{}{}:
+	default:
+	}
+}
+
+// StartCategoryCountWorker запускает фоновый пересчет product_count категорий
+// по запросам ScheduleCategoryCountRefresh. При старте счетчики пересчитываются
+// один раз: запросы, не выполненные до перезапуска, теряются.
+func StartCategoryCountWorker(ctx context.Context) {
+	ScheduleCategoryCountRefresh()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-categoryCountRefresh:
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(categoryCountRefreshDelay):
+			}
+
+			// Запросы, пришедшие во время ожидания, покрываются этим пересчетом
+			select {
+			case <-categoryCountRefresh:
+			default:
+			}
+
+			if database.DB != nil {
+				if err := RefreshCategoryProductCounts(database.DB); err != nil {
+					log.Printf("⚠️ %v", err)
+				}
+			}
+		}
+	}()
+
+	log.Printf("✅ Category count worker запущен (задержка: %s)", categoryCountRefreshDelay)
+}
+
+// BuildCategoryTree строит полное дерево категорий с живым количеством доступных
+// товаров (включая подкатегории). cityID ограничивает подсчет товарами города,
+// includeInactive добавляет выключенные категории (для админки).
+func BuildCategoryTree(db *gorm.DB, cityID *uuid.UUID, includeInactive bool) ([]models.CategoryTree, error) {
+	var categories []models.Category
+	query := db.Model(&models.Category{})
+	if !includeInactive {
+		query = query.Where("is_active = ?", true)
+	}
+	if err := query.Find(&categories).Error; err != nil {
+		return nil, fmt.Errorf("ошибка загрузки категорий: %w", err)
+	}
+
+	var rows []struct {
+		CategoryID uuid.UUID
+		Count      int
+	}
+	countQuery := db.Model(&models.Product{}).
+		Select("products.category_id, COUNT(*) AS count").
+		Where("products.is_available = ?", true)
+	if cityID != nil {
+		countQuery = countQuery.Where("products.city_id = ? OR products.shop_id IN (SELECT id FROM shops WHERE city_id = ?)", *cityID, *cityID)
+	}
+	if err := countQuery.Group("products.category_id").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("ошибка подсчета товаров по категориям: %w", err)
+	}
+	direct := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		direct[row.CategoryID] = row.Count
+	}
+
+	byID := make(map[uuid.UUID]*models.Category, len(categories))
+	for i := range categories {
+		byID[categories[i].ID] = &categories[i]
+	}
+	children := make(map[uuid.UUID][]*models.Category)
+	var roots []*models.Category
+	for i := range categories {
+		category := &categories[i]
+		// Категории, чей родитель скрыт или отсутствует, не показываем
+		// (выключенный раздел скрывает все поддерево)
+		if category.ParentID == nil {
+			roots = append(roots, category)
+		} else if _, ok := byID[*category.ParentID]; ok {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		}
+	}
+
+	visited := make(map[uuid.UUID]bool, len(categories))
+	var build func(category *models.Category) models.CategoryTree
+	build = func(category *models.Category) models.CategoryTree {
+		visited[category.ID] = true
+		node := models.CategoryTree{
+			ID:           category.ID,
+			Name:         category.Name,
+			Description:  category.Description,
+			IconURL:      category.IconURL,
+			ParentID:     category.ParentID,
+			ProductCount: direct[category.ID],
+			IsActive:     category.IsActive,
+			SortOrder:    category.SortOrder,
+		}
+		subs := children[category.ID]
+		sortCategories(subs)
+		for _, sub := range subs {
+			if visited[sub.ID] {
+				continue
+			}
+			child := build(sub)
+			node.ProductCount += child.ProductCount
+			node.Children = append(node.Children, child)
+		}
+		return node
+	}
+
+	sortCategories(roots)
+	tree := make([]models.CategoryTree, 0, len(roots))
+	for _, root := range roots {
+		tree = append(tree, build(root))
+	}
+	return tree, nil
+}
+
+// sortCategories упорядочивает соседние категории по sort_order, затем по имени
+func sortCategories(categories []*models.Category) {
+	sort.SliceStable(categories, func(i, j int) bool {
+		if categories[i].SortOrder != categories[j].SortOrder {
+			return categories[i].SortOrder < categories[j].SortOrder
+		}
+		return categories[i].Name < categories[j].Name
+	})
+}
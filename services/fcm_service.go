@@ -3,54 +3,201 @@ package services
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+)
+
+const (
+	// fcmDefaultAPIBaseURL базовый адрес FCM HTTP v1 API
+	fcmDefaultAPIBaseURL = "https://fcm.googleapis.com"
+	// fcmDefaultTokenURL адрес Google OAuth2 для обмена JWT на access token
+	fcmDefaultTokenURL = "https://oauth2.googleapis.com/token"
+	// fcmScope область доступа, необходимая для отправки сообщений
+	fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+	// fcmTokenRefreshMargin запас времени до истечения access token, после которого он обновляется
+	fcmTokenRefreshMargin = time.Minute
+)
+
+// FCM коды ошибок из google.firebase.fcm.v1.FcmError
+const (
+	FCMErrorUnregistered        = "UNREGISTERED"
+	FCMErrorInvalidArgument     = "INVALID_ARGUMENT"
+	FCMErrorSenderIDMismatch    = "SENDER_ID_MISMATCH"
+	FCMErrorQuotaExceeded       = "QUOTA_EXCEEDED"
+	FCMErrorUnavailable         = "UNAVAILABLE"
+	FCMErrorInternal            = "INTERNAL"
+	FCMErrorThirdPartyAuthError = "THIRD_PARTY_AUTH_ERROR"
 )
 
-// FCMService обрабатывает отправку push-уведомлений через Firebase Cloud Messaging
+// FCMServiceAccount представляет JSON ключ сервисного аккаунта Google
+type FCMServiceAccount struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// FCMConfig параметры инициализации FCM сервиса
+type FCMConfig struct {
+	CredentialsFile string // Путь к JSON ключу сервисного аккаунта
+	CredentialsJSON string // Содержимое JSON ключа (альтернатива файлу)
+	ProjectID       string // Переопределяет project_id из ключа
+	APIBaseURL      string // Базовый адрес FCM API (для локального фейкового сервера)
+	TokenURL        string // Адрес OAuth2 (переопределяет token_uri из ключа)
+}
+
+// FCMService обрабатывает отправку push-уведомлений через FCM HTTP v1 API
 type FCMService struct {
-	ServerKey string
-	APIURL    string
+	ProjectID  string
+	APIBaseURL string
+	TokenURL   string
+
+	account    FCMServiceAccount
+	privateKey interface{}
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
 }
 
-// FCMRequest представляет запрос к FCM API
-type FCMRequest struct {
-	To           string                 `json:"to,omitempty"`
-	RegistrationIDs []string            `json:"registration_ids,omitempty"`
-	Notification FCMNotification        `json:"notification"`
-	Data         map[string]interface{} `json:"data,omitempty"`
-	Priority     string                 `json:"priority,omitempty"`
+// FCMMessage представляет сообщение FCM HTTP v1
+type FCMMessage struct {
+	Token        string            `json:"token"`
+	Notification *FCMNotification  `json:"notification,omitempty"`
+	Data         map[string]string `json:"data,omitempty"`
+	Android      *FCMAndroidConfig `json:"android,omitempty"`
+	APNS         *FCMAPNSConfig    `json:"apns,omitempty"`
+	Webpush      *FCMWebpushConfig `json:"webpush,omitempty"`
 }
 
-// FCMNotification представляет уведомление FCM
+// FCMNotification представляет видимую часть уведомления
 type FCMNotification struct {
 	Title string `json:"title"`
 	Body  string `json:"body"`
-	Icon  string `json:"icon,omitempty"`
-	Sound string `json:"sound,omitempty"`
+	Image string `json:"image,omitempty"`
+}
+
+// FCMAndroidConfig настройки доставки для Android
+type FCMAndroidConfig struct {
+	Priority     string                  `json:"priority,omitempty"`
+	Notification *FCMAndroidNotification `json:"notification,omitempty"`
+}
+
+// FCMAndroidNotification настройки отображения уведомления на Android
+type FCMAndroidNotification struct {
+	Sound       string `json:"sound,omitempty"`
+	ClickAction string `json:"click_action,omitempty"`
+}
+
+// FCMAPNSConfig настройки доставки для iOS
+type FCMAPNSConfig struct {
+	Headers map[string]string      `json:"headers,omitempty"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+// FCMWebpushConfig настройки доставки для Web
+type FCMWebpushConfig struct {
+	FCMOptions *FCMWebpushOptions `json:"fcm_options,omitempty"`
+}
+
+// FCMWebpushOptions опции Web push
+type FCMWebpushOptions struct {
+	Link string `json:"link,omitempty"`
+}
+
+// fcmSendRequest тело запроса messages:send
+type fcmSendRequest struct {
+	ValidateOnly bool       `json:"validate_only,omitempty"`
+	Message      FCMMessage `json:"message"`
+}
+
+// fcmSendResponse ответ messages:send
+type fcmSendResponse struct {
+	Name string `json:"name"`
+}
+
+// fcmErrorResponse тело ответа с ошибкой Google API
+type fcmErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			Type      string `json:"@type"`
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// fcmTokenResponse ответ OAuth2 на обмен JWT
+type fcmTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	TokenType   string `json:"token_type"`
+}
+
+// FCMError ошибка, возвращенная FCM API
+type FCMError struct {
+	HTTPStatus int
+	Status     string // Статус Google API (NOT_FOUND, INVALID_ARGUMENT, ...)
+	ErrorCode  string // Код FcmError (UNREGISTERED, QUOTA_EXCEEDED, ...)
+	Message    string
+}
+
+func (e *FCMError) Error() string {
+	code := e.ErrorCode
+	if code == "" {
+		code = e.Status
+	}
+	return fmt.Sprintf("FCM error %d %s: %s", e.HTTPStatus, code, e.Message)
 }
 
-// FCMResponse представляет ответ от FCM API
-type FCMResponse struct {
-	Success int `json:"success"`
-	Failure int `json:"failure"`
-	Results []struct {
-		MessageID string `json:"message_id,omitempty"`
-		Error     string `json:"error,omitempty"`
-	} `json:"results,omitempty"`
+// IsUnregistered сообщает, что токен устройства больше недействителен
+func (e *FCMError) IsUnregistered() bool {
+	return e.ErrorCode == FCMErrorUnregistered
+}
+
+// IsRetryable сообщает, имеет ли смысл повторить отправку позже
+func (e *FCMError) IsRetryable() bool {
+	switch e.ErrorCode {
+	case FCMErrorQuotaExceeded, FCMErrorUnavailable, FCMErrorInternal:
+		return true
+	}
+	return e.HTTPStatus == http.StatusTooManyRequests || e.HTTPStatus >= 500
+}
+
+// IsUnregisteredError проверяет, что ошибка означает недействительный токен устройства
+func IsUnregisteredError(err error) bool {
+	var fcmErr *FCMError
+	return errors.As(err, &fcmErr) && fcmErr.IsUnregistered()
 }
 
 var fcmService *FCMService
 
-// InitFCMService инициализирует FCM сервис
-func InitFCMService(serverKey string) {
-	fcmService = &FCMService{
-		ServerKey: serverKey,
-		APIURL:    "https://fcm.googleapis.com/fcm/send",
+// InitFCMService инициализирует FCM сервис по ключу сервисного аккаунта
+func InitFCMService(cfg FCMConfig) error {
+	service, err := NewFCMService(cfg)
+	if err != nil {
+		return err
 	}
-	log.Println("✅ FCM Service инициализирован")
+	fcmService = service
+	log.Printf("✅ FCM Service инициализирован (проект %s)", service.ProjectID)
+	return nil
 }
 
 // GetFCMService возвращает экземпляр FCM сервиса
@@ -58,95 +205,298 @@ func GetFCMService() *FCMService {
 	return fcmService
 }
 
-// SendPushNotification отправляет push-уведомление на одно устройство
-func (fcm *FCMService) SendPushNotification(token, title, body, actionURL string) error {
-	if fcm.ServerKey == "" {
-		log.Println("⚠️ FCM Server Key не настроен, пропускаем отправку push-уведомления")
-		return nil
+// NewFCMService создает FCM клиент по ключу сервисного аккаунта
+func NewFCMService(cfg FCMConfig) (*FCMService, error) {
+	raw := []byte(cfg.CredentialsJSON)
+	if len(raw) == 0 {
+		if cfg.CredentialsFile == "" {
+			return nil, fmt.Errorf("не указан ключ сервисного аккаунта FCM")
+		}
+		data, err := os.ReadFile(cfg.CredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения ключа сервисного аккаунта: %w", err)
+		}
+		raw = data
 	}
 
-	req := FCMRequest{
-		To: token,
-		Notification: FCMNotification{
-			Title: title,
-			Body:  body,
-			Sound: "default",
-		},
-		Data: map[string]interface{}{
-			"action_url": actionURL,
-			"click_action": actionURL,
-		},
-		Priority: "high",
+	var account FCMServiceAccount
+	if err := json.Unmarshal(raw, &account); err != nil {
+		return nil, fmt.Errorf("ошибка разбора ключа сервисного аккаунта: %w", err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("в ключе сервисного аккаунта отсутствуют client_email или private_key")
+	}
+
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора private_key: %w", err)
+	}
+
+	service := &FCMService{
+		ProjectID:  account.ProjectID,
+		APIBaseURL: fcmDefaultAPIBaseURL,
+		TokenURL:   fcmDefaultTokenURL,
+		account:    account,
+		privateKey: privateKey,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	if account.TokenURI != "" {
+		service.TokenURL = account.TokenURI
+	}
+	if cfg.ProjectID != "" {
+		service.ProjectID = cfg.ProjectID
+	}
+	if cfg.APIBaseURL != "" {
+		service.APIBaseURL = strings.TrimRight(cfg.APIBaseURL, "/")
+	}
+	if cfg.TokenURL != "" {
+		service.TokenURL = cfg.TokenURL
+	}
+	if service.ProjectID == "" {
+		return nil, fmt.Errorf("не указан project_id для FCM")
 	}
 
-	return fcm.sendRequest(req)
+	return service, nil
 }
 
-// SendPushNotificationToMultiple отправляет push-уведомление на несколько устройств
+// SendPushNotification отправляет push-уведомление на одно устройство
+func (fcm *FCMService) SendPushNotification(token, title, body, actionURL string) error {
+	return fcm.Send(newNotificationMessage(token, title, body, actionURL))
+}
+
+// SendPushNotificationToMultiple отправляет push-уведомление на несколько устройств.
+// HTTP v1 API не поддерживает multicast, поэтому сообщения отправляются по одному.
+// Возвращает ошибку, только если не удалось доставить ни одно сообщение.
 func (fcm *FCMService) SendPushNotificationToMultiple(tokens []string, title, body, actionURL string) error {
-	if fcm.ServerKey == "" {
-		log.Println("⚠️ FCM Server Key не настроен, пропускаем отправку push-уведомления")
+	if len(tokens) == 0 {
 		return nil
 	}
 
-	if len(tokens) == 0 {
-		return nil
+	var lastErr error
+	sent := 0
+	for _, token := range tokens {
+		if err := fcm.SendPushNotification(token, title, body, actionURL); err != nil {
+			lastErr = err
+			continue
+		}
+		sent++
+	}
+
+	log.Printf("✅ FCM: успешно отправлено %d, ошибок %d", sent, len(tokens)-sent)
+	if sent == 0 {
+		return lastErr
 	}
+	return nil
+}
+
+// SendDataMessage отправляет data-only сообщение (без видимого уведомления)
+func (fcm *FCMService) SendDataMessage(token string, data map[string]string) error {
+	return fcm.Send(FCMMessage{
+		Token:   token,
+		Data:    data,
+		Android: &FCMAndroidConfig{Priority: "high"},
+		APNS: &FCMAPNSConfig{
+			Headers: map[string]string{"apns-priority": "5", "apns-push-type": "background"},
+			Payload: map[string]interface{}{"aps": map[string]interface{}{"content-available": 1}},
+		},
+	})
+}
+
+// Send отправляет одно сообщение. Если FCM сообщает, что токен больше не
+// зарегистрирован, соответствующий DeviceToken деактивируется.
+func (fcm *FCMService) Send(message FCMMessage) error {
+	err := fcm.send(message)
+	if IsUnregisteredError(err) {
+		deactivateDeviceToken(message.Token)
+	}
+	return err
+}
 
-	req := FCMRequest{
-		RegistrationIDs: tokens,
-		Notification: FCMNotification{
+// newNotificationMessage собирает сообщение с видимым уведомлением и deep link
+func newNotificationMessage(token, title, body, actionURL string) FCMMessage {
+	message := FCMMessage{
+		Token: token,
+		Notification: &FCMNotification{
 			Title: title,
 			Body:  body,
-			Sound: "default",
 		},
-		Data: map[string]interface{}{
-			"action_url": actionURL,
-			"click_action": actionURL,
+		Android: &FCMAndroidConfig{
+			Priority:     "high",
+			Notification: &FCMAndroidNotification{Sound: "default", ClickAction: actionURL},
+		},
+		APNS: &FCMAPNSConfig{
+			Payload: map[string]interface{}{"aps": map[string]interface{}{"sound": "default"}},
 		},
-		Priority: "high",
 	}
-
-	return fcm.sendRequest(req)
+	if actionURL != "" {
+		message.Data = map[string]string{
+			"action_url":   actionURL,
+			"click_action": actionURL,
+		}
+		if strings.HasPrefix(actionURL, "https://") {
+			message.Webpush = &FCMWebpushConfig{FCMOptions: &FCMWebpushOptions{Link: actionURL}}
+		}
+	}
+	return message
 }
 
-// sendRequest отправляет запрос к FCM API
-func (fcm *FCMService) sendRequest(req FCMRequest) error {
-	jsonData, err := json.Marshal(req)
+// send выполняет запрос messages:send
+func (fcm *FCMService) send(message FCMMessage) error {
+	accessToken, err := fcm.getAccessToken()
+	if err != nil {
+		return err
+	}
+
+	jsonData, err := json.Marshal(fcmSendRequest{Message: message})
 	if err != nil {
 		return fmt.Errorf("ошибка маршалинга FCM запроса: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", fcm.APIURL, bytes.NewBuffer(jsonData))
+	endpoint := fmt.Sprintf("%s/v1/projects/%s/messages:send", fcm.APIBaseURL, fcm.ProjectID)
+	httpReq, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("ошибка создания HTTP запроса: %w", err)
 	}
-
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "key="+fcm.ServerKey)
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	httpReq.Header.Set("Authorization", "Bearer "+accessToken)
 
-	resp, err := client.Do(httpReq)
+	resp, err := fcm.httpClient.Do(httpReq)
 	if err != nil {
 		return fmt.Errorf("ошибка отправки запроса к FCM: %w", err)
 	}
 	defer resp.Body.Close()
 
-	var fcmResp FCMResponse
-	if err := json.NewDecoder(resp.Body).Decode(&fcmResp); err != nil {
-		log.Printf("⚠️ Ошибка декодирования ответа FCM: %v", err)
-		return nil // Не критично, продолжаем
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения ответа FCM: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		// Токен мог быть отозван раньше срока - сбрасываем кэш
+		fcm.mu.Lock()
+		fcm.accessToken = ""
+		fcm.mu.Unlock()
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("⚠️ FCM вернул статус %d: успешно %d, ошибок %d", resp.StatusCode, fcmResp.Success, fcmResp.Failure)
-		return nil
+		return parseFCMError(resp.StatusCode, respBody)
 	}
 
-	log.Printf("✅ FCM: успешно отправлено %d, ошибок %d", fcmResp.Success, fcmResp.Failure)
+	var sendResp fcmSendResponse
+	if err := json.Unmarshal(respBody, &sendResp); err != nil {
+		log.Printf("⚠️ Ошибка декодирования ответа FCM: %v", err)
+	}
 	return nil
 }
 
+// parseFCMError разбирает тело ответа FCM с ошибкой
+func parseFCMError(statusCode int, body []byte) *FCMError {
+	fcmErr := &FCMError{HTTPStatus: statusCode}
+
+	var errResp fcmErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		fcmErr.Message = strings.TrimSpace(string(body))
+		return fcmErr
+	}
+
+	fcmErr.Status = errResp.Error.Status
+	fcmErr.Message = errResp.Error.Message
+	for _, detail := range errResp.Error.Details {
+		if strings.HasSuffix(detail.Type, "google.firebase.fcm.v1.FcmError") && detail.ErrorCode != "" {
+			fcmErr.ErrorCode = detail.ErrorCode
+			break
+		}
+	}
+	// Некоторые ответы содержат только статус NOT_FOUND без деталей
+	if fcmErr.ErrorCode == "" && statusCode == http.StatusNotFound && fcmErr.Status == "NOT_FOUND" {
+		fcmErr.ErrorCode = FCMErrorUnregistered
+	}
+	return fcmErr
+}
+
+// getAccessToken возвращает закэшированный OAuth2 access token или получает новый
+func (fcm *FCMService) getAccessToken() (string, error) {
+	fcm.mu.Lock()
+	defer fcm.mu.Unlock()
+
+	if fcm.accessToken != "" && time.Now().Add(fcmTokenRefreshMargin).Before(fcm.tokenExpiry) {
+		return fcm.accessToken, nil
+	}
+
+	assertion, err := fcm.signAssertion(time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+
+	resp, err := fcm.httpClient.PostForm(fcm.TokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("ошибка запроса OAuth2 токена: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения ответа OAuth2: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OAuth2 вернул статус %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokenResp fcmTokenResponse
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", fmt.Errorf("ошибка декодирования ответа OAuth2: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", fmt.Errorf("OAuth2 не вернул access_token")
+	}
+
+	expiresIn := time.Duration(tokenResp.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = time.Hour
+	}
+	fcm.accessToken = tokenResp.AccessToken
+	fcm.tokenExpiry = time.Now().Add(expiresIn)
+
+	return fcm.accessToken, nil
+}
+
+// signAssertion подписывает JWT для обмена на OAuth2 access token (RS256, локально)
+func (fcm *FCMService) signAssertion(now time.Time) (string, error) {
+	claims := jwt.MapClaims{
+		"iss":   fcm.account.ClientEmail,
+		"sub":   fcm.account.ClientEmail,
+		"aud":   fcm.TokenURL,
+		"scope": fcmScope,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if fcm.account.PrivateKeyID != "" {
+		token.Header["kid"] = fcm.account.PrivateKeyID
+	}
+
+	signed, err := token.SignedString(fcm.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("ошибка подписи JWT для OAuth2: %w", err)
+	}
+	return signed, nil
+}
+
+// deactivateDeviceToken помечает токен устройства как неактивный
+func deactivateDeviceToken(token string) {
+	if database.DB == nil || token == "" {
+		return
+	}
+	if err := database.DB.Model(&models.DeviceToken{}).
+		Where("token = ?", token).
+		Update("is_active", false).Error; err != nil {
+		log.Printf("⚠️ Ошибка деактивации токена устройства: %v", err)
+		return
+	}
+	log.Printf("ℹ️ Токен устройства деактивирован: FCM вернул %s", FCMErrorUnregistered)
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mm-api/mm-api/database"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	fakeFCMProject     = "test-project"
+	fakeFCMAccessToken = "fake-access-token"
+	fakeFCMStaleToken  = "stale-device-token"
+)
+
+// fakeFCM локальный сервер OAuth2 и FCM HTTP v1: проверяет подпись assertion,
+// выдает access token и отвечает UNREGISTERED для fakeFCMStaleToken
+type fakeFCM struct {
+	t         *testing.T
+	publicKey *rsa.PublicKey
+
+	mu            sync.Mutex
+	tokenRequests int
+	messages      []FCMMessage
+}
+
+func newFakeFCM(t *testing.T) (*FCMService, *fakeFCM) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	fake := &fakeFCM{t: t, publicKey: &key.PublicKey}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	credentials, err := json.Marshal(FCMServiceAccount{
+		Type:         "service_account",
+		ProjectID:    fakeFCMProject,
+		PrivateKeyID: "test-key",
+		PrivateKey:   string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		ClientEmail:  "push@test-project.iam.gserviceaccount.com",
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	service, err := NewFCMService(FCMConfig{
+		CredentialsJSON: string(credentials),
+		APIBaseURL:      server.URL,
+		TokenURL:        server.URL + "/token",
+	})
+	if err != nil {
+		t.Fatalf("NewFCMService: %v", err)
+	}
+	return service, fake
+}
+
+func (f *fakeFCM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/token":
+		f.token(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/projects/"+fakeFCMProject+"/messages:send":
+		f.send(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeFCM) token(w http.ResponseWriter, r *http.Request) {
+	if r.PostFormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+		http.Error(w, `{"error":"unsupported_grant_type"}`, http.StatusBadRequest)
+		return
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(r.PostFormValue("assertion"), claims, func(token *jwt.Token) (interface{}, error) {
+		return f.publicKey, nil
+	}, jwt.WithValidMethods([]string{"RS256"}))
+	if err != nil || claims["scope"] != fcmScope {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.tokenRequests++
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fcmTokenResponse{AccessToken: fakeFCMAccessToken, ExpiresIn: 3600, TokenType: "Bearer"})
+}
+
+func (f *fakeFCM) send(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Header.Get("Authorization") != "Bearer "+fakeFCMAccessToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"code":401,"message":"bad token","status":"UNAUTHENTICATED"}}`)
+		return
+	}
+
+	var req fcmSendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"code":400,"message":"bad json","status":"INVALID_ARGUMENT"}}`)
+		return
+	}
+
+	f.mu.Lock()
+	f.messages = append(f.messages, req.Message)
+	f.mu.Unlock()
+
+	if req.Message.Token == fakeFCMStaleToken {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":{"code":404,"message":"Requested entity was not found.","status":"NOT_FOUND",`+
+			`"details":[{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"UNREGISTERED"}]}}`)
+		return
+	}
+	fmt.Fprintf(w, `{"name":"projects/%s/messages/%d"}`, fakeFCMProject, len(f.messages))
+}
+
+func TestFCMSendCachesAccessToken(t *testing.T) {
+	service, fake := newFakeFCM(t)
+
+	if err := service.SendPushNotification("device-1", "Заказ", "Заказ отправлен", "mmshop://orders/1"); err != nil {
+		t.Fatalf("first send: %v", err)
+	}
+	if err := service.SendDataMessage("device-2", map[string]string{"type": "sync"}); err != nil {
+		t.Fatalf("second send: %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.tokenRequests != 1 {
+		t.Fatalf("token requests = %d, want 1 (cached)", fake.tokenRequests)
+	}
+	if len(fake.messages) != 2 {
+		t.Fatalf("messages = %d, want 2", len(fake.messages))
+	}
+	first := fake.messages[0]
+	if first.Token != "device-1" || first.Notification == nil || first.Notification.Title != "Заказ" {
+		t.Fatalf("unexpected first message: %+v", first)
+	}
+	if first.Android == nil || first.Android.Notification == nil || first.Android.Notification.ClickAction != "mmshop://orders/1" {
+		t.Fatalf("deep link not passed to android: %+v", first.Android)
+	}
+	if second := fake.messages[1]; second.Notification != nil || second.Data["type"] != "sync" {
+		t.Fatalf("unexpected data message: %+v", second)
+	}
+}
+
+func TestFCMSendDeactivatesUnregisteredToken(t *testing.T) {
+	service, _ := newFakeFCM(t)
+	execs := useRecordingDB(t)
+
+	err := service.SendPushNotification(fakeFCMStaleToken, "Заказ", "Заказ отправлен", "")
+	if !IsUnregisteredError(err) {
+		t.Fatalf("err = %v, want UNREGISTERED", err)
+	}
+
+	for _, exec := range execs.list() {
+		if strings.HasPrefix(exec.query, `UPDATE "device_tokens" SET "is_active"=`) && containsArg(exec.args, fakeFCMStaleToken) {
+			if !containsArg(exec.args, false) {
+				t.Fatalf("device token update does not set is_active=false: %+v", exec)
+			}
+			return
+		}
+	}
+	t.Fatalf("device token was not deactivated, executed: %+v", execs.list())
+}
+
+func TestFCMSendKeepsTokenOnOtherErrors(t *testing.T) {
+	service, _ := newFakeFCM(t)
+	execs := useRecordingDB(t)
+	service.ProjectID = "unknown-project"
+
+	if err := service.SendPushNotification("device-1", "Заказ", "Заказ отправлен", ""); err == nil {
+		t.Fatal("send to unknown project must fail")
+	}
+	if list := execs.list(); len(list) != 0 {
+		t.Fatalf("unexpected queries: %+v", list)
+	}
+}
+
+// useRecordingDB подменяет database.DB на gorm поверх драйвера, который
+// только записывает выполненные запросы
+func useRecordingDB(t *testing.T) *recordedExecs {
+	t.Helper()
+	execs := &recordedExecs{}
+	sqlDB := sql.OpenDB(recordingConnector{execs: execs})
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		sqlDB.Close()
+	})
+	return execs
+}
+
+func containsArg(args []driver.NamedValue, want interface{}) bool {
+	for _, arg := range args {
+		if arg.Value == want {
+			return true
+		}
+	}
+	return false
+}
+
+type recordedExec struct {
+	query string
+	args  []driver.NamedValue
+}
+
+type recordedExecs struct {
+	mu    sync.Mutex
+	execs []recordedExec
+}
+
+func (r *recordedExecs) list() []recordedExec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]recordedExec(nil), r.execs...)
+}
+
+type recordingConnector struct{ execs *recordedExecs }
+
+func (c recordingConnector) Connect(context.Context) (driver.Conn, error) {
+	return recordingConn{execs: c.execs}, nil
+}
+
+func (c recordingConnector) Driver() driver.Driver { return recordingDriver{} }
+
+type recordingDriver struct{}
+
+func (recordingDriver) Open(string) (driver.Conn, error) {
+	return nil, fmt.Errorf("recordingDriver: use the connector")
+}
+
+type recordingConn struct{ execs *recordedExecs }
+
+func (c recordingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("recordingConn: prepared statements are not supported")
+}
+
+func (c recordingConn) Close() error { return nil }
+
+func (c recordingConn) Begin() (driver.Tx, error) { return recordingTx{}, nil }
+
+func (c recordingConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.execs.mu.Lock()
+	c.execs.execs = append(c.execs.execs, recordedExec{query: query, args: args})
+	c.execs.mu.Unlock()
+	return driver.RowsAffected(1), nil
+}
+
+type recordingTx struct{}
+
+func (recordingTx) Commit() error   { return nil }
+func (recordingTx) Rollback() error { return nil }
@@ -0,0 +1,493 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrImageImportInProgress у магазина уже есть незавершенный импорт
+var ErrImageImportInProgress = errors.New("image import is already running for this shop")
+
+// ImageImportExtensions расширения фото, которые берутся из архива
+var ImageImportExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true,
+}
+
+// imageImportSaveEvery как часто (в файлах) прогресс сохраняется в задачу
+const imageImportSaveEvery = 10
+
+const (
+	imageImportHeartbeatInterval = 30 * time.Second // Как часто обработчик отмечает, что задача жива
+	imageImportStaleAfter        = 5 * time.Minute  // Задача без сигнала дольше - прервана
+)
+
+// imageImportInstance экземпляр API, владеющий задачами импорта. Имя хоста не
+// меняется при перезапуске контейнера, поэтому после перезапуска экземпляр
+// узнает свои прерванные задачи.
+var imageImportInstance = func() string {
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return host
+	}
+	return "unknown"
+}()
+
+// imageImportInterrupted сообщение для задач, архив которых больше недоступен
+const imageImportInterrupted = "Import was interrupted by a server restart, upload the archive again"
+
+// ImageImportSaver сохраняет фото из архива (обработка, хранилище, реестр медиа)
+// и возвращает его адрес. name - имя файла в архиве.
+type ImageImportSaver func(ctx context.Context, name string, data []byte) (string, error)
+
+// ImageImportConfig параметры обработки архива
+type ImageImportConfig struct {
+	MaxImages   int   // Фото на цвет вариации (MAX_IMAGES_PER_VARIATION)
+	MaxFiles    int   // Максимум фото в архиве
+	MaxFileSize int64 // Максимальный размер одного фото
+	Save        ImageImportSaver
+}
+
+// ImageImportName разобранное имя файла: SKU или штрих-код, цвет и номер фото
+type ImageImportName struct {
+	Code  string // SKU или штрих-код
+	Color string // Пусто - первый цвет вариации
+	Index int    // N из SKU_цвет_N; 0 - не указан
+}
+
+// ImageImportNameCandidates варианты разбора имени файла без расширения, от
+// самого точного к менее точному. SKU может содержать "_", поэтому сначала
+// имя целиком проверяется как SKU/штрих-код, затем отделяются номер и цвет:
+// ABC_1 -> (ABC_1), (ABC, N=1); ABC_red_2 -> (ABC_red_2), (ABC_red, N=2), (ABC, red, N=2).
+func ImageImportNameCandidates(base string) []ImageImportName {
+	base = strings.TrimSpace(base)
+	if base == "" {
+		return nil
+	}
+	candidates := []ImageImportName{{Code: base}}
+
+	parts := strings.Split(base, "_")
+	index := 0
+	if len(parts) > 1 {
+		if n, err := strconv.Atoi(parts[len(parts)-1]); err == nil && n > 0 {
+			index = n
+			parts = parts[:len(parts)-1]
+			candidates = append(candidates, ImageImportName{Code: strings.Join(parts, "_"), Index: index})
+		}
+	}
+	if len(parts) > 1 {
+		candidates = append(candidates, ImageImportName{
+			Code:  strings.Join(parts[:len(parts)-1], "_"),
+			Color: parts[len(parts)-1],
+			Index: index,
+		})
+	}
+	return candidates
+}
+
+// imageImportIndex вариации магазина по SKU и штрих-коду (без учета регистра)
+type imageImportIndex struct {
+	byCode map[string][]*models.ProductVariation
+}
+
+func newImageImportIndex(variations []models.ProductVariation) *imageImportIndex {
+	idx := &imageImportIndex{byCode: make(map[string][]*models.ProductVariation)}
+	for i := range variations {
+		variation := &variations[i]
+		seen := make(map[string]bool, 2)
+		for _, code := range []string{variation.Barcode, variation.SKU} {
+			code = strings.ToLower(strings.TrimSpace(code))
+			if code == "" || seen[code] {
+				continue
+			}
+			seen[code] = true
+			idx.byCode[code] = append(idx.byCode[code], variation)
+		}
+	}
+	return idx
+}
+
+// variationColor цвет вариации, совпадающий с цветом из имени файла
+func variationColor(variation *models.ProductVariation, color string) (string, bool) {
+	for _, c := range variation.Colors {
+		if strings.EqualFold(strings.TrimSpace(c), color) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// match находит вариацию и цвет для имени файла без расширения
+func (idx *imageImportIndex) match(base string) (*models.ProductVariation, string, int, error) {
+	var matchErr error
+	for _, name := range ImageImportNameCandidates(base) {
+		variations := idx.byCode[strings.ToLower(name.Code)]
+		if len(variations) == 0 {
+			continue
+		}
+
+		if name.Color != "" {
+			var found *models.ProductVariation
+			var color string
+			for _, variation := range variations {
+				if c, ok := variationColor(variation, name.Color); ok {
+					if found != nil {
+						return nil, "", 0, fmt.Errorf("SKU %s с цветом %s есть у нескольких вариаций", name.Code, name.Color)
+					}
+					found, color = variation, c
+				}
+			}
+			if found == nil {
+				matchErr = fmt.Errorf("у вариации %s нет цвета %s", name.Code, name.Color)
+				continue
+			}
+			return found, color, name.Index, nil
+		}
+
+		if len(variations) > 1 {
+			matchErr = fmt.Errorf("SKU %s есть у нескольких вариаций, укажите цвет: SKU_цвет_N", name.Code)
+			continue
+		}
+		if len(variations[0].Colors) == 0 {
+			return nil, "", 0, fmt.Errorf("у вариации %s не указаны цвета", name.Code)
+		}
+		return variations[0], variations[0].Colors[0], name.Index, nil
+	}
+	if matchErr != nil {
+		return nil, "", 0, matchErr
+	}
+	return nil, "", 0, errors.New("не найдена вариация с таким SKU или штрих-кодом")
+}
+
+// imageImportFile фото из архива, сопоставленное с вариацией
+type imageImportFile struct {
+	entry *zip.File
+	name  string
+	color string
+	index int
+}
+
+// activeImageImports незавершенные задачи импорта
+func activeImageImports(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.ImageImportJob{}).
+		Where("status IN ?", []models.ImageImportStatus{models.ImageImportStatusPending, models.ImageImportStatusProcessing})
+}
+
+// failImageImports завершает задачи с ошибкой о прерванном импорте
+func failImageImports(query *gorm.DB, now time.Time) *gorm.DB {
+	return query.Updates(map[string]interface{}{
+		"status":      models.ImageImportStatusFailed,
+		"error":       imageImportInterrupted,
+		"finished_at": now,
+	})
+}
+
+// CreateImageImportJob создает задачу импорта, если у магазина нет незавершенной.
+// Задачи упавших экземпляров (без сигнала дольше imageImportStaleAfter) не мешают новой.
+func CreateImageImportJob(db *gorm.DB, job *models.ImageImportJob) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "mm_image_import:"+job.ShopID.String()).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := failImageImports(activeImageImports(tx).
+			Where("shop_id = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)", job.ShopID, now.Add(-imageImportStaleAfter)), now).Error; err != nil {
+			return err
+		}
+
+		var active int64
+		if err := activeImageImports(tx).Where("shop_id = ?", job.ShopID).Count(&active).Error; err != nil {
+			return err
+		}
+		if active > 0 {
+			return ErrImageImportInProgress
+		}
+		job.Instance = imageImportInstance
+		job.HeartbeatAt = &now
+		return tx.Create(job).Error
+	})
+}
+
+// FailInterruptedImageImports помечает задачи, прерванные перезапуском сервера:
+// архив хранится во временном файле и после перезапуска недоступен. Завершаются
+// только задачи этого экземпляра и задачи, обработчик которых перестал подавать
+// сигнал; импорты других работающих экземпляров не затрагиваются.
+func FailInterruptedImageImports(db *gorm.DB) {
+	now := time.Now()
+	result := failImageImports(activeImageImports(db).
+		Where("instance = ? OR heartbeat_at IS NULL OR heartbeat_at < ?", imageImportInstance, now.Add(-imageImportStaleAfter)), now)
+	if result.Error != nil {
+		log.Printf("⚠️ Не удалось завершить прерванные импорты фото: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("⚠️ Прервано импортов фото после перезапуска: %d", result.RowsAffected)
+	}
+}
+
+// RunImageImport обрабатывает архив задачи: сопоставляет фото с вариациями магазина,
+// сохраняет их и заполняет ImageURLsByColor. Архив удаляется после обработки.
+func RunImageImport(ctx context.Context, db *gorm.DB, jobID uuid.UUID, archivePath string, cfg ImageImportConfig) {
+	defer os.Remove(archivePath)
+
+	var job models.ImageImportJob
+	if err := db.First(&job, "id = ?", jobID).Error; err != nil {
+		log.Printf("❌ Задача импорта фото %s не найдена: %v", jobID, err)
+		return
+	}
+
+	now := time.Now()
+	job.Status = models.ImageImportStatusProcessing
+	job.StartedAt = &now
+	saveImageImportJob(db, &job)
+
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	defer stopHeartbeat()
+	go imageImportHeartbeat(heartbeatCtx, db, job.ID)
+
+	if err := runImageImport(ctx, db, &job, archivePath, cfg); err != nil {
+		log.Printf("❌ Импорт фото %s не выполнен: %v", job.ID, err)
+		job.Status = models.ImageImportStatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = models.ImageImportStatusCompleted
+		log.Printf("✅ Импорт фото %s: загружено %d из %d, пропущено %d, ошибок %d, вариаций обновлено %d",
+			job.ID, job.ImportedFiles, job.TotalFiles, job.SkippedFiles, job.FailedFiles, job.UpdatedVariations)
+	}
+	finished := time.Now()
+	job.FinishedAt = &finished
+	saveImageImportJob(db, &job)
+}
+
+// imageImportHeartbeat отмечает, что задача обрабатывается, пока импорт не завершится
+func imageImportHeartbeat(ctx context.Context, db *gorm.DB, jobID uuid.UUID) {
+	ticker := time.NewTicker(imageImportHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := db.Model(&models.ImageImportJob{}).Where("id = ?", jobID).
+			UpdateColumn("heartbeat_at", time.Now()).Error; err != nil {
+			log.Printf("⚠️ Не удалось отметить импорт фото %s: %v", jobID, err)
+		}
+	}
+}
+
+func saveImageImportJob(db *gorm.DB, job *models.ImageImportJob) {
+	if err := db.Model(job).Select(
+		"Status", "TotalFiles", "ProcessedFiles", "ImportedFiles", "SkippedFiles", "FailedFiles",
+		"UpdatedVariations", "Errors", "Error", "StartedAt", "FinishedAt",
+	).Updates(job).Error; err != nil {
+		log.Printf("⚠️ Не удалось сохранить прогресс импорта фото %s: %v", job.ID, err)
+	}
+}
+
+func runImageImport(ctx context.Context, db *gorm.DB, job *models.ImageImportJob, archivePath string, cfg ImageImportConfig) error {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("invalid ZIP archive: %w", err)
+	}
+	defer archive.Close()
+
+	// Фото из архива (папки, служебные файлы macOS и прочие файлы пропускаются)
+	var entries []*zip.File
+	for _, entry := range archive.File {
+		name := path.Base(entry.Name)
+		if entry.FileInfo().IsDir() || strings.HasPrefix(entry.Name, "__MACOSX/") || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if !ImageImportExtensions[strings.ToLower(path.Ext(name))] {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return errors.New("archive contains no images")
+	}
+	if cfg.MaxFiles > 0 && len(entries) > cfg.MaxFiles {
+		return fmt.Errorf("archive contains %d images, maximum is %d", len(entries), cfg.MaxFiles)
+	}
+	job.TotalFiles = len(entries)
+
+	var variations []models.ProductVariation
+	if err := db.Where("product_id IN (?)", db.Model(&models.Product{}).Select("id").Where("shop_id = ?", job.ShopID)).
+		Find(&variations).Error; err != nil {
+		return fmt.Errorf("failed to load variations: %w", err)
+	}
+	index := newImageImportIndex(variations)
+
+	skip := func(file, reason string) {
+		job.SkippedFiles++
+		job.ProcessedFiles++
+		job.AddError(file, reason)
+	}
+
+	// Сопоставляем файлы с вариациями
+	byVariation := make(map[uuid.UUID][]imageImportFile)
+	var order []uuid.UUID
+	for _, entry := range entries {
+		name := path.Base(entry.Name)
+		if cfg.MaxFileSize > 0 && entry.UncompressedSize64 > uint64(cfg.MaxFileSize) {
+			skip(name, fmt.Sprintf("файл больше %d МБ", cfg.MaxFileSize/(1024*1024)))
+			continue
+		}
+		variation, color, n, err := index.match(strings.TrimSuffix(name, path.Ext(name)))
+		if err != nil {
+			skip(name, err.Error())
+			continue
+		}
+		if _, ok := byVariation[variation.ID]; !ok {
+			order = append(order, variation.ID)
+		}
+		byVariation[variation.ID] = append(byVariation[variation.ID], imageImportFile{entry: entry, name: name, color: color, index: n})
+	}
+	saveImageImportJob(db, job)
+
+	maxImages := cfg.MaxImages
+	if maxImages <= 0 {
+		maxImages = 2
+	}
+
+	lastSaved := job.ProcessedFiles
+	for _, variationID := range order {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		files := byVariation[variationID]
+		sort.SliceStable(files, func(i, j int) bool {
+			if files[i].color != files[j].color {
+				return files[i].color < files[j].color
+			}
+			// Файлы с номером - по номеру, без номера - после них по имени
+			if (files[i].index == 0) != (files[j].index == 0) {
+				return files[j].index == 0
+			}
+			if files[i].index != files[j].index {
+				return files[i].index < files[j].index
+			}
+			return files[i].name < files[j].name
+		})
+
+		// Сохраняем фото вариации с учетом лимита на цвет
+		existing := variationByID(variations, variationID).ImageURLsByColor
+		imported := make(map[string][]string)
+		for _, file := range files {
+			free := maxImages - len(imported[file.color])
+			if job.Mode == models.ImageImportModeAppend {
+				free -= len(existing[file.color])
+			}
+			if free <= 0 {
+				skip(file.name, fmt.Sprintf("превышен лимит %d фото на цвет %s", maxImages, file.color))
+				continue
+			}
+
+			url, err := saveImageImportFile(ctx, file, cfg)
+			job.ProcessedFiles++
+			if err != nil {
+				job.FailedFiles++
+				job.AddError(file.name, err.Error())
+			} else {
+				job.ImportedFiles++
+				imported[file.color] = append(imported[file.color], url)
+			}
+			if job.ProcessedFiles-lastSaved >= imageImportSaveEvery {
+				saveImageImportJob(db, job)
+				lastSaved = job.ProcessedFiles
+			}
+		}
+
+		if len(imported) == 0 {
+			continue
+		}
+		if err := applyImportedImages(db, variationID, imported, job.Mode, maxImages); err != nil {
+			log.Printf("❌ Не удалось обновить фото вариации %s: %v", variationID, err)
+			for _, urls := range imported {
+				job.ImportedFiles -= len(urls)
+				job.FailedFiles += len(urls)
+				ReleaseMedia(db, urls...)
+			}
+			job.AddError(variationID.String(), "не удалось сохранить фото вариации: "+err.Error())
+			continue
+		}
+		job.UpdatedVariations++
+	}
+	return nil
+}
+
+func variationByID(variations []models.ProductVariation, id uuid.UUID) *models.ProductVariation {
+	for i := range variations {
+		if variations[i].ID == id {
+			return &variations[i]
+		}
+	}
+	return &models.ProductVariation{}
+}
+
+// saveImageImportFile читает фото из архива и передает его в ImageImportConfig.Save
+func saveImageImportFile(ctx context.Context, file imageImportFile, cfg ImageImportConfig) (string, error) {
+	reader, err := file.entry.Open()
+	if err != nil {
+		return "", fmt.Errorf("не удалось прочитать файл: %w", err)
+	}
+	defer reader.Close()
+
+	limit := cfg.MaxFileSize
+	if limit <= 0 {
+		limit = 1 << 30
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return "", fmt.Errorf("не удалось прочитать файл: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return "", fmt.Errorf("файл больше %d МБ", limit/(1024*1024))
+	}
+	return cfg.Save(ctx, file.name, data)
+}
+
+// applyImportedImages записывает загруженные фото в ImageURLsByColor вариации.
+// Замененные фото освобождаются для сборщика мусора медиа.
+func applyImportedImages(db *gorm.DB, variationID uuid.UUID, imported map[string][]string, mode string, maxImages int) error {
+	var released []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var variation models.ProductVariation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&variation, "id = ?", variationID).Error; err != nil {
+			return err
+		}
+		if variation.ImageURLsByColor == nil {
+			variation.ImageURLsByColor = make(map[string][]string)
+		}
+		for color, urls := range imported {
+			if mode == models.ImageImportModeAppend {
+				urls = append(append([]string{}, variation.ImageURLsByColor[color]...), urls...)
+				if len(urls) > maxImages {
+					released = append(released, urls[maxImages:]...)
+					urls = urls[:maxImages]
+				}
+			} else {
+				released = append(released, variation.ImageURLsByColor[color]...)
+			}
+			variation.ImageURLsByColor[color] = urls
+		}
+		return tx.Model(&variation).Select("ImageURLsByColor").Updates(&variation).Error
+	})
+	if err != nil {
+		return err
+	}
+	ReleaseMedia(db, released...)
+	return nil
+}
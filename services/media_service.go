@@ -0,0 +1,598 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/config"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/storage"
+	"github.com/mm-api/mm-api/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrMediaSweepInProgress сборка мусора уже выполняется другим экземпляром
+var ErrMediaSweepInProgress = errors.New("media sweep is already running")
+
+// DefaultMediaGCFolders папки images/, файлы в которых принадлежат сущностям приложения.
+// Файлы других папок (например uploads) учитываются в реестре, но не удаляются.
+var DefaultMediaGCFolders = []string{
+	"products", "variations", "avatars", "users", "shop-logos",
+	"reviews", "categories", "notifications", "campaigns",
+}
+
+// mediaSweepBatchSize сколько файлов удаляется за один проход
+const mediaSweepBatchSize = 500
+
+// MediaSweepConfig настройки сборщика мусора медиа
+type MediaSweepConfig struct {
+	Interval    time.Duration // Интервал запуска
+	GracePeriod time.Duration // Сколько файл должен пробыть без ссылок до удаления
+	DryRun      bool          // Только отчет, без удаления
+	Discover    bool          // Добавлять в реестр файлы хранилища, загруженные до его появления
+	Folders     []string      // Папки, из которых разрешено удалять
+}
+
+// cloudinaryVersionSegment сегмент версии в адресе Cloudinary (v1712345678)
+var cloudinaryVersionSegment = regexp.MustCompile(`^v\d+$`)
+
+// MediaKeyFromURL возвращает ключ реестра для адреса файла: images/... для хранилища
+// приложения (относительные и абсолютные адреса), cloudinary:<public_id> для Cloudinary.
+// Для внешних ссылок и уменьшенных копий возвращает пустую строку.
+func MediaKeyFromURL(rawURL string) (string, string) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", ""
+	}
+
+	if strings.Contains(rawURL, "res.cloudinary.com") {
+		if publicID := cloudinaryPublicID(rawURL); publicID != "" {
+			return models.MediaCloudinaryKeyPrefix + publicID, models.MediaProviderCloudinary
+		}
+		return "", ""
+	}
+
+	if parsed, err := url.Parse(rawURL); err == nil {
+		rawURL = parsed.Path
+	}
+	i := strings.Index(rawURL, "images/")
+	if i < 0 || (i > 0 && rawURL[i-1] != '/') {
+		return "", ""
+	}
+	key, err := storage.CleanKey(rawURL[i:])
+	if err != nil || strings.HasPrefix(key, utils.ImageVariantPrefix) || strings.HasSuffix(key, "/") {
+		return "", ""
+	}
+	return key, models.MediaProviderStorage
+}
+
+// OwnMediaKey возвращает ключ медиа, только если адрес указывает на файл, загруженный
+// через приложение: относительный путь /images/..., постоянный адрес хранилища или
+// облако Cloudinary проекта. Для остальных адресов возвращает пустую строку.
+func OwnMediaKey(rawURL string) string {
+	key, provider := MediaKeyFromURL(rawURL)
+	if key == "" {
+		return ""
+	}
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+
+	if parsed.Scheme == "" && parsed.Host == "" {
+		if provider == models.MediaProviderStorage && strings.HasPrefix(parsed.Path, "/images/") {
+			return key
+		}
+		return ""
+	}
+
+	switch provider {
+	case models.MediaProviderCloudinary:
+		cloudName := config.GetConfig().CloudinaryCloudName
+		if parsed.Scheme == "https" && parsed.Host == "res.cloudinary.com" &&
+			cloudName != "" && strings.HasPrefix(parsed.Path, "/"+cloudName+"/") {
+			return key
+		}
+	case models.MediaProviderStorage:
+		// Абсолютный адрес принимается, только если хранилище само выдает такие (S3_PUBLIC_URL)
+		store := storage.Default()
+		if store == nil {
+			return ""
+		}
+		own, err := url.Parse(store.URL(key))
+		if err == nil && own.Host != "" && own.Scheme == parsed.Scheme && own.Host == parsed.Host {
+			return key
+		}
+	}
+	return ""
+}
+
+// cloudinaryPublicID извлекает public_id из адреса Cloudinary:
+// .../image/upload/<трансформации>/v123/<public_id>.<ext>
+func cloudinaryPublicID(rawURL string) string {
+	if parsed, err := url.Parse(rawURL); err == nil {
+		rawURL = parsed.Path
+	}
+	i := strings.Index(rawURL, "/upload/")
+	if i < 0 {
+		return ""
+	}
+	segments := strings.Split(rawURL[i+len("/upload/"):], "/")
+
+	start := 0
+	for j, segment := range segments {
+		if cloudinaryVersionSegment.MatchString(segment) {
+			start = j + 1
+			break
+		}
+	}
+	if start == 0 {
+		// Без версии пропускаем трансформации (w_400,h_400,c_fit)
+		for start < len(segments)-1 && strings.Contains(segments[start], "_") &&
+			(strings.Contains(segments[start], ",") || len(strings.SplitN(segments[start], "_", 2)[0]) <= 2) {
+			start++
+		}
+	}
+
+	publicID := strings.Join(segments[start:], "/")
+	return strings.TrimSuffix(publicID, path.Ext(publicID))
+}
+
+// mediaFolder папка файла: images/<папка>/... или <папка>/<id> для Cloudinary
+func mediaFolder(key string) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(key, models.MediaCloudinaryKeyPrefix), "images/")
+	if i := strings.Index(rel, "/"); i > 0 {
+		return rel[:i]
+	}
+	return ""
+}
+
+// RegisterMediaUpload добавляет загруженный файл в реестр медиа. Ошибки
+// только логируются: загрузка не должна падать из-за реестра.
+func RegisterMediaUpload(db *gorm.DB, fileURL string, size int64, contentType string, uploadedBy *uuid.UUID) {
+	key, provider := MediaKeyFromURL(fileURL)
+	if key == "" || db == nil {
+		return
+	}
+
+	asset := models.MediaAsset{
+		Key:         key,
+		URL:         fileURL,
+		Provider:    provider,
+		Folder:      mediaFolder(key),
+		Size:        size,
+		ContentType: contentType,
+		UploadedBy:  uploadedBy,
+	}
+	if err := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "key"}}, DoNothing: true}).Create(&asset).Error; err != nil {
+		log.Printf("⚠️ Не удалось добавить %s в реестр медиа: %v", key, err)
+	}
+}
+
+// ReleaseMedia отмечает, что сущность перестала ссылаться на файлы (товар удален,
+// фото заменено). Период ожидания начинается сразу; перед удалением сборщик
+// мусора все равно проверяет, что ссылок на файл не осталось.
+func ReleaseMedia(db *gorm.DB, urls ...string) {
+	keys := make([]string, 0, len(urls))
+	for _, fileURL := range urls {
+		if key, _ := MediaKeyFromURL(fileURL); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 || db == nil {
+		return
+	}
+
+	if err := db.Model(&models.MediaAsset{}).
+		Where("key IN ? AND orphaned_at IS NULL", keys).
+		Update("orphaned_at", time.Now()).Error; err != nil {
+		log.Printf("⚠️ Не удалось отметить освобожденные файлы: %v", err)
+	}
+}
+
+// VariationImageURLs все фото вариаций товара (imageUrls и imageUrlsByColor)
+func VariationImageURLs(variations []models.ProductVariation) []string {
+	var urls []string
+	for _, variation := range variations {
+		urls = append(urls, variation.ImageURLs...)
+		for _, colorURLs := range variation.ImageURLsByColor {
+			urls = append(urls, colorURLs...)
+		}
+	}
+	return urls
+}
+
+// mediaReferenceSet ссылки на файлы, собранные по таблицам сущностей
+type mediaReferenceSet struct {
+	refs map[string][]models.MediaReference // Ключ файла -> ссылки
+	urls map[string]string                  // Ключ файла -> адрес (для новых записей реестра)
+	seen map[string]bool
+}
+
+func (s *mediaReferenceSet) add(fileURL, entityType string, entityID uuid.UUID, field string) {
+	key, _ := MediaKeyFromURL(fileURL)
+	if key == "" {
+		return
+	}
+	dedup := key + "|" + entityType + "|" + entityID.String() + "|" + field
+	if s.seen[dedup] {
+		return
+	}
+	s.seen[dedup] = true
+	s.refs[key] = append(s.refs[key], models.MediaReference{EntityType: entityType, EntityID: entityID, Field: field})
+	if _, ok := s.urls[key]; !ok {
+		s.urls[key] = fileURL
+	}
+}
+
+// collectMediaReferences собирает ссылки на файлы из всех сущностей
+func collectMediaReferences(db *gorm.DB) (*mediaReferenceSet, error) {
+	set := &mediaReferenceSet{
+		refs: make(map[string][]models.MediaReference),
+		urls: make(map[string]string),
+		seen: make(map[string]bool),
+	}
+
+	var variations []models.ProductVariation
+	if err := db.Model(&models.ProductVariation{}).Select("id", "image_urls", "image_urls_by_color").
+		FindInBatches(&variations, 1000, func(tx *gorm.DB, batch int) error {
+			for _, variation := range variations {
+				for _, imageURL := range variation.ImageURLs {
+					set.add(imageURL, models.MediaEntityProductVariation, variation.ID, "image_urls")
+				}
+				for color, colorURLs := range variation.ImageURLsByColor {
+					for _, imageURL := range colorURLs {
+						set.add(imageURL, models.MediaEntityProductVariation, variation.ID, "image_urls_by_color."+color)
+					}
+				}
+			}
+			return nil
+		}).Error; err != nil {
+		return nil, fmt.Errorf("ошибка чтения фото вариаций: %w", err)
+	}
+
+	var reviews []models.ProductReview
+	if err := db.Model(&models.ProductReview{}).Select("id", "photos").
+		FindInBatches(&reviews, 1000, func(tx *gorm.DB, batch int) error {
+			for _, review := range reviews {
+				for _, photo := range review.Photos {
+					set.add(photo, models.MediaEntityReview, review.ID, "photos")
+				}
+			}
+			return nil
+		}).Error; err != nil {
+		return nil, fmt.Errorf("ошибка чтения фото отзывов: %w", err)
+	}
+
+	// Сущности с одним полем-адресом
+	columns := []struct {
+		model      interface{}
+		entityType string
+		column     string
+	}{
+		{&models.User{}, models.MediaEntityUser, "avatar"},
+		{&models.Shop{}, models.MediaEntityShop, "logo"},
+		{&models.Category{}, models.MediaEntityCategory, "icon_url"},
+		{&models.Notification{}, models.MediaEntityNotification, "image_url"},
+		{&models.NotificationOutbox{}, models.MediaEntityNotificationOutbox, "image_url"},
+		{&models.Campaign{}, models.MediaEntityCampaign, "image_url"},
+		{&models.OrderItem{}, models.MediaEntityOrderItem, "image_url"},
+	}
+	for _, source := range columns {
+		var rows []struct {
+			ID  uuid.UUID
+			URL string
+		}
+		if err := db.Model(source.model).
+			Select("id, " + source.column + " AS url").
+			Where(source.column + " <> ''").
+			Scan(&rows).Error; err != nil {
+			return nil, fmt.Errorf("ошибка чтения %s (%s): %w", source.entityType, source.column, err)
+		}
+		for _, row := range rows {
+			set.add(row.URL, source.entityType, row.ID, source.column)
+		}
+	}
+
+	return set, nil
+}
+
+// discoverStorageMedia добавляет в реестр файлы images/, загруженные до его появления
+func discoverStorageMedia(ctx context.Context, db *gorm.DB, store storage.Storage) (int, error) {
+	var known []string
+	if err := db.Model(&models.MediaAsset{}).Where("provider = ?", models.MediaProviderStorage).Pluck("key", &known).Error; err != nil {
+		return 0, err
+	}
+	registered := make(map[string]bool, len(known))
+	for _, key := range known {
+		registered[key] = true
+	}
+
+	var assets []models.MediaAsset
+	err := store.List(ctx, "images/", func(obj storage.Object) error {
+		if registered[obj.Key] || strings.HasPrefix(obj.Key, utils.ImageVariantPrefix) {
+			return nil
+		}
+		assets = append(assets, models.MediaAsset{
+			Key:         obj.Key,
+			URL:         "/" + obj.Key,
+			Provider:    models.MediaProviderStorage,
+			Folder:      mediaFolder(obj.Key),
+			Size:        obj.Size,
+			ContentType: obj.ContentType,
+			CreatedAt:   obj.ModTime,
+		})
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ошибка чтения списка файлов: %w", err)
+	}
+	if len(assets) == 0 {
+		return 0, nil
+	}
+
+	if err := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "key"}}, DoNothing: true}).
+		CreateInBatches(&assets, 500).Error; err != nil {
+		return 0, fmt.Errorf("ошибка добавления файлов в реестр: %w", err)
+	}
+	return len(assets), nil
+}
+
+// markMediaReferences пересобирает таблицу ссылок и отмечает файлы без ссылок.
+// Файлы, на которые ссылаются сущности, но которых нет в реестре, добавляются в него.
+// Вызывается в транзакции под блокировкой сборщика мусора.
+func markMediaReferences(tx *gorm.DB, set *mediaReferenceSet, now time.Time) (int, error) {
+	total := 0
+	missing := make([]models.MediaAsset, 0)
+	keys := make([]string, 0, len(set.refs))
+	for key := range set.refs {
+		keys = append(keys, key)
+	}
+	assetIDs := make(map[string]uuid.UUID, len(keys))
+	for start := 0; start < len(keys); start += 1000 {
+		end := start + 1000
+		if end > len(keys) {
+			end = len(keys)
+		}
+		var assets []models.MediaAsset
+		if err := tx.Select("id", "key").Where("key IN ?", keys[start:end]).Find(&assets).Error; err != nil {
+			return 0, err
+		}
+		for _, asset := range assets {
+			assetIDs[asset.Key] = asset.ID
+		}
+	}
+	for _, key := range keys {
+		if _, ok := assetIDs[key]; ok {
+			continue
+		}
+		provider := models.MediaProviderStorage
+		if strings.HasPrefix(key, models.MediaCloudinaryKeyPrefix) {
+			provider = models.MediaProviderCloudinary
+		}
+		asset := models.MediaAsset{ID: uuid.New(), Key: key, URL: set.urls[key], Provider: provider, Folder: mediaFolder(key)}
+		assetIDs[key] = asset.ID
+		missing = append(missing, asset)
+	}
+	if len(missing) > 0 {
+		if err := tx.CreateInBatches(&missing, 500).Error; err != nil {
+			return 0, fmt.Errorf("ошибка добавления файлов в реестр: %w", err)
+		}
+	}
+
+	if err := tx.Exec("DELETE FROM media_references").Error; err != nil {
+		return 0, fmt.Errorf("ошибка очистки ссылок: %w", err)
+	}
+	references := make([]models.MediaReference, 0, len(set.seen))
+	for key, refs := range set.refs {
+		for _, ref := range refs {
+			ref.ID = uuid.New()
+			ref.AssetID = assetIDs[key]
+			ref.CreatedAt = now
+			references = append(references, ref)
+		}
+	}
+	total = len(references)
+	if len(references) > 0 {
+		if err := tx.CreateInBatches(&references, 1000).Error; err != nil {
+			return 0, fmt.Errorf("ошибка сохранения ссылок: %w", err)
+		}
+	}
+
+	if err := tx.Exec(`UPDATE media_assets SET reference_count = r.cnt, last_referenced_at = @now, orphaned_at = NULL, updated_at = @now
+			FROM (SELECT asset_id, COUNT(*) AS cnt FROM media_references GROUP BY asset_id) r
+			WHERE media_assets.id = r.asset_id`, map[string]interface{}{"now": now}).Error; err != nil {
+		return 0, fmt.Errorf("ошибка подсчета ссылок: %w", err)
+	}
+	if err := tx.Exec(`UPDATE media_assets SET reference_count = 0, orphaned_at = COALESCE(orphaned_at, @now), updated_at = @now
+			WHERE NOT EXISTS (SELECT 1 FROM media_references r WHERE r.asset_id = media_assets.id)
+			AND (reference_count <> 0 OR orphaned_at IS NULL)`, map[string]interface{}{"now": now}).Error; err != nil {
+		return 0, fmt.Errorf("ошибка отметки файлов без ссылок: %w", err)
+	}
+	return total, nil
+}
+
+// deleteMediaAsset удаляет файл (и его уменьшенные копии) из хранилища или Cloudinary
+func deleteMediaAsset(ctx context.Context, store storage.Storage, asset *models.MediaAsset) error {
+	if asset.Provider == models.MediaProviderCloudinary {
+		cfg := config.GetConfig()
+		if cfg.CloudinaryCloudName == "" || cfg.CloudinaryAPIKey == "" || cfg.CloudinaryAPISecret == "" {
+			return fmt.Errorf("Cloudinary API не настроен")
+		}
+		processor := utils.NewCloudinaryProcessor(cfg.CloudinaryCloudName, cfg.CloudinaryAPIKey, cfg.CloudinaryAPISecret, cfg.CloudinaryUploadPreset)
+		return processor.DeleteImage(strings.TrimPrefix(asset.Key, models.MediaCloudinaryKeyPrefix))
+	}
+
+	if err := store.Delete(ctx, asset.Key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	for _, rendition := range utils.ImageRenditions {
+		for _, format := range []string{utils.ImageFormatJPEG, utils.ImageFormatWebP} {
+			variantKey := utils.ImageVariantKey(asset.Key, rendition.Width, format)
+			if err := store.Delete(ctx, variantKey); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				log.Printf("⚠️ Не удалось удалить копию %s: %v", variantKey, err)
+			}
+		}
+	}
+	return nil
+}
+
+// SweepOrphanedMedia проход сборщика мусора: пересобирает ссылки на файлы
+// и удаляет файлы, на которые никто не ссылается дольше периода ожидания.
+// В режиме DryRun реестр обновляется, но файлы не удаляются.
+func SweepOrphanedMedia(ctx context.Context, db *gorm.DB, cfg MediaSweepConfig) (*models.MediaSweepReport, error) {
+	if cfg.GracePeriod <= 0 {
+		cfg.GracePeriod = 7 * 24 * time.Hour
+	}
+	if len(cfg.Folders) == 0 {
+		cfg.Folders = DefaultMediaGCFolders
+	}
+
+	now := time.Now()
+	report := &models.MediaSweepReport{
+		DryRun:      cfg.DryRun,
+		GracePeriod: cfg.GracePeriod.String(),
+		Candidates:  []models.MediaAsset{},
+		StartedAt:   now,
+	}
+	store := storage.Default()
+
+	if cfg.Discover {
+		discovered, err := discoverStorageMedia(ctx, db, store)
+		if err != nil {
+			return nil, err
+		}
+		report.Discovered = discovered
+	}
+
+	// Блокировка удерживается до конца удаления: параллельный проход не
+	// пересоберет ссылки, пока этот удаляет файлы
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(hashtext('mm_media_sweep'))").Scan(&locked).Error; err != nil {
+			return fmt.Errorf("ошибка блокировки сборки мусора: %w", err)
+		}
+		if !locked {
+			return ErrMediaSweepInProgress
+		}
+
+		set, err := collectMediaReferences(tx)
+		if err != nil {
+			return err
+		}
+		if report.References, err = markMediaReferences(tx, set, now); err != nil {
+			return err
+		}
+
+		tx.Model(&models.MediaAsset{}).Count(&report.Assets)
+		tx.Model(&models.MediaAsset{}).Where("reference_count = 0").Count(&report.Orphaned)
+
+		cutoff := now.Add(-cfg.GracePeriod)
+		var candidates []models.MediaAsset
+		if err := tx.Where("reference_count = 0 AND orphaned_at <= ? AND created_at <= ? AND folder IN ?", cutoff, cutoff, cfg.Folders).
+			Order("orphaned_at").Limit(mediaSweepBatchSize).Find(&candidates).Error; err != nil {
+			return fmt.Errorf("ошибка выбора файлов для удаления: %w", err)
+		}
+		report.Expired = len(candidates)
+		if cfg.DryRun {
+			for _, asset := range candidates {
+				report.Candidates = append(report.Candidates, asset)
+				report.FreedBytes += asset.Size
+			}
+			return nil
+		}
+
+		// Сущности могли сослаться на файл после сбора ссылок - собираем их повторно
+		// непосредственно перед удалением
+		if len(candidates) > 0 {
+			if set, err = collectMediaReferences(tx); err != nil {
+				return err
+			}
+		}
+
+		for i := range candidates {
+			asset := &candidates[i]
+			if _, referenced := set.refs[asset.Key]; referenced {
+				continue
+			}
+
+			// Сначала удаляется запись реестра, затем файл. Если файл удалить не
+			// удалось, точка сохранения откатывается и запись остается до следующего прохода.
+			err := tx.Transaction(func(inner *gorm.DB) error {
+				result := inner.Where("id = ? AND reference_count = 0", asset.ID).Delete(&models.MediaAsset{})
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					return gorm.ErrRecordNotFound
+				}
+				return deleteMediaAsset(ctx, store, asset)
+			})
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			if err != nil {
+				log.Printf("⚠️ Не удалось удалить файл %s: %v", asset.Key, err)
+				report.Failed++
+				continue
+			}
+			report.Candidates = append(report.Candidates, *asset)
+			report.Deleted++
+			report.FreedBytes += asset.Size
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// StartMediaSweeper запускает периодическую сборку мусора медиа
+func StartMediaSweeper(ctx context.Context, cfg MediaSweepConfig) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 24 * time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if database.DB == nil {
+				continue
+			}
+			report, err := SweepOrphanedMedia(ctx, database.DB, cfg)
+			if errors.Is(err, ErrMediaSweepInProgress) {
+				log.Printf("ℹ️ Media GC: сборка уже выполняется другим экземпляром")
+				continue
+			}
+			if err != nil {
+				log.Printf("⚠️ Media GC: %v", err)
+				continue
+			}
+			log.Printf("✅ Media GC: файлов %d, без ссылок %d, удалено %d (%d байт), ошибок %d, dry-run %v",
+				report.Assets, report.Orphaned, report.Deleted, report.FreedBytes, report.Failed, report.DryRun)
+		}
+	}()
+
+	log.Printf("✅ Media GC запущен (интервал: %s, период ожидания: %s)", cfg.Interval, cfg.GracePeriod)
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/models"
+	"gorm.io/gorm"
+)
+
+// DispatchResult описывает, по каким каналам было отправлено уведомление
+type DispatchResult struct {
+	Stored     bool       // Уведомление сохранено в списке уведомлений (in_app)
+	Queued     bool       // Push-уведомление поставлено в очередь
+	DeferUntil *time.Time // Push отложен до окончания тихих часов
+}
+
+// DispatchNotification единая точка отправки уведомлений пользователю.
+// Учитывает глобальные флаги UserSettings, предпочтения по типу и каналу
+// и тихие часы. Вызывается в транзакции бизнес-изменения.
+func DispatchNotification(tx *gorm.DB, notification *models.Notification) (DispatchResult, error) {
+	var result DispatchResult
+
+	settings, prefs, err := loadNotificationSettings(tx, notification.UserID)
+	if err != nil {
+		return result, err
+	}
+
+	if channelEnabled(prefs, notification.Type, models.NotificationChannelInApp) {
+		if err := tx.Create(notification).Error; err != nil {
+			return result, fmt.Errorf("ошибка создания уведомления: %w", err)
+		}
+		result.Stored = true
+		PublishRealtime(tx, notification.UserID, RealtimeEventNotificationCreated, notification.ToResponse())
+	}
+
+	if !pushAllowed(settings, prefs, notification.Type) {
+		return result, nil
+	}
+
+	entry := &models.NotificationOutbox{
+		UserID:    notification.UserID,
+		Title:     notification.Title,
+		Body:      notification.Body,
+		ActionURL: notification.ActionURL,
+	}
+	if strings.HasPrefix(notification.ImageURL, "http://") || strings.HasPrefix(notification.ImageURL, "https://") {
+		entry.ImageURL = notification.ImageURL
+	}
+	if result.Stored {
+		entry.NotificationID = &notification.ID
+	}
+	result.DeferUntil = applyQuietHours(settings, notification.Type, entry)
+
+	if err := enqueueOutboxEntry(tx, entry, notificationData(notification)); err != nil {
+		return result, err
+	}
+	result.Queued = true
+	return result, nil
+}
+
+// DispatchPush отправляет только push-уведомление (без записи в список уведомлений),
+// соблюдая те же настройки, что и DispatchNotification
+func DispatchPush(tx *gorm.DB, userID uuid.UUID, notificationType models.NotificationType, title, body, actionURL string, data map[string]string) (DispatchResult, error) {
+	var result DispatchResult
+
+	settings, prefs, err := loadNotificationSettings(tx, userID)
+	if err != nil {
+		return result, err
+	}
+	if !pushAllowed(settings, prefs, notificationType) {
+		return result, nil
+	}
+
+	entry := &models.NotificationOutbox{
+		UserID:    userID,
+		Title:     title,
+		Body:      body,
+		ActionURL: actionURL,
+	}
+	result.DeferUntil = applyQuietHours(settings, notificationType, entry)
+
+	if err := enqueueOutboxEntry(tx, entry, data); err != nil {
+		return result, err
+	}
+	result.Queued = true
+	return result, nil
+}
+
+// notificationData извлекает строковые поля из JSON-данных уведомления для push-сообщения
+func notificationData(notification *models.Notification) map[string]string {
+	if notification.Data == "" {
+		return nil
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(notification.Data), &raw); err != nil {
+		return nil
+	}
+	data := make(map[string]string, len(raw))
+	for key, value := range raw {
+		if str, ok := value.(string); ok {
+			data[key] = str
+		}
+	}
+	return data
+}
+
+// loadNotificationSettings загружает настройки и предпочтения пользователя.
+// Если настроек нет, возвращаются значения по умолчанию (все включено, тихие часы выключены).
+func loadNotificationSettings(tx *gorm.DB, userID uuid.UUID) (models.UserSettings, []models.NotificationPreference, error) {
+	settings := models.UserSettings{
+		UserID:               userID,
+		NotificationsEnabled: true,
+		PushNotifications:    true,
+		EmailNotifications:   true,
+		Timezone:             models.DefaultTimezone,
+	}
+	if err := tx.Where("user_id = ?", userID).First(&settings).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return settings, nil, fmt.Errorf("ошибка загрузки настроек пользователя: %w", err)
+	}
+
+	var prefs []models.NotificationPreference
+	if err := tx.Where("user_id = ?", userID).Find(&prefs).Error; err != nil {
+		return settings, nil, fmt.Errorf("ошибка загрузки предпочтений уведомлений: %w", err)
+	}
+	return settings, prefs, nil
+}
+
+// channelEnabled проверяет предпочтение пользователя для типа и канала (по умолчанию включено)
+func channelEnabled(prefs []models.NotificationPreference, notificationType models.NotificationType, channel models.NotificationChannel) bool {
+	for _, pref := range prefs {
+		if pref.Type == notificationType && pref.Channel == channel {
+			return pref.Enabled
+		}
+	}
+	return true
+}
+
+// pushAllowed проверяет глобальные флаги и предпочтение для push-канала
+func pushAllowed(settings models.UserSettings, prefs []models.NotificationPreference, notificationType models.NotificationType) bool {
+	if !settings.NotificationsEnabled || !settings.PushNotifications {
+		return false
+	}
+	return channelEnabled(prefs, notificationType, models.NotificationChannelPush)
+}
+
+// applyQuietHours откладывает доставку до окончания тихих часов для типов, которые их соблюдают
+func applyQuietHours(settings models.UserSettings, notificationType models.NotificationType, entry *models.NotificationOutbox) *time.Time {
+	if notificationType.BypassesQuietHours() {
+		return nil
+	}
+	until, ok := settings.QuietHoursUntil(time.Now())
+	if !ok {
+		return nil
+	}
+	entry.NextAttemptAt = until
+	log.Printf("🌙 Push для пользователя %s отложен до %s (тихие часы)", entry.UserID, until.Format(time.RFC3339))
+	return &until
+}
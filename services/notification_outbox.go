@@ -0,0 +1,360 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// outboxBatchSize количество записей, захватываемых за один опрос
+	outboxBatchSize = 50
+	// outboxStaleTimeout время, после которого захваченная, но не завершенная запись считается брошенной
+	outboxStaleTimeout = 5 * time.Minute
+	// outboxBackoffBase начальная задержка перед повторной попыткой
+	outboxBackoffBase = 30 * time.Second
+	// outboxBackoffMax максимальная задержка перед повторной попыткой
+	outboxBackoffMax = time.Hour
+	// outboxDefaultMaxAttempts количество попыток по умолчанию
+	outboxDefaultMaxAttempts = 5
+)
+
+// OutboxWorkerConfig параметры пула воркеров очереди уведомлений
+type OutboxWorkerConfig struct {
+	Workers      int           // Количество параллельных воркеров
+	PollInterval time.Duration // Интервал опроса очереди
+	MaxAttempts  int           // Максимальное количество попыток доставки
+}
+
+// OutboxWorker доставляет записи NotificationOutbox через FCM
+type OutboxWorker struct {
+	config OutboxWorkerConfig
+	jobs   chan models.NotificationOutbox
+	wg     sync.WaitGroup
+}
+
+// enqueueOutboxEntry сохраняет запись очереди со статусом pending
+func enqueueOutboxEntry(tx *gorm.DB, entry *models.NotificationOutbox, data map[string]string) error {
+	entry.Status = models.OutboxStatusPending
+	// Лимит по умолчанию; воркер при доставке применяет лимит из своей конфигурации
+	if entry.MaxAttempts < 1 {
+		entry.MaxAttempts = outboxDefaultMaxAttempts
+	}
+	if len(data) > 0 {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("ошибка сериализации данных уведомления: %w", err)
+		}
+		entry.Data = string(raw)
+	}
+
+	if err := tx.Create(entry).Error; err != nil {
+		return fmt.Errorf("ошибка добавления уведомления в очередь: %w", err)
+	}
+	return nil
+}
+
+// RetryOutboxEntry возвращает неудачную запись в очередь для новой серии попыток
+func RetryOutboxEntry(id uuid.UUID) error {
+	result := database.DB.Model(&models.NotificationOutbox{}).
+		Where("id = ? AND status IN ?", id, []models.OutboxStatus{models.OutboxStatusFailed, models.OutboxStatusSkipped}).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+			"locked_at":       nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// StartOutboxWorker запускает пул воркеров доставки уведомлений
+func StartOutboxWorker(ctx context.Context, cfg OutboxWorkerConfig) *OutboxWorker {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = outboxDefaultMaxAttempts
+	}
+
+	w := &OutboxWorker{
+		config: cfg,
+		jobs:   make(chan models.NotificationOutbox, outboxBatchSize),
+	}
+
+	for i := 0; i < cfg.Workers; i++ {
+		w.wg.Add(1)
+		go w.work()
+	}
+	go w.poll(ctx)
+
+	log.Printf("✅ Outbox worker запущен (воркеров: %d, интервал: %s, попыток: %d)", cfg.Workers, cfg.PollInterval, cfg.MaxAttempts)
+	return w
+}
+
+// Wait ожидает завершения воркеров после отмены контекста
+func (w *OutboxWorker) Wait() {
+	w.wg.Wait()
+}
+
+// poll периодически захватывает готовые к отправке записи и передает их воркерам
+func (w *OutboxWorker) poll(ctx context.Context) {
+	defer close(w.jobs)
+
+	ticker := time.NewTicker(w.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		entries, err := w.claimBatch()
+		if err != nil {
+			log.Printf("⚠️ Outbox: ошибка захвата записей: %v", err)
+		}
+		for _, entry := range entries {
+			select {
+			case w.jobs <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// Если пачка заполнена целиком - сразу берем следующую
+		if len(entries) == outboxBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimBatch атомарно переводит готовые записи в статус processing.
+// Записи, застрявшие в processing дольше outboxStaleTimeout (например, после рестарта), захватываются повторно.
+func (w *OutboxWorker) claimBatch() ([]models.NotificationOutbox, error) {
+	if database.DB == nil {
+		return nil, nil
+	}
+
+	var entries []models.NotificationOutbox
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND locked_at < ?)",
+				models.OutboxStatusPending, now,
+				models.OutboxStatusProcessing, now.Add(-outboxStaleTimeout)).
+			Order("next_attempt_at").
+			Limit(outboxBatchSize).
+			Find(&entries).Error; err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(entries))
+		for i := range entries {
+			ids[i] = entries[i].ID
+			entries[i].Status = models.OutboxStatusProcessing
+			entries[i].LockedAt = &now
+		}
+		return tx.Model(&models.NotificationOutbox{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{"status": models.OutboxStatusProcessing, "locked_at": now}).Error
+	})
+	return entries, err
+}
+
+// work обрабатывает записи из канала до его закрытия
+func (w *OutboxWorker) work() {
+	defer w.wg.Done()
+	for entry := range w.jobs {
+		w.deliver(entry)
+	}
+}
+
+// deliver выполняет одну попытку доставки записи на все активные устройства пользователя.
+// Устройства, на которые запись уже была доставлена в прошлых попытках, пропускаются.
+func (w *OutboxWorker) deliver(entry models.NotificationOutbox) {
+	attempt := entry.Attempts + 1
+	entry.MaxAttempts = w.config.MaxAttempts
+
+	fcm := GetFCMService()
+	if fcm == nil {
+		w.finish(entry, attempt, models.OutboxStatusSkipped, "FCM не настроен")
+		return
+	}
+
+	var deviceTokens []models.DeviceToken
+	if err := database.DB.Where("user_id = ? AND is_active = ? AND platform IN ?", entry.UserID, true, []string{"android", "ios", "web"}).
+		Find(&deviceTokens).Error; err != nil {
+		w.reschedule(entry, attempt, fmt.Sprintf("ошибка получения токенов устройств: %v", err))
+		return
+	}
+
+	var deliveredTokenIDs []uuid.UUID
+	if err := database.DB.Model(&models.NotificationDelivery{}).
+		Where("outbox_id = ? AND status = ?", entry.ID, models.DeliveryStatusSent).
+		Pluck("device_token_id", &deliveredTokenIDs).Error; err != nil {
+		w.reschedule(entry, attempt, fmt.Sprintf("ошибка получения истории доставки: %v", err))
+		return
+	}
+	delivered := make(map[uuid.UUID]bool, len(deliveredTokenIDs))
+	for _, id := range deliveredTokenIDs {
+		delivered[id] = true
+	}
+
+	if len(deviceTokens) == 0 && len(delivered) == 0 {
+		w.finish(entry, attempt, models.OutboxStatusSkipped, "нет активных устройств")
+		return
+	}
+
+	data := map[string]string{}
+	if entry.Data != "" {
+		if err := json.Unmarshal([]byte(entry.Data), &data); err != nil {
+			log.Printf("⚠️ Outbox: некорректные данные записи %s: %v", entry.ID, err)
+		}
+	}
+
+	sentCount := len(delivered)
+	retryable := false
+	var lastErr error
+	for _, deviceToken := range deviceTokens {
+		if delivered[deviceToken.ID] {
+			continue
+		}
+
+		message := newNotificationMessage(deviceToken.Token, entry.Title, entry.Body, entry.ActionURL)
+		if entry.ImageURL != "" && message.Notification != nil {
+			message.Notification.Image = entry.ImageURL
+		}
+		for key, value := range data {
+			if message.Data == nil {
+				message.Data = map[string]string{}
+			}
+			message.Data[key] = value
+		}
+
+		err := fcm.Send(message)
+		record := models.NotificationDelivery{
+			OutboxID:      entry.ID,
+			DeviceTokenID: deviceToken.ID,
+			Platform:      deviceToken.Platform,
+			Attempt:       attempt,
+			Status:        models.DeliveryStatusSent,
+		}
+		switch {
+		case err == nil:
+			sentCount++
+		case IsUnregisteredError(err):
+			record.Status = models.DeliveryStatusUnregistered
+			record.Error = err.Error()
+		default:
+			record.Status = models.DeliveryStatusFailed
+			record.Error = err.Error()
+			lastErr = err
+			var fcmErr *FCMError
+			if !errors.As(err, &fcmErr) || fcmErr.IsRetryable() {
+				retryable = true
+			}
+		}
+
+		if err := database.DB.Create(&record).Error; err != nil {
+			log.Printf("⚠️ Outbox: ошибка записи результата доставки: %v", err)
+		}
+	}
+
+	switch {
+	case retryable && attempt < entry.MaxAttempts:
+		w.reschedule(entry, attempt, lastErr.Error())
+	case sentCount > 0:
+		w.finish(entry, attempt, models.OutboxStatusSent, "")
+	case lastErr != nil:
+		w.finish(entry, attempt, models.OutboxStatusFailed, lastErr.Error())
+	default:
+		// Все токены оказались недействительными
+		w.finish(entry, attempt, models.OutboxStatusFailed, "все токены устройств недействительны")
+	}
+}
+
+// reschedule планирует повторную попытку с экспоненциальной задержкой
+func (w *OutboxWorker) reschedule(entry models.NotificationOutbox, attempt int, lastError string) {
+	if attempt >= entry.MaxAttempts {
+		w.finish(entry, attempt, models.OutboxStatusFailed, lastError)
+		return
+	}
+
+	next := time.Now().Add(outboxBackoff(attempt))
+	if err := database.DB.Model(&models.NotificationOutbox{}).Where("id = ?", entry.ID).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxStatusPending,
+			"attempts":        attempt,
+			"max_attempts":    entry.MaxAttempts,
+			"next_attempt_at": next,
+			"locked_at":       nil,
+			"last_error":      lastError,
+		}).Error; err != nil {
+		log.Printf("⚠️ Outbox: ошибка планирования повторной попытки %s: %v", entry.ID, err)
+		return
+	}
+	log.Printf("🔁 Outbox: запись %s, попытка %d/%d не удалась, следующая в %s", entry.ID, attempt, entry.MaxAttempts, next.Format(time.RFC3339))
+}
+
+// finish переводит запись в конечный статус
+func (w *OutboxWorker) finish(entry models.NotificationOutbox, attempt int, status models.OutboxStatus, lastError string) {
+	updates := map[string]interface{}{
+		"status":       status,
+		"attempts":     attempt,
+		"max_attempts": entry.MaxAttempts,
+		"locked_at":    nil,
+		"last_error":   lastError,
+	}
+	if status == models.OutboxStatusSent {
+		updates["sent_at"] = time.Now()
+	}
+
+	if err := database.DB.Model(&models.NotificationOutbox{}).Where("id = ?", entry.ID).Updates(updates).Error; err != nil {
+		log.Printf("⚠️ Outbox: ошибка обновления статуса %s: %v", entry.ID, err)
+		return
+	}
+
+	switch status {
+	case models.OutboxStatusSent:
+		log.Printf("✅ Outbox: уведомление %s доставлено пользователю %s", entry.ID, entry.UserID)
+	case models.OutboxStatusFailed:
+		log.Printf("❌ Outbox: уведомление %s не доставлено: %s", entry.ID, lastError)
+	default:
+		log.Printf("ℹ️ Outbox: уведомление %s пропущено: %s", entry.ID, lastError)
+	}
+}
+
+// outboxBackoff вычисляет задержку перед попыткой attempt+1
+func outboxBackoff(attempt int) time.Duration {
+	delay := outboxBackoffBase
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= outboxBackoffMax {
+			return outboxBackoffMax
+		}
+	}
+	return delay
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/mm-api/mm-api/config"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	phoneCodeTTL            = 5 * time.Minute  // Время жизни кода
+	phoneCodeResendInterval = 60 * time.Second // Минимальный интервал между отправками
+	phoneCodeMaxAttempts    = 5                // Попыток ввода на один код
+)
+
+var (
+	// ErrPhoneCodeTooFrequent код запрошен слишком часто
+	ErrPhoneCodeTooFrequent = errors.New("код уже отправлен, повторите позже")
+	// ErrPhoneCodeInvalid код неверный, истек или уже использован
+	ErrPhoneCodeInvalid = errors.New("неверный или истекший код")
+	// ErrPhoneCodeAttempts превышено количество попыток ввода
+	ErrPhoneCodeAttempts = errors.New("превышено количество попыток, запросите новый код")
+	// ErrSMSNotConfigured провайдер SMS не настроен, код доставить нельзя
+	ErrSMSNotConfigured = errors.New("отправка SMS не настроена")
+)
+
+// SMSSender отправляет SMS сообщения
+type SMSSender interface {
+	Send(phone, text string) error
+}
+
+// logSMSSender пишет сообщения в лог вместо отправки. Используется вне release
+// режима, когда SMS_PROVIDER не задан.
+type logSMSSender struct{}
+
+// Send записывает сообщение в лог
+func (s *logSMSSender) Send(phone, text string) error {
+	log.Printf("📱 SMS для %s: %s", phone, text)
+	return nil
+}
+
+var (
+	smsSender   SMSSender
+	smsSenderMu sync.RWMutex
+)
+
+// SetSMSSender устанавливает провайдера SMS
+func SetSMSSender(sender SMSSender) {
+	smsSenderMu.Lock()
+	defer smsSenderMu.Unlock()
+	smsSender = sender
+}
+
+// getSMSSender возвращает провайдера SMS. Без провайдера вне release режима коды
+// пишутся в лог, в release режиме возвращается nil - код доставить нельзя.
+func getSMSSender() SMSSender {
+	smsSenderMu.RLock()
+	defer smsSenderMu.RUnlock()
+	if smsSender == nil {
+		if config.GetConfig().GinMode == "release" {
+			return nil
+		}
+		return &logSMSSender{}
+	}
+	return smsSender
+}
+
+// PhoneCodesAvailable сообщает, можно ли доставить код подтверждения телефона
+func PhoneCodesAvailable() bool {
+	return getSMSSender() != nil
+}
+
+// SendPhoneCode создает одноразовый код для телефона и отправляет его по SMS
+func SendPhoneCode(phone string, purpose models.PhoneVerificationPurpose) error {
+	normalized := utils.NormalizePhone(phone)
+	if normalized == "" {
+		return fmt.Errorf("некорректный номер телефона")
+	}
+	sender := getSMSSender()
+	if sender == nil {
+		return ErrSMSNotConfigured
+	}
+
+	var last models.PhoneVerification
+	err := database.DB.Where("phone = ? AND purpose = ?", normalized, purpose).
+		Order("created_at DESC").First(&last).Error
+	if err == nil && time.Since(last.CreatedAt) < phoneCodeResendInterval {
+		return ErrPhoneCodeTooFrequent
+	}
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	code, err := generatePhoneCode()
+	if err != nil {
+		return fmt.Errorf("ошибка генерации кода: %w", err)
+	}
+
+	verification := models.PhoneVerification{
+		Phone:     normalized,
+		Purpose:   purpose,
+		CodeHash:  hashPhoneCode(normalized, code),
+		ExpiresAt: time.Now().Add(phoneCodeTTL),
+	}
+	if err := database.DB.Create(&verification).Error; err != nil {
+		return fmt.Errorf("ошибка сохранения кода: %w", err)
+	}
+
+	if err := sender.Send(normalized, fmt.Sprintf("Код подтверждения: %s", code)); err != nil {
+		// Недоставленный код не должен блокировать повторный запрос
+		database.DB.Delete(&verification)
+		return fmt.Errorf("ошибка отправки SMS: %w", err)
+	}
+	return nil
+}
+
+// VerifyPhoneCode проверяет последний код для телефона и помечает его использованным.
+// Строка кода блокируется до конца проверки, поэтому параллельные попытки не обходят
+// лимит ввода, а один код нельзя использовать дважды.
+func VerifyPhoneCode(phone string, purpose models.PhoneVerificationPurpose, code string) error {
+	normalized := utils.NormalizePhone(phone)
+
+	var verifyErr error
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var verification models.PhoneVerification
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("phone = ? AND purpose = ? AND consumed_at IS NULL AND expires_at > ?", normalized, purpose, time.Now()).
+			Order("created_at DESC").First(&verification).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				verifyErr = ErrPhoneCodeInvalid
+				return nil
+			}
+			return err
+		}
+
+		if verification.Attempts >= phoneCodeMaxAttempts {
+			verifyErr = ErrPhoneCodeAttempts
+			return nil
+		}
+
+		expected := []byte(verification.CodeHash)
+		actual := []byte(hashPhoneCode(normalized, code))
+		if subtle.ConstantTimeCompare(expected, actual) != 1 {
+			// Неудачная попытка фиксируется вместе с транзакцией, ошибка возвращается после нее
+			verifyErr = ErrPhoneCodeInvalid
+			return tx.Model(&verification).Update("attempts", gorm.Expr("attempts + 1")).Error
+		}
+
+		result := tx.Model(&models.PhoneVerification{}).
+			Where("id = ? AND consumed_at IS NULL", verification.ID).
+			Update("consumed_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			verifyErr = ErrPhoneCodeInvalid
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return verifyErr
+}
+
+// generatePhoneCode генерирует случайный шестизначный код
+func generatePhoneCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashPhoneCode хеширует код вместе с телефоном
+func hashPhoneCode(phone, code string) string {
+	sum := sha256.Sum256([]byte(phone + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
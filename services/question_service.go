@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrQuestionAlreadyReported пользователь уже жаловался на этот вопрос
+var ErrQuestionAlreadyReported = errors.New("question already reported by this user")
+
+// PublicQuestionsCondition условие вопросов, видимых на странице товара
+const PublicQuestionsCondition = "product_questions.status = 'published' AND product_questions.answered_at IS NOT NULL"
+
+// ProductOwnerID возвращает владельца товара: владельца магазина или
+// (для старых товаров без магазина) owner_id товара
+func ProductOwnerID(db *gorm.DB, product *models.Product) (uuid.UUID, error) {
+	if product.ShopID != nil {
+		var shop models.Shop
+		if err := db.Select("id", "owner_id").First(&shop, "id = ?", *product.ShopID).Error; err == nil {
+			return shop.OwnerID, nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, fmt.Errorf("ошибка загрузки магазина: %w", err)
+		}
+	}
+	if product.OwnerID != nil {
+		return *product.OwnerID, nil
+	}
+	return uuid.Nil, nil
+}
+
+// NotifyShopAboutQuestion уведомляет владельца магазина о новом вопросе
+func NotifyShopAboutQuestion(tx *gorm.DB, question *models.ProductQuestion, product *models.Product) error {
+	ownerID, err := ProductOwnerID(tx, product)
+	if err != nil {
+		return err
+	}
+	if ownerID == uuid.Nil {
+		log.Printf("⚠️ У товара %s нет владельца, уведомление о вопросе не отправлено", product.ID)
+		return nil
+	}
+
+	notification := models.Notification{
+		UserID:    ownerID,
+		Title:     "Новый вопрос о товаре",
+		Body:      fmt.Sprintf("«%s»: %s", product.Name, truncateText(question.Text, 120)),
+		Type:      models.NotificationTypeSystem,
+		ActionURL: fmt.Sprintf("/questions?questionId=%s", question.ID), // Экран вопросов в приложении магазина
+	}
+	if _, err := DispatchNotification(tx, &notification); err != nil {
+		return fmt.Errorf("ошибка уведомления владельца о вопросе: %w", err)
+	}
+	return nil
+}
+
+// NotifyAskerAboutAnswer уведомляет автора вопроса об ответе магазина
+func NotifyAskerAboutAnswer(tx *gorm.DB, question *models.ProductQuestion, productName string) error {
+	notification := models.Notification{
+		UserID:    question.UserID,
+		Title:     "Магазин ответил на ваш вопрос",
+		Body:      fmt.Sprintf("«%s»: %s", productName, truncateText(question.Answer, 120)),
+		Type:      models.NotificationTypeSystem,
+		ActionURL: fmt.Sprintf("/products/%s#questions", question.ProductID),
+	}
+	if _, err := DispatchNotification(tx, &notification); err != nil {
+		return fmt.Errorf("ошибка уведомления об ответе на вопрос: %w", err)
+	}
+	return nil
+}
+
+// truncateText обрезает текст до limit символов
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "…"
+}
+
+// GetPublicQuestions возвращает последние отвеченные вопросы товара и их общее количество
+func GetPublicQuestions(db *gorm.DB, productID uuid.UUID, limit int) ([]models.ProductQuestion, int64, error) {
+	query := db.Model(&models.ProductQuestion{}).
+		Where("product_questions.product_id = ?", productID).
+		Where(PublicQuestionsCondition)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("ошибка подсчета вопросов: %w", err)
+	}
+
+	var questions []models.ProductQuestion
+	if err := query.Preload("User").Order("product_questions.answered_at DESC").Limit(limit).Find(&questions).Error; err != nil {
+		return nil, 0, fmt.Errorf("ошибка загрузки вопросов: %w", err)
+	}
+	return questions, total, nil
+}
+
+// ReportQuestion сохраняет жалобу и скрывает вопрос, когда жалоб становится
+// QuestionReportsToHide. Возвращает true, если вопрос был скрыт этой жалобой.
+func ReportQuestion(db *gorm.DB, question *models.ProductQuestion, report *models.QuestionReport) (bool, error) {
+	hidden := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Уникальный индекс (question_id, user_id, target) отсекает повторную жалобу,
+		// в том числе из двух одновременных запросов
+		report.QuestionID = question.ID
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(report)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrQuestionAlreadyReported
+		}
+
+		// Блокируем строку вопроса, чтобы параллельные жалобы считались последовательно
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(question, "id = ?", question.ID).Error; err != nil {
+			return err
+		}
+		question.ReportsCount++
+		updates := map[string]interface{}{"reports_count": question.ReportsCount}
+		if question.ReportsCount >= models.QuestionReportsToHide && question.Status == models.QuestionStatusPublished {
+			question.Status = models.QuestionStatusHidden
+			updates["status"] = question.Status
+			hidden = true
+		}
+		return tx.Model(question).Updates(updates).Error
+	})
+	return hidden, err
+}
+
+// ModerateQuestion применяет решение модератора и закрывает жалобы на вопрос
+func ModerateQuestion(db *gorm.DB, question *models.ProductQuestion, moderatorID uuid.UUID, req models.QuestionModerationRequest) error {
+	now := time.Now()
+	question.Status = req.Status
+	question.ModerationNote = req.Note
+	question.ModeratedBy = &moderatorID
+	question.ModeratedAt = &now
+	question.ReportsCount = 0
+
+	columns := []string{"status", "moderation_note", "moderated_by", "moderated_at", "reports_count"}
+	if req.ClearAnswer {
+		question.Answer = ""
+		question.AnsweredBy = nil
+		question.AnsweredAt = nil
+		columns = append(columns, "answer", "answered_by", "answered_at")
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(question).Select(columns).Updates(question).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.QuestionReport{}).
+			Where("question_id = ? AND resolved_at IS NULL", question.ID).
+			Update("resolved_at", now).Error
+	})
+}
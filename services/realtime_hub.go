@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RealtimeEventType тип события потока реального времени
+type RealtimeEventType string
+
+const (
+	RealtimeEventConnected           RealtimeEventType = "connected"            // Подключение установлено
+	RealtimeEventNotificationCreated RealtimeEventType = "notification.created" // Новое уведомление
+	RealtimeEventOrderStatus         RealtimeEventType = "order.status"         // Изменился статус заказа покупателя
+	RealtimeEventShopOrderCreated    RealtimeEventType = "shop.order.created"   // Новый заказ в магазине владельца
+)
+
+// realtimeBufferSize размер буфера событий одного подключения.
+// Медленный клиент теряет события, а не блокирует публикацию.
+const realtimeBufferSize = 64
+
+// RealtimeEvent событие для конкретного пользователя
+type RealtimeEvent struct {
+	ID        string            `json:"id"`
+	Type      RealtimeEventType `json:"type"`
+	UserID    uuid.UUID         `json:"-"`
+	Data      interface{}       `json:"data"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// RealtimeBroker доставляет опубликованные события во все экземпляры API.
+// Локальный брокер передает событие прямо в хаб процесса; брокер на основе
+// PostgreSQL LISTEN/NOTIFY должен отправлять NOTIFY в Publish и вызывать
+// RealtimeHub.Deliver для каждого полученного уведомления.
+type RealtimeBroker interface {
+	Publish(event RealtimeEvent) error
+}
+
+// RealtimeSubscription подписка одного подключения на события пользователя
+type RealtimeSubscription struct {
+	UserID uuid.UUID
+	Events <-chan RealtimeEvent
+
+	events chan RealtimeEvent
+	hub    *RealtimeHub
+	once   sync.Once
+}
+
+// Close отписывает подключение от хаба
+func (s *RealtimeSubscription) Close() {
+	s.once.Do(func() {
+		s.hub.unsubscribe(s)
+	})
+}
+
+// RealtimeHub pub/sub хаб процесса с каналами по пользователям
+type RealtimeHub struct {
+	mu          sync.RWMutex
+	subscribers map[uuid.UUID]map[*RealtimeSubscription]struct{}
+	broker      RealtimeBroker
+}
+
+// localRealtimeBroker доставляет события только внутри текущего процесса
+type localRealtimeBroker struct {
+	hub *RealtimeHub
+}
+
+// Publish передает событие в хаб процесса
+func (b *localRealtimeBroker) Publish(event RealtimeEvent) error {
+	b.hub.Deliver(event)
+	return nil
+}
+
+var (
+	realtimeHub     *RealtimeHub
+	realtimeHubOnce sync.Once
+)
+
+// GetRealtimeHub возвращает глобальный хаб событий реального времени
+func GetRealtimeHub() *RealtimeHub {
+	realtimeHubOnce.Do(func() {
+		realtimeHub = &RealtimeHub{
+			subscribers: make(map[uuid.UUID]map[*RealtimeSubscription]struct{}),
+		}
+		realtimeHub.broker = &localRealtimeBroker{hub: realtimeHub}
+	})
+	return realtimeHub
+}
+
+// SetBroker подменяет брокер (например, на PostgreSQL LISTEN/NOTIFY для нескольких реплик)
+func (h *RealtimeHub) SetBroker(broker RealtimeBroker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.broker = broker
+}
+
+// Subscribe создает подписку на события пользователя
+func (h *RealtimeHub) Subscribe(userID uuid.UUID) *RealtimeSubscription {
+	events := make(chan RealtimeEvent, realtimeBufferSize)
+	sub := &RealtimeSubscription{
+		UserID: userID,
+		Events: events,
+		events: events,
+		hub:    h,
+	}
+
+	h.mu.Lock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[*RealtimeSubscription]struct{})
+	}
+	h.subscribers[userID][sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub
+}
+
+// unsubscribe удаляет подписку и закрывает ее канал
+func (h *RealtimeHub) unsubscribe(sub *RealtimeSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if subs, ok := h.subscribers[sub.UserID]; ok {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(h.subscribers, sub.UserID)
+		}
+	}
+	close(sub.events)
+}
+
+// Publish публикует событие пользователю через брокер
+func (h *RealtimeHub) Publish(userID uuid.UUID, eventType RealtimeEventType, data interface{}) {
+	event := RealtimeEvent{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		UserID:    userID,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+
+	h.mu.RLock()
+	broker := h.broker
+	h.mu.RUnlock()
+
+	if err := broker.Publish(event); err != nil {
+		log.Printf("⚠️ Realtime: ошибка публикации события %s пользователю %s: %v", eventType, userID, err)
+	}
+}
+
+// Deliver передает событие всем подключениям пользователя в этом процессе
+func (h *RealtimeHub) Deliver(event RealtimeEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers[event.UserID] {
+		select {
+		case sub.events <- event:
+		default:
+			log.Printf("⚠️ Realtime: буфер подключения пользователя %s переполнен, событие %s пропущено", event.UserID, event.Type)
+		}
+	}
+}
+
+// ConnectionsCount возвращает количество активных подключений пользователя
+func (h *RealtimeHub) ConnectionsCount(userID uuid.UUID) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers[userID])
+}
+
+// realtimeCollectorKey ключ контекста транзакции для отложенных событий
+type realtimeCollectorKey struct{}
+
+// realtimeCollector накапливает события транзакции до ее фиксации
+type realtimeCollector struct {
+	mu     sync.Mutex
+	events []RealtimeEvent
+}
+
+// WithRealtimeEvents выполняет транзакцию и публикует события, добавленные
+// через PublishRealtime, только после успешной фиксации
+func WithRealtimeEvents(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	collector := &realtimeCollector{}
+	parent := db.Statement.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx := context.WithValue(parent, realtimeCollectorKey{}, collector)
+
+	if err := db.WithContext(ctx).Transaction(fn); err != nil {
+		return err
+	}
+
+	hub := GetRealtimeHub()
+	for _, event := range collector.events {
+		hub.Publish(event.UserID, event.Type, event.Data)
+	}
+	return nil
+}
+
+// PublishRealtime публикует событие пользователю. Внутри WithRealtimeEvents
+// событие откладывается до фиксации транзакции, иначе отправляется сразу.
+func PublishRealtime(tx *gorm.DB, userID uuid.UUID, eventType RealtimeEventType, data interface{}) {
+	if tx != nil && tx.Statement != nil && tx.Statement.Context != nil {
+		if collector, ok := tx.Statement.Context.Value(realtimeCollectorKey{}).(*realtimeCollector); ok {
+			collector.mu.Lock()
+			collector.events = append(collector.events, RealtimeEvent{Type: eventType, UserID: userID, Data: data})
+			collector.mu.Unlock()
+			return
+		}
+	}
+	GetRealtimeHub().Publish(userID, eventType, data)
+}
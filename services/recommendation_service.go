@@ -0,0 +1,417 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"gorm.io/gorm"
+)
+
+// recommendationFeaturedSize сколько популярных товаров хранится на город
+const recommendationFeaturedSize = 100
+
+// recommendationSimilarWindow во сколько раз больше PerProduct кандидатов в похожие
+// берется с каждой стороны по цене внутри категории
+const recommendationSimilarWindow = 2
+
+// RecommendationConfig параметры пересчета рекомендаций
+type RecommendationConfig struct {
+	Interval    time.Duration // Интервал пересчета
+	HistoryDays int           // Окно истории заказов, избранного и корзин
+	PerProduct  int           // Похожих и "покупают вместе" на товар
+	PerUser     int           // Персональных рекомендаций на пользователя
+}
+
+// recommendationSignalsSQL сигналы интереса пользователей к товарам за окно истории:
+// заказ (вес 3 за штуку), избранное (1), добавление в корзину (0.5)
+const recommendationSignalsSQL = `signals AS (
+	SELECT pv.product_id, o.user_id, oi.quantity * 3.0 AS weight
+	FROM order_items oi
+	JOIN orders o ON o.id = oi.order_id
+	JOIN product_variations pv ON pv.id = oi.variation_id
+	WHERE o.created_at >= @since AND o.status <> 'cancelled'
+	UNION ALL
+	SELECT f.product_id, f.user_id, 1.0 FROM favorites f WHERE f.created_at >= @since
+	UNION ALL
+	SELECT ci.product_id, ci.user_id, 0.5 FROM cart_items ci WHERE ci.created_at >= @since
+)`
+
+// recommendationFeaturedSQL популярные товары: по всем городам (subject_id NULL) и по каждому городу
+const recommendationFeaturedSQL = `WITH ` + recommendationSignalsSQL + `,
+scored AS (
+	SELECT s.product_id, COALESCE(p.city_id, sh.city_id) AS city_id, SUM(s.weight) AS score
+	FROM signals s
+	JOIN products p ON p.id = s.product_id AND p.is_available = true
+	LEFT JOIN shops sh ON sh.id = p.shop_id
+	GROUP BY s.product_id, COALESCE(p.city_id, sh.city_id)
+),
+ranked AS (
+	SELECT product_id, NULL::uuid AS subject_id, score, ROW_NUMBER() OVER (ORDER BY score DESC) AS rn FROM scored
+	UNION ALL
+	SELECT product_id, city_id, score, ROW_NUMBER() OVER (PARTITION BY city_id ORDER BY score DESC)
+	FROM scored WHERE city_id IS NOT NULL
+)
+INSERT INTO product_recommendations (id, kind, subject_id, product_id, score, reason, computed_at)
+SELECT gen_random_uuid(), 'featured', subject_id, product_id, score, 'popular', @now
+FROM ranked WHERE rn <= @featured_size`
+
+// recommendationBoughtTogetherSQL товары из одних заказов
+const recommendationBoughtTogetherSQL = `WITH order_products AS (
+	SELECT DISTINCT oi.order_id, pv.product_id
+	FROM order_items oi
+	JOIN orders o ON o.id = oi.order_id
+	JOIN product_variations pv ON pv.id = oi.variation_id
+	WHERE o.created_at >= @since AND o.status <> 'cancelled'
+),
+pairs AS (
+	SELECT a.product_id AS subject_id, b.product_id, COUNT(*) AS score
+	FROM order_products a
+	JOIN order_products b ON b.order_id = a.order_id AND b.product_id <> a.product_id
+	GROUP BY a.product_id, b.product_id
+),
+ranked AS (
+	SELECT pairs.*, ROW_NUMBER() OVER (PARTITION BY subject_id ORDER BY score DESC) AS rn
+	FROM pairs JOIN products p ON p.id = pairs.product_id AND p.is_available = true
+)
+INSERT INTO product_recommendations (id, kind, subject_id, product_id, score, reason, computed_at)
+SELECT gen_random_uuid(), 'bought_together', subject_id, product_id, score, 'together', @now
+FROM ranked WHERE rn <= @per_product`
+
+// recommendationSimilarSQL похожие товары той же категории: бренд, пол,
+// магазин и близость цены. Кандидаты - соседи по цене внутри категории
+// (@similar_window с каждой стороны), чтобы число пар росло линейно,
+// а не как квадрат размера категории.
+const recommendationSimilarSQL = `WITH prices AS (
+	SELECT product_id, MIN(price) AS price FROM product_variations WHERE is_available = true GROUP BY product_id
+),
+candidates AS (
+	SELECT p.id, p.category_id, p.gender, lower(trim(p.brand)) AS brand, p.shop_id, pr.price
+	FROM products p JOIN prices pr ON pr.product_id = p.id
+	WHERE p.is_available = true
+),
+ordered AS (
+	SELECT candidates.*, ROW_NUMBER() OVER (PARTITION BY category_id ORDER BY price, id) AS pos
+	FROM candidates
+),
+pairs AS (
+	SELECT a.id AS subject_id, b.id AS product_id,
+		CASE WHEN a.brand <> '' AND a.brand = b.brand THEN 2 ELSE 0 END
+		+ CASE WHEN a.gender = b.gender THEN 1 ELSE 0 END
+		+ CASE WHEN a.shop_id = b.shop_id THEN 0.5 ELSE 0 END
+		+ 1 - abs(a.price - b.price) / NULLIF(GREATEST(a.price, b.price), 0) AS score,
+		CASE WHEN a.brand <> '' AND a.brand = b.brand THEN 'brand' ELSE 'category' END AS reason
+	FROM ordered a
+	CROSS JOIN generate_series(-@similar_window, @similar_window) AS offsets(delta)
+	JOIN ordered b ON b.category_id = a.category_id AND b.pos = a.pos + offsets.delta
+	WHERE offsets.delta <> 0
+),
+ranked AS (
+	SELECT pairs.*, ROW_NUMBER() OVER (PARTITION BY subject_id ORDER BY score DESC NULLS LAST) AS rn FROM pairs
+)
+INSERT INTO product_recommendations (id, kind, subject_id, product_id, score, reason, computed_at)
+SELECT gen_random_uuid(), 'similar', subject_id, product_id, COALESCE(score, 0), reason, @now
+FROM ranked WHERE rn <= @per_product`
+
+// recommendationForYouSQL персональные рекомендации: категории, бренды и магазины,
+// с которыми пользователь взаимодействовал, плюс подписки на магазины.
+// Уже заказанные, избранные и добавленные в корзину товары исключаются.
+const recommendationForYouSQL = `WITH ` + recommendationSignalsSQL + `,
+user_categories AS (
+	SELECT s.user_id, p.category_id, SUM(s.weight) AS weight
+	FROM signals s JOIN products p ON p.id = s.product_id
+	GROUP BY s.user_id, p.category_id
+),
+user_brands AS (
+	SELECT s.user_id, lower(trim(p.brand)) AS brand, SUM(s.weight) AS weight
+	FROM signals s JOIN products p ON p.id = s.product_id
+	WHERE trim(p.brand) <> ''
+	GROUP BY s.user_id, lower(trim(p.brand))
+),
+user_shops AS (
+	SELECT user_id, shop_id, SUM(weight) AS weight FROM (
+		SELECT s.user_id, p.shop_id, s.weight
+		FROM signals s JOIN products p ON p.id = s.product_id
+		WHERE p.shop_id IS NOT NULL
+		UNION ALL
+		SELECT user_id, shop_id, 2.0 FROM shop_subscriptions
+	) shop_signals
+	GROUP BY user_id, shop_id
+),
+popularity AS (
+	SELECT product_id, SUM(weight) AS score FROM signals GROUP BY product_id
+),
+candidates AS (
+	SELECT uc.user_id, p.id AS product_id, uc.weight AS score, 'category' AS reason
+	FROM user_categories uc JOIN products p ON p.category_id = uc.category_id AND p.is_available = true
+	UNION ALL
+	SELECT ub.user_id, p.id, ub.weight * 1.5, 'brand'
+	FROM user_brands ub JOIN products p ON lower(trim(p.brand)) = ub.brand AND p.is_available = true
+	UNION ALL
+	SELECT us.user_id, p.id, us.weight, 'shop'
+	FROM user_shops us JOIN products p ON p.shop_id = us.shop_id AND p.is_available = true
+),
+scored AS (
+	SELECT c.user_id, c.product_id,
+		SUM(c.score) * (1 + ln(1 + COALESCE(MAX(pop.score), 0))) AS score,
+		(array_agg(c.reason ORDER BY c.score DESC))[1] AS reason
+	FROM candidates c
+	LEFT JOIN popularity pop ON pop.product_id = c.product_id
+	WHERE NOT EXISTS (SELECT 1 FROM signals s WHERE s.user_id = c.user_id AND s.product_id = c.product_id)
+	GROUP BY c.user_id, c.product_id
+),
+ranked AS (
+	SELECT scored.*, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY score DESC) AS rn FROM scored
+)
+INSERT INTO product_recommendations (id, kind, subject_id, product_id, score, reason, computed_at)
+SELECT gen_random_uuid(), 'for_you', user_id, product_id, score, reason, @now
+FROM ranked WHERE rn <= @per_user`
+
+// StartRecommendationWorker запускает периодический пересчет рекомендаций
+func StartRecommendationWorker(ctx context.Context, cfg RecommendationConfig) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			if database.DB != nil {
+				if err := RecomputeRecommendations(database.DB, cfg); err != nil {
+					log.Printf("⚠️ Recommendations: %v", err)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	log.Printf("✅ Recommendation worker запущен (интервал: %s)", cfg.Interval)
+}
+
+// RecomputeRecommendations пересчитывает таблицу рекомендаций в одной транзакции:
+// читатели видят старые рекомендации, пока новые не зафиксированы.
+// Advisory lock не дает нескольким экземплярам API считать одновременно.
+func RecomputeRecommendations(db *gorm.DB, cfg RecommendationConfig) error {
+	if cfg.HistoryDays < 1 {
+		cfg.HistoryDays = 90
+	}
+	if cfg.PerProduct < 1 {
+		cfg.PerProduct = 20
+	}
+	if cfg.PerUser < 1 {
+		cfg.PerUser = 50
+	}
+
+	started := time.Now()
+	params := map[string]interface{}{
+		"since":          started.AddDate(0, 0, -cfg.HistoryDays),
+		"now":            started,
+		"featured_size":  recommendationFeaturedSize,
+		"per_product":    cfg.PerProduct,
+		"similar_window": cfg.PerProduct * recommendationSimilarWindow,
+		"per_user":       cfg.PerUser,
+	}
+
+	steps := []struct {
+		kind models.RecommendationKind
+		sql  string
+	}{
+		{models.RecommendationFeatured, recommendationFeaturedSQL},
+		{models.RecommendationBoughtTogether, recommendationBoughtTogetherSQL},
+		{models.RecommendationSimilar, recommendationSimilarSQL},
+		{models.RecommendationForYou, recommendationForYouSQL},
+	}
+
+	counts := make(map[models.RecommendationKind]int64, len(steps))
+	skipped := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(hashtext('mm_product_recommendations'))").Scan(&locked).Error; err != nil {
+			return fmt.Errorf("ошибка блокировки пересчета рекомендаций: %w", err)
+		}
+		if !locked {
+			skipped = true
+			return nil
+		}
+
+		for _, step := range steps {
+			if err := tx.Where("kind = ?", step.kind).Delete(&models.ProductRecommendation{}).Error; err != nil {
+				return fmt.Errorf("ошибка очистки рекомендаций %s: %w", step.kind, err)
+			}
+			result := tx.Exec(step.sql, params)
+			if result.Error != nil {
+				return fmt.Errorf("ошибка расчета рекомендаций %s: %w", step.kind, result.Error)
+			}
+			counts[step.kind] = result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if skipped {
+		log.Printf("ℹ️ Recommendations: пересчет уже выполняется другим экземпляром")
+		return nil
+	}
+	log.Printf("✅ Recommendations пересчитаны за %s: популярное %d, похожие %d, вместе %d, для вас %d",
+		time.Since(started).Round(time.Millisecond), counts[models.RecommendationFeatured], counts[models.RecommendationSimilar],
+		counts[models.RecommendationBoughtTogether], counts[models.RecommendationForYou])
+	return nil
+}
+
+// recommendedProductIDs возвращает ID рекомендованных товаров по убыванию оценки
+func recommendedProductIDs(db *gorm.DB, kind models.RecommendationKind, subjectID *uuid.UUID, limit int) ([]uuid.UUID, error) {
+	query := db.Model(&models.ProductRecommendation{}).Where("kind = ?", kind)
+	if subjectID != nil {
+		query = query.Where("subject_id = ?", *subjectID)
+	} else {
+		query = query.Where("subject_id IS NULL")
+	}
+
+	var ids []uuid.UUID
+	err := query.Order("score DESC").Limit(limit).Pluck("product_id", &ids).Error
+	return ids, err
+}
+
+// appendUniqueIDs добавляет ID без повторов, пока не наберется limit
+func appendUniqueIDs(ids []uuid.UUID, seen map[uuid.UUID]bool, more []uuid.UUID, limit int) []uuid.UUID {
+	for _, id := range more {
+		if len(ids) >= limit {
+			break
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// LoadRecommendedProducts загружает доступные товары в порядке ids
+func LoadRecommendedProducts(db *gorm.DB, ids []uuid.UUID) ([]models.Product, error) {
+	if len(ids) == 0 {
+		return []models.Product{}, nil
+	}
+
+	var products []models.Product
+	if err := db.Where("id IN ? AND is_available = ?", ids, true).
+		Preload("Variations").Preload("Category").Preload("Shop").
+		Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]models.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+	ordered := make([]models.Product, 0, len(products))
+	for _, id := range ids {
+		if product, ok := byID[id]; ok {
+			ordered = append(ordered, product)
+		}
+	}
+	return ordered, nil
+}
+
+// GetFeaturedProductIDs возвращает рекомендуемые товары: сначала действующие
+// закрепления администратора, затем популярное в городе, затем популярное везде,
+// затем новинки (если рекомендации еще не посчитаны)
+func GetFeaturedProductIDs(db *gorm.DB, cityID *uuid.UUID, limit int) ([]uuid.UUID, error) {
+	seen := make(map[uuid.UUID]bool)
+	ids := make([]uuid.UUID, 0, limit)
+	now := time.Now()
+
+	var pinned []uuid.UUID
+	pins := db.Model(&models.ProductPin{}).
+		Where("(starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)", now, now)
+	if cityID != nil {
+		pins = pins.Where("city_id IS NULL OR city_id = ?", *cityID)
+	} else {
+		pins = pins.Where("city_id IS NULL")
+	}
+	if err := pins.Order("position ASC, created_at DESC").Pluck("product_id", &pinned).Error; err != nil {
+		return nil, fmt.Errorf("ошибка загрузки закрепленных товаров: %w", err)
+	}
+	ids = appendUniqueIDs(ids, seen, pinned, limit)
+
+	if cityID != nil && len(ids) < limit {
+		cityIDs, err := recommendedProductIDs(db, models.RecommendationFeatured, cityID, limit)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка загрузки популярного в городе: %w", err)
+		}
+		ids = appendUniqueIDs(ids, seen, cityIDs, limit)
+	}
+
+	if len(ids) < limit {
+		globalIDs, err := recommendedProductIDs(db, models.RecommendationFeatured, nil, limit)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка загрузки популярного: %w", err)
+		}
+		ids = appendUniqueIDs(ids, seen, globalIDs, limit)
+	}
+
+	if len(ids) < limit {
+		var newest []uuid.UUID
+		query := db.Model(&models.Product{}).Where("is_available = ?", true)
+		if cityID != nil {
+			query = query.Where("city_id = ? OR shop_id IN (SELECT id FROM shops WHERE city_id = ?)", *cityID, *cityID)
+		}
+		if err := query.Order("created_at DESC").Limit(limit).Pluck("id", &newest).Error; err != nil {
+			return nil, fmt.Errorf("ошибка загрузки новинок: %w", err)
+		}
+		ids = appendUniqueIDs(ids, seen, newest, limit)
+	}
+
+	return ids, nil
+}
+
+// GetProductRecommendationIDs возвращает похожие товары или товары,
+// которые покупают вместе с productID
+func GetProductRecommendationIDs(db *gorm.DB, kind models.RecommendationKind, productID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	ids, err := recommendedProductIDs(db, kind, &productID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки рекомендаций %s: %w", kind, err)
+	}
+	return ids, nil
+}
+
+// GetForYouProductIDs возвращает персональные рекомендации; если их мало
+// (новый пользователь), дополняет популярным
+func GetForYouProductIDs(db *gorm.DB, userID uuid.UUID, cityID *uuid.UUID, limit int) ([]uuid.UUID, error) {
+	seen := make(map[uuid.UUID]bool)
+	ids := make([]uuid.UUID, 0, limit)
+
+	query := db.Model(&models.ProductRecommendation{}).
+		Where("product_recommendations.kind = ? AND product_recommendations.subject_id = ?", models.RecommendationForYou, userID)
+	if cityID != nil {
+		query = query.Joins("JOIN products ON products.id = product_recommendations.product_id").
+			Where("products.city_id = ? OR products.shop_id IN (SELECT id FROM shops WHERE city_id = ?)", *cityID, *cityID)
+	}
+	var personal []uuid.UUID
+	if err := query.Order("product_recommendations.score DESC").Limit(limit).
+		Pluck("product_recommendations.product_id", &personal).Error; err != nil {
+		return nil, fmt.Errorf("ошибка загрузки персональных рекомендаций: %w", err)
+	}
+	ids = appendUniqueIDs(ids, seen, personal, limit)
+
+	if len(ids) < limit {
+		featured, err := GetFeaturedProductIDs(db, cityID, limit)
+		if err != nil {
+			return nil, err
+		}
+		ids = appendUniqueIDs(ids, seen, featured, limit)
+	}
+	return ids, nil
+}
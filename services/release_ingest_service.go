@@ -0,0 +1,844 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/storage"
+	"github.com/mm-api/mm-api/utils"
+	"gorm.io/gorm"
+)
+
+// releaseIngestMaxSize максимальный размер файла релиза (как у загрузки установщиков POS)
+const releaseIngestMaxSize = 600 << 20
+
+// releaseIngestProcessingDir папка во входящих, куда файл переносится на время обработки
+const releaseIngestProcessingDir = ".processing"
+
+// releaseVersionPattern версия в имени файла: windows_1.2.0.exe, app-android-1.0.0-beta.1.apk
+var releaseVersionPattern = regexp.MustCompile(`(?i)(\d+\.\d+\.\d+(?:\.\d+)?(?:-(?:alpha|beta|rc)(?:\.?\d+)*)?)`)
+
+// releaseUpdatePlatformByExt платформа обновления по расширению файла
+var releaseUpdatePlatformByExt = map[string]models.UpdatePlatform{
+	".apk": models.UpdatePlatformAndroid,
+	".exe": models.UpdatePlatformWindows,
+	".zip": models.UpdatePlatformServer,
+}
+
+// releaseLibissPlatformByExt платформа установщика POS по расширению файла
+var releaseLibissPlatformByExt = map[string]models.LibissPosPlatform{
+	".exe": models.LibissPosPlatformWindows,
+	".apk": models.LibissPosPlatformAndroid,
+}
+
+// ReleaseIngestConfig настройки приема релизов из папки входящих
+type ReleaseIngestConfig struct {
+	InboxDir      string
+	QuarantineDir string
+	ProcessedDir  string        // Пусто - принятые файлы удаляются из входящих
+	Interval      time.Duration // Как часто проверять папку
+	SettleTime    time.Duration // Файл берется в обработку, если не менялся дольше (загрузка по FTP завершена)
+}
+
+// releaseIngestRejection файл не прошел проверку (в отличие от ошибок хранилища и БД,
+// после которых файл остается во входящих и обрабатывается повторно)
+type releaseIngestRejection struct {
+	reason string
+}
+
+func (r *releaseIngestRejection) Error() string {
+	return r.reason
+}
+
+func rejectRelease(format string, args ...interface{}) error {
+	return &releaseIngestRejection{reason: fmt.Sprintf(format, args...)}
+}
+
+// releaseIngestItem файл релиза с описанием
+type releaseIngestItem struct {
+	entry  models.ReleaseIngestEntry
+	source models.ReleaseIngestSource
+	path   string // Файл на диске
+	name   string // Имя для журнала
+}
+
+// releaseIngestState состояние приема из папки входящих для админки
+var releaseIngestState struct {
+	sync.Mutex
+	started    bool
+	cfg        ReleaseIngestConfig
+	lastScanAt *time.Time
+	lastError  string
+}
+
+// releaseIngestNudge проверка входящих без ожидания интервала
+var releaseIngestNudge = make(chan struct{}, 1)
+
+// NudgeReleaseIngest просит воркер проверить папку входящих
+func NudgeReleaseIngest() {
+	select {
+	case releaseIngestNudge <- struct{}{}:
+	default:
+	}
+}
+
+// resolveReleaseIngestEntry дополняет описание файла значениями по имени файла и проверяет его
+func resolveReleaseIngestEntry(entry *models.ReleaseIngestEntry, name string) error {
+	ext := strings.ToLower(filepath.Ext(name))
+	if entry.Kind == "" {
+		entry.Kind = models.ReleaseKindUpdate
+	}
+
+	switch entry.Kind {
+	case models.ReleaseKindUpdate:
+		if entry.Platform == "" {
+			entry.Platform = string(releaseUpdatePlatformByExt[ext])
+		}
+		if _, ok := releaseUpdatePlatformByExt[ext]; !ok {
+			return rejectRelease("unsupported extension %q (allowed: .zip, .exe, .apk)", ext)
+		}
+		switch models.UpdatePlatform(entry.Platform) {
+		case models.UpdatePlatformServer, models.UpdatePlatformWindows, models.UpdatePlatformAndroid:
+		default:
+			return rejectRelease("invalid platform %q (allowed: server, windows, android)", entry.Platform)
+		}
+		entry.Type = ""
+	case models.ReleaseKindLibissPos:
+		switch models.LibissPosType(entry.Type) {
+		case models.LibissPosTypeFull, models.LibissPosTypeCassa2, models.LibissPosTypeServerOnly:
+		default:
+			return rejectRelease("invalid type %q (allowed: full, cassa2, server_only)", entry.Type)
+		}
+		platform, ok := releaseLibissPlatformByExt[ext]
+		if !ok {
+			return rejectRelease("unsupported extension %q (allowed: .exe, .apk)", ext)
+		}
+		if entry.Platform == "" {
+			entry.Platform = string(platform)
+		}
+		if entry.Platform != string(platform) {
+			return rejectRelease("platform %s does not match extension %s", entry.Platform, ext)
+		}
+	default:
+		return rejectRelease("invalid kind %q (allowed: update, libiss_pos)", entry.Kind)
+	}
+
+	if entry.Version == "" {
+		match := releaseVersionPattern.FindString(strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)))
+		if match == "" {
+			return rejectRelease("version not found in file name %q (expected e.g. windows_1.2.0%s)", filepath.Base(name), ext)
+		}
+		entry.Version = match
+	}
+	if _, err := utils.ParseVersion(entry.Version); err != nil {
+		return rejectRelease("%v", err)
+	}
+
+	if entry.Kind == models.ReleaseKindUpdate {
+		channel, err := NormalizeUpdateChannel(models.UpdateChannel(entry.Channel))
+		if err != nil {
+			return rejectRelease("%v", err)
+		}
+		entry.Channel = string(channel)
+		rollout := 100
+		if entry.RolloutPercentage != nil {
+			rollout = *entry.RolloutPercentage
+		}
+		if err := ValidateReleaseVersions(entry.Version, entry.MinSupportedVersion, rollout); err != nil {
+			return rejectRelease("%v", err)
+		}
+	} else {
+		entry.Channel = ""
+	}
+	return nil
+}
+
+// hashReleaseFile размер и SHA256 файла
+func hashReleaseFile(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// releaseVersionConflict проверяет, что версия выше всех версий серии (платформы или
+// типа и платформы POS), включая отозванные. Та же версия с тем же содержимым - повтор.
+func releaseVersionConflict(db *gorm.DB, entry models.ReleaseIngestEntry, checksum string) (*releaseFile, error) {
+	files, err := loadReleaseFiles(db, entry.Kind, false)
+	if err != nil {
+		return nil, err
+	}
+	series := groupReleaseFiles(files)[entry.Platform+"/"+entry.Type]
+	for i := range series {
+		if utils.CompareVersions(series[i].Version, entry.Version) == 0 {
+			if series[i].ChecksumSHA256 == checksum {
+				return &series[i], nil
+			}
+			return nil, rejectRelease("version %s already exists with different content", entry.Version)
+		}
+	}
+	if len(series) > 0 && utils.CompareVersions(entry.Version, series[0].Version) < 0 {
+		return nil, rejectRelease("version %s is lower than the latest version %s", entry.Version, series[0].Version)
+	}
+	return nil, nil
+}
+
+// createIngestedRelease создает UpdateRelease или LibissPosFile для сохраненного файла
+func createIngestedRelease(tx *gorm.DB, entry models.ReleaseIngestEntry, name, key, filename string, size int64, checksum string) (uuid.UUID, error) {
+	if entry.Kind == models.ReleaseKindLibissPos {
+		isPublic := true
+		if entry.IsPublic != nil {
+			isPublic = *entry.IsPublic
+		}
+		file := models.LibissPosFile{
+			Type:           models.LibissPosType(entry.Type),
+			Platform:       models.LibissPosPlatform(entry.Platform),
+			Version:        entry.Version,
+			FileName:       filename,
+			OriginalName:   filepath.Base(name),
+			FilePath:       key,
+			FileURL:        fmt.Sprintf("/api/v1/libiss-pos/download/%s", filename),
+			PublicURL:      fmt.Sprintf("/api/v1/libiss-pos/public/%s", filename),
+			FileSize:       size,
+			ChecksumSHA256: checksum,
+			Description:    entry.ReleaseNotes,
+			IsActive:       true,
+			IsPublic:       isPublic,
+		}
+		err := tx.Create(&file).Error
+		return file.ID, err
+	}
+
+	rollout := 100
+	if entry.RolloutPercentage != nil {
+		rollout = *entry.RolloutPercentage
+	}
+	release := models.UpdateRelease{
+		Platform:            models.UpdatePlatform(entry.Platform),
+		Version:             entry.Version,
+		FileName:            filename,
+		FilePath:            key,
+		FileURL:             "/" + key,
+		FileSize:            size,
+		ChecksumSHA256:      checksum,
+		ReleaseNotes:        entry.ReleaseNotes,
+		IsActive:            true,
+		Channel:             models.UpdateChannel(entry.Channel),
+		RolloutPercentage:   rollout,
+		MinSupportedVersion: entry.MinSupportedVersion,
+	}
+	err := tx.Create(&release).Error
+	return release.ID, err
+}
+
+// ingestRelease проверяет файл и создает релиз. Возвращает запись журнала (не сохраненную)
+// или ошибку хранилища/БД - тогда файл нужно обработать повторно.
+func ingestRelease(ctx context.Context, db *gorm.DB, item releaseIngestItem, dryRun bool) (*models.ReleaseIngestion, error) {
+	record := &models.ReleaseIngestion{Source: item.source, FileName: item.name}
+	reject := func(err error) (*models.ReleaseIngestion, error) {
+		record.Status = models.ReleaseIngestStatusRejected
+		record.Error = err.Error()
+		return record, nil
+	}
+
+	entry := item.entry
+	err := resolveReleaseIngestEntry(&entry, item.name)
+	record.Kind, record.Platform, record.Type = entry.Kind, entry.Platform, entry.Type
+	record.Version, record.Channel = entry.Version, entry.Channel
+	if err != nil {
+		return reject(err)
+	}
+
+	size, checksum, err := hashReleaseFile(item.path)
+	if err != nil {
+		return reject(rejectRelease("failed to read file: %v", err))
+	}
+	record.FileSize, record.ChecksumSHA256 = size, checksum
+	switch {
+	case size == 0:
+		return reject(rejectRelease("file is empty"))
+	case size > releaseIngestMaxSize:
+		return reject(rejectRelease("file size too large (max %d MB), got %d bytes", releaseIngestMaxSize>>20, size))
+	case entry.SHA256 != "" && !strings.EqualFold(entry.SHA256, checksum):
+		return reject(rejectRelease("checksum mismatch: expected %s, got %s", entry.SHA256, checksum))
+	}
+
+	existing, err := releaseVersionConflict(db, entry, checksum)
+	var rejection *releaseIngestRejection
+	if errors.As(err, &rejection) {
+		return reject(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		record.Status = models.ReleaseIngestStatusDuplicate
+		record.ReleaseID = &existing.ID
+		return record, nil
+	}
+	if dryRun {
+		record.Status = models.ReleaseIngestStatusImported
+		return record, nil
+	}
+
+	// Файл сохраняется до транзакции: загрузка в S3 может занять минуты
+	ext := strings.ToLower(filepath.Ext(item.name))
+	var filename, key string
+	if entry.Kind == models.ReleaseKindLibissPos {
+		filename = fmt.Sprintf("%s_%s_%s%s", entry.Type, entry.Version, uuid.NewString()[:8], ext)
+		key = storage.JoinKey("libiss_pos", entry.Type, filename)
+	} else {
+		filename = fmt.Sprintf("%s_%s_%s%s", entry.Platform, entry.Version, uuid.NewString(), ext)
+		key = storage.JoinKey("updates", entry.Platform, filename)
+	}
+	file, err := os.Open(item.path)
+	if err != nil {
+		return nil, err
+	}
+	err = storage.Default().Put(ctx, key, file, size, "application/octet-stream")
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сохранения %s: %w", key, err)
+	}
+
+	var releaseID uuid.UUID
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Прием в одну серию (платформу или тип POS) - по одному, чтобы проверка версий не устарела
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))",
+			"mm_release_ingest:"+string(entry.Kind)+":"+entry.Platform+":"+entry.Type).Error; err != nil {
+			return err
+		}
+		existing, err := releaseVersionConflict(tx, entry, checksum)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return rejectRelease("version %s was added concurrently", entry.Version)
+		}
+		releaseID, err = createIngestedRelease(tx, entry, item.name, key, filename, size, checksum)
+		return err
+	})
+	if err != nil {
+		storage.Default().Delete(context.Background(), key)
+		if errors.As(err, &rejection) {
+			return reject(err)
+		}
+		return nil, err
+	}
+
+	record.Status = models.ReleaseIngestStatusImported
+	record.ReleaseID = &releaseID
+	log.Printf("✅ Релиз принят: %s %s/%s %s (%d байт)", entry.Kind, entry.Platform, entry.Type, entry.Version, size)
+	NudgeReleasePatches()
+	return record, nil
+}
+
+// releaseInboxCandidate файл релиза во входящих
+type releaseInboxCandidate struct {
+	rel  string // Путь относительно входящих
+	kind models.ReleaseKind
+	typ  string
+	info fs.FileInfo
+
+	series  string // Серия релиза (вид, платформа, тип); пусто - файл будет отклонен
+	version string
+}
+
+// resolveInboxVersion определяет серию и версию файла по описанию и имени, чтобы
+// файлы серии принимались по возрастанию версии. Ошибки не возвращаются: такой
+// файл отклонит processInboxFile.
+func resolveInboxVersion(inbox string, candidate *releaseInboxCandidate) {
+	var entry models.ReleaseIngestEntry
+	if metaPath, _ := releaseSidecars(filepath.Join(inbox, candidate.rel)); metaPath != "" {
+		data, err := os.ReadFile(metaPath)
+		if err != nil || json.Unmarshal(data, &entry) != nil {
+			return
+		}
+	}
+	entry.Kind, entry.Type = candidate.kind, candidate.typ
+	if err := resolveReleaseIngestEntry(&entry, candidate.rel); err != nil {
+		return
+	}
+	candidate.series = string(entry.Kind) + "/" + entry.Platform + "/" + entry.Type
+	candidate.version = entry.Version
+}
+
+// releaseInboxIgnored временные файлы FTP-клиентов и сопроводительные файлы
+func releaseInboxIgnored(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".part", ".filepart", ".tmp", ".crdownload", ".md", ".txt", ".json":
+		return true
+	}
+	return false
+}
+
+// listReleaseInbox файлы релизов во входящих:
+//
+//	<inbox>/windows_1.2.0.exe                - обновление (как раньше по FTP)
+//	<inbox>/updates/windows_1.2.0.exe        - обновление
+//	<inbox>/libiss_pos/<тип>/setup_1.2.0.exe - установщик POS типа full, cassa2 или server_only
+func listReleaseInbox(inbox string) ([]releaseInboxCandidate, error) {
+	var candidates []releaseInboxCandidate
+	add := func(dir string, kind models.ReleaseKind, typ string) error {
+		entries, err := os.ReadDir(filepath.Join(inbox, dir))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.IsDir() || releaseInboxIgnored(e.Name()) {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			candidates = append(candidates, releaseInboxCandidate{
+				rel: filepath.Join(dir, e.Name()), kind: kind, typ: typ, info: info,
+			})
+		}
+		return nil
+	}
+
+	if err := add("", models.ReleaseKindUpdate, ""); err != nil {
+		return nil, err
+	}
+	if err := add("updates", models.ReleaseKindUpdate, ""); err != nil {
+		return nil, err
+	}
+	types, err := os.ReadDir(filepath.Join(inbox, "libiss_pos"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	for _, t := range types {
+		if t.IsDir() && !strings.HasPrefix(t.Name(), ".") {
+			if err := add(filepath.Join("libiss_pos", t.Name()), models.ReleaseKindLibissPos, t.Name()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// releaseSidecars сопроводительные файлы: <файл>.json (описание), <файл>.md/.txt или
+// <имя без расширения>.md/.txt (описание изменений)
+func releaseSidecars(path string) (meta string, notes string) {
+	stem := strings.TrimSuffix(path, filepath.Ext(path))
+	if _, err := os.Stat(path + ".json"); err == nil {
+		meta = path + ".json"
+	}
+	for _, candidate := range []string{path + ".md", path + ".txt", stem + ".md", stem + ".txt"} {
+		if _, err := os.Stat(candidate); err == nil {
+			notes = candidate
+			break
+		}
+	}
+	return meta, notes
+}
+
+// readReleaseNotes читает описание изменений из файла
+func readReleaseNotes(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimPrefix(string(data), "\ufeff")), nil
+}
+
+// moveReleaseFile переносит файл в папку с отметкой времени в имени
+func moveReleaseFile(path, dir, name string, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	dst := filepath.Join(dir, now.Format("20060102_150405")+"_"+name)
+	if err := os.Rename(path, dst); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// finishInboxFile переносит обработанный файл и его сопроводительные файлы:
+// принятые - в папку обработанных (или удаляет), отклоненные - в карантин с описанием ошибки
+func finishInboxFile(cfg ReleaseIngestConfig, processing string, candidate releaseInboxCandidate, sidecars []string, record *models.ReleaseIngestion) {
+	now := time.Now()
+	name := filepath.Base(candidate.rel)
+	dir := cfg.ProcessedDir
+	if record.Status == models.ReleaseIngestStatusRejected {
+		dir = cfg.QuarantineDir
+	}
+
+	if dir == "" {
+		os.Remove(processing)
+		for _, sidecar := range sidecars {
+			os.Remove(sidecar)
+		}
+		return
+	}
+
+	dst, err := moveReleaseFile(processing, dir, name, now)
+	if err != nil {
+		log.Printf("⚠️ Не удалось перенести %s в %s: %v", name, dir, err)
+		os.Remove(processing)
+		return
+	}
+	for _, sidecar := range sidecars {
+		if _, err := moveReleaseFile(sidecar, dir, filepath.Base(sidecar), now); err != nil {
+			log.Printf("⚠️ Не удалось перенести %s в %s: %v", sidecar, dir, err)
+		}
+	}
+	if record.Status == models.ReleaseIngestStatusRejected {
+		record.QuarantinePath = dst
+		if err := os.WriteFile(dst+".error.txt", []byte(record.Error+"\n"), 0644); err != nil {
+			log.Printf("⚠️ Не удалось записать причину отклонения %s: %v", name, err)
+		}
+	}
+}
+
+// processInboxFile обрабатывает один файл входящих. Ошибка - файл возвращен во входящие.
+func processInboxFile(ctx context.Context, db *gorm.DB, cfg ReleaseIngestConfig, candidate releaseInboxCandidate) error {
+	path := filepath.Join(cfg.InboxDir, candidate.rel)
+	entry := models.ReleaseIngestEntry{Kind: candidate.kind, Type: candidate.typ}
+	metaPath, notesPath := releaseSidecars(path)
+
+	var sidecarErr error
+	if metaPath != "" {
+		data, err := os.ReadFile(metaPath)
+		if err == nil {
+			err = json.Unmarshal(data, &entry)
+		}
+		if err != nil {
+			sidecarErr = rejectRelease("invalid %s: %v", filepath.Base(metaPath), err)
+		}
+		// Вид и тип задаются папкой
+		entry.Kind, entry.Type = candidate.kind, candidate.typ
+	}
+	if sidecarErr == nil && entry.ReleaseNotes == "" && notesPath != "" {
+		notes, err := readReleaseNotes(notesPath)
+		if err != nil {
+			return err
+		}
+		entry.ReleaseNotes = notes
+	}
+
+	// Переносим файл в .processing: другой экземпляр сервера его уже не возьмет
+	processing := filepath.Join(cfg.InboxDir, releaseIngestProcessingDir, candidate.rel)
+	if err := os.MkdirAll(filepath.Dir(processing), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(path, processing); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	item := releaseIngestItem{entry: entry, source: models.ReleaseIngestSourceInbox, path: processing, name: candidate.rel}
+	var record *models.ReleaseIngestion
+	var err error
+	if sidecarErr != nil {
+		record = &models.ReleaseIngestion{
+			Source: item.source, Kind: candidate.kind, Type: candidate.typ, FileName: item.name,
+			Status: models.ReleaseIngestStatusRejected, Error: sidecarErr.Error(),
+		}
+	} else {
+		record, err = ingestRelease(ctx, db, item, false)
+	}
+	if err != nil {
+		if renameErr := os.Rename(processing, path); renameErr != nil {
+			log.Printf("⚠️ Не удалось вернуть %s во входящие: %v", candidate.rel, renameErr)
+		}
+		return err
+	}
+
+	var sidecars []string
+	for _, sidecar := range []string{metaPath, notesPath} {
+		if sidecar != "" {
+			sidecars = append(sidecars, sidecar)
+		}
+	}
+	finishInboxFile(cfg, processing, candidate, sidecars, record)
+	if record.Status == models.ReleaseIngestStatusRejected {
+		log.Printf("⚠️ Файл %s отклонен: %s", candidate.rel, record.Error)
+	}
+	if err := db.Create(record).Error; err != nil {
+		log.Printf("⚠️ Не удалось сохранить запись о приеме %s: %v", candidate.rel, err)
+	}
+	return nil
+}
+
+// ScanReleaseInbox обрабатывает загруженные во входящие файлы. Возвращает число обработанных.
+// Файлы одной серии принимаются по возрастанию версии (версия ниже последней
+// отклоняется), поэтому несколько версий, загруженных за раз, принимаются все.
+func ScanReleaseInbox(ctx context.Context, db *gorm.DB, cfg ReleaseIngestConfig) (int, error) {
+	candidates, err := listReleaseInbox(cfg.InboxDir)
+	if err != nil {
+		return 0, err
+	}
+	for i := range candidates {
+		resolveInboxVersion(cfg.InboxDir, &candidates[i])
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].series != candidates[j].series {
+			return candidates[i].series < candidates[j].series
+		}
+		return utils.CompareVersions(candidates[i].version, candidates[j].version) < 0
+	})
+
+	processed := 0
+	var lastErr error
+	// Серии, в которых младшая версия еще загружается или не принята: старшие
+	// версии ждут следующей проверки, иначе младшая будет отклонена
+	waiting := make(map[string]bool)
+	for _, candidate := range candidates {
+		if ctx.Err() != nil {
+			return processed, ctx.Err()
+		}
+		if candidate.series != "" && waiting[candidate.series] {
+			continue
+		}
+		if time.Since(candidate.info.ModTime()) < cfg.SettleTime {
+			waiting[candidate.series] = true
+			continue
+		}
+		if err := processInboxFile(ctx, db, cfg, candidate); err != nil {
+			log.Printf("❌ Ошибка приема %s (повтор при следующей проверке): %v", candidate.rel, err)
+			waiting[candidate.series] = true
+			lastErr = err
+			continue
+		}
+		processed++
+	}
+	return processed, lastErr
+}
+
+// recoverReleaseInbox возвращает во входящие файлы, обработка которых прервана перезапуском
+func recoverReleaseInbox(inbox string) {
+	root := filepath.Join(inbox, releaseIngestProcessingDir)
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		dst := filepath.Join(inbox, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err == nil {
+			if err := os.Rename(path, dst); err == nil {
+				log.Printf("ℹ️ Файл %s возвращен во входящие после перезапуска", rel)
+			}
+		}
+		return nil
+	})
+}
+
+// StartReleaseIngestWorker запускает прием релизов из папки входящих
+func StartReleaseIngestWorker(ctx context.Context, cfg ReleaseIngestConfig) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	for _, dir := range []string{cfg.InboxDir, cfg.QuarantineDir, cfg.ProcessedDir} {
+		if dir == "" {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Printf("❌ Прием релизов не запущен: папка %s: %v", dir, err)
+			return
+		}
+	}
+	recoverReleaseInbox(cfg.InboxDir)
+
+	releaseIngestState.Lock()
+	releaseIngestState.started = true
+	releaseIngestState.cfg = cfg
+	releaseIngestState.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			if database.DB != nil {
+				processed, err := ScanReleaseInbox(ctx, database.DB, cfg)
+				now := time.Now()
+				releaseIngestState.Lock()
+				releaseIngestState.lastScanAt = &now
+				releaseIngestState.lastError = ""
+				if err != nil {
+					releaseIngestState.lastError = err.Error()
+				}
+				releaseIngestState.Unlock()
+				if processed > 0 {
+					log.Printf("✅ Прием релизов: обработано файлов %d", processed)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-releaseIngestNudge:
+			}
+		}
+	}()
+
+	log.Printf("✅ Прием релизов запущен (входящие: %s, карантин: %s, интервал: %s)", cfg.InboxDir, cfg.QuarantineDir, cfg.Interval)
+}
+
+// ReleaseIngestEnabled запущен ли прием релизов из папки входящих
+func ReleaseIngestEnabled() bool {
+	releaseIngestState.Lock()
+	defer releaseIngestState.Unlock()
+	return releaseIngestState.started
+}
+
+// GetReleaseIngestStatus состояние приема релизов: папки, ожидающие файлы, итоги за 30 дней
+func GetReleaseIngestStatus(db *gorm.DB) (*models.ReleaseIngestStatusResponse, error) {
+	releaseIngestState.Lock()
+	status := &models.ReleaseIngestStatusResponse{
+		Enabled:       releaseIngestState.started,
+		InboxDir:      releaseIngestState.cfg.InboxDir,
+		QuarantineDir: releaseIngestState.cfg.QuarantineDir,
+		ProcessedDir:  releaseIngestState.cfg.ProcessedDir,
+		LastScanAt:    releaseIngestState.lastScanAt,
+		LastError:     releaseIngestState.lastError,
+		Pending:       []models.ReleaseInboxFile{},
+		Counts:        map[models.ReleaseIngestStatus]int64{},
+	}
+	settle := releaseIngestState.cfg.SettleTime
+	releaseIngestState.Unlock()
+
+	if status.Enabled {
+		candidates, err := listReleaseInbox(status.InboxDir)
+		if err != nil {
+			status.LastError = err.Error()
+		}
+		for _, candidate := range candidates {
+			status.Pending = append(status.Pending, models.ReleaseInboxFile{
+				Path:       filepath.ToSlash(candidate.rel),
+				Size:       candidate.info.Size(),
+				ModifiedAt: candidate.info.ModTime(),
+				Settled:    time.Since(candidate.info.ModTime()) >= settle,
+			})
+		}
+	}
+
+	var counts []struct {
+		Status models.ReleaseIngestStatus
+		Count  int64
+	}
+	if err := db.Model(&models.ReleaseIngestion{}).
+		Select("status, COUNT(*) AS count").
+		Where("created_at >= ?", time.Now().AddDate(0, 0, -30)).
+		Group("status").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range counts {
+		status.Counts[c.Status] = c.Count
+	}
+	if err := db.Order("created_at DESC").Limit(20).Find(&status.Recent).Error; err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ImportReleaseManifest принимает релизы по манифесту (команда import-releases).
+// Пути файлов - относительно манифеста. Внутри серии файлы принимаются по возрастанию
+// версии. Отклоненные файлы остаются на месте; при ошибке хранилища или БД импорт
+// прерывается, повторный запуск пропустит уже принятые версии (duplicate).
+func ImportReleaseManifest(ctx context.Context, db *gorm.DB, manifestPath string, dryRun bool) ([]models.ReleaseIngestion, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	var manifest models.ReleaseIngestManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	baseDir := filepath.Dir(manifestPath)
+	resolve := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(baseDir, path)
+	}
+
+	records := make([]models.ReleaseIngestion, 0, len(manifest.Releases))
+	var items []releaseIngestItem
+	for _, entry := range manifest.Releases {
+		item := releaseIngestItem{entry: entry, source: models.ReleaseIngestSourceManifest, path: resolve(entry.File), name: entry.File}
+		err := resolveReleaseIngestEntry(&item.entry, entry.File)
+		if err == nil && entry.File == "" {
+			err = rejectRelease("file is required")
+		}
+		if err == nil && item.entry.ReleaseNotes == "" && item.entry.ReleaseNotesFile != "" {
+			item.entry.ReleaseNotes, err = readReleaseNotes(resolve(item.entry.ReleaseNotesFile))
+			if err != nil {
+				err = rejectRelease("releaseNotesFile: %v", err)
+			}
+		}
+		if err != nil {
+			records = append(records, models.ReleaseIngestion{
+				Source: item.source, Kind: item.entry.Kind, FileName: item.name, Platform: item.entry.Platform,
+				Type: item.entry.Type, Version: item.entry.Version, Status: models.ReleaseIngestStatusRejected, Error: err.Error(),
+			})
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].entry, items[j].entry
+		if seriesA, seriesB := string(a.Kind)+":"+a.Platform+"/"+a.Type, string(b.Kind)+":"+b.Platform+"/"+b.Type; seriesA != seriesB {
+			return seriesA < seriesB
+		}
+		return utils.CompareVersions(a.Version, b.Version) < 0
+	})
+
+	var importErr error
+	for _, item := range items {
+		record, err := ingestRelease(ctx, db, item, dryRun)
+		if err != nil {
+			importErr = fmt.Errorf("%s: %w", item.name, err)
+			break
+		}
+		records = append(records, *record)
+	}
+
+	if !dryRun && len(records) > 0 {
+		if err := db.Create(&records).Error; err != nil {
+			log.Printf("⚠️ Не удалось сохранить журнал импорта: %v", err)
+		}
+	}
+	return records, importErr
+}
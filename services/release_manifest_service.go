@@ -0,0 +1,163 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/mm-api/mm-api/config"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/utils"
+	"gorm.io/gorm"
+)
+
+const releaseSigningAlgorithm = "ed25519"
+
+// ErrReleaseSigningDisabled ключ подписи манифестов не задан (RELEASE_SIGNING_KEY)
+var ErrReleaseSigningDisabled = errors.New("release signing key is not configured")
+
+var (
+	releaseSigningOnce sync.Once
+	releaseSigningPriv ed25519.PrivateKey
+)
+
+// releaseSigningKey ключ подписи манифестов. RELEASE_SIGNING_KEY - base64 seed (32 байта)
+// или приватного ключа (64 байта); любое другое значение превращается в seed через
+// SHA256. Ключ не выводится из других секретов: клиенты встраивают публичный ключ
+// в сборку, и он не должен меняться вместе с JWT_SECRET. Без ключа - nil.
+func releaseSigningKey() ed25519.PrivateKey {
+	releaseSigningOnce.Do(func() {
+		secret := config.GetConfig().ReleaseSigningKey
+		if secret == "" {
+			log.Printf("⚠️ RELEASE_SIGNING_KEY не задан, манифесты релизов не подписываются")
+			return
+		}
+		if raw, err := base64.StdEncoding.DecodeString(secret); err == nil {
+			switch len(raw) {
+			case ed25519.SeedSize:
+				releaseSigningPriv = ed25519.NewKeyFromSeed(raw)
+				return
+			case ed25519.PrivateKeySize:
+				releaseSigningPriv = ed25519.NewKeyFromSeed(raw[:ed25519.SeedSize])
+				return
+			}
+		}
+		log.Printf("⚠️ RELEASE_SIGNING_KEY не является base64 ключа ed25519, ключ получен из строки через SHA256")
+		seed := sha256.Sum256([]byte(secret))
+		releaseSigningPriv = ed25519.NewKeyFromSeed(seed[:])
+	})
+	return releaseSigningPriv
+}
+
+// ReleaseSigningPublicKey публичный ключ проверки манифестов (ErrReleaseSigningDisabled без ключа)
+func ReleaseSigningPublicKey() (*models.ReleaseSigningKeyResponse, error) {
+	priv := releaseSigningKey()
+	if priv == nil {
+		return nil, ErrReleaseSigningDisabled
+	}
+	pub := priv.Public().(ed25519.PublicKey)
+	sum := sha256.Sum256(pub)
+	return &models.ReleaseSigningKeyResponse{
+		Algorithm: releaseSigningAlgorithm,
+		KeyID:     hex.EncodeToString(sum[:8]),
+		PublicKey: base64.StdEncoding.EncodeToString(pub),
+	}, nil
+}
+
+// SignReleaseManifest сериализует манифест и подписывает полученные байты
+func SignReleaseManifest(manifest *models.ReleaseManifest) (*models.SignedReleaseManifest, error) {
+	key, err := ReleaseSigningPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	return &models.SignedReleaseManifest{
+		Manifest:  base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(releaseSigningKey(), payload)),
+		Algorithm: key.Algorithm,
+		KeyID:     key.KeyID,
+		Data:      *manifest,
+	}, nil
+}
+
+// BuildUpdateManifest манифест активных обновлений платформы в каналах клиента (от новой версии к старой)
+func BuildUpdateManifest(db *gorm.DB, platform models.UpdatePlatform, channel models.UpdateChannel) (*models.ReleaseManifest, error) {
+	channel, err := NormalizeUpdateChannel(channel)
+	if err != nil {
+		return nil, err
+	}
+	set, err := loadUpdateReleases(db, platform, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &models.ReleaseManifest{
+		Kind:     models.ReleaseKindUpdate,
+		Platform: string(platform),
+		Channel:  string(channel),
+		Entries:  []models.ReleaseManifestEntry{},
+	}
+	for _, release := range set.releases {
+		if release.UpdatedAt.After(manifest.UpdatedAt) {
+			manifest.UpdatedAt = release.UpdatedAt
+		}
+		manifest.Entries = append(manifest.Entries, models.ReleaseManifestEntry{
+			ID:             release.ID,
+			Version:        release.Version,
+			Channel:        string(release.Channel),
+			FileName:       release.FileName,
+			FileSize:       release.FileSize,
+			ChecksumSHA256: release.ChecksumSHA256,
+		})
+	}
+	return manifest, nil
+}
+
+// BuildLibissPosManifest манифест активных публичных установщиков POS платформы
+// (всех типов, если fileType пустой), от новой версии к старой
+func BuildLibissPosManifest(db *gorm.DB, platform, fileType string) (*models.ReleaseManifest, error) {
+	query := db.Where("platform = ? AND is_active = ? AND is_public = ?", platform, true, true)
+	if fileType != "" {
+		query = query.Where("type = ?", fileType)
+	}
+	var files []models.LibissPosFile
+	if err := query.Order("type, created_at DESC").Find(&files).Error; err != nil {
+		return nil, err
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Type != files[j].Type {
+			return files[i].Type < files[j].Type
+		}
+		return utils.CompareVersions(files[i].Version, files[j].Version) > 0
+	})
+
+	manifest := &models.ReleaseManifest{
+		Kind:     models.ReleaseKindLibissPos,
+		Platform: platform,
+		Type:     fileType,
+		Entries:  []models.ReleaseManifestEntry{},
+	}
+	for _, file := range files {
+		if file.UpdatedAt.After(manifest.UpdatedAt) {
+			manifest.UpdatedAt = file.UpdatedAt
+		}
+		manifest.Entries = append(manifest.Entries, models.ReleaseManifestEntry{
+			ID:             file.ID,
+			Version:        file.Version,
+			Type:           string(file.Type),
+			FileName:       file.FileName,
+			FileSize:       file.FileSize,
+			ChecksumSHA256: file.ChecksumSHA256,
+		})
+	}
+	return manifest, nil
+}
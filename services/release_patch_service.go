@@ -0,0 +1,466 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/storage"
+	"github.com/mm-api/mm-api/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// releasePatchMaxRatio патч не выдается, если он больше этой доли полного файла
+const releasePatchMaxRatio = 0.9
+
+// releasePatchStaleTimeout через сколько патч в статусе processing считается брошенным (рестарт)
+const releasePatchStaleTimeout = time.Hour
+
+// ReleasePatchConfig настройки построения патчей
+type ReleasePatchConfig struct {
+	Interval  time.Duration // Как часто искать новые версии
+	Depth     int           // Патчи строятся для N последних версий от N предыдущих
+	Platforms []string      // Платформы, для которых строятся патчи (windows)
+}
+
+// releasePatchNudge запуск построения без ожидания интервала (после загрузки версии)
+var releasePatchNudge = make(chan struct{}, 1)
+
+// NudgeReleasePatches просит воркер построить патчи для новых версий
+func NudgeReleasePatches() {
+	select {
+	case releasePatchNudge <- struct{}{}:
+	default:
+	}
+}
+
+// releaseFile общий вид UpdateRelease и LibissPosFile
+type releaseFile struct {
+	ID             uuid.UUID
+	Kind           models.ReleaseKind
+	Platform       string
+	Type           string
+	Channel        string // Канал обновления (у установщиков POS пусто)
+	Version        string
+	FilePath       string
+	FileSize       int64
+	ChecksumSHA256 string
+	IsActive       bool
+	CreatedAt      time.Time
+
+	MinSupportedVersion string // Только у обновлений
+}
+
+// releaseStoragePrefix папка хранилища для файлов выпуска
+func releaseStoragePrefix(kind models.ReleaseKind) string {
+	if kind == models.ReleaseKindLibissPos {
+		return "libiss_pos"
+	}
+	return "updates"
+}
+
+func loadReleaseFiles(db *gorm.DB, kind models.ReleaseKind, activeOnly bool) ([]releaseFile, error) {
+	var files []releaseFile
+	switch kind {
+	case models.ReleaseKindUpdate:
+		var releases []models.UpdateRelease
+		query := db.Model(&models.UpdateRelease{})
+		if activeOnly {
+			query = query.Where("is_active = ?", true)
+		}
+		if err := query.Find(&releases).Error; err != nil {
+			return nil, err
+		}
+		for _, r := range releases {
+			files = append(files, releaseFile{
+				ID: r.ID, Kind: kind, Platform: string(r.Platform), Channel: string(r.Channel), Version: r.Version,
+				FilePath: r.FilePath, FileSize: r.FileSize, ChecksumSHA256: r.ChecksumSHA256,
+				IsActive: r.IsActive, CreatedAt: r.CreatedAt, MinSupportedVersion: r.MinSupportedVersion,
+			})
+		}
+	case models.ReleaseKindLibissPos:
+		var posFiles []models.LibissPosFile
+		query := db.Model(&models.LibissPosFile{})
+		if activeOnly {
+			query = query.Where("is_active = ?", true)
+		}
+		if err := query.Find(&posFiles).Error; err != nil {
+			return nil, err
+		}
+		for _, f := range posFiles {
+			files = append(files, releaseFile{
+				ID: f.ID, Kind: kind, Platform: string(f.Platform), Type: string(f.Type), Version: f.Version,
+				FilePath: f.FilePath, FileSize: f.FileSize, ChecksumSHA256: f.ChecksumSHA256,
+				IsActive: f.IsActive, CreatedAt: f.CreatedAt,
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unknown release kind %q", kind)
+	}
+	return files, nil
+}
+
+// groupReleaseFiles группирует версии по платформе (и типу для POS), от новой к старой
+func groupReleaseFiles(files []releaseFile) map[string][]releaseFile {
+	groups := make(map[string][]releaseFile)
+	for _, f := range files {
+		key := f.Platform + "/" + f.Type
+		groups[key] = append(groups[key], f)
+	}
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			if cmp := utils.CompareVersions(group[i].Version, group[j].Version); cmp != 0 {
+				return cmp > 0
+			}
+			return group[i].CreatedAt.After(group[j].CreatedAt)
+		})
+	}
+	return groups
+}
+
+// PlanReleasePatches добавляет в очередь патчи для последних cfg.Depth версий каждой
+// платформы (и типа POS) от cfg.Depth предыдущих. Уже построенные не пересоздаются.
+func PlanReleasePatches(db *gorm.DB, cfg ReleasePatchConfig) (int, error) {
+	depth := cfg.Depth
+	if depth <= 0 {
+		depth = 3
+	}
+	platforms := make(map[string]bool, len(cfg.Platforms))
+	for _, p := range cfg.Platforms {
+		platforms[p] = true
+	}
+
+	planned := 0
+	for _, kind := range []models.ReleaseKind{models.ReleaseKindUpdate, models.ReleaseKindLibissPos} {
+		files, err := loadReleaseFiles(db, kind, true)
+		if err != nil {
+			return planned, err
+		}
+		for _, group := range groupReleaseFiles(files) {
+			if !platforms[group[0].Platform] {
+				continue
+			}
+			for t := 0; t < depth && t < len(group); t++ {
+				target := group[t]
+				for s := t + 1; s <= t+depth && s < len(group); s++ {
+					source := group[s]
+					if utils.CompareVersions(source.Version, target.Version) == 0 || source.ChecksumSHA256 == target.ChecksumSHA256 {
+						continue
+					}
+					patch := models.ReleasePatch{
+						Kind:          kind,
+						Platform:      target.Platform,
+						Type:          target.Type,
+						SourceID:      source.ID,
+						TargetID:      target.ID,
+						SourceVersion: source.Version,
+						TargetVersion: target.Version,
+						SourceSHA256:  source.ChecksumSHA256,
+						TargetSHA256:  target.ChecksumSHA256,
+						TargetSize:    target.FileSize,
+						Status:        models.ReleasePatchStatusPending,
+					}
+					result := db.Clauses(clause.OnConflict{
+						Columns:   []clause.Column{{Name: "target_id"}, {Name: "source_id"}},
+						DoNothing: true,
+					}).Create(&patch)
+					if result.Error != nil {
+						return planned, result.Error
+					}
+					planned += int(result.RowsAffected)
+				}
+			}
+		}
+	}
+	return planned, nil
+}
+
+// claimReleasePatch захватывает следующий патч из очереди
+func claimReleasePatch(db *gorm.DB) (*models.ReleasePatch, error) {
+	var patch models.ReleasePatch
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ReleasePatch{}).
+			Where("status = ? AND updated_at < ?", models.ReleasePatchStatusProcessing, time.Now().Add(-releasePatchStaleTimeout)).
+			Update("status", models.ReleasePatchStatusPending).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", models.ReleasePatchStatusPending).
+			Order("created_at").
+			First(&patch).Error; err != nil {
+			return err
+		}
+		return tx.Model(&patch).Update("status", models.ReleasePatchStatusProcessing).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &patch, nil
+}
+
+// downloadToTemp копирует объект хранилища во временный файл
+func downloadToTemp(ctx context.Context, store storage.Storage, key, expectedSHA256 string) (*os.File, int64, error) {
+	reader, _, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer reader.Close()
+
+	tmp, err := os.CreateTemp("", "release-*")
+	if err != nil {
+		return nil, 0, err
+	}
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), reader)
+	if err == nil && expectedSHA256 != "" && !strings.EqualFold(hex.EncodeToString(hasher.Sum(nil)), expectedSHA256) {
+		err = fmt.Errorf("checksum of %s does not match the release", key)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, 0, err
+	}
+	return tmp, size, nil
+}
+
+func removeTemp(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// releasePatchKey ключ патча в хранилище
+func releasePatchKey(patch *models.ReleasePatch) string {
+	safe := func(s string) string {
+		return strings.NewReplacer("/", "_", "\\", "_", " ", "_").Replace(s)
+	}
+	dir := storage.JoinKey(releaseStoragePrefix(patch.Kind), "patches", patch.Platform)
+	if patch.Type != "" {
+		dir = storage.JoinKey(dir, patch.Type)
+	}
+	return storage.JoinKey(dir, fmt.Sprintf("%s_to_%s_%s.mmdelta", safe(patch.SourceVersion), safe(patch.TargetVersion), patch.ID.String()[:8]))
+}
+
+// BuildReleasePatch строит патч, проверяет его применением к исходной версии
+// и сохраняет в хранилище. Патчи, почти не меньше полного файла, пропускаются.
+func BuildReleasePatch(ctx context.Context, db *gorm.DB, store storage.Storage, patch *models.ReleasePatch) error {
+	buildErr := buildReleasePatch(ctx, db, store, patch)
+	if buildErr != nil {
+		patch.Status = models.ReleasePatchStatusFailed
+		patch.Error = buildErr.Error()
+	}
+	if err := db.Model(patch).Select("Status", "Error", "FilePath", "FileSize", "ChecksumSHA256", "SourceSHA256", "TargetSHA256", "TargetSize").
+		Updates(patch).Error; err != nil {
+		return err
+	}
+	return buildErr
+}
+
+func buildReleasePatch(ctx context.Context, db *gorm.DB, store storage.Storage, patch *models.ReleasePatch) error {
+	files, err := loadReleaseFiles(db.Where("id IN ?", []uuid.UUID{patch.SourceID, patch.TargetID}), patch.Kind, false)
+	if err != nil {
+		return err
+	}
+	var source, target *releaseFile
+	for i := range files {
+		switch files[i].ID {
+		case patch.SourceID:
+			source = &files[i]
+		case patch.TargetID:
+			target = &files[i]
+		}
+	}
+	if source == nil || target == nil {
+		return errors.New("source or target release no longer exists")
+	}
+
+	sourceFile, sourceSize, err := downloadToTemp(ctx, store, source.FilePath, source.ChecksumSHA256)
+	if err != nil {
+		return fmt.Errorf("source %s: %w", source.Version, err)
+	}
+	defer removeTemp(sourceFile)
+	targetFile, targetSize, err := downloadToTemp(ctx, store, target.FilePath, target.ChecksumSHA256)
+	if err != nil {
+		return fmt.Errorf("target %s: %w", target.Version, err)
+	}
+	defer removeTemp(targetFile)
+
+	patchFile, err := os.CreateTemp("", "release-patch-*.mmdelta")
+	if err != nil {
+		return err
+	}
+	defer removeTemp(patchFile)
+
+	started := time.Now()
+	info, err := utils.CreateDelta(sourceFile, sourceSize, targetFile, targetSize, patchFile)
+	if err != nil {
+		return err
+	}
+	patchSize, err := patchFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	patch.SourceSHA256 = info.SourceSHA256
+	patch.TargetSHA256 = info.TargetSHA256
+	patch.TargetSize = info.TargetSize
+
+	if float64(patchSize) > float64(targetSize)*releasePatchMaxRatio {
+		patch.Status = models.ReleasePatchStatusSkipped
+		patch.Error = fmt.Sprintf("patch is %d bytes, full file is %d bytes", patchSize, targetSize)
+		log.Printf("ℹ️ Патч %s %s -> %s не дает выигрыша (%d из %d байт), пропущен",
+			patch.Platform, patch.SourceVersion, patch.TargetVersion, patchSize, targetSize)
+		return nil
+	}
+
+	// Проверяем патч так же, как клиент: применяем к исходной версии
+	if _, err := patchFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	hasher := sha256.New()
+	if _, err := utils.ApplyDelta(sourceFile, io.TeeReader(patchFile, hasher), io.Discard); err != nil {
+		return fmt.Errorf("verification failed: %w", err)
+	}
+	// ApplyDelta может не дочитать хвост потока deflate - досчитываем хеш
+	if _, err := io.Copy(hasher, patchFile); err != nil {
+		return err
+	}
+
+	if _, err := patchFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	key := releasePatchKey(patch)
+	if err := store.Put(ctx, key, patchFile, patchSize, "application/octet-stream"); err != nil {
+		return fmt.Errorf("save patch: %w", err)
+	}
+
+	patch.FilePath = key
+	patch.FileSize = patchSize
+	patch.ChecksumSHA256 = hex.EncodeToString(hasher.Sum(nil))
+	patch.Status = models.ReleasePatchStatusReady
+	patch.Error = ""
+	log.Printf("✅ Патч %s %s -> %s: %d байт вместо %d (%.1f%%), построен за %s",
+		patch.Platform, patch.SourceVersion, patch.TargetVersion, patchSize, targetSize,
+		float64(patchSize)*100/float64(max(targetSize, 1)), time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+// FindReleasePatch готовый патч к версии targetID от версии клиента fromVersion.
+// Если патча нет, возвращает причину для ответа клиенту.
+func FindReleasePatch(db *gorm.DB, kind models.ReleaseKind, targetID uuid.UUID, fromVersion string) (*models.ReleasePatch, string) {
+	var patches []models.ReleasePatch
+	if err := db.Where("kind = ? AND target_id = ?", kind, targetID).Find(&patches).Error; err != nil {
+		return nil, "patch lookup failed"
+	}
+	for i := range patches {
+		if utils.CompareVersions(patches[i].SourceVersion, fromVersion) != 0 {
+			continue
+		}
+		switch patches[i].Status {
+		case models.ReleasePatchStatusReady:
+			return &patches[i], ""
+		case models.ReleasePatchStatusPending, models.ReleasePatchStatusProcessing:
+			return nil, "patch is being built"
+		default:
+			return nil, "patch is not available for this version"
+		}
+	}
+	return nil, "client version is too old for a patch"
+}
+
+// RetryReleasePatch возвращает неудачный патч в очередь
+func RetryReleasePatch(db *gorm.DB, id uuid.UUID) error {
+	result := db.Model(&models.ReleasePatch{}).
+		Where("id = ? AND status IN ?", id, []models.ReleasePatchStatus{models.ReleasePatchStatusFailed, models.ReleasePatchStatusSkipped}).
+		Updates(map[string]interface{}{"status": models.ReleasePatchStatusPending, "error": ""})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	NudgeReleasePatches()
+	return nil
+}
+
+// DeleteReleasePatches удаляет патчи, построенные от версии или к версии (при удалении файла выпуска)
+func DeleteReleasePatches(ctx context.Context, db *gorm.DB, releaseID uuid.UUID) {
+	var patches []models.ReleasePatch
+	if err := db.Where("source_id = ? OR target_id = ?", releaseID, releaseID).Find(&patches).Error; err != nil {
+		log.Printf("⚠️ Не удалось найти патчи версии %s: %v", releaseID, err)
+		return
+	}
+	for _, patch := range patches {
+		if patch.FilePath != "" {
+			if err := storage.Default().Delete(ctx, patch.FilePath); err != nil {
+				log.Printf("⚠️ Не удалось удалить патч %s: %v", patch.FilePath, err)
+			}
+		}
+		db.Delete(&patch)
+	}
+}
+
+// processReleasePatches строит все патчи из очереди
+func processReleasePatches(ctx context.Context, cfg ReleasePatchConfig) {
+	if database.DB == nil {
+		return
+	}
+	if planned, err := PlanReleasePatches(database.DB, cfg); err != nil {
+		log.Printf("⚠️ Патчи обновлений: ошибка планирования: %v", err)
+	} else if planned > 0 {
+		log.Printf("ℹ️ Патчи обновлений: в очереди %d новых", planned)
+	}
+
+	for ctx.Err() == nil {
+		patch, err := claimReleasePatch(database.DB)
+		if err != nil {
+			log.Printf("⚠️ Патчи обновлений: ошибка захвата: %v", err)
+			return
+		}
+		if patch == nil {
+			return
+		}
+		if err := BuildReleasePatch(ctx, database.DB, storage.Default(), patch); err != nil {
+			log.Printf("❌ Патч %s %s -> %s не построен: %v", patch.Platform, patch.SourceVersion, patch.TargetVersion, err)
+		}
+	}
+}
+
+// StartReleasePatchWorker запускает построение патчей между версиями
+func StartReleasePatchWorker(ctx context.Context, cfg ReleasePatchConfig) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			processReleasePatches(ctx, cfg)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-releasePatchNudge:
+			}
+		}
+	}()
+
+	log.Printf("✅ Построение патчей обновлений запущено (платформы: %s, глубина: %d, интервал: %s)",
+		strings.Join(cfg.Platforms, ","), cfg.Depth, cfg.Interval)
+}
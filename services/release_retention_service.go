@@ -0,0 +1,409 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/database"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/storage"
+	"github.com/mm-api/mm-api/utils"
+	"gorm.io/gorm"
+)
+
+// ErrReleaseRetentionInProgress политика хранения уже выполняется
+var ErrReleaseRetentionInProgress = errors.New("release retention is already running")
+
+// releaseRetentionMu один проход политики хранения за раз (воркер и запуск из админки)
+var releaseRetentionMu sync.Mutex
+
+// ReleaseRetentionConfig настройки политики хранения релизов
+type ReleaseRetentionConfig struct {
+	Interval      time.Duration // Как часто применять политику
+	KeepLast      int           // Сколько последних версий хранить в каждой серии
+	MinAge        time.Duration // Версии моложе не удаляются
+	InstallWindow time.Duration // Версия защищена, пока устройства с ней проверяли обновления за этот период
+	DryRun        bool          // Только отчет, без удаления
+}
+
+// releaseRetentionSeries серия версий, в которой хранятся последние KeepLast:
+// обновления - платформа и канал, установщики POS - платформа и тип
+func releaseRetentionSeries(f releaseFile) string {
+	return string(f.Kind) + ":" + f.Platform + ":" + f.Type + ":" + f.Channel
+}
+
+// releaseInstallKey ключ подсчета устройств по платформе и версии
+func releaseInstallKey(platform, version string) string {
+	return platform + ":" + version
+}
+
+// releaseInstallPlatform платформа для подсчета устройств: у обновлений - платформа,
+// у установщиков POS - тип и платформа (libiss_pos/full/windows)
+func releaseInstallPlatform(f releaseFile) string {
+	if f.Kind == models.ReleaseKindLibissPos {
+		return string(models.ReleaseKindLibissPos) + "/" + f.Type + "/" + f.Platform
+	}
+	return f.Platform
+}
+
+// countReleaseInstalls считает устройства и кассы, проверявшие версию за период, по установленной
+// и предложенной версии (предложенную устройство могло уже скачать)
+func countReleaseInstalls(db *gorm.DB, since time.Time) (map[string]int64, error) {
+	type installRow struct {
+		Platform       string
+		Version        string
+		OfferedVersion string
+	}
+	var rows []installRow
+	if err := db.Model(&models.UpdateCheckIn{}).
+		Select("platform, version, offered_version").
+		Where("last_checked_at >= ?", since).
+		Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("ошибка подсчета устройств: %w", err)
+	}
+
+	var posRows []models.LibissPosCheckIn
+	if err := db.Select("type", "platform", "version", "offered_version").
+		Where("last_checked_at >= ?", since).
+		Find(&posRows).Error; err != nil {
+		return nil, fmt.Errorf("ошибка подсчета касс: %w", err)
+	}
+	for _, row := range posRows {
+		platform := releaseInstallPlatform(releaseFile{Kind: models.ReleaseKindLibissPos, Type: row.Type, Platform: row.Platform})
+		rows = append(rows, installRow{Platform: platform, Version: row.Version, OfferedVersion: row.OfferedVersion})
+	}
+
+	installs := make(map[string]int64)
+	for _, row := range rows {
+		if row.Version != "" {
+			installs[releaseInstallKey(row.Platform, row.Version)]++
+		}
+		if row.OfferedVersion != "" && row.OfferedVersion != row.Version {
+			installs[releaseInstallKey(row.Platform, row.OfferedVersion)]++
+		}
+	}
+	return installs, nil
+}
+
+// releaseInstallCount устройства с версией; версии сравниваются как semver ("1.2" == "1.2.0")
+func releaseInstallCount(installs map[string]int64, platform, version string) int64 {
+	if count, ok := installs[releaseInstallKey(platform, version)]; ok {
+		return count
+	}
+	var count int64
+	prefix := platform + ":"
+	for key, n := range installs {
+		if len(key) > len(prefix) && key[:len(prefix)] == prefix &&
+			utils.CompareVersions(key[len(prefix):], version) == 0 {
+			count += n
+		}
+	}
+	return count
+}
+
+// PlanReleaseRetention решает для каждого обновления и установщика POS, хранить его или удалить.
+// Версия хранится, если она среди KeepLast последних в своей серии, является минимальной
+// поддерживаемой для активного релиза платформы (или ближайшей к ней сверху, если такой
+// версии нет), установлена (или предложена) на устройствах и кассах, проверявших версию
+// за InstallWindow, или загружена менее MinAge назад.
+func PlanReleaseRetention(db *gorm.DB, cfg ReleaseRetentionConfig, now time.Time) ([]models.ReleaseRetentionDecision, error) {
+	if cfg.KeepLast < 1 {
+		cfg.KeepLast = 1
+	}
+
+	updates, err := loadReleaseFiles(db, models.ReleaseKindUpdate, false)
+	if err != nil {
+		return nil, err
+	}
+	posFiles, err := loadReleaseFiles(db, models.ReleaseKindLibissPos, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Минимальные поддерживаемые версии активных релизов: клиенты ниже обязаны обновиться,
+	// а сама версия должна оставаться доступной. Если релиза с точно такой версией нет,
+	// хранится самый младший релиз не ниже нее - клиент может обновиться хотя бы до него.
+	minSupported := make(map[string][]string)
+	for _, f := range updates {
+		if f.IsActive && f.MinSupportedVersion != "" {
+			if version := lowestReleaseAtLeast(updates, f.Platform, f.MinSupportedVersion); version != "" {
+				minSupported[f.Platform] = append(minSupported[f.Platform], version)
+			}
+		}
+	}
+
+	installs := map[string]int64{}
+	if cfg.InstallWindow > 0 {
+		if installs, err = countReleaseInstalls(db, now.Add(-cfg.InstallWindow)); err != nil {
+			return nil, err
+		}
+	}
+
+	series := make(map[string][]releaseFile)
+	var keys []string
+	for _, f := range append(updates, posFiles...) {
+		key := releaseRetentionSeries(f)
+		if _, ok := series[key]; !ok {
+			keys = append(keys, key)
+		}
+		series[key] = append(series[key], f)
+	}
+	sort.Strings(keys)
+
+	decisions := make([]models.ReleaseRetentionDecision, 0, len(updates)+len(posFiles))
+	for _, key := range keys {
+		group := series[key]
+		sort.SliceStable(group, func(i, j int) bool {
+			if cmp := utils.CompareVersions(group[i].Version, group[j].Version); cmp != 0 {
+				return cmp > 0
+			}
+			return group[i].CreatedAt.After(group[j].CreatedAt)
+		})
+
+		for i, f := range group {
+			decision := models.ReleaseRetentionDecision{
+				Kind: f.Kind, ID: f.ID, Platform: f.Platform, Type: f.Type, Channel: f.Channel,
+				Version: f.Version, IsActive: f.IsActive, FileSize: f.FileSize, CreatedAt: f.CreatedAt,
+			}
+			decision.Devices = releaseInstallCount(installs, releaseInstallPlatform(f), f.Version)
+
+			switch {
+			case i < cfg.KeepLast:
+				decision.Reason = fmt.Sprintf("one of the last %d versions", cfg.KeepLast)
+			case f.Kind == models.ReleaseKindUpdate && isMinSupportedVersion(minSupported[f.Platform], f.Version):
+				decision.Reason = "minimum supported version of an active release"
+			case decision.Devices > 0:
+				decision.Reason = fmt.Sprintf("installed on %d devices", decision.Devices)
+			case now.Sub(f.CreatedAt) < cfg.MinAge:
+				decision.Reason = fmt.Sprintf("uploaded less than %s ago", cfg.MinAge)
+			default:
+				decision.Delete = true
+				decision.Reason = fmt.Sprintf("older than the last %d versions", cfg.KeepLast)
+			}
+			decisions = append(decisions, decision)
+		}
+	}
+	return decisions, nil
+}
+
+// lowestReleaseAtLeast самая младшая версия обновлений платформы не ниже min (пусто - нет такой)
+func lowestReleaseAtLeast(files []releaseFile, platform, min string) string {
+	lowest := ""
+	for _, f := range files {
+		if f.Platform != platform || utils.CompareVersions(f.Version, min) < 0 {
+			continue
+		}
+		if lowest == "" || utils.CompareVersions(f.Version, lowest) < 0 {
+			lowest = f.Version
+		}
+	}
+	return lowest
+}
+
+// isMinSupportedVersion версия совпадает с одной из минимальных поддерживаемых
+func isMinSupportedVersion(versions []string, version string) bool {
+	for _, v := range versions {
+		if utils.CompareVersions(v, version) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyReleaseRetention применяет политику хранения: удаляет записи и файлы релизов, которые
+// не нужно хранить, и записывает каждое удаление в журнал. При cfg.DryRun только отчет.
+func ApplyReleaseRetention(ctx context.Context, db *gorm.DB, cfg ReleaseRetentionConfig, deletedBy *uuid.UUID) (*models.ReleaseRetentionReport, error) {
+	if !releaseRetentionMu.TryLock() {
+		return nil, ErrReleaseRetentionInProgress
+	}
+	defer releaseRetentionMu.Unlock()
+
+	if cfg.KeepLast < 1 {
+		cfg.KeepLast = 1
+	}
+	now := time.Now()
+	report := &models.ReleaseRetentionReport{
+		DryRun:        cfg.DryRun,
+		KeepLast:      cfg.KeepLast,
+		MinAge:        cfg.MinAge.String(),
+		InstallWindow: cfg.InstallWindow.String(),
+		StartedAt:     now,
+	}
+
+	decisions, err := PlanReleaseRetention(db, cfg, now)
+	if err != nil {
+		return nil, err
+	}
+	report.Releases = decisions
+
+	for _, decision := range decisions {
+		if !decision.Delete || ctx.Err() != nil {
+			continue
+		}
+		report.Candidates++
+		if cfg.DryRun {
+			report.FreedBytes += decision.FileSize
+			continue
+		}
+
+		deleted, err := deleteRetainedRelease(ctx, db, decision, deletedBy)
+		if err != nil {
+			log.Printf("⚠️ Хранение релизов: не удалось удалить %s %s/%s %s: %v",
+				decision.Kind, decision.Platform, decision.Type, decision.Version, err)
+			report.Failed++
+			continue
+		}
+		if deleted {
+			report.Deleted++
+			report.FreedBytes += decision.FileSize
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// deleteRetainedRelease удаляет релиз по решению политики хранения. Запись удаляется вместе
+// с записью журнала; false - релиз уже удален (другим экземпляром или вручную).
+func deleteRetainedRelease(ctx context.Context, db *gorm.DB, decision models.ReleaseRetentionDecision, deletedBy *uuid.UUID) (bool, error) {
+	var deletion *models.ReleaseDeletion
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Та же блокировка, что и при приеме версий в серию
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))",
+			"mm_release_ingest:"+string(decision.Kind)+":"+decision.Platform+":"+decision.Type).Error; err != nil {
+			return err
+		}
+
+		var err error
+		switch decision.Kind {
+		case models.ReleaseKindUpdate:
+			var release models.UpdateRelease
+			if err = tx.Where("id = ?", decision.ID).First(&release).Error; err != nil {
+				break
+			}
+			deletion = NewUpdateReleaseDeletion(release, models.ReleaseDeletionTriggerRetention, decision.Reason, deletedBy)
+			err = tx.Delete(&release).Error
+		case models.ReleaseKindLibissPos:
+			var file models.LibissPosFile
+			if err = tx.Where("id = ?", decision.ID).First(&file).Error; err != nil {
+				break
+			}
+			deletion = NewLibissPosFileDeletion(file, models.ReleaseDeletionTriggerRetention, decision.Reason, deletedBy)
+			err = tx.Delete(&file).Error
+		default:
+			err = fmt.Errorf("unknown release kind %q", decision.Kind)
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Create(deletion).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	removeReleaseFiles(ctx, db, deletion)
+	log.Printf("🗑️ Хранение релизов: удален %s %s/%s %s (%s)",
+		deletion.Kind, deletion.Platform, deletion.Type, deletion.Version, deletion.Reason)
+	return true, nil
+}
+
+// removeReleaseFiles удаляет файл удаленного релиза из хранилища и патчи к нему.
+// Ошибка удаления файла сохраняется в журнал - файл можно удалить вручную.
+func removeReleaseFiles(ctx context.Context, db *gorm.DB, deletion *models.ReleaseDeletion) {
+	if deletion.FilePath != "" {
+		if err := storage.Default().Delete(ctx, deletion.FilePath); err != nil {
+			log.Printf("⚠️ Не удалось удалить файл релиза %s: %v", deletion.FilePath, err)
+			db.Model(deletion).Update("file_error", err.Error())
+		}
+	}
+	DeleteReleasePatches(ctx, db, deletion.ReleaseID)
+}
+
+// NewUpdateReleaseDeletion запись журнала удаления обновления
+func NewUpdateReleaseDeletion(release models.UpdateRelease, trigger models.ReleaseDeletionTrigger, reason string, deletedBy *uuid.UUID) *models.ReleaseDeletion {
+	return &models.ReleaseDeletion{
+		Kind:           models.ReleaseKindUpdate,
+		ReleaseID:      release.ID,
+		Platform:       string(release.Platform),
+		Channel:        string(release.Channel),
+		Version:        release.Version,
+		FilePath:       release.FilePath,
+		FileSize:       release.FileSize,
+		ChecksumSHA256: release.ChecksumSHA256,
+		Trigger:        trigger,
+		Reason:         reason,
+		DeletedBy:      deletedBy,
+	}
+}
+
+// NewLibissPosFileDeletion запись журнала удаления установщика POS
+func NewLibissPosFileDeletion(file models.LibissPosFile, trigger models.ReleaseDeletionTrigger, reason string, deletedBy *uuid.UUID) *models.ReleaseDeletion {
+	return &models.ReleaseDeletion{
+		Kind:           models.ReleaseKindLibissPos,
+		ReleaseID:      file.ID,
+		Platform:       string(file.Platform),
+		Type:           string(file.Type),
+		Version:        file.Version,
+		FilePath:       file.FilePath,
+		FileSize:       file.FileSize,
+		ChecksumSHA256: file.ChecksumSHA256,
+		Trigger:        trigger,
+		Reason:         reason,
+		DeletedBy:      deletedBy,
+	}
+}
+
+// RecordReleaseDeletion записывает в журнал удаление релиза администратором
+func RecordReleaseDeletion(db *gorm.DB, deletion *models.ReleaseDeletion) {
+	if err := db.Create(deletion).Error; err != nil {
+		log.Printf("⚠️ Не удалось записать удаление релиза %s в журнал: %v", deletion.ReleaseID, err)
+	}
+}
+
+// StartReleaseRetentionWorker запускает периодическое применение политики хранения
+func StartReleaseRetentionWorker(ctx context.Context, cfg ReleaseRetentionConfig) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 24 * time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if database.DB == nil {
+				continue
+			}
+			report, err := ApplyReleaseRetention(ctx, database.DB, cfg, nil)
+			if errors.Is(err, ErrReleaseRetentionInProgress) {
+				log.Printf("ℹ️ Хранение релизов: проход уже выполняется")
+				continue
+			}
+			if err != nil {
+				log.Printf("⚠️ Хранение релизов: %v", err)
+				continue
+			}
+			log.Printf("✅ Хранение релизов: версий %d, к удалению %d, удалено %d (%d байт), ошибок %d, dry-run %v",
+				len(report.Releases), report.Candidates, report.Deleted, report.FreedBytes, report.Failed, report.DryRun)
+		}
+	}()
+
+	log.Printf("✅ Политика хранения релизов запущена (интервал: %s, последних версий: %d, минимальный возраст: %s)",
+		cfg.Interval, cfg.KeepLast, cfg.MinAge)
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/models"
+	"gorm.io/gorm"
+)
+
+// ErrReviewNotEligible пользователь не получал этот товар
+var ErrReviewNotEligible = errors.New("review requires a delivered order with this product")
+
+// ErrReviewExists пользователь уже оставил отзыв на этот товар
+var ErrReviewExists = errors.New("review for this product already exists")
+
+// reviewableOrderStatuses статусы заказа, после которых можно оставить отзыв
+var reviewableOrderStatuses = []models.OrderStatus{models.OrderStatusDelivered, models.OrderStatusCompleted}
+
+// FindReviewableOrderItem ищет доставленную позицию заказа пользователя с товаром.
+// Возвращает ErrReviewNotEligible, если такой позиции нет.
+func FindReviewableOrderItem(db *gorm.DB, userID, productID uuid.UUID) (*models.OrderItem, error) {
+	var item models.OrderItem
+	err := db.Model(&models.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Joins("JOIN product_variations ON product_variations.id = order_items.variation_id").
+		Where("orders.user_id = ? AND product_variations.product_id = ? AND orders.status IN ?", userID, productID, reviewableOrderStatuses).
+		Order("orders.updated_at DESC").
+		First(&item).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReviewNotEligible
+		}
+		return nil, fmt.Errorf("ошибка проверки покупки товара: %w", err)
+	}
+	return &item, nil
+}
+
+// ValidateReviewPhotos проверяет, что фото загружены через /upload/image: адрес
+// должен указывать на хранилище приложения или Cloudinary проекта
+func ValidateReviewPhotos(photos []string) error {
+	if len(photos) > models.MaxReviewPhotos {
+		return fmt.Errorf("не больше %d фото", models.MaxReviewPhotos)
+	}
+	for _, photo := range photos {
+		if OwnMediaKey(photo) == "" {
+			return fmt.Errorf("недопустимый адрес фото: %s", photo)
+		}
+	}
+	return nil
+}
+
+// RecalculateRatings пересчитывает рейтинг товара и магазина по опубликованным отзывам.
+// Вызывается в транзакции изменения отзыва. Строки товара и магазина блокируются до
+// пересчета: параллельная транзакция ждет коммита и затем видит уже сохраненный отзыв,
+// иначе ее подзапрос, прочитанный до коммита, перезаписал бы рейтинг устаревшим.
+func RecalculateRatings(tx *gorm.DB, productID uuid.UUID, shopID *uuid.UUID) error {
+	// Всегда товар, затем магазин - одинаковый порядок блокировок без взаимоблокировок
+	if err := tx.Exec("SELECT id FROM products WHERE id = ? FOR UPDATE", productID).Error; err != nil {
+		return fmt.Errorf("ошибка блокировки товара: %w", err)
+	}
+	if shopID != nil {
+		if err := tx.Exec("SELECT id FROM shops WHERE id = ? FOR UPDATE", *shopID).Error; err != nil {
+			return fmt.Errorf("ошибка блокировки магазина: %w", err)
+		}
+	}
+
+	if err := tx.Exec(`UPDATE products SET
+		rating = COALESCE((SELECT ROUND(AVG(rating)::numeric, 2) FROM product_reviews WHERE product_id = @id AND status = @status), 0),
+		reviews_count = (SELECT COUNT(*) FROM product_reviews WHERE product_id = @id AND status = @status)
+		WHERE id = @id`,
+		map[string]interface{}{"id": productID, "status": models.ReviewStatusPublished}).Error; err != nil {
+		return fmt.Errorf("ошибка пересчета рейтинга товара: %w", err)
+	}
+
+	if shopID == nil {
+		return nil
+	}
+	if err := tx.Exec(`UPDATE shops SET
+		rating = COALESCE((SELECT ROUND(AVG(rating)::numeric, 2) FROM product_reviews WHERE shop_id = @id AND status = @status), 0),
+		reviews_count = (SELECT COUNT(*) FROM product_reviews WHERE shop_id = @id AND status = @status)
+		WHERE id = @id`,
+		map[string]interface{}{"id": *shopID, "status": models.ReviewStatusPublished}).Error; err != nil {
+		return fmt.Errorf("ошибка пересчета рейтинга магазина: %w", err)
+	}
+	return nil
+}
+
+// GetRatingSummary возвращает среднюю оценку и распределение оценок товара
+func GetRatingSummary(db *gorm.DB, productID uuid.UUID) (models.RatingSummary, error) {
+	summary := models.RatingSummary{
+		Distribution: map[int]int64{1: 0, 2: 0, 3: 0, 4: 0, 5: 0},
+	}
+
+	var rows []struct {
+		Rating int
+		Count  int64
+	}
+	if err := db.Model(&models.ProductReview{}).
+		Select("rating, COUNT(*) AS count").
+		Where("product_id = ? AND status = ?", productID, models.ReviewStatusPublished).
+		Group("rating").
+		Scan(&rows).Error; err != nil {
+		return summary, fmt.Errorf("ошибка подсчета оценок: %w", err)
+	}
+
+	var sum int64
+	for _, row := range rows {
+		summary.Distribution[row.Rating] = row.Count
+		summary.Count += row.Count
+		sum += int64(row.Rating) * row.Count
+	}
+	if summary.Count > 0 {
+		summary.Average = math.Round(float64(sum)/float64(summary.Count)*100) / 100
+	}
+	return summary, nil
+}
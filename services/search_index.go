@@ -0,0 +1,326 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SearchSuggestionType тип подсказки автодополнения
+type SearchSuggestionType string
+
+const (
+	SearchSuggestionProduct  SearchSuggestionType = "product"
+	SearchSuggestionBrand    SearchSuggestionType = "brand"
+	SearchSuggestionCategory SearchSuggestionType = "category"
+)
+
+// SearchSuggestion подсказка автодополнения
+type SearchSuggestion struct {
+	Type SearchSuggestionType `json:"type"`
+	Text string               `json:"text"`
+	ID   *uuid.UUID           `json:"id,omitempty"` // Товар или категория
+}
+
+// SearchHighlight подсвеченные фрагменты товара (совпадения в <mark>)
+type SearchHighlight struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// SearchIndex поиск товаров. Реализация на PostgreSQL работает поверх
+// запроса GORM; внешний движок может реализовать Apply через
+// products.id IN (...) с порядком из своего ответа.
+type SearchIndex interface {
+	// Name возвращает название реализации (для логов и диагностики)
+	Name() string
+	// Apply ограничивает запрос товаров совпадениями с text; ranked добавляет сортировку по релевантности
+	Apply(query *gorm.DB, text string, ranked bool) *gorm.DB
+	// Highlight возвращает подсвеченные фрагменты для найденных товаров
+	Highlight(productIDs []uuid.UUID, text string) (map[uuid.UUID]SearchHighlight, error)
+	// Suggest возвращает подсказки автодополнения
+	Suggest(text string, limit int) ([]SearchSuggestion, error)
+}
+
+var (
+	searchIndex   SearchIndex
+	searchIndexMu sync.RWMutex
+)
+
+// InitSearchIndex выбирает реализацию поиска по возможностям базы данных
+func InitSearchIndex(db *gorm.DB) SearchIndex {
+	var index SearchIndex
+	var hasVector bool
+	db.Raw(`SELECT EXISTS (SELECT 1 FROM information_schema.columns
+		WHERE table_name = 'products' AND column_name = 'search_vector')`).Scan(&hasVector)
+
+	if hasVector {
+		var hasTrigram bool
+		db.Raw(`SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')`).Scan(&hasTrigram)
+		index = &PostgresSearchIndex{db: db, trigram: hasTrigram}
+	} else {
+		index = &LikeSearchIndex{db: db}
+	}
+
+	SetSearchIndex(index)
+	log.Printf("✅ Search index: %s", index.Name())
+	return index
+}
+
+// SetSearchIndex устанавливает реализацию поиска
+func SetSearchIndex(index SearchIndex) {
+	searchIndexMu.Lock()
+	defer searchIndexMu.Unlock()
+	searchIndex = index
+}
+
+// GetSearchIndex возвращает текущую реализацию поиска (по умолчанию - ILIKE)
+func GetSearchIndex() SearchIndex {
+	searchIndexMu.RLock()
+	defer searchIndexMu.RUnlock()
+	if searchIndex == nil {
+		return &LikeSearchIndex{}
+	}
+	return searchIndex
+}
+
+// tajikReplacer приводит таджикские буквы к русским (как mm_search_normalize в БД)
+var tajikReplacer = strings.NewReplacer("ғ", "г", "ӣ", "и", "қ", "к", "ӯ", "у", "ҳ", "х", "ҷ", "ч", "ё", "е")
+
+// NormalizeSearchText приводит текст запроса к виду, в котором хранится индекс
+func NormalizeSearchText(text string) string {
+	return strings.TrimSpace(tajikReplacer.Replace(strings.ToLower(text)))
+}
+
+// searchTokens разбивает запрос на слова из букв и цифр
+func searchTokens(text string) []string {
+	return strings.FieldsFunc(NormalizeSearchText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// buildTSQuery строит tsquery: все слова обязательны, последнее - как префикс (ввод еще не закончен)
+func buildTSQuery(tokens []string) string {
+	parts := make([]string, len(tokens))
+	for i, token := range tokens {
+		parts[i] = token
+		if i == len(tokens)-1 {
+			parts[i] += ":*"
+		}
+	}
+	return strings.Join(parts, " & ")
+}
+
+// PostgresSearchIndex поиск на tsvector (морфология russian + simple) и pg_trgm (опечатки)
+type PostgresSearchIndex struct {
+	db      *gorm.DB
+	trigram bool
+}
+
+// Name возвращает название реализации
+func (p *PostgresSearchIndex) Name() string {
+	if p.trigram {
+		return "postgres (tsvector + pg_trgm)"
+	}
+	return "postgres (tsvector)"
+}
+
+// tsQueryExpr выражение tsquery для обеих конфигураций
+const tsQueryExpr = "(to_tsquery('russian', ?) || to_tsquery('simple', ?))"
+
+// Apply ограничивает запрос совпадениями и при необходимости сортирует по релевантности
+func (p *PostgresSearchIndex) Apply(query *gorm.DB, text string, ranked bool) *gorm.DB {
+	tokens := searchTokens(text)
+	if len(tokens) == 0 {
+		return query
+	}
+	tsQuery := buildTSQuery(tokens)
+	normalized := strings.Join(tokens, " ")
+
+	if p.trigram {
+		query = query.Where("(products.search_vector @@ "+tsQueryExpr+
+			" OR ? <% mm_search_normalize(products.name) OR ? <% mm_search_normalize(products.brand))",
+			tsQuery, tsQuery, normalized, normalized)
+	} else {
+		query = query.Where("products.search_vector @@ "+tsQueryExpr, tsQuery, tsQuery)
+	}
+
+	if !ranked {
+		return query
+	}
+
+	// Order не принимает параметры: значения подставляются литералами
+	// (слова запроса содержат только буквы и цифры, кавычки экранируются)
+	rankSQL := "ts_rank_cd(products.search_vector, " + tsQueryLiteral(tsQuery) + ")"
+	if p.trigram {
+		rankSQL += " + word_similarity(" + quoteSearchLiteral(normalized) + ", mm_search_normalize(products.name))"
+	}
+	return query.Order(rankSQL + " DESC")
+}
+
+// quoteSearchLiteral экранирует строку как SQL литерал
+func quoteSearchLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// tsQueryLiteral выражение tsquery с подставленным литералом
+func tsQueryLiteral(tsQuery string) string {
+	literal := quoteSearchLiteral(tsQuery)
+	return "(to_tsquery('russian', " + literal + ") || to_tsquery('simple', " + literal + "))"
+}
+
+// Highlight подсвечивает совпадения в названии и описании
+func (p *PostgresSearchIndex) Highlight(productIDs []uuid.UUID, text string) (map[uuid.UUID]SearchHighlight, error) {
+	result := make(map[uuid.UUID]SearchHighlight, len(productIDs))
+	tokens := searchTokens(text)
+	if len(productIDs) == 0 || len(tokens) == 0 {
+		return result, nil
+	}
+	tsQuery := buildTSQuery(tokens)
+
+	var rows []struct {
+		ID          uuid.UUID
+		Name        string
+		Description string
+	}
+	err := p.db.Raw(`SELECT id,
+			ts_headline('russian', name, q, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS name,
+			ts_headline('russian', coalesce(description, ''), q,
+				'StartSel=<mark>, StopSel=</mark>, MaxFragments=1, MaxWords=20, MinWords=5') AS description
+		FROM products, (SELECT `+tsQueryExpr+` AS q) AS search
+		WHERE id IN ?`, tsQuery, tsQuery, productIDs).Scan(&rows).Error
+	if err != nil {
+		return result, fmt.Errorf("ошибка подсветки результатов поиска: %w", err)
+	}
+
+	for _, row := range rows {
+		result[row.ID] = SearchHighlight{Name: row.Name, Description: row.Description}
+	}
+	return result, nil
+}
+
+// Suggest возвращает подсказки: товары, бренды и категории
+func (p *PostgresSearchIndex) Suggest(text string, limit int) ([]SearchSuggestion, error) {
+	tokens := searchTokens(text)
+	if len(tokens) == 0 {
+		return []SearchSuggestion{}, nil
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	tsQuery := buildTSQuery(tokens)
+	normalized := strings.Join(tokens, " ")
+	suggestions := make([]SearchSuggestion, 0, limit)
+
+	// Категории и бренды по префиксу, затем товары по релевантности
+	var categories []struct {
+		ID   uuid.UUID
+		Name string
+	}
+	if err := p.db.Raw(`SELECT id, name FROM categories
+		WHERE is_active = true AND mm_search_normalize(name) LIKE ?
+		ORDER BY length(name) LIMIT 3`, "%"+normalized+"%").Scan(&categories).Error; err != nil {
+		return nil, fmt.Errorf("ошибка подсказок категорий: %w", err)
+	}
+	for i := range categories {
+		suggestions = append(suggestions, SearchSuggestion{Type: SearchSuggestionCategory, Text: categories[i].Name, ID: &categories[i].ID})
+	}
+
+	var brands []string
+	brandQuery := `SELECT brand FROM products
+		WHERE is_available = true AND brand <> '' AND mm_search_normalize(brand) LIKE ?
+		GROUP BY brand ORDER BY count(*) DESC LIMIT 3`
+	if err := p.db.Raw(brandQuery, normalized+"%").Scan(&brands).Error; err != nil {
+		return nil, fmt.Errorf("ошибка подсказок брендов: %w", err)
+	}
+	for _, brand := range brands {
+		suggestions = append(suggestions, SearchSuggestion{Type: SearchSuggestionBrand, Text: brand})
+	}
+
+	remaining := limit - len(suggestions)
+	if remaining <= 0 {
+		return suggestions[:limit], nil
+	}
+
+	var products []struct {
+		ID   uuid.UUID
+		Name string
+	}
+	where := "search_vector @@ " + tsQueryExpr
+	vars := []interface{}{tsQuery, tsQuery}
+	order := "ts_rank_cd(search_vector, " + tsQueryExpr + ") DESC"
+	orderVars := []interface{}{tsQuery, tsQuery}
+	if p.trigram {
+		where = "(" + where + " OR ? <% mm_search_normalize(name))"
+		vars = append(vars, normalized)
+		order = "word_similarity(?, mm_search_normalize(name)) DESC, " + order
+		orderVars = append([]interface{}{normalized}, orderVars...)
+	}
+	vars = append(vars, orderVars...)
+	vars = append(vars, remaining)
+	if err := p.db.Raw(`SELECT id, name FROM products
+		WHERE is_available = true AND `+where+`
+		ORDER BY `+order+` LIMIT ?`, vars...).Scan(&products).Error; err != nil {
+		return nil, fmt.Errorf("ошибка подсказок товаров: %w", err)
+	}
+	for i := range products {
+		suggestions = append(suggestions, SearchSuggestion{Type: SearchSuggestionProduct, Text: products[i].Name, ID: &products[i].ID})
+	}
+	return suggestions, nil
+}
+
+// LikeSearchIndex запасной поиск через ILIKE (если tsvector недоступен)
+type LikeSearchIndex struct {
+	db *gorm.DB
+}
+
+// Name возвращает название реализации
+func (l *LikeSearchIndex) Name() string {
+	return "ilike"
+}
+
+// Apply ограничивает запрос подстрокой в названии или описании
+func (l *LikeSearchIndex) Apply(query *gorm.DB, text string, _ bool) *gorm.DB {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return query
+	}
+	pattern := "%" + text + "%"
+	return query.Where("(products.name ILIKE ? OR products.description ILIKE ? OR products.brand ILIKE ?)", pattern, pattern, pattern)
+}
+
+// Highlight не поддерживается - возвращает пустой результат
+func (l *LikeSearchIndex) Highlight(_ []uuid.UUID, _ string) (map[uuid.UUID]SearchHighlight, error) {
+	return map[uuid.UUID]SearchHighlight{}, nil
+}
+
+// Suggest возвращает названия товаров, начинающиеся с text
+func (l *LikeSearchIndex) Suggest(text string, limit int) ([]SearchSuggestion, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || l.db == nil {
+		return []SearchSuggestion{}, nil
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	var products []struct {
+		ID   uuid.UUID
+		Name string
+	}
+	if err := l.db.Raw(`SELECT id, name FROM products WHERE is_available = true AND name ILIKE ? ORDER BY name LIMIT ?`,
+		text+"%", limit).Scan(&products).Error; err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]SearchSuggestion, len(products))
+	for i := range products {
+		suggestions[i] = SearchSuggestion{Type: SearchSuggestionProduct, Text: products[i].Name, ID: &products[i].ID}
+	}
+	return suggestions, nil
+}
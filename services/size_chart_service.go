@@ -0,0 +1,105 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/models"
+	"gorm.io/gorm"
+)
+
+// ValidateSizeChart проверяет размерную сетку: привязка к категории или бренду,
+// уникальные размеры и по одной мерке на каждую колонку
+func ValidateSizeChart(req *models.SizeChartRequest) error {
+	var problems []string
+	req.Brand = strings.TrimSpace(req.Brand)
+	if req.CategoryID == nil && req.Brand == "" {
+		problems = append(problems, "categoryId or brand is required")
+	}
+
+	for i := range req.Columns {
+		req.Columns[i] = strings.TrimSpace(req.Columns[i])
+		if req.Columns[i] == "" {
+			problems = append(problems, fmt.Sprintf("columns[%d]: empty name", i))
+		}
+	}
+
+	seen := make(map[string]bool, len(req.Rows))
+	for i := range req.Rows {
+		row := &req.Rows[i]
+		row.Size = strings.TrimSpace(row.Size)
+		key := strings.ToLower(row.Size)
+		switch {
+		case row.Size == "":
+			problems = append(problems, fmt.Sprintf("rows[%d]: size is required", i))
+		case seen[key]:
+			problems = append(problems, fmt.Sprintf("rows[%d]: duplicate size %q", i, row.Size))
+		}
+		seen[key] = true
+		if len(row.Values) != len(req.Columns) {
+			problems = append(problems, fmt.Sprintf("rows[%d]: expected %d values, got %d", i, len(req.Columns), len(row.Values)))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &AttributeValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// FindSizeChart подбирает размерную сетку для товара: бренд + категория, затем
+// только бренд, затем только категория. Категория ищется от самой товарной
+// вверх по родителям. Возвращает nil, если подходящей сетки нет.
+func FindSizeChart(db *gorm.DB, categoryID uuid.UUID, brand string) (*models.SizeChart, error) {
+	breadcrumbs, err := GetCategoryBreadcrumbs(db, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	// distance: 0 - категория товара, 1 - ее родитель и т.д.
+	distance := make(map[uuid.UUID]int, len(breadcrumbs))
+	ids := make([]uuid.UUID, 0, len(breadcrumbs))
+	for i, crumb := range breadcrumbs {
+		distance[crumb.ID] = len(breadcrumbs) - 1 - i
+		ids = append(ids, crumb.ID)
+	}
+
+	brand = strings.TrimSpace(brand)
+	query := db.Model(&models.SizeChart{})
+	switch {
+	case brand != "" && len(ids) > 0:
+		query = query.Where("(category_id IN ? OR category_id IS NULL) AND (lower(brand) = lower(?) OR brand = '')", ids, brand)
+	case brand != "":
+		query = query.Where("category_id IS NULL AND lower(brand) = lower(?)", brand)
+	case len(ids) > 0:
+		query = query.Where("category_id IN ? AND brand = ''", ids)
+	default:
+		return nil, nil
+	}
+
+	var candidates []models.SizeChart
+	if err := query.Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("ошибка загрузки размерных сеток: %w", err)
+	}
+
+	var best *models.SizeChart
+	bestScore := -1
+	for i := range candidates {
+		chart := &candidates[i]
+		score := 0
+		if chart.Brand != "" {
+			score += 1000
+		}
+		if chart.CategoryID != nil {
+			score += 500 - distance[*chart.CategoryID]
+		}
+		if chart.Brand == "" && chart.CategoryID == nil {
+			continue
+		}
+		if score > bestScore || (score == bestScore && chart.UpdatedAt.After(best.UpdatedAt)) {
+			best = chart
+			bestScore = score
+		}
+	}
+	return best, nil
+}
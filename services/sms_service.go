@@ -0,0 +1,152 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// smsRuDefaultAPIURL адрес отправки SMS.ru
+	smsRuDefaultAPIURL = "https://sms.ru/sms/send"
+	// smsRequestTimeout таймаут запроса к провайдеру
+	smsRequestTimeout = 10 * time.Second
+)
+
+// SMSConfig параметры провайдера SMS
+type SMSConfig struct {
+	Provider string // smsru или http
+	APIKey   string // api_id для SMS.ru, Bearer токен для http
+	From     string // Имя отправителя
+	APIURL   string // Адрес API (обязателен для http)
+}
+
+// NewSMSSender создает отправителя SMS по настройкам провайдера
+func NewSMSSender(cfg SMSConfig) (SMSSender, error) {
+	client := &http.Client{Timeout: smsRequestTimeout}
+	switch cfg.Provider {
+	case "smsru":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("не указан ключ API SMS.ru")
+		}
+		apiURL := cfg.APIURL
+		if apiURL == "" {
+			apiURL = smsRuDefaultAPIURL
+		}
+		return &smsRuSender{apiURL: apiURL, apiKey: cfg.APIKey, from: cfg.From, httpClient: client}, nil
+	case "http":
+		if cfg.APIURL == "" {
+			return nil, fmt.Errorf("не указан адрес HTTP шлюза SMS")
+		}
+		return &httpSMSSender{apiURL: cfg.APIURL, apiKey: cfg.APIKey, from: cfg.From, httpClient: client}, nil
+	default:
+		return nil, fmt.Errorf("неизвестный провайдер SMS: %s", cfg.Provider)
+	}
+}
+
+// smsRuSender отправляет SMS через SMS.ru (https://sms.ru/api/send)
+type smsRuSender struct {
+	apiURL     string
+	apiKey     string
+	from       string
+	httpClient *http.Client
+}
+
+// smsRuStatus статус запроса или отдельного сообщения SMS.ru
+type smsRuStatus struct {
+	Status     string `json:"status"`
+	StatusCode int    `json:"status_code"`
+	StatusText string `json:"status_text"`
+}
+
+// smsRuResponse ответ SMS.ru на отправку
+type smsRuResponse struct {
+	smsRuStatus
+	SMS map[string]smsRuStatus `json:"sms"`
+}
+
+// Send отправляет сообщение на номер телефона
+func (s *smsRuSender) Send(phone, text string) error {
+	to := strings.TrimPrefix(phone, "+")
+	form := url.Values{}
+	form.Set("api_id", s.apiKey)
+	form.Set("to", to)
+	form.Set("msg", text)
+	form.Set("json", "1")
+	if s.from != "" {
+		form.Set("from", s.from)
+	}
+
+	resp, err := s.httpClient.PostForm(s.apiURL, form)
+	if err != nil {
+		return fmt.Errorf("ошибка запроса к SMS.ru: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения ответа SMS.ru: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("SMS.ru вернул статус %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result smsRuResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("ошибка декодирования ответа SMS.ru: %w", err)
+	}
+	if result.Status != "OK" {
+		return fmt.Errorf("SMS.ru: %s (код %d)", result.StatusText, result.StatusCode)
+	}
+	if message, ok := result.SMS[to]; ok && message.Status != "OK" {
+		return fmt.Errorf("SMS.ru не принял сообщение: %s (код %d)", message.StatusText, message.StatusCode)
+	}
+	return nil
+}
+
+// httpSMSSender отправляет SMS через собственный HTTP шлюз: POST JSON
+// {"phone", "text", "from"}, любой ответ 2xx считается успешной отправкой
+type httpSMSSender struct {
+	apiURL     string
+	apiKey     string
+	from       string
+	httpClient *http.Client
+}
+
+// Send отправляет сообщение на номер телефона
+func (s *httpSMSSender) Send(phone, text string) error {
+	payload, err := json.Marshal(map[string]string{
+		"phone": phone,
+		"text":  text,
+		"from":  s.from,
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка маршалинга запроса SMS: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.apiURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса SMS: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка запроса к шлюзу SMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("шлюз SMS вернул статус %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
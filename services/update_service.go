@@ -0,0 +1,348 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mm-api/mm-api/models"
+	"github.com/mm-api/mm-api/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NormalizeUpdateChannel проверяет канал обновлений (пусто - stable)
+func NormalizeUpdateChannel(channel models.UpdateChannel) (models.UpdateChannel, error) {
+	switch channel {
+	case "":
+		return models.UpdateChannelStable, nil
+	case models.UpdateChannelStable, models.UpdateChannelBeta:
+		return channel, nil
+	}
+	return "", fmt.Errorf("invalid channel %q (allowed: stable, beta)", channel)
+}
+
+// updateChannelsFor каналы, релизы которых получает клиент канала: beta получает и stable
+func updateChannelsFor(channel models.UpdateChannel) []models.UpdateChannel {
+	if channel == models.UpdateChannelBeta {
+		return []models.UpdateChannel{models.UpdateChannelBeta, models.UpdateChannelStable}
+	}
+	return []models.UpdateChannel{models.UpdateChannelStable}
+}
+
+// ValidateReleaseVersions проверяет версию релиза, минимальную поддерживаемую версию и долю выпуска
+func ValidateReleaseVersions(version, minSupported string, rollout int) error {
+	v, err := utils.ParseVersion(version)
+	if err != nil {
+		return err
+	}
+	if minSupported != "" {
+		min, err := utils.ParseVersion(minSupported)
+		if err != nil {
+			return fmt.Errorf("minSupportedVersion: %v", err)
+		}
+		if min.Compare(v) > 0 {
+			return fmt.Errorf("minSupportedVersion %s is higher than release version %s", minSupported, version)
+		}
+	}
+	if rollout < 0 || rollout > 100 {
+		return fmt.Errorf("rolloutPercentage must be between 0 and 100")
+	}
+	return nil
+}
+
+// UpdateRolloutBucket номер группы устройства (0-99) для релиза. Группа зависит от
+// релиза, поэтому первыми получают обновления разные устройства; при увеличении
+// доли выпуска устройства, уже получившие релиз, его не теряют.
+func UpdateRolloutBucket(deviceID string, releaseID uuid.UUID) int {
+	sum := sha256.Sum256([]byte(releaseID.String() + ":" + deviceID))
+	return int(binary.BigEndian.Uint64(sum[:8]) % 100)
+}
+
+// updateReleaseAvailable выдан ли релиз устройству при текущей доле выпуска.
+// Без deviceId устройство получает только полностью выпущенные релизы.
+func updateReleaseAvailable(release *models.UpdateRelease, deviceID string) bool {
+	if release.RolloutPercentage >= 100 {
+		return true
+	}
+	if deviceID == "" || release.RolloutPercentage <= 0 {
+		return false
+	}
+	return UpdateRolloutBucket(deviceID, release.ID) < release.RolloutPercentage
+}
+
+// updateReleaseSet активные релизы платформы в каналах клиента, от новой версии к старой
+type updateReleaseSet struct {
+	releases     []models.UpdateRelease
+	minSupported string // Максимальная minSupportedVersion среди полностью выпущенных релизов
+}
+
+func loadUpdateReleases(db *gorm.DB, platform models.UpdatePlatform, channel models.UpdateChannel) (*updateReleaseSet, error) {
+	var releases []models.UpdateRelease
+	if err := db.Where("platform = ? AND is_active = ? AND channel IN ?", platform, true, updateChannelsFor(channel)).
+		Order("created_at DESC").
+		Find(&releases).Error; err != nil {
+		return nil, err
+	}
+	sort.SliceStable(releases, func(i, j int) bool {
+		return utils.CompareVersions(releases[i].Version, releases[j].Version) > 0
+	})
+
+	// Минимальную версию задают только релизы, выпущенные на все устройства каналов
+	// клиента: релиз с частичной долей выпуска не делает обновление обязательным
+	// для тех, кому он еще не выдан
+	set := &updateReleaseSet{releases: releases}
+	for _, release := range releases {
+		if release.RolloutPercentage < 100 {
+			continue
+		}
+		if release.MinSupportedVersion != "" && utils.CompareVersions(release.MinSupportedVersion, set.minSupported) > 0 {
+			set.minSupported = release.MinSupportedVersion
+		}
+	}
+	return set, nil
+}
+
+// target версия для устройства: самый новый выданный ему релиз. Если версия клиента
+// ниже минимальной, а выданные релизы ее не закрывают, выдается самый новый релиз
+// не ниже минимальной независимо от доли выпуска.
+func (s *updateReleaseSet) target(deviceID string, required bool) *models.UpdateRelease {
+	for i := range s.releases {
+		release := &s.releases[i]
+		if !updateReleaseAvailable(release, deviceID) {
+			continue
+		}
+		if required && utils.CompareVersions(release.Version, s.minSupported) < 0 {
+			break
+		}
+		return release
+	}
+	if required && len(s.releases) > 0 && utils.CompareVersions(s.releases[0].Version, s.minSupported) >= 0 {
+		return &s.releases[0]
+	}
+	return nil
+}
+
+// LatestUpdateRelease последний релиз платформы, выданный устройству (без учета версии клиента)
+func LatestUpdateRelease(db *gorm.DB, req models.UpdateCheckRequest) (*models.UpdateRelease, error) {
+	channel, err := NormalizeUpdateChannel(req.Channel)
+	if err != nil {
+		return nil, err
+	}
+	set, err := loadUpdateReleases(db, req.Platform, channel)
+	if err != nil {
+		return nil, err
+	}
+	required := req.CurrentVersion != "" && set.minSupported != "" &&
+		utils.CompareVersions(req.CurrentVersion, set.minSupported) < 0
+	return set.target(strings.TrimSpace(req.DeviceID), required), nil
+}
+
+// UpdateReleaseVersion релиз с версией version, если он выдан устройству: учитываются
+// каналы клиента и доля выпуска, как в LatestUpdateRelease
+func UpdateReleaseVersion(db *gorm.DB, req models.UpdateCheckRequest, version string) (*models.UpdateRelease, error) {
+	channel, err := NormalizeUpdateChannel(req.Channel)
+	if err != nil {
+		return nil, err
+	}
+	set, err := loadUpdateReleases(db, req.Platform, channel)
+	if err != nil {
+		return nil, err
+	}
+	deviceID := strings.TrimSpace(req.DeviceID)
+	for i := range set.releases {
+		release := &set.releases[i]
+		if utils.CompareVersions(release.Version, version) == 0 && updateReleaseAvailable(release, deviceID) {
+			return release, nil
+		}
+	}
+	return nil, nil
+}
+
+// CheckForUpdate решает, что делать клиенту с версией req.CurrentVersion:
+// обновиться (обязательно, если версия ниже минимальной поддерживаемой),
+// откатиться (его версия отозвана) или ничего не делать.
+func CheckForUpdate(db *gorm.DB, req models.UpdateCheckRequest) (*models.UpdateDecision, error) {
+	channel, err := NormalizeUpdateChannel(req.Channel)
+	if err != nil {
+		return nil, err
+	}
+	current := strings.TrimSpace(req.CurrentVersion)
+	if current != "" {
+		if _, err := utils.ParseVersion(current); err != nil {
+			return nil, err
+		}
+	}
+	deviceID := strings.TrimSpace(req.DeviceID)
+
+	set, err := loadUpdateReleases(db, req.Platform, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := &models.UpdateDecision{
+		Action:              models.UpdateActionNone,
+		Platform:            req.Platform,
+		Channel:             channel,
+		CurrentVersion:      current,
+		MinSupportedVersion: set.minSupported,
+	}
+	if len(set.releases) > 0 {
+		decision.LatestVersion = set.releases[0].Version
+	}
+
+	required := current != "" && set.minSupported != "" && utils.CompareVersions(current, set.minSupported) < 0
+	target := set.target(deviceID, required)
+	if current == "" {
+		// Версия неизвестна (первая установка) - предлагаем выданный релиз
+		if target != nil {
+			decision.Action = models.UpdateActionUpdate
+			decision.Release = target
+		}
+	} else {
+		decision.Outdated = decision.LatestVersion != "" && utils.CompareVersions(current, decision.LatestVersion) < 0
+		switch {
+		case target == nil:
+		case utils.CompareVersions(current, target.Version) < 0:
+			decision.Action = models.UpdateActionUpdate
+			if required {
+				decision.Action = models.UpdateActionRequired
+			}
+			decision.Release = target
+		case utils.CompareVersions(current, target.Version) > 0 && updateVersionRevoked(db, req.Platform, current):
+			decision.Action = models.UpdateActionRollback
+			decision.Release = target
+		}
+	}
+	decision.UpdateAvailable = decision.Release != nil
+	decision.Mandatory = decision.Action == models.UpdateActionRequired || decision.Action == models.UpdateActionRollback
+	return decision, nil
+}
+
+// updateVersionRevoked отозван ли релиз с этой версией (деактивирован и нет активного)
+func updateVersionRevoked(db *gorm.DB, platform models.UpdatePlatform, version string) bool {
+	var releases []models.UpdateRelease
+	if err := db.Select("version", "is_active").Where("platform = ?", platform).Find(&releases).Error; err != nil {
+		return false
+	}
+	revoked := false
+	for _, release := range releases {
+		if utils.CompareVersions(release.Version, version) != 0 {
+			continue
+		}
+		if release.IsActive {
+			return false
+		}
+		revoked = true
+	}
+	return revoked
+}
+
+// updateCheckInWindow окно ограничения записей проверок обновлений
+const updateCheckInWindow = time.Hour
+
+// updateCheckInLimiter ограничивает число устройств, которые записывают проверки
+// обновлений с одного адреса за окно. /updates/check публичный, и без ограничения
+// любой клиент создал бы сколько угодно записей с произвольным deviceId.
+// Повторные проверки уже учтенного устройства лимит не расходуют.
+type updateCheckInLimiter struct {
+	mu      sync.Mutex
+	started time.Time
+	devices map[string]map[string]bool // Адрес -> устройства в текущем окне
+}
+
+var checkInLimiter = &updateCheckInLimiter{devices: make(map[string]map[string]bool)}
+
+func (l *updateCheckInLimiter) allow(clientIP, device string, limit int, now time.Time) bool {
+	if limit <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.started) >= updateCheckInWindow {
+		l.started = now
+		l.devices = make(map[string]map[string]bool)
+	}
+	seen := l.devices[clientIP]
+	if seen[device] {
+		return true
+	}
+	if len(seen) >= limit {
+		return false
+	}
+	if seen == nil {
+		seen = make(map[string]bool)
+		l.devices[clientIP] = seen
+	}
+	seen[device] = true
+	return true
+}
+
+// RecordUpdateCheckIn сохраняет версию устройства и выданное решение. С одного
+// адреса за час записываются не больше limit устройств (0 - без ограничения). Ошибки только
+// логируются: проверка обновлений не должна падать из-за статистики.
+func RecordUpdateCheckIn(db *gorm.DB, req models.UpdateCheckRequest, decision *models.UpdateDecision, clientIP string, limit int) {
+	deviceID := strings.TrimSpace(req.DeviceID)
+	if deviceID == "" || len(deviceID) > 255 {
+		return
+	}
+	if !checkInLimiter.allow(clientIP, string(req.Platform)+":"+deviceID, limit, time.Now()) {
+		return
+	}
+
+	checkIn := models.UpdateCheckIn{
+		Platform:      req.Platform,
+		DeviceID:      deviceID,
+		Channel:       decision.Channel,
+		Version:       decision.CurrentVersion,
+		LastAction:    decision.Action,
+		LastCheckedAt: time.Now(),
+	}
+	if decision.Release != nil {
+		checkIn.OfferedVersion = decision.Release.Version
+	}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "platform"}, {Name: "device_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"channel", "version", "last_action", "offered_version", "last_checked_at"}),
+	}).Create(&checkIn).Error; err != nil {
+		log.Printf("⚠️ Не удалось сохранить проверку обновлений устройства %s: %v", deviceID, err)
+	}
+}
+
+// RecordLibissPosCheckIn сохраняет версию установщика POS на кассе и предложенную ей версию.
+// Лимит по адресу общий с RecordUpdateCheckIn. Ошибки только логируются.
+func RecordLibissPosCheckIn(db *gorm.DB, fileType, platform, deviceID, version, offered, clientIP string, limit int) {
+	deviceID = strings.TrimSpace(deviceID)
+	version = strings.TrimSpace(version)
+	if deviceID == "" || len(deviceID) > 255 || version == "" {
+		return
+	}
+	if _, err := utils.ParseVersion(version); err != nil {
+		return
+	}
+	if !checkInLimiter.allow(clientIP, "libiss_pos:"+fileType+":"+platform+":"+deviceID, limit, time.Now()) {
+		return
+	}
+
+	checkIn := models.LibissPosCheckIn{
+		Type:           fileType,
+		Platform:       platform,
+		DeviceID:       deviceID,
+		Version:        version,
+		OfferedVersion: offered,
+		LastCheckedAt:  time.Now(),
+	}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "type"}, {Name: "platform"}, {Name: "device_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"version", "offered_version", "last_checked_at"}),
+	}).Create(&checkIn).Error; err != nil {
+		log.Printf("⚠️ Не удалось сохранить версию POS на кассе %s: %v", deviceID, err)
+	}
+}
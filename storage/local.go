@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalStorage хранит объекты в папке на диске.
+// Временные ссылки подписываются HMAC и обслуживаются API (SignedURLBase).
+type LocalStorage struct {
+	Root          string
+	signingKey    []byte
+	signedURLBase string
+}
+
+// NewLocal создает локальное хранилище
+func NewLocal(root, signingKey, signedURLBase string) *LocalStorage {
+	if root == "" {
+		root = "."
+	}
+	if signedURLBase == "" {
+		signedURLBase = "/api/v1/storage/objects"
+	}
+	return &LocalStorage{
+		Root:          root,
+		signingKey:    []byte(signingKey),
+		signedURLBase: strings.TrimRight(signedURLBase, "/"),
+	}
+}
+
+// Name возвращает название бэкенда
+func (l *LocalStorage) Name() string {
+	return BackendLocal
+}
+
+// Path возвращает путь файла объекта на диске
+func (l *LocalStorage) Path(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.Root, filepath.FromSlash(key)), nil
+}
+
+// Put записывает объект через временный файл, чтобы читатели не видели недописанный файл
+func (l *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	filePath, err := l.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+// Get открывает файл объекта (*os.File реализует io.ReadSeeker)
+func (l *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	filePath, err := l.Path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, nil, ErrNotFound
+	}
+	return file, l.object(key, info), nil
+}
+
+// Stat возвращает метаданные объекта
+func (l *LocalStorage) Stat(ctx context.Context, key string) (*Object, error) {
+	filePath, err := l.Path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, ErrNotFound
+	}
+	return l.object(key, info), nil
+}
+
+// object собирает метаданные из информации о файле
+func (l *LocalStorage) object(key string, info fs.FileInfo) *Object {
+	cleaned, _ := CleanKey(key)
+	return &Object{
+		Key:         cleaned,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(cleaned)),
+		ModTime:     info.ModTime(),
+	}
+}
+
+// Delete удаляет файл объекта
+func (l *LocalStorage) Delete(ctx context.Context, key string) error {
+	filePath, err := l.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List обходит файлы с префиксом (временные файлы загрузки пропускаются)
+func (l *LocalStorage) List(ctx context.Context, prefix string, fn func(Object) error) error {
+	start := l.Root
+	if prefix != "" {
+		// Обходим ближайшую папку префикса, остальное фильтруем по строке
+		dir := prefix
+		if !strings.HasSuffix(dir, "/") {
+			dir = path.Dir(dir)
+		}
+		if dir != "." && dir != "" {
+			cleaned, err := CleanKey(dir)
+			if err != nil {
+				return err
+			}
+			start = filepath.Join(l.Root, filepath.FromSlash(cleaned))
+		}
+	}
+
+	err := filepath.WalkDir(start, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(l.Root, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return fn(*l.object(key, info))
+	})
+	return err
+}
+
+// URL публичный адрес объекта (папки images, updates, libiss_pos раздает API)
+func (l *LocalStorage) URL(key string) string {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return ""
+	}
+	return "/" + cleaned
+}
+
+// PresignGet подписанная ссылка на скачивание через API
+func (l *LocalStorage) PresignGet(ctx context.Context, key string, ttl time.Duration, downloadName string) (string, error) {
+	return l.presign("GET", key, ttl, downloadName, 0)
+}
+
+// PresignPut подписанная ссылка на загрузку через API
+func (l *LocalStorage) PresignPut(ctx context.Context, key string, ttl time.Duration, contentType string) (string, error) {
+	return l.presign("PUT", key, ttl, "", 0)
+}
+
+// PresignUpload подписанная ссылка на загрузку через API; лимит размера входит в подпись,
+// API обрывает загрузку больше maxSize
+func (l *LocalStorage) PresignUpload(ctx context.Context, key string, ttl time.Duration, contentType string, maxSize int64) (*PresignedUpload, error) {
+	signedURL, err := l.presign("PUT", key, ttl, "", maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return &PresignedUpload{Method: http.MethodPut, URL: signedURL}, nil
+}
+
+// presign формирует ссылку вида <base>/<key>?expires=...&name=...&max=...&signature=...
+func (l *LocalStorage) presign(method, key string, ttl time.Duration, downloadName string, maxSize int64) (string, error) {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if len(l.signingKey) == 0 {
+		return "", errors.New("storage: signing key is not configured")
+	}
+	expires := time.Now().Add(ttl).Unix()
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	if downloadName != "" {
+		query.Set("name", downloadName)
+	}
+	if maxSize > 0 {
+		query.Set("max", strconv.FormatInt(maxSize, 10))
+	}
+	query.Set("signature", l.signature(method, cleaned, expires, downloadName, maxSize))
+	return l.signedURLBase + "/" + escapeKey(cleaned) + "?" + query.Encode(), nil
+}
+
+// VerifySignature проверяет подписанную ссылку локального хранилища
+// (maxSize 0 - ссылка без лимита размера)
+func (l *LocalStorage) VerifySignature(method, key string, expires int64, downloadName string, maxSize int64, signature string) bool {
+	if len(l.signingKey) == 0 || time.Now().Unix() > expires {
+		return false
+	}
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return false
+	}
+	expected := l.signature(method, cleaned, expires, downloadName, maxSize)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// signature HMAC-SHA256 от метода, ключа, срока действия, имени файла и лимита размера.
+// Лимит дописывается только если задан: ссылки без лимита подписываются как раньше.
+func (l *LocalStorage) signature(method, key string, expires int64, downloadName string, maxSize int64) string {
+	payload := method + "\n" + key + "\n" + strconv.FormatInt(expires, 10) + "\n" + downloadName
+	if maxSize > 0 {
+		payload += "\n" + strconv.FormatInt(maxSize, 10)
+	}
+	mac := hmac.New(sha256.New, l.signingKey)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// escapeKey экранирует сегменты ключа для URL
+func escapeKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalPresignUploadSignsLimit(t *testing.T) {
+	store := NewLocal(t.TempDir(), "signing-key", "/api/v1/storage/objects")
+
+	upload, err := store.PresignUpload(context.Background(), "images/uploads/photo.png", time.Minute, "image/png", 1024)
+	if err != nil {
+		t.Fatalf("PresignUpload: %v", err)
+	}
+	if upload.Method != "PUT" {
+		t.Fatalf("Method = %s, want PUT", upload.Method)
+	}
+
+	link, err := url.Parse(upload.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := strings.TrimPrefix(link.Path, "/api/v1/storage/objects/")
+	query := link.Query()
+	expires, _ := strconv.ParseInt(query.Get("expires"), 10, 64)
+	maxSize, _ := strconv.ParseInt(query.Get("max"), 10, 64)
+	signature := query.Get("signature")
+
+	if maxSize != 1024 {
+		t.Fatalf("max = %d, want 1024", maxSize)
+	}
+	if !store.VerifySignature("PUT", key, expires, "", maxSize, signature) {
+		t.Fatal("signature with the signed limit must be valid")
+	}
+	if store.VerifySignature("PUT", key, expires, "", 0, signature) {
+		t.Fatal("dropping the limit must invalidate the signature")
+	}
+	if store.VerifySignature("PUT", key, expires, "", maxSize*10, signature) {
+		t.Fatal("raising the limit must invalidate the signature")
+	}
+}
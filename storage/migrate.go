@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// MigrateOptions параметры переноса файлов между хранилищами
+type MigrateOptions struct {
+	Prefixes     []string // Какие папки переносить (по умолчанию images/, updates/, libiss_pos/)
+	DryRun       bool     // Только показать, что будет перенесено
+	DeleteSource bool     // Удалять файл из источника после успешного копирования
+}
+
+// MigrateStats итоги переноса
+type MigrateStats struct {
+	Copied  int
+	Skipped int
+	Failed  int
+	Bytes   int64
+}
+
+// DefaultPrefixes папки приложения, которые хранятся в хранилище
+var DefaultPrefixes = []string{"images/", "updates/", "libiss_pos/"}
+
+// Migrate копирует объекты из src в dst с теми же ключами. Объекты, которые уже есть
+// в dst с тем же размером, пропускаются, поэтому перенос можно безопасно перезапускать.
+// Ссылки в БД хранят ключи, а не адреса бэкенда, поэтому правка БД не нужна.
+func Migrate(ctx context.Context, src, dst Storage, opts MigrateOptions) (MigrateStats, error) {
+	var stats MigrateStats
+	prefixes := opts.Prefixes
+	if len(prefixes) == 0 {
+		prefixes = DefaultPrefixes
+	}
+
+	for _, prefix := range prefixes {
+		err := src.List(ctx, prefix, func(obj Object) error {
+			if existing, err := dst.Stat(ctx, obj.Key); err == nil && existing.Size == obj.Size {
+				stats.Skipped++
+				return nil
+			}
+			if opts.DryRun {
+				log.Printf("📦 %s (%d bytes)", obj.Key, obj.Size)
+				stats.Copied++
+				stats.Bytes += obj.Size
+				return nil
+			}
+
+			if err := copyObject(ctx, src, dst, obj); err != nil {
+				log.Printf("❌ Ошибка переноса %s: %v", obj.Key, err)
+				stats.Failed++
+				return nil
+			}
+			stats.Copied++
+			stats.Bytes += obj.Size
+
+			if opts.DeleteSource {
+				if err := src.Delete(ctx, obj.Key); err != nil {
+					log.Printf("⚠️ Не удалось удалить %s из источника: %v", obj.Key, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return stats, fmt.Errorf("ошибка обхода %s: %w", prefix, err)
+		}
+	}
+	return stats, nil
+}
+
+// copyObject копирует один объект
+func copyObject(ctx context.Context, src, dst Storage, obj Object) error {
+	reader, info, err := src.Get(ctx, obj.Key)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = obj.ContentType
+	}
+	return dst.Put(ctx, obj.Key, reader, info.Size, contentType)
+}
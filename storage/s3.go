@@ -0,0 +1,531 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unsignedPayload тело запроса не подписывается (стримим большие файлы без предварительного хеширования)
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Storage хранилище на S3-совместимом сервисе (AWS S3, MinIO и т.п.).
+// Запросы подписываются AWS Signature V4 без внешних SDK.
+type S3Storage struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	publicURL string
+	client    *http.Client
+}
+
+// NewS3 создает S3 хранилище
+func NewS3(cfg Config) (*S3Storage, error) {
+	if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+		return nil, errors.New("storage: S3_ENDPOINT and S3_BUCKET are required for s3 backend")
+	}
+	if cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+		return nil, errors.New("storage: S3_ACCESS_KEY and S3_SECRET_KEY are required for s3 backend")
+	}
+	endpoint, err := url.Parse(strings.TrimRight(cfg.S3Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("storage: invalid S3_ENDPOINT %q", cfg.S3Endpoint)
+	}
+	region := cfg.S3Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3Storage{
+		endpoint:  endpoint,
+		region:    region,
+		bucket:    cfg.S3Bucket,
+		accessKey: cfg.S3AccessKey,
+		secretKey: cfg.S3SecretKey,
+		pathStyle: cfg.S3PathStyle,
+		publicURL: strings.TrimRight(cfg.S3PublicURL, "/"),
+		client:    &http.Client{Timeout: 30 * time.Minute},
+	}, nil
+}
+
+// Name возвращает название бэкенда
+func (s *S3Storage) Name() string {
+	return BackendS3
+}
+
+// objectURL адрес объекта (или бакета при пустом ключе) в S3 API
+func (s *S3Storage) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	escaped := ""
+	if key != "" {
+		escaped = "/" + uriEncode(key, false)
+	}
+	if s.pathStyle {
+		u.Path = s.endpoint.Path + "/" + s.bucket + "/" + key
+		u.RawPath = s.endpoint.Path + "/" + uriEncode(s.bucket, true) + escaped
+		if key == "" {
+			u.Path = s.endpoint.Path + "/" + s.bucket
+		}
+	} else {
+		u.Host = s.bucket + "." + s.endpoint.Host
+		u.Path = s.endpoint.Path + "/" + key
+		u.RawPath = s.endpoint.Path + escaped
+		if key == "" {
+			u.Path = s.endpoint.Path + "/"
+			u.RawPath = ""
+		}
+	}
+	return &u
+}
+
+// Put загружает объект. Если размер неизвестен, тело сначала пишется во временный файл:
+// S3 PUT требует Content-Length.
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	if size < 0 {
+		tmp, err := os.CreateTemp("", "mm-s3-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		if size, err = io.Copy(tmp, r); err != nil {
+			return err
+		}
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		r = tmp
+	}
+
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(key))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+	if size > 0 {
+		req.Body = io.NopCloser(r)
+	} else {
+		req.Body = http.NoBody
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get скачивает объект
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, objectFromHeaders(key, resp), nil
+}
+
+// Stat возвращает метаданные объекта (HEAD)
+func (s *S3Storage) Stat(ctx context.Context, key string) (*Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return objectFromHeaders(key, resp), nil
+}
+
+// objectFromHeaders собирает метаданные из заголовков ответа
+func objectFromHeaders(key string, resp *http.Response) *Object {
+	obj := &Object{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		obj.ModTime = modified
+	}
+	return obj
+}
+
+// Delete удаляет объект (S3 возвращает 204 и для отсутствующих объектов)
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listBucketResult ответ ListObjectsV2
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// List обходит объекты с префиксом постранично (ListObjectsV2)
+func (s *S3Storage) List(ctx context.Context, prefix string, fn func(Object) error) error {
+	token := ""
+	for {
+		u := s.objectURL("")
+		query := url.Values{}
+		query.Set("list-type", "2")
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u.RawQuery = query.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := s.do(req)
+		if err != nil {
+			return err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("storage: invalid ListObjectsV2 response: %w", err)
+		}
+
+		for _, item := range result.Contents {
+			obj := Object{
+				Key:         item.Key,
+				Size:        item.Size,
+				ContentType: mime.TypeByExtension(path.Ext(item.Key)),
+				ModTime:     item.LastModified,
+			}
+			if err := fn(obj); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// URL постоянный адрес объекта: через S3_PUBLIC_URL (CDN, публичный бакет)
+// или через API, который перенаправляет на временную ссылку
+func (s *S3Storage) URL(key string) string {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return ""
+	}
+	if s.publicURL != "" {
+		return s.publicURL + "/" + uriEncode(cleaned, false)
+	}
+	return "/" + cleaned
+}
+
+// PresignGet временная ссылка на скачивание
+func (s *S3Storage) PresignGet(ctx context.Context, key string, ttl time.Duration, downloadName string) (string, error) {
+	query := url.Values{}
+	if downloadName != "" {
+		query.Set("response-content-disposition", contentDisposition(downloadName))
+	}
+	return s.presign(http.MethodGet, key, ttl, query)
+}
+
+// PresignPut временная ссылка на загрузку
+func (s *S3Storage) PresignPut(ctx context.Context, key string, ttl time.Duration, contentType string) (string, error) {
+	return s.presign(http.MethodPut, key, ttl, url.Values{})
+}
+
+// PresignUpload временная форма загрузки (POST policy): в отличие от PUT по ссылке,
+// S3 сам проверяет ключ, Content-Type и content-length-range
+func (s *S3Storage) PresignUpload(ctx context.Context, key string, ttl time.Duration, contentType string, maxSize int64) (*PresignedUpload, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	credential := s.accessKey + "/" + now.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+
+	conditions := []interface{}{
+		map[string]string{"bucket": s.bucket},
+		map[string]string{"key": key},
+		map[string]string{"Content-Type": contentType},
+		map[string]string{"x-amz-algorithm": "AWS4-HMAC-SHA256"},
+		map[string]string{"x-amz-credential": credential},
+		map[string]string{"x-amz-date": amzDate},
+	}
+	if maxSize > 0 {
+		conditions = append(conditions, []interface{}{"content-length-range", 1, maxSize})
+	}
+	policy, err := json.Marshal(map[string]interface{}{
+		"expiration": now.Add(time.Duration(presignSeconds(ttl)) * time.Second).Format("2006-01-02T15:04:05.000Z"),
+		"conditions": conditions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	encodedPolicy := base64.StdEncoding.EncodeToString(policy)
+
+	return &PresignedUpload{
+		Method: http.MethodPost,
+		URL:    s.objectURL("").String(),
+		Fields: map[string]string{
+			"key":              key,
+			"Content-Type":     contentType,
+			"policy":           encodedPolicy,
+			"x-amz-algorithm":  "AWS4-HMAC-SHA256",
+			"x-amz-credential": credential,
+			"x-amz-date":       amzDate,
+			"x-amz-signature":  hex.EncodeToString(hmacSHA256(s.signingKey(now), encodedPolicy)),
+		},
+	}, nil
+}
+
+// presignSeconds срок действия ссылки в секундах в пределах, допустимых SigV4 (до 7 дней)
+func presignSeconds(ttl time.Duration) int64 {
+	seconds := int64(ttl / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	if seconds > 7*24*3600 {
+		seconds = 7 * 24 * 3600
+	}
+	return seconds
+}
+
+// presign подписывает ссылку параметрами запроса (SigV4 query string)
+func (s *S3Storage) presign(method, key string, ttl time.Duration, query url.Values) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	seconds := presignSeconds(ttl)
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+
+	u := s.objectURL(key)
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.accessKey+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.FormatInt(seconds, 10))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		canonicalURI(u),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	signature := s.signature(now, scope, amzDate, canonicalRequest)
+
+	u.RawQuery = canonicalQuery(query) + "&X-Amz-Signature=" + signature
+	return u.String(), nil
+}
+
+// do подписывает и выполняет запрос; ответы 4xx/5xx превращаются в ошибки
+func (s *S3Storage) do(req *http.Request) (*http.Response, error) {
+	s.sign(req)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("storage: s3 %s %s: %s %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+}
+
+// sign добавляет заголовок Authorization (SigV4)
+func (s *S3Storage) sign(req *http.Request) {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": unsignedPayload,
+		"x-amz-date":           amzDate,
+	}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = contentType
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+	signature := s.signature(now, scope, amzDate, canonicalRequest)
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature,
+	))
+}
+
+// signature вычисляет подпись канонического запроса
+func (s *S3Storage) signature(now time.Time, scope, amzDate, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+	return hex.EncodeToString(hmacSHA256(s.signingKey(now), stringToSign))
+}
+
+// signingKey ключ подписи SigV4 на дату запроса
+func (s *S3Storage) signingKey(now time.Time) []byte {
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalURI путь запроса в формате SigV4 (каждый сегмент кодируется один раз)
+func canonicalURI(u *url.URL) string {
+	p := u.Path
+	if p == "" {
+		return "/"
+	}
+	return uriEncode(p, false)
+}
+
+// canonicalQuery параметры, отсортированные по имени, в строгом URI-кодировании
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode кодирует строку по правилам AWS: незарезервированные символы
+// остаются как есть, "/" кодируется только при encodeSlash
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch >= 'A' && ch <= 'Z', ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9',
+			ch == '-', ch == '_', ch == '.', ch == '~':
+			b.WriteByte(ch)
+		case ch == '/' && !encodeSlash:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// contentDisposition заголовок скачивания файла с именем
+func contentDisposition(filename string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+}
@@ -0,0 +1,442 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	standInBucket    = "test-bucket"
+	standInAccessKey = "minioadmin"
+	standInSecretKey = "minioadmin-secret"
+	standInRegion    = "us-east-1"
+	standInPageSize  = 2 // Маленькая страница ListObjectsV2, чтобы проверить continuation-token
+)
+
+// standInObject объект в памяти S3-заглушки
+type standInObject struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+// s3StandIn минимальная S3-совместимая заглушка в стиле MinIO (path-style):
+// PUT/GET/HEAD/DELETE объектов, ListObjectsV2 и загрузка формой POST с проверкой policy
+type s3StandIn struct {
+	mu      sync.Mutex
+	objects map[string]standInObject
+}
+
+func newS3StandIn(t *testing.T) (*S3Storage, *s3StandIn) {
+	t.Helper()
+	standIn := &s3StandIn{objects: make(map[string]standInObject)}
+	server := httptest.NewServer(standIn)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3(Config{
+		S3Endpoint:  server.URL,
+		S3Region:    standInRegion,
+		S3Bucket:    standInBucket,
+		S3AccessKey: standInAccessKey,
+		S3SecretKey: standInSecretKey,
+		S3PathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+	return store, standIn
+}
+
+func (s *s3StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	bucket, key, _ := strings.Cut(path, "/")
+	if bucket != standInBucket {
+		standInError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	if r.Method == http.MethodPost && key == "" {
+		s.postObject(w, r)
+		return
+	}
+
+	authorization := r.Header.Get("Authorization")
+	signed := strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential="+standInAccessKey+"/") ||
+		(r.URL.Query().Get("X-Amz-Signature") != "" && strings.HasPrefix(r.URL.Query().Get("X-Amz-Credential"), standInAccessKey+"/"))
+	if !signed {
+		standInError(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	if key == "" {
+		if r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2" {
+			s.listObjects(w, r)
+			return
+		}
+		standInError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		if r.ContentLength < 0 {
+			standInError(w, http.StatusLengthRequired, "MissingContentLength")
+			return
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			standInError(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		s.store(key, data, r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		s.mu.Lock()
+		obj, ok := s.objects[key]
+		s.mu.Unlock()
+		if !ok {
+			standInError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Last-Modified", obj.modTime.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(obj.data)
+		}
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.objects, key)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		standInError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (s *s3StandIn) store(key string, data []byte, contentType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = standInObject{data: data, contentType: contentType, modTime: time.Now()}
+}
+
+// listObjects ListObjectsV2; continuation-token - последний ключ предыдущей страницы
+func (s *s3StandIn) listObjects(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	after := r.URL.Query().Get("continuation-token")
+
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	}
+	result := struct {
+		XMLName               xml.Name  `xml:"ListBucketResult"`
+		IsTruncated           bool      `xml:"IsTruncated"`
+		NextContinuationToken string    `xml:"NextContinuationToken,omitempty"`
+		Contents              []content `xml:"Contents"`
+	}{}
+	for i, key := range keys {
+		if i == standInPageSize {
+			result.IsTruncated = true
+			result.NextContinuationToken = keys[i-1]
+			break
+		}
+		obj := s.objects[key]
+		result.Contents = append(result.Contents, content{Key: key, Size: int64(len(obj.data)), LastModified: obj.modTime.UTC()})
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+// postObject загрузка формой (POST policy): подпись, срок, условия и content-length-range
+func (s *s3StandIn) postObject(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		standInError(w, http.StatusBadRequest, "MalformedPOSTRequest")
+		return
+	}
+	form := func(name string) string { return r.MultipartForm.Value[name][0] }
+	for _, name := range []string{"key", "policy", "x-amz-algorithm", "x-amz-credential", "x-amz-date", "x-amz-signature"} {
+		if len(r.MultipartForm.Value[name]) == 0 {
+			standInError(w, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+	}
+	files := r.MultipartForm.File["file"]
+	if len(files) != 1 {
+		standInError(w, http.StatusBadRequest, "InvalidArgument")
+		return
+	}
+
+	// Подпись по правилам SigV4 POST: HMAC ключа подписи от base64 policy
+	credential := strings.Split(form("x-amz-credential"), "/")
+	if len(credential) != 5 || credential[0] != standInAccessKey {
+		standInError(w, http.StatusForbidden, "InvalidAccessKeyId")
+		return
+	}
+	key := standInSign([]byte("AWS4"+standInSecretKey), credential[1])
+	key = standInSign(key, credential[2])
+	key = standInSign(key, credential[3])
+	key = standInSign(key, credential[4])
+	if !hmac.Equal([]byte(hex.EncodeToString(standInSign(key, form("policy")))), []byte(form("x-amz-signature"))) {
+		standInError(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(form("policy"))
+	if err != nil {
+		standInError(w, http.StatusBadRequest, "InvalidPolicyDocument")
+		return
+	}
+	var policy struct {
+		Expiration time.Time         `json:"expiration"`
+		Conditions []json.RawMessage `json:"conditions"`
+	}
+	if err := json.Unmarshal(raw, &policy); err != nil {
+		standInError(w, http.StatusBadRequest, "InvalidPolicyDocument")
+		return
+	}
+	if time.Now().After(policy.Expiration) {
+		standInError(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	size := files[0].Size
+	for _, condition := range policy.Conditions {
+		var exact map[string]string
+		if json.Unmarshal(condition, &exact) == nil {
+			for name, value := range exact {
+				actual := ""
+				if name == "bucket" {
+					actual = standInBucket
+				} else if values := r.MultipartForm.Value[name]; len(values) > 0 {
+					actual = values[0]
+				}
+				if actual != value {
+					standInError(w, http.StatusForbidden, "AccessDenied")
+					return
+				}
+			}
+			continue
+		}
+		var rule []interface{}
+		if err := json.Unmarshal(condition, &rule); err != nil || len(rule) != 3 || rule[0] != "content-length-range" {
+			standInError(w, http.StatusBadRequest, "InvalidPolicyDocument")
+			return
+		}
+		if size < int64(rule[1].(float64)) {
+			standInError(w, http.StatusBadRequest, "EntityTooSmall")
+			return
+		}
+		if size > int64(rule[2].(float64)) {
+			standInError(w, http.StatusBadRequest, "EntityTooLarge")
+			return
+		}
+	}
+
+	file, err := files[0].Open()
+	if err != nil {
+		standInError(w, http.StatusInternalServerError, "InternalError")
+		return
+	}
+	defer file.Close()
+	data, _ := io.ReadAll(file)
+	contentType := ""
+	if values := r.MultipartForm.Value["Content-Type"]; len(values) > 0 {
+		contentType = values[0]
+	}
+	s.store(form("key"), data, contentType)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func standInSign(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func standInError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code></Error>", code)
+}
+
+// postForm отправляет файл по ответу PresignUpload так, как это делает клиент
+func postForm(t *testing.T, upload *PresignedUpload, data []byte) (int, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range upload.Fields {
+		writer.WriteField(name, value)
+	}
+	part, err := writer.CreateFormFile("file", "photo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	resp, err := http.Post(upload.URL, writer.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("POST %s: %v", upload.URL, err)
+	}
+	defer resp.Body.Close()
+	response, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(response)
+}
+
+func TestS3StorageRoundTrip(t *testing.T) {
+	store, _ := newS3StandIn(t)
+	ctx := context.Background()
+
+	objects := map[string]string{
+		"images/products/a.png": "first",
+		"images/products/b.png": "second",
+		"images/products/c.png": "third",
+		"updates/windows/x.exe": "installer",
+	}
+	for key, data := range objects {
+		if err := store.Put(ctx, key, strings.NewReader(data), int64(len(data)), ""); err != nil {
+			t.Fatalf("Put %s: %v", key, err)
+		}
+	}
+	// Размер неизвестен: тело буферизуется во временный файл
+	if err := store.Put(ctx, "images/avatars/d.jpg", strings.NewReader("unknown size"), -1, ""); err != nil {
+		t.Fatalf("Put with unknown size: %v", err)
+	}
+
+	obj, err := store.Stat(ctx, "images/products/a.png")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if obj.Size != int64(len("first")) || obj.ContentType != "image/png" {
+		t.Fatalf("Stat = %+v, want size 5 and image/png", obj)
+	}
+
+	reader, _, err := store.Get(ctx, "images/avatars/d.jpg")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "unknown size" {
+		t.Fatalf("Get = %q", data)
+	}
+
+	var listed []string
+	if err := store.List(ctx, "images/products/", func(obj Object) error {
+		listed = append(listed, obj.Key)
+		return nil
+	}); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if strings.Join(listed, ",") != "images/products/a.png,images/products/b.png,images/products/c.png" {
+		t.Fatalf("List across pages = %v", listed)
+	}
+
+	if err := store.Delete(ctx, "images/products/a.png"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Stat(ctx, "images/products/a.png"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Stat after Delete = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "images/products/a.png"); err != nil {
+		t.Fatalf("Delete of missing object = %v, want nil", err)
+	}
+}
+
+func TestS3PresignGet(t *testing.T) {
+	store, standIn := newS3StandIn(t)
+	standIn.store("updates/windows/x.exe", []byte("installer"), "application/octet-stream")
+
+	link, err := store.PresignGet(context.Background(), "updates/windows/x.exe", time.Minute, "Setup.exe")
+	if err != nil {
+		t.Fatalf("PresignGet: %v", err)
+	}
+	resp, err := http.Get(link)
+	if err != nil {
+		t.Fatalf("GET presigned: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(data) != "installer" {
+		t.Fatalf("GET presigned = %d %q", resp.StatusCode, data)
+	}
+}
+
+func TestS3PresignUploadPolicy(t *testing.T) {
+	store, standIn := newS3StandIn(t)
+	ctx := context.Background()
+	const maxSize = 16
+
+	upload, err := store.PresignUpload(ctx, "images/uploads/photo.png", time.Minute, "image/png", maxSize)
+	if err != nil {
+		t.Fatalf("PresignUpload: %v", err)
+	}
+	if upload.Method != http.MethodPost {
+		t.Fatalf("Method = %s, want POST", upload.Method)
+	}
+
+	if status, body := postForm(t, upload, []byte("small png")); status != http.StatusNoContent {
+		t.Fatalf("upload within limit = %d %s", status, body)
+	}
+	obj, err := store.Stat(ctx, "images/uploads/photo.png")
+	if err != nil || obj.ContentType != "image/png" {
+		t.Fatalf("Stat after upload = %+v, %v", obj, err)
+	}
+
+	if status, body := postForm(t, upload, bytes.Repeat([]byte("x"), maxSize+1)); status != http.StatusBadRequest || !strings.Contains(body, "EntityTooLarge") {
+		t.Fatalf("upload over limit = %d %s, want 400 EntityTooLarge", status, body)
+	}
+
+	tampered := *upload
+	tampered.Fields = map[string]string{}
+	for name, value := range upload.Fields {
+		tampered.Fields[name] = value
+	}
+	tampered.Fields["Content-Type"] = "image/svg+xml"
+	if status, _ := postForm(t, &tampered, []byte("<svg/>")); status != http.StatusForbidden {
+		t.Fatalf("upload with changed Content-Type = %d, want 403", status)
+	}
+	tampered.Fields["Content-Type"] = "image/png"
+	tampered.Fields["key"] = "images/uploads/other.png"
+	if status, _ := postForm(t, &tampered, []byte("png")); status != http.StatusForbidden {
+		t.Fatalf("upload with changed key = %d, want 403", status)
+	}
+
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	if len(standIn.objects) != 1 {
+		t.Fatalf("stand-in has %d objects, want only the accepted upload", len(standIn.objects))
+	}
+}
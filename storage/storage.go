@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Бэкенды хранилища
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+var (
+	// ErrNotFound объект не найден в хранилище
+	ErrNotFound = errors.New("storage: object not found")
+	// ErrInvalidKey ключ пустой, абсолютный или выходит за пределы хранилища ("..")
+	ErrInvalidKey = errors.New("storage: invalid object key")
+)
+
+// Object метаданные объекта
+type Object struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"contentType,omitempty"`
+	ModTime     time.Time `json:"modTime"`
+}
+
+// Storage хранилище файлов: изображения, обновления, установщики POS.
+// Ключи - пути через "/" вида images/products/<uuid>.jpg, updates/windows/<file>.exe.
+type Storage interface {
+	// Name возвращает название бэкенда (local, s3)
+	Name() string
+	// Put сохраняет объект; size = -1, если размер заранее неизвестен
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get открывает объект на чтение. Для локального хранилища reader
+	// реализует io.ReadSeeker (поддержка Range запросов).
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	// Stat возвращает метаданные объекта или ErrNotFound
+	Stat(ctx context.Context, key string) (*Object, error)
+	// Delete удаляет объект; отсутствие объекта не считается ошибкой
+	Delete(ctx context.Context, key string) error
+	// List обходит объекты с префиксом
+	List(ctx context.Context, prefix string, fn func(Object) error) error
+	// URL постоянный публичный адрес объекта
+	URL(key string) string
+	// PresignGet временная ссылка на скачивание; downloadName задает имя файла
+	// в Content-Disposition (пусто - без заголовка)
+	PresignGet(ctx context.Context, key string, ttl time.Duration, downloadName string) (string, error)
+	// PresignPut временная ссылка для загрузки объекта методом PUT
+	PresignPut(ctx context.Context, key string, ttl time.Duration, contentType string) (string, error)
+	// PresignUpload временная ссылка на загрузку объекта с типом contentType не больше
+	// maxSize байт; ограничения проверяет само хранилище, а не клиент
+	PresignUpload(ctx context.Context, key string, ttl time.Duration, contentType string, maxSize int64) (*PresignedUpload, error)
+}
+
+// PresignedUpload временная ссылка на загрузку с ограничениями. Method PUT - файл
+// отправляется телом запроса; POST - multipart-формой: поля Fields, последним поле file.
+type PresignedUpload struct {
+	Method string            `json:"method"`
+	URL    string            `json:"url"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// Config настройки хранилища
+type Config struct {
+	Backend string // local или s3
+
+	// Локальная файловая система
+	LocalRoot     string // Корень: ключ images/x.jpg -> <LocalRoot>/images/x.jpg
+	SigningKey    string // Секрет подписи временных ссылок локального хранилища
+	SignedURLBase string // Путь API, который обслуживает подписанные ссылки
+
+	// S3-совместимое хранилище (AWS S3, MinIO, Yandex Object Storage...)
+	S3Endpoint  string // https://s3.amazonaws.com или http://minio:9000
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool   // endpoint/bucket/key вместо bucket.endpoint/key (нужно для MinIO)
+	S3PublicURL string // Адрес CDN или публичного бакета для постоянных ссылок
+}
+
+// New создает хранилище по настройкам
+func New(cfg Config) (Storage, error) {
+	switch cfg.Backend {
+	case "", BackendLocal:
+		return NewLocal(cfg.LocalRoot, cfg.SigningKey, cfg.SignedURLBase), nil
+	case BackendS3:
+		return NewS3(cfg)
+	}
+	return nil, fmt.Errorf("storage: unknown backend %q (allowed: local, s3)", cfg.Backend)
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultStorage Storage
+)
+
+// SetDefault задает хранилище приложения
+func SetDefault(s Storage) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultStorage = s
+}
+
+// Default возвращает хранилище приложения (локальное в текущей папке, если не настроено)
+func Default() Storage {
+	defaultMu.RLock()
+	s := defaultStorage
+	defaultMu.RUnlock()
+	if s != nil {
+		return s
+	}
+
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultStorage == nil {
+		defaultStorage = NewLocal(".", "", "")
+	}
+	return defaultStorage
+}
+
+// CleanKey нормализует ключ объекта. Принимает и старые пути из БД
+// (./updates/..., /app/updates/...).
+func CleanKey(key string) (string, error) {
+	key = strings.ReplaceAll(strings.TrimSpace(key), "\\", "/")
+	key = strings.TrimPrefix(key, "/app/")
+	key = strings.TrimPrefix(key, "./")
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
+
+// JoinKey собирает ключ из частей пути. В отличие от path.Join не раскрывает "..",
+// чтобы CleanKey отклонил попытку выйти из папки (images/a/../../updates)
+func JoinKey(parts ...string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.Trim(part, "/"); part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, "/")
+}
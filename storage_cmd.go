@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"strings"
+
+	"github.com/mm-api/mm-api/config"
+	"github.com/mm-api/mm-api/storage"
+)
+
+// newStorage создает хранилище заданного бэкенда из настроек приложения
+func newStorage(cfg *config.Config, backend string) (storage.Storage, error) {
+	return storage.New(storage.Config{
+		Backend:       backend,
+		LocalRoot:     cfg.StorageLocalRoot,
+		SigningKey:    cfg.GetStorageSigningKey(),
+		SignedURLBase: "/api/v1/storage/objects",
+		S3Endpoint:    cfg.S3Endpoint,
+		S3Region:      cfg.S3Region,
+		S3Bucket:      cfg.S3Bucket,
+		S3AccessKey:   cfg.S3AccessKey,
+		S3SecretKey:   cfg.S3SecretKey,
+		S3PathStyle:   cfg.S3PathStyle,
+		S3PublicURL:   cfg.S3PublicURL,
+	})
+}
+
+// runStorageMigration переносит файлы между хранилищами:
+//
+//	./mm_shop migrate-storage -from local -to s3 [-prefix images/,updates/] [-dry-run] [-delete-source]
+//
+// Настройки обоих бэкендов берутся из тех же переменных окружения (STORAGE_LOCAL_ROOT, S3_*).
+// Повторный запуск пропускает уже перенесенные файлы.
+func runStorageMigration(args []string) {
+	flags := flag.NewFlagSet("migrate-storage", flag.ExitOnError)
+	from := flags.String("from", storage.BackendLocal, "source backend (local, s3)")
+	to := flags.String("to", storage.BackendS3, "destination backend (local, s3)")
+	prefixes := flags.String("prefix", strings.Join(storage.DefaultPrefixes, ","), "comma-separated key prefixes to migrate")
+	dryRun := flags.Bool("dry-run", false, "only list files that would be copied")
+	deleteSource := flags.Bool("delete-source", false, "delete files from the source after copying")
+	flags.Parse(args)
+
+	if *from == *to {
+		log.Fatalf("❌ Источник и назначение совпадают: %s", *from)
+	}
+
+	cfg := config.Load()
+	src, err := newStorage(cfg, *from)
+	if err != nil {
+		log.Fatalf("❌ Источник: %v", err)
+	}
+	dst, err := newStorage(cfg, *to)
+	if err != nil {
+		log.Fatalf("❌ Назначение: %v", err)
+	}
+
+	var prefixList []string
+	for _, prefix := range strings.Split(*prefixes, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixList = append(prefixList, prefix)
+		}
+	}
+
+	log.Printf("📦 Перенос файлов %s -> %s (%s)", src.Name(), dst.Name(), strings.Join(prefixList, ", "))
+	stats, err := storage.Migrate(context.Background(), src, dst, storage.MigrateOptions{
+		Prefixes:     prefixList,
+		DryRun:       *dryRun,
+		DeleteSource: *deleteSource,
+	})
+	log.Printf("📊 Скопировано: %d (%d байт), пропущено: %d, ошибок: %d", stats.Copied, stats.Bytes, stats.Skipped, stats.Failed)
+	if err != nil {
+		log.Fatalf("❌ Перенос прерван: %v", err)
+	}
+	if stats.Failed > 0 {
+		log.Fatalf("⚠️ Часть файлов не перенесена, запустите команду повторно")
+	}
+	log.Println("✅ Перенос завершен")
+}
@@ -0,0 +1,402 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Формат патча (MMDELTA1):
+//
+//	"MMDELTA1"
+//	uvarint размер исходного файла, uvarint размер итогового файла
+//	32 байта SHA256 исходного файла, 32 байта SHA256 итогового файла
+//	uvarint размер блока
+//	поток deflate с командами:
+//	  0x01 uvarint смещение, uvarint длина - скопировать из исходного файла
+//	  0x02 uvarint длина, данные          - вставить данные
+//	  0x00                                - конец патча
+const DeltaMagic = "MMDELTA1"
+
+const (
+	deltaOpEnd  = 0x00
+	deltaOpCopy = 0x01
+	deltaOpAdd  = 0x02
+
+	deltaMinBlockSize = 512
+	deltaMaxBlocks    = 1 << 21  // Не больше ~2 млн блоков в индексе исходного файла
+	deltaLiteralFlush = 1 << 20  // Вставки длиннее 1 МБ разбиваются
+	deltaExtendChunk  = 64 << 10 // Шаг продления совпадения
+)
+
+// ErrDeltaSourceMismatch исходный файл не совпадает с тем, из которого построен патч
+var ErrDeltaSourceMismatch = errors.New("delta: source file checksum mismatch")
+
+// ErrDeltaTargetMismatch результат применения патча не совпадает с ожидаемым файлом
+var ErrDeltaTargetMismatch = errors.New("delta: target file checksum mismatch")
+
+// DeltaInfo заголовок и статистика патча
+type DeltaInfo struct {
+	SourceSize   int64  `json:"sourceSize"`
+	TargetSize   int64  `json:"targetSize"`
+	SourceSHA256 string `json:"sourceSha256"`
+	TargetSHA256 string `json:"targetSha256"`
+	BlockSize    int    `json:"blockSize"`
+	CopiedBytes  int64  `json:"copiedBytes"` // Взято из исходного файла
+	AddedBytes   int64  `json:"addedBytes"`  // Передано в патче
+}
+
+// deltaBlockSize размер блока для исходного файла
+func deltaBlockSize(sourceSize int64) int {
+	size := deltaMinBlockSize
+	for sourceSize/int64(size) > deltaMaxBlocks {
+		size *= 2
+	}
+	return size
+}
+
+// deltaHash слабый скользящий хеш блока (как в rsync)
+type deltaHash struct {
+	a, b uint32
+	n    uint32
+}
+
+func newDeltaHash(block []byte) deltaHash {
+	h := deltaHash{n: uint32(len(block))}
+	for i, c := range block {
+		h.a += uint32(c)
+		h.b += (h.n - uint32(i)) * uint32(c)
+	}
+	return h
+}
+
+func (h *deltaHash) roll(out, in byte) {
+	h.a = h.a - uint32(out) + uint32(in)
+	h.b = h.b - h.n*uint32(out) + h.a
+}
+
+func (h deltaHash) sum() uint32 {
+	return h.a&0xffff | h.b<<16
+}
+
+func sha256Section(r io.ReaderAt, size int64) ([32]byte, error) {
+	var sum [32]byte
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, io.NewSectionReader(r, 0, size)); err != nil {
+		return sum, err
+	}
+	copy(sum[:], hasher.Sum(nil))
+	return sum, nil
+}
+
+// deltaWriter записывает команды патча
+type deltaWriter struct {
+	w    *flate.Writer
+	buf  [2*binary.MaxVarintLen64 + 1]byte
+	info *DeltaInfo
+}
+
+func (dw *deltaWriter) copyOp(offset, length int64) error {
+	dw.buf[0] = deltaOpCopy
+	n := 1 + binary.PutUvarint(dw.buf[1:], uint64(offset))
+	n += binary.PutUvarint(dw.buf[n:], uint64(length))
+	dw.info.CopiedBytes += length
+	_, err := dw.w.Write(dw.buf[:n])
+	return err
+}
+
+func (dw *deltaWriter) addOp(data []byte) error {
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > deltaLiteralFlush {
+			chunk = chunk[:deltaLiteralFlush]
+		}
+		dw.buf[0] = deltaOpAdd
+		n := 1 + binary.PutUvarint(dw.buf[1:], uint64(len(chunk)))
+		if _, err := dw.w.Write(dw.buf[:n]); err != nil {
+			return err
+		}
+		if _, err := dw.w.Write(chunk); err != nil {
+			return err
+		}
+		dw.info.AddedBytes += int64(len(chunk))
+		data = data[len(chunk):]
+	}
+	return nil
+}
+
+// CreateDelta строит патч, превращающий source в target. Файлы читаются потоково,
+// в памяти держится только индекс блоков исходного файла.
+func CreateDelta(source io.ReaderAt, sourceSize int64, target io.ReaderAt, targetSize int64, out io.Writer) (*DeltaInfo, error) {
+	sourceSum, err := sha256Section(source, sourceSize)
+	if err != nil {
+		return nil, fmt.Errorf("delta: read source: %w", err)
+	}
+	targetSum, err := sha256Section(target, targetSize)
+	if err != nil {
+		return nil, fmt.Errorf("delta: read target: %w", err)
+	}
+
+	blockSize := deltaBlockSize(sourceSize)
+	info := &DeltaInfo{
+		SourceSize:   sourceSize,
+		TargetSize:   targetSize,
+		SourceSHA256: hex.EncodeToString(sourceSum[:]),
+		TargetSHA256: hex.EncodeToString(targetSum[:]),
+		BlockSize:    blockSize,
+	}
+
+	// Заголовок
+	header := make([]byte, 0, len(DeltaMagic)+3*binary.MaxVarintLen64+64)
+	header = append(header, DeltaMagic...)
+	header = binary.AppendUvarint(header, uint64(sourceSize))
+	header = binary.AppendUvarint(header, uint64(targetSize))
+	header = append(header, sourceSum[:]...)
+	header = append(header, targetSum[:]...)
+	header = binary.AppendUvarint(header, uint64(blockSize))
+	if _, err := out.Write(header); err != nil {
+		return nil, err
+	}
+
+	// Индекс блоков исходного файла: слабый хеш -> смещение первого такого блока
+	index := make(map[uint32]int64, sourceSize/int64(blockSize)+1)
+	sourceReader := bufio.NewReaderSize(io.NewSectionReader(source, 0, sourceSize), 1<<20)
+	block := make([]byte, blockSize)
+	for offset := int64(0); offset+int64(blockSize) <= sourceSize; offset += int64(blockSize) {
+		if _, err := io.ReadFull(sourceReader, block); err != nil {
+			return nil, fmt.Errorf("delta: index source: %w", err)
+		}
+		sum := newDeltaHash(block).sum()
+		if _, exists := index[sum]; !exists {
+			index[sum] = offset
+		}
+	}
+
+	fw, err := flate.NewWriter(out, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	dw := &deltaWriter{w: fw, info: info}
+
+	targetReader := bufio.NewReaderSize(io.NewSectionReader(target, 0, targetSize), 1<<20)
+	sourceBlock := make([]byte, blockSize)
+	sourceChunk := make([]byte, deltaExtendChunk)
+
+	// pending - данные для вставки, в конце которых окно длиной blockSize
+	pending := make([]byte, 0, deltaLiteralFlush+blockSize)
+	fill := func() (bool, error) {
+		pending = pending[:blockSize]
+		n, err := io.ReadFull(targetReader, pending)
+		pending = pending[:n]
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	full, err := fill()
+	if err != nil {
+		return nil, fmt.Errorf("delta: read target: %w", err)
+	}
+	var hash deltaHash
+	if full {
+		hash = newDeltaHash(pending)
+	}
+
+	for full {
+		window := pending[len(pending)-blockSize:]
+		if offset, ok := index[hash.sum()]; ok {
+			if _, err := source.ReadAt(sourceBlock, offset); err == nil && bytes.Equal(window, sourceBlock) {
+				if err := dw.addOp(pending[:len(pending)-blockSize]); err != nil {
+					return nil, err
+				}
+
+				// Продлеваем совпадение вперед
+				length := int64(blockSize)
+				for offset+length < sourceSize {
+					peek, peekErr := targetReader.Peek(deltaExtendChunk)
+					if len(peek) == 0 {
+						break
+					}
+					want := int64(len(peek))
+					if rest := sourceSize - offset - length; want > rest {
+						want = rest
+					}
+					n, readErr := source.ReadAt(sourceChunk[:want], offset+length)
+					if readErr != nil && readErr != io.EOF {
+						return nil, fmt.Errorf("delta: read source: %w", readErr)
+					}
+					same := 0
+					for same < n && peek[same] == sourceChunk[same] {
+						same++
+					}
+					targetReader.Discard(same)
+					length += int64(same)
+					if same < len(peek) || peekErr != nil {
+						break
+					}
+				}
+				if err := dw.copyOp(offset, length); err != nil {
+					return nil, err
+				}
+
+				if full, err = fill(); err != nil {
+					return nil, fmt.Errorf("delta: read target: %w", err)
+				}
+				if full {
+					hash = newDeltaHash(pending)
+				}
+				continue
+			}
+		}
+
+		c, err := targetReader.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("delta: read target: %w", err)
+		}
+		hash.roll(window[0], c)
+		pending = append(pending, c)
+
+		if len(pending)-blockSize >= deltaLiteralFlush {
+			if err := dw.addOp(pending[:len(pending)-blockSize]); err != nil {
+				return nil, err
+			}
+			copy(pending, pending[len(pending)-blockSize:])
+			pending = pending[:blockSize]
+		}
+	}
+
+	if err := dw.addOp(pending); err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write([]byte{deltaOpEnd}); err != nil {
+		return nil, err
+	}
+	if err := fw.Close(); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ReadDeltaHeader читает заголовок патча
+func ReadDeltaHeader(r io.ByteReader) (*DeltaInfo, error) {
+	magic := make([]byte, len(DeltaMagic))
+	for i := range magic {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("delta: read header: %w", err)
+		}
+		magic[i] = c
+	}
+	if string(magic) != DeltaMagic {
+		return nil, errors.New("delta: not a patch file")
+	}
+
+	sourceSize, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("delta: read header: %w", err)
+	}
+	targetSize, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("delta: read header: %w", err)
+	}
+	sums := make([]byte, 64)
+	for i := range sums {
+		if sums[i], err = r.ReadByte(); err != nil {
+			return nil, fmt.Errorf("delta: read header: %w", err)
+		}
+	}
+	blockSize, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("delta: read header: %w", err)
+	}
+
+	return &DeltaInfo{
+		SourceSize:   int64(sourceSize),
+		TargetSize:   int64(targetSize),
+		SourceSHA256: hex.EncodeToString(sums[:32]),
+		TargetSHA256: hex.EncodeToString(sums[32:]),
+		BlockSize:    int(blockSize),
+	}, nil
+}
+
+// ApplyDelta применяет патч к source и пишет результат в out. Перед применением
+// проверяется SHA256 исходного файла, после - итогового (ErrDeltaSourceMismatch,
+// ErrDeltaTargetMismatch): при ошибке клиент должен скачать полный файл.
+func ApplyDelta(source io.ReaderAt, patch io.Reader, out io.Writer) (*DeltaInfo, error) {
+	br := bufio.NewReader(patch)
+	info, err := ReadDeltaHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceSum, err := sha256Section(source, info.SourceSize)
+	if err != nil {
+		return nil, fmt.Errorf("delta: read source: %w", err)
+	}
+	if hex.EncodeToString(sourceSum[:]) != info.SourceSHA256 {
+		return nil, ErrDeltaSourceMismatch
+	}
+
+	hasher := sha256.New()
+	w := io.MultiWriter(out, hasher)
+	ops := bufio.NewReader(flate.NewReader(br))
+	var written int64
+	for {
+		op, err := ops.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("delta: read patch: %w", err)
+		}
+		switch op {
+		case deltaOpEnd:
+			if written != info.TargetSize || hex.EncodeToString(hasher.Sum(nil)) != info.TargetSHA256 {
+				return nil, ErrDeltaTargetMismatch
+			}
+			return info, nil
+		case deltaOpCopy:
+			offset, err := binary.ReadUvarint(ops)
+			if err != nil {
+				return nil, fmt.Errorf("delta: read patch: %w", err)
+			}
+			length, err := binary.ReadUvarint(ops)
+			if err != nil {
+				return nil, fmt.Errorf("delta: read patch: %w", err)
+			}
+			if offset+length > uint64(info.SourceSize) {
+				return nil, errors.New("delta: copy outside of source file")
+			}
+			if _, err := io.Copy(w, io.NewSectionReader(source, int64(offset), int64(length))); err != nil {
+				return nil, err
+			}
+			info.CopiedBytes += int64(length)
+			written += int64(length)
+		case deltaOpAdd:
+			length, err := binary.ReadUvarint(ops)
+			if err != nil {
+				return nil, fmt.Errorf("delta: read patch: %w", err)
+			}
+			if written+int64(length) > info.TargetSize {
+				return nil, errors.New("delta: patch is larger than target file")
+			}
+			if _, err := io.CopyN(w, ops, int64(length)); err != nil {
+				return nil, fmt.Errorf("delta: read patch: %w", err)
+			}
+			info.AddedBytes += int64(length)
+			written += int64(length)
+		default:
+			return nil, fmt.Errorf("delta: unknown operation %d", op)
+		}
+		if written > info.TargetSize {
+			return nil, ErrDeltaTargetMismatch
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func deltaTestData(seed int64, size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+func createTestDelta(t *testing.T, source, target []byte) ([]byte, *DeltaInfo) {
+	t.Helper()
+	var patch bytes.Buffer
+	info, err := CreateDelta(bytes.NewReader(source), int64(len(source)), bytes.NewReader(target), int64(len(target)), &patch)
+	if err != nil {
+		t.Fatalf("CreateDelta: %v", err)
+	}
+	return patch.Bytes(), info
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	base := deltaTestData(1, 70001)
+	inserted := append(append(append([]byte{}, base[:30000]...), deltaTestData(2, 777)...), base[30000:]...)
+	shifted := append([]byte{0x4d, 0x4d, 0x21}, base...)
+
+	tests := []struct {
+		name   string
+		source []byte
+		target []byte
+		reuse  bool // Большая часть результата должна браться из исходного файла
+	}{
+		{name: "both empty", source: nil, target: nil},
+		{name: "empty source", source: nil, target: deltaTestData(3, 1000)},
+		{name: "empty target", source: base, target: nil},
+		{name: "smaller than block", source: deltaTestData(4, 100), target: deltaTestData(5, 200)},
+		{name: "inserted", source: base, target: inserted, reuse: true},
+		{name: "shifted", source: base, target: shifted, reuse: true},
+		{name: "identical", source: base, target: base, reuse: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, created := createTestDelta(t, tt.source, tt.target)
+
+			var out bytes.Buffer
+			applied, err := ApplyDelta(bytes.NewReader(tt.source), bytes.NewReader(patch), &out)
+			if err != nil {
+				t.Fatalf("ApplyDelta: %v", err)
+			}
+			if !bytes.Equal(out.Bytes(), tt.target) {
+				t.Fatalf("result differs from target: got %d bytes, want %d", out.Len(), len(tt.target))
+			}
+			if applied.TargetSHA256 != created.TargetSHA256 || applied.SourceSHA256 != created.SourceSHA256 {
+				t.Fatal("applied header differs from created one")
+			}
+			if applied.CopiedBytes+applied.AddedBytes != int64(len(tt.target)) {
+				t.Fatalf("copied %d + added %d != target size %d", applied.CopiedBytes, applied.AddedBytes, len(tt.target))
+			}
+			if tt.reuse && applied.AddedBytes > int64(len(tt.target)-len(tt.source))+int64(2*created.BlockSize) {
+				t.Fatalf("added %d bytes, expected most of the target to be copied", applied.AddedBytes)
+			}
+		})
+	}
+}
+
+func TestDeltaIdenticalCopiesEverything(t *testing.T) {
+	base := deltaTestData(6, 64*1024)
+	patch, _ := createTestDelta(t, base, base)
+
+	var out bytes.Buffer
+	info, err := ApplyDelta(bytes.NewReader(base), bytes.NewReader(patch), &out)
+	if err != nil {
+		t.Fatalf("ApplyDelta: %v", err)
+	}
+	if info.AddedBytes != 0 {
+		t.Fatalf("identical files: added %d bytes, want 0", info.AddedBytes)
+	}
+}
+
+func TestDeltaSourceMismatch(t *testing.T) {
+	source := deltaTestData(7, 20000)
+	target := append(append([]byte{}, source[:5000]...), deltaTestData(8, 300)...)
+	patch, _ := createTestDelta(t, source, target)
+
+	other := append([]byte{}, source...)
+	other[100] ^= 0xff
+	if _, err := ApplyDelta(bytes.NewReader(other), bytes.NewReader(patch), &bytes.Buffer{}); !errors.Is(err, ErrDeltaSourceMismatch) {
+		t.Fatalf("modified source: err = %v, want ErrDeltaSourceMismatch", err)
+	}
+	if _, err := ApplyDelta(bytes.NewReader(source[:len(source)-1]), bytes.NewReader(patch), &bytes.Buffer{}); err == nil {
+		t.Fatal("truncated source must be rejected")
+	}
+}
+
+func TestDeltaTargetMismatch(t *testing.T) {
+	source := deltaTestData(9, 20000)
+	target := append(append([]byte{}, source[:5000]...), deltaTestData(10, 300)...)
+	patch, _ := createTestDelta(t, source, target)
+
+	// SHA256 целевого файла идет в заголовке после размеров и SHA256 исходного
+	offset := len(DeltaMagic) + len(binary.AppendUvarint(nil, uint64(len(source)))) +
+		len(binary.AppendUvarint(nil, uint64(len(target)))) + 32
+	tampered := append([]byte{}, patch...)
+	tampered[offset] ^= 0xff
+
+	if _, err := ApplyDelta(bytes.NewReader(source), bytes.NewReader(tampered), &bytes.Buffer{}); !errors.Is(err, ErrDeltaTargetMismatch) {
+		t.Fatalf("tampered target checksum: err = %v, want ErrDeltaTargetMismatch", err)
+	}
+}
@@ -0,0 +1,385 @@
+package utils
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// Шаги локальной обработки фото товаров
+const (
+	ImageStepOrient    = "orient"    // Поворот по EXIF (фото с телефонов)
+	ImageStepChromaKey = "chroma"    // Замена однотонного фона (студийная съемка) на цвет фона
+	ImageStepTrim      = "trim"      // Обрезка по границам товара
+	ImageStepNormalize = "normalize" // Выравнивание яркости и контраста
+	ImageStepPad       = "pad"       // Вписывание в холст TargetWidth x TargetHeight на цвете фона
+)
+
+// ImagePipelinePresets готовые наборы шагов
+var ImagePipelinePresets = map[string][]string{
+	"default": {ImageStepOrient, ImageStepPad},
+	"clean":   {ImageStepOrient, ImageStepTrim, ImageStepNormalize, ImageStepPad},
+	"studio":  {ImageStepOrient, ImageStepChromaKey, ImageStepTrim, ImageStepNormalize, ImageStepPad},
+	"none":    {},
+}
+
+// ParseImagePipeline разбирает набор шагов: имя пресета (studio) или список (orient,trim,pad)
+func ParseImagePipeline(spec string) ([]string, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if steps, ok := ImagePipelinePresets[spec]; ok {
+		return steps, nil
+	}
+
+	var steps []string
+	for _, step := range strings.Split(spec, ",") {
+		step = strings.TrimSpace(step)
+		switch step {
+		case "":
+		case ImageStepOrient, ImageStepChromaKey, ImageStepTrim, ImageStepNormalize, ImageStepPad:
+			steps = append(steps, step)
+		default:
+			return nil, fmt.Errorf("неизвестный шаг обработки %q", step)
+		}
+	}
+	return steps, nil
+}
+
+// ParseImagePipelines разбирает наборы шагов по папкам загрузки:
+// "products=default;variations=studio;reviews=orient"
+func ParseImagePipelines(spec string) (map[string][]string, error) {
+	pipelines := make(map[string][]string)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		folder := strings.Trim(strings.TrimSpace(parts[0]), "/")
+		if len(parts) != 2 || folder == "" {
+			return nil, fmt.Errorf("ожидается папка=шаги, получено %q", entry)
+		}
+		steps, err := ParseImagePipeline(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("папка %s: %v", folder, err)
+		}
+		pipelines[folder] = steps
+	}
+	return pipelines, nil
+}
+
+// ParseColor разбирает цвет: white, black, gray, transparent или #rrggbb
+func ParseColor(value string) (color.Color, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "white":
+		return color.RGBA{R: 255, G: 255, B: 255, A: 255}, nil
+	case "black":
+		return color.RGBA{A: 255}, nil
+	case "gray", "grey":
+		return color.RGBA{R: 128, G: 128, B: 128, A: 255}, nil
+	case "transparent":
+		return color.Transparent, nil
+	}
+
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return nil, fmt.Errorf("неверный цвет %q", value)
+	}
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("неверный цвет %q", value)
+	}
+	return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 255}, nil
+}
+
+// hasImageStep проверяет, включен ли шаг
+func hasImageStep(steps []string, step string) bool {
+	for _, s := range steps {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+// colorDistance расстояние между цветами (0 - одинаковые, ~441 - черный и белый)
+func colorDistance(r1, g1, b1, r2, g2, b2 uint8) float64 {
+	dr := float64(r1) - float64(r2)
+	dg := float64(g1) - float64(g2)
+	db := float64(b1) - float64(b2)
+	return math.Sqrt(dr*dr + dg*dg + db*db)
+}
+
+// borderColor цвет фона по краям изображения (медиана по каналам)
+func borderColor(img *image.NRGBA) color.NRGBA {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	var rs, gs, bs []int
+	sample := func(x, y int) {
+		i := img.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+		rs = append(rs, int(img.Pix[i]))
+		gs = append(gs, int(img.Pix[i+1]))
+		bs = append(bs, int(img.Pix[i+2]))
+	}
+	step := 1 + (w+h)/400
+	for x := 0; x < w; x += step {
+		sample(x, 0)
+		sample(x, h-1)
+	}
+	for y := 0; y < h; y += step {
+		sample(0, y)
+		sample(w-1, y)
+	}
+
+	median := func(values []int) uint8 {
+		sort.Ints(values)
+		return uint8(values[len(values)/2])
+	}
+	return color.NRGBA{R: median(rs), G: median(gs), B: median(bs), A: 255}
+}
+
+// ChromaKeyBackground заменяет фон цвета key (nil - цвет краев снимка) на bg.
+// Заменяются только пиксели, связанные с краями изображения, поэтому детали
+// товара похожего цвета внутри контура не затрагиваются. На границе фона
+// цвета смешиваются для мягкого края.
+func ChromaKeyBackground(src image.Image, key color.Color, tolerance float64, bg color.Color) *image.NRGBA {
+	img := imaging.Clone(src)
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return img
+	}
+
+	keyColor := borderColor(img)
+	if key != nil {
+		keyColor = color.NRGBAModel.Convert(key).(color.NRGBA)
+	}
+	bgColor := color.NRGBAModel.Convert(bg).(color.NRGBA)
+	if tolerance <= 0 {
+		tolerance = 40
+	}
+	feather := tolerance * 1.5
+
+	distance := func(p int) float64 {
+		i := p * 4
+		return colorDistance(img.Pix[i], img.Pix[i+1], img.Pix[i+2], keyColor.R, keyColor.G, keyColor.B)
+	}
+
+	// Заливка от краев по пикселям, близким к цвету фона
+	visited := make([]bool, w*h)
+	queue := make([]int32, 0, 2*(w+h))
+	push := func(x, y int) {
+		p := y*w + x
+		if visited[p] {
+			return
+		}
+		visited[p] = true
+		if distance(p) <= tolerance {
+			queue = append(queue, int32(p))
+		}
+	}
+	for x := 0; x < w; x++ {
+		push(x, 0)
+		push(x, h-1)
+	}
+	for y := 0; y < h; y++ {
+		push(0, y)
+		push(w-1, y)
+	}
+
+	background := make([]bool, w*h)
+	for len(queue) > 0 {
+		p := int(queue[len(queue)-1])
+		queue = queue[:len(queue)-1]
+		background[p] = true
+		x, y := p%w, p/w
+		if x > 0 {
+			push(x-1, y)
+		}
+		if x < w-1 {
+			push(x+1, y)
+		}
+		if y > 0 {
+			push(x, y-1)
+		}
+		if y < h-1 {
+			push(x, y+1)
+		}
+	}
+
+	blend := func(i int, t float64) {
+		img.Pix[i] = uint8(float64(img.Pix[i])*(1-t) + float64(bgColor.R)*t)
+		img.Pix[i+1] = uint8(float64(img.Pix[i+1])*(1-t) + float64(bgColor.G)*t)
+		img.Pix[i+2] = uint8(float64(img.Pix[i+2])*(1-t) + float64(bgColor.B)*t)
+		img.Pix[i+3] = uint8(float64(img.Pix[i+3])*(1-t) + float64(bgColor.A)*t)
+	}
+	for p := 0; p < w*h; p++ {
+		if background[p] {
+			blend(p*4, 1)
+			continue
+		}
+		// Мягкий край: соседи фона, чуть отличающиеся от него по цвету
+		x, y := p%w, p/w
+		edge := (x > 0 && background[p-1]) || (x < w-1 && background[p+1]) ||
+			(y > 0 && background[p-w]) || (y < h-1 && background[p+w])
+		if !edge {
+			continue
+		}
+		if d := distance(p); d < feather {
+			blend(p*4, (feather-d)/(feather-tolerance)*0.5)
+		}
+	}
+	return img
+}
+
+// TrimToSubject обрезает однотонные поля вокруг товара. padding - доля
+// размера товара, оставляемая вокруг него (0.05 = 5%).
+func TrimToSubject(src image.Image, tolerance, padding float64) image.Image {
+	img := imaging.Clone(src)
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return img
+	}
+	if tolerance <= 0 {
+		tolerance = 40
+	}
+
+	bg := borderColor(img)
+	isSubject := func(x, y int) bool {
+		i := y*img.Stride + x*4
+		if img.Pix[i+3] < 16 {
+			return false
+		}
+		return colorDistance(img.Pix[i], img.Pix[i+1], img.Pix[i+2], bg.R, bg.G, bg.B) > tolerance
+	}
+
+	// Строка или столбец считается частью товара, если в нем больше 0.5% "не фона" (защита от шума)
+	minX, minY, maxX, maxY := w, h, -1, -1
+	rowThreshold := 1 + w/200
+	for y := 0; y < h; y++ {
+		count := 0
+		for x := 0; x < w; x++ {
+			if isSubject(x, y) {
+				count++
+			}
+		}
+		if count >= rowThreshold {
+			if y < minY {
+				minY = y
+			}
+			maxY = y
+		}
+	}
+	colThreshold := 1 + h/200
+	for x := 0; x < w; x++ {
+		count := 0
+		for y := 0; y < h; y++ {
+			if isSubject(x, y) {
+				count++
+			}
+		}
+		if count >= colThreshold {
+			if x < minX {
+				minX = x
+			}
+			maxX = x
+		}
+	}
+	if maxX < minX || maxY < minY {
+		return img // Товар не найден - оставляем как есть
+	}
+
+	padX := int(float64(maxX-minX+1) * padding)
+	padY := int(float64(maxY-minY+1) * padding)
+	rect := image.Rect(minX-padX, minY-padY, maxX+1+padX, maxY+1+padY).Intersect(image.Rect(0, 0, w, h))
+	if rect.Dx() >= w-2 && rect.Dy() >= h-2 {
+		return img
+	}
+	return imaging.Crop(img, rect)
+}
+
+// NormalizeLevels выравнивает контраст (растяжение гистограммы яркости между
+// 0.5% и 99.5% перцентилями) и яркость (гамма, если медиана слишком темная
+// или светлая). Фон (цвет краев снимка) в расчете не участвует и не меняется.
+func NormalizeLevels(src image.Image) *image.NRGBA {
+	img := imaging.Clone(src)
+	if img.Bounds().Empty() {
+		return img
+	}
+	bg := borderColor(img)
+	isBackground := func(i int) bool {
+		return img.Pix[i+3] < 16 || colorDistance(img.Pix[i], img.Pix[i+1], img.Pix[i+2], bg.R, bg.G, bg.B) <= 8
+	}
+
+	var histogram [256]int
+	total := 0
+	for i := 0; i < len(img.Pix); i += 4 {
+		if isBackground(i) {
+			continue
+		}
+		luma := (299*int(img.Pix[i]) + 587*int(img.Pix[i+1]) + 114*int(img.Pix[i+2])) / 1000
+		histogram[luma]++
+		total++
+	}
+	if total < len(img.Pix)/4/100 {
+		return img // Товар занимает меньше 1% снимка - нечего выравнивать
+	}
+
+	percentile := func(p float64) int {
+		target := int(float64(total) * p)
+		sum := 0
+		for v, count := range histogram {
+			sum += count
+			if sum > target {
+				return v
+			}
+		}
+		return 255
+	}
+	low, high, median := percentile(0.005), percentile(0.995), percentile(0.5)
+	if high-low < 48 {
+		return img // Почти однотонный товар - не растягиваем шум
+	}
+
+	// Растяжение до 0..255, но не более чем 1.6-кратное усиление контраста;
+	// при ограниченном усилении середина исходного диапазона остается на месте
+	gain := 255 / float64(high-low)
+	offset := float64(low)
+	if gain > 1.6 {
+		gain = 1.6
+		middle := float64(low+high) / 2
+		offset = middle - middle/gain
+	}
+	stretch := func(v float64) float64 {
+		return math.Max(0, math.Min(255, (v-offset)*gain))
+	}
+	stretchedMedian := math.Max(1, math.Min(254, stretch(float64(median))))
+	// Гамма сдвигает медиану в диапазон 90..170 (светлые и темные товары остаются такими)
+	target := math.Max(90, math.Min(170, stretchedMedian))
+	gamma := math.Log(target/255) / math.Log(stretchedMedian/255)
+
+	var lut [256]uint8
+	for v := 0; v < 256; v++ {
+		stretched := stretch(float64(v))
+		lut[v] = uint8(math.Round(255 * math.Pow(stretched/255, gamma)))
+	}
+	for i := 0; i < len(img.Pix); i += 4 {
+		if isBackground(i) {
+			continue
+		}
+		img.Pix[i] = lut[img.Pix[i]]
+		img.Pix[i+1] = lut[img.Pix[i+1]]
+		img.Pix[i+2] = lut[img.Pix[i+2]]
+	}
+	return img
+}
@@ -24,52 +24,66 @@ type ImageProcessor struct {
 	TargetHeight    int
 	BackgroundColor color.Color
 	JPEGQuality     int
+
+	// Шаги обработки (см. ImagePipelinePresets), по умолчанию orient + pad
+	Steps           []string
+	ChromaKey       color.Color // Цвет студийного фона; nil - определяется по краям снимка
+	ChromaTolerance float64     // Допуск цвета фона для chroma и trim (0-441)
+	TrimPadding     float64     // Поля вокруг товара после trim (доля размера товара)
 }
 
 // NewImageProcessor создает новый процессор изображений
 func NewImageProcessor(width, height int, bgColor string) *ImageProcessor {
 	processor := &ImageProcessor{
-		TargetWidth:  width,
-		TargetHeight: height,
-		JPEGQuality:  85, // Хороший баланс между качеством и размером
+		TargetWidth:     width,
+		TargetHeight:    height,
+		JPEGQuality:     85, // Хороший баланс между качеством и размером
+		Steps:           ImagePipelinePresets["default"],
+		ChromaTolerance: 40,
+		TrimPadding:     0.05,
 	}
 
-	// Парсим цвет фона
-	switch strings.ToLower(bgColor) {
-	case "white":
-		processor.BackgroundColor = color.RGBA{R: 255, G: 255, B: 255, A: 255}
-	case "transparent":
-		processor.BackgroundColor = color.Transparent
-	default:
-		processor.BackgroundColor = color.RGBA{R: 255, G: 255, B: 255, A: 255} // По умолчанию белый
+	// Парсим цвет фона (white, transparent, #rrggbb)
+	background, err := ParseColor(bgColor)
+	if err != nil {
+		log.Printf("⚠️ %v, используется белый фон", err)
+		background = color.RGBA{R: 255, G: 255, B: 255, A: 255} // По умолчанию белый
 	}
+	processor.BackgroundColor = background
 
 	return processor
 }
 
-// ProcessProductImage обрабатывает изображение товара:
-// 1. Читает и декодирует изображение с учетом EXIF ориентации (для фото с телефонов)
-// 2. Изменяет размер с сохранением пропорций
-// 3. Добавляет фон (белый/прозрачный)
-// 4. Центрирует изображение
-// 5. Сжимает в JPEG
-func (ip *ImageProcessor) ProcessProductImage(input io.Reader, outputPath string) (int64, error) {
-	// Читаем все данные в память (нужно для обработки EXIF)
-	data, err := io.ReadAll(input)
-	if err != nil {
-		return 0, fmt.Errorf("ошибка чтения файла: %v", err)
+// Process применяет шаги обработки к декодированному изображению
+// (поворот по EXIF выполняется при декодировании)
+func (ip *ImageProcessor) Process(img image.Image) image.Image {
+	// Большие снимки сначала уменьшаем: дальнейшие шаги работают попиксельно
+	maxSide := 2 * ip.TargetWidth
+	if ip.TargetHeight > ip.TargetWidth {
+		maxSide = 2 * ip.TargetHeight
+	}
+	if maxSide > 0 && (img.Bounds().Dx() > maxSide || img.Bounds().Dy() > maxSide) {
+		img = imaging.Fit(img, maxSide, maxSide, imaging.Lanczos)
 	}
 
-	// Декодируем изображение с автоматической обработкой EXIF ориентации
-	// imaging.Decode автоматически поворачивает изображение согласно EXIF данным
-	img, err := imaging.Decode(bytes.NewReader(data))
-	if err != nil {
-		return 0, fmt.Errorf("ошибка декодирования изображения: %v", err)
+	for _, step := range ip.Steps {
+		switch step {
+		case ImageStepChromaKey:
+			img = ChromaKeyBackground(img, ip.ChromaKey, ip.ChromaTolerance, ip.BackgroundColor)
+		case ImageStepTrim:
+			img = TrimToSubject(img, ip.ChromaTolerance, ip.TrimPadding)
+		case ImageStepNormalize:
+			img = NormalizeLevels(img)
+		}
 	}
 
-	originalWidth := img.Bounds().Dx()
-	originalHeight := img.Bounds().Dy()
-	log.Printf("📸 Обработка изображения с телефона: размер=%dx%d (после обработки EXIF ориентации)", originalWidth, originalHeight)
+	if !hasImageStep(ip.Steps, ImageStepPad) {
+		// Без холста только уменьшаем до целевого размера
+		if img.Bounds().Dx() > ip.TargetWidth || img.Bounds().Dy() > ip.TargetHeight {
+			img = imaging.Fit(img, ip.TargetWidth, ip.TargetHeight, imaging.Lanczos)
+		}
+		return img
+	}
 
 	// Создаем новое изображение с нужным размером и фоном
 	bg := image.NewRGBA(image.Rect(0, 0, ip.TargetWidth, ip.TargetHeight))
@@ -94,6 +108,35 @@ func (ip *ImageProcessor) ProcessProductImage(input io.Reader, outputPath string
 		resized,
 		bounds.Min,
 		draw.Over)
+	return bg
+}
+
+// ProcessProductImage обрабатывает изображение товара:
+// 1. Читает и декодирует изображение с учетом EXIF ориентации (для фото с телефонов)
+// 2. Выполняет шаги Steps: замена фона, обрезка по товару, выравнивание яркости
+// 3. Изменяет размер с сохранением пропорций
+// 4. Добавляет фон и центрирует изображение (шаг pad)
+// 5. Сжимает в JPEG
+func (ip *ImageProcessor) ProcessProductImage(input io.Reader, outputPath string) (int64, error) {
+	// Читаем все данные в память (нужно для обработки EXIF)
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+
+	// Декодируем изображение с автоматической обработкой EXIF ориентации
+	// (без AutoOrientation imaging.Decode EXIF не учитывает)
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(hasImageStep(ip.Steps, ImageStepOrient)))
+	if err != nil {
+		return 0, fmt.Errorf("ошибка декодирования изображения: %v", err)
+	}
+
+	originalWidth := img.Bounds().Dx()
+	originalHeight := img.Bounds().Dy()
+	log.Printf("📸 Обработка изображения с телефона: размер=%dx%d (после обработки EXIF ориентации)", originalWidth, originalHeight)
+
+	log.Printf("🎨 Шаги обработки: %s", strings.Join(ip.Steps, ", "))
+	result := ip.Process(img)
 
 	// Создаем директорию, если её нет
 	dir := filepath.Dir(outputPath)
@@ -109,7 +152,7 @@ func (ip *ImageProcessor) ProcessProductImage(input io.Reader, outputPath string
 	defer outputFile.Close()
 
 	// Сохраняем как JPEG (всегда, для единообразия и лучшего сжатия)
-	err = jpeg.Encode(outputFile, bg, &jpeg.Options{Quality: ip.JPEGQuality})
+	err = jpeg.Encode(outputFile, result, &jpeg.Options{Quality: ip.JPEGQuality})
 	if err != nil {
 		return 0, fmt.Errorf("ошибка кодирования JPEG: %v", err)
 	}
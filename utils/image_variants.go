@@ -0,0 +1,253 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/disintegration/imaging"
+)
+
+// Форматы вариантов изображений
+const (
+	ImageFormatJPEG = "jpeg"
+	ImageFormatWebP = "webp"
+)
+
+// ImageVariantPrefix папка хранилища с закэшированными вариантами изображений
+const ImageVariantPrefix = "images/_variants/"
+
+// MaxImageVariantWidth максимальная ширина варианта (защита от огромных ресайзов)
+const MaxImageVariantWidth = 2400
+
+// ImageRendition именованный размер изображения (ширина и высота вписываются в квадрат Width x Width)
+type ImageRendition struct {
+	Name  string
+	Width int
+}
+
+// ImageRenditions размеры для каталога: сетка, список, карточка товара, зум
+var ImageRenditions = []ImageRendition{
+	{Name: "thumbnail", Width: 160},
+	{Name: "list", Width: 400},
+	{Name: "detail", Width: 800},
+	{Name: "zoom", Width: 1600},
+}
+
+// ImageVariantConfig настройки вариантов изображений
+type ImageVariantConfig struct {
+	SigningKey  string // Секрет подписи параметров ресайза
+	ResizeURL   string // Путь API ресайза
+	WebPEncoder string // Путь к cwebp; пусто - WebP не создается
+	JPEGQuality int
+	WebPQuality int
+	OnUpload    bool // Создавать варианты сразу при загрузке фото товара
+	Workers     int  // Фоновых обработчиков вариантов после загрузки
+}
+
+var (
+	variantConfigMu sync.RWMutex
+	variantConfig   = ImageVariantConfig{
+		ResizeURL:   "/api/v1/images/resize",
+		WebPEncoder: "cwebp",
+		JPEGQuality: 82,
+		WebPQuality: 80,
+		OnUpload:    true,
+		Workers:     2,
+	}
+	webpOnce      sync.Once
+	webpAvailable bool
+)
+
+// ConfigureImageVariants задает настройки вариантов изображений (вызывается при старте)
+func ConfigureImageVariants(cfg ImageVariantConfig) {
+	variantConfigMu.Lock()
+	defer variantConfigMu.Unlock()
+	if cfg.ResizeURL == "" {
+		cfg.ResizeURL = "/api/v1/images/resize"
+	}
+	if cfg.JPEGQuality <= 0 || cfg.JPEGQuality > 100 {
+		cfg.JPEGQuality = 82
+	}
+	if cfg.WebPQuality <= 0 || cfg.WebPQuality > 100 {
+		cfg.WebPQuality = 80
+	}
+	if cfg.Workers < 1 {
+		cfg.Workers = 2
+	}
+	variantConfig = cfg
+	webpOnce = sync.Once{}
+}
+
+// ImageVariantSettings возвращает текущие настройки вариантов
+func ImageVariantSettings() ImageVariantConfig {
+	variantConfigMu.RLock()
+	defer variantConfigMu.RUnlock()
+	return variantConfig
+}
+
+// WebPSupported проверяет, доступен ли кодировщик WebP (cwebp)
+func WebPSupported() bool {
+	cfg := ImageVariantSettings()
+	webpOnce.Do(func() {
+		if cfg.WebPEncoder == "" {
+			return
+		}
+		if _, err := exec.LookPath(cfg.WebPEncoder); err != nil {
+			log.Printf("⚠️ Кодировщик WebP (%s) не найден, варианты изображений создаются только в JPEG", cfg.WebPEncoder)
+			return
+		}
+		webpAvailable = true
+	})
+	return webpAvailable
+}
+
+// ImageVariantFormats форматы, в которых отдаются варианты
+func ImageVariantFormats() []string {
+	if WebPSupported() {
+		return []string{ImageFormatWebP, ImageFormatJPEG}
+	}
+	return []string{ImageFormatJPEG}
+}
+
+// ImageVariantKey ключ закэшированного варианта в хранилище:
+// images/products/abc.jpg -> images/_variants/w400/products/abc.webp
+func ImageVariantKey(sourceKey string, width int, format string) string {
+	rel := strings.TrimPrefix(sourceKey, "images/")
+	rel = strings.TrimSuffix(rel, path.Ext(rel))
+	ext := ".jpg"
+	if format == ImageFormatWebP {
+		ext = ".webp"
+	}
+	return fmt.Sprintf("%sw%d/%s%s", ImageVariantPrefix, width, rel, ext)
+}
+
+// ImageVariantSignature подпись параметров ресайза (ширина, формат, ключ)
+func ImageVariantSignature(sourceKey string, width int, format string) string {
+	mac := hmac.New(sha256.New, []byte(ImageVariantSettings().SigningKey))
+	mac.Write([]byte(strconv.Itoa(width) + "\n" + format + "\n" + sourceKey))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// VerifyImageVariantSignature проверяет подпись параметров ресайза
+func VerifyImageVariantSignature(sourceKey string, width int, format, signature string) bool {
+	// Без секрета подпись мог бы собрать кто угодно
+	if ImageVariantSettings().SigningKey == "" {
+		return false
+	}
+	expected := ImageVariantSignature(sourceKey, width, format)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// ImageVariantURL адрес варианта изображения нужной ширины. Для локальных файлов
+// (/images/...) - подписанный адрес API ресайза, для Cloudinary - URL с трансформацией
+// (как CloudinaryProcessor.GetOptimizedURL). Для прочих адресов возвращает пустую строку.
+func ImageVariantURL(imageURL string, width int, format string) string {
+	if i := strings.Index(imageURL, "/image/upload/"); i >= 0 && strings.Contains(imageURL, "res.cloudinary.com") {
+		transformation := fmt.Sprintf("w_%d,h_%d,c_fit,q_auto:good,f_%s", width, width, cloudinaryFormat(format))
+		insertAt := i + len("/image/upload/")
+		return imageURL[:insertAt] + transformation + "/" + imageURL[insertAt:]
+	}
+
+	key := strings.TrimPrefix(imageURL, "/")
+	if !strings.HasPrefix(key, "images/") || strings.HasPrefix(key, ImageVariantPrefix) {
+		return ""
+	}
+	cfg := ImageVariantSettings()
+	return fmt.Sprintf("%s/%d/%s/%s?s=%s", cfg.ResizeURL, width, format, key, ImageVariantSignature(key, width, format))
+}
+
+// cloudinaryFormat формат Cloudinary (f_jpg / f_webp)
+func cloudinaryFormat(format string) string {
+	if format == ImageFormatWebP {
+		return "webp"
+	}
+	return "jpg"
+}
+
+// DecodeImageAutoOrient декодирует изображение с учетом EXIF ориентации
+func DecodeImageAutoOrient(data []byte) (image.Image, error) {
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка декодирования изображения: %v", err)
+	}
+	return img, nil
+}
+
+// RenderImageVariant уменьшает изображение до квадрата width x width (без увеличения)
+// и кодирует в нужный формат
+func RenderImageVariant(img image.Image, width int, format string) ([]byte, error) {
+	bounds := img.Bounds()
+	resized := img
+	if bounds.Dx() > width || bounds.Dy() > width {
+		resized = imaging.Fit(img, width, width, imaging.Lanczos)
+	}
+
+	cfg := ImageVariantSettings()
+	switch format {
+	case ImageFormatJPEG:
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: cfg.JPEGQuality}); err != nil {
+			return nil, fmt.Errorf("ошибка кодирования JPEG: %v", err)
+		}
+		return buf.Bytes(), nil
+	case ImageFormatWebP:
+		return EncodeWebP(resized, cfg.WebPQuality)
+	}
+	return nil, fmt.Errorf("неподдерживаемый формат %q", format)
+}
+
+// EncodeWebP кодирует изображение в WebP внешним кодировщиком cwebp
+// (в Go нет встроенного кодировщика WebP)
+func EncodeWebP(img image.Image, quality int) ([]byte, error) {
+	if !WebPSupported() {
+		return nil, fmt.Errorf("кодировщик WebP недоступен")
+	}
+
+	dir, err := os.MkdirTemp("", "webp-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := dir + "/in.png"
+	output := dir + "/out.webp"
+	file, err := os.Create(input)
+	if err != nil {
+		return nil, err
+	}
+	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := encoder.Encode(file, img); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(ImageVariantSettings().WebPEncoder, "-quiet", "-q", strconv.Itoa(quality), input, "-o", output)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("ошибка cwebp: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return os.ReadFile(output)
+}
+
+// ImageVariantContentType Content-Type варианта
+func ImageVariantContentType(format string) string {
+	if format == ImageFormatWebP {
+		return "image/webp"
+	}
+	return "image/jpeg"
+}
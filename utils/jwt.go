@@ -1,106 +1,158 @@
-package utils
-
-import (
-	"errors"
-	"time"
-
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
-)
-
-// JWT секретный ключ (в продакшене должен быть в переменных окружения)
-var jwtSecret = []byte("your-secret-key-here-make-it-stronger")
-
-// Claims представляет структуру JWT claims
-type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
-	jwt.RegisteredClaims
-}
-
-// GenerateJWT создает новый JWT токен
-func GenerateJWT(userID uuid.UUID, email, role string) (string, error) {
-	claims := Claims{
-		UserID: userID.String(),
-		Email:  email,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 24 часа
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "mm-api",
-			Subject:   userID.String(),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
-	if err != nil {
-		return "", err
-	}
-
-	return tokenString, nil
-}
-
-// ValidateJWT проверяет и парсит JWT токен
-func ValidateJWT(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Проверяем метод подписи
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return jwtSecret, nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
-		return nil, errors.New("invalid token")
-	}
-
-	return claims, nil
-}
-
-// RefreshJWT создает новый токен на основе старого
-func RefreshJWT(tokenString string) (string, error) {
-	claims, err := ValidateJWT(tokenString)
-	if err != nil {
-		return "", err
-	}
-
-	// Проверяем, что токен не слишком старый для обновления (например, не старше 7 дней)
-	if time.Since(claims.IssuedAt.Time) > 7*24*time.Hour {
-		return "", errors.New("token too old to refresh")
-	}
-
-	userID, err := uuid.Parse(claims.UserID)
-	if err != nil {
-		return "", err
-	}
-
-	return GenerateJWT(userID, claims.Email, claims.Role)
-}
-
-// NormalizePhone нормализует номер телефона (убирает пробелы, дефисы и другие символы)
-// Оставляет только цифры и знак + в начале
-func NormalizePhone(phone string) string {
-	if phone == "" {
-		return ""
-	}
-	
-	// Убираем все пробелы, дефисы, скобки и другие символы кроме цифр и +
-	result := ""
-	for _, char := range phone {
-		if char >= '0' && char <= '9' {
-			result += string(char)
-		} else if char == '+' && len(result) == 0 {
-			result += string(char)
-		}
-	}
-	
-	return result
+package utils
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// JWT секретный ключ (в продакшене должен быть в переменных окружения)
+var jwtSecret = []byte("your-secret-key-here-make-it-stronger")
+
+// Claims представляет структуру JWT claims
+type Claims struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// StreamTokenTTL срок действия токена подключения к потоку событий
+const StreamTokenTTL = time.Minute
+
+// streamTokenAudience аудитория токена потока событий: такой токен не принимается как токен сессии
+const streamTokenAudience = "mm-api:events"
+
+// GenerateJWT создает новый JWT токен
+func GenerateJWT(userID uuid.UUID, email, role string) (string, error) {
+	claims := Claims{
+		UserID: userID.String(),
+		Email:  email,
+		Role:   role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 24 часа
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "mm-api",
+			Subject:   userID.String(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString(jwtSecret)
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+// ValidateJWT проверяет и парсит JWT токен
+func ValidateJWT(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		// Проверяем метод подписи
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return jwtSecret, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	for _, audience := range claims.Audience {
+		if audience == streamTokenAudience {
+			return nil, errors.New("stream token cannot be used as a session token")
+		}
+	}
+
+	return claims, nil
+}
+
+// GenerateStreamToken создает короткоживущий токен только для подключения к потоку событий.
+// Передается в ?stream_token= (EventSource не умеет заголовки) вместо токена сессии,
+// поэтому попадание в логи прокси не дает доступа к API.
+func GenerateStreamToken(userID uuid.UUID) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(StreamTokenTTL)
+	claims := jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Issuer:    "mm-api",
+		Subject:   userID.String(),
+		Audience:  jwt.ClaimStrings{streamTokenAudience},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ValidateStreamToken проверяет токен потока событий и возвращает ID пользователя
+func ValidateStreamToken(tokenString string) (uuid.UUID, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return jwtSecret, nil
+	}, jwt.WithAudience(streamTokenAudience))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	if !ok || !token.Valid {
+		return uuid.Nil, errors.New("invalid token")
+	}
+	return uuid.Parse(claims.Subject)
+}
+
+// RefreshJWT создает новый токен на основе старого
+func RefreshJWT(tokenString string) (string, error) {
+	claims, err := ValidateJWT(tokenString)
+	if err != nil {
+		return "", err
+	}
+
+	// Проверяем, что токен не слишком старый для обновления (например, не старше 7 дней)
+	if time.Since(claims.IssuedAt.Time) > 7*24*time.Hour {
+		return "", errors.New("token too old to refresh")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	return GenerateJWT(userID, claims.Email, claims.Role)
+}
+
+// NormalizePhone нормализует номер телефона (убирает пробелы, дефисы и другие символы)
+// Оставляет только цифры и знак + в начале
+func NormalizePhone(phone string) string {
+	if phone == "" {
+		return ""
+	}
+	
+	// Убираем все пробелы, дефисы, скобки и другие символы кроме цифр и +
+	result := ""
+	for _, char := range phone {
+		if char >= '0' && char <= '9' {
+			result += string(char)
+		} else if char == '+' && len(result) == 0 {
+			result += string(char)
+		}
+	}
+	
+	return result
 }
\ No newline at end of file
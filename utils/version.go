@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version версия приложения в формате semver: 1.2.3, v1.2.3-beta.1, 1.2.3+45.
+// Допускается любое количество числовых частей (1.2, 1.0.0.12 у Windows сборок),
+// недостающие части считаются нулями.
+type Version struct {
+	Parts      []int
+	Prerelease []string // beta.1 -> [beta 1]; пусто - релиз
+	Build      string   // Метаданные сборки (+45), в сравнении не участвуют
+	Raw        string
+}
+
+// ParseVersion разбирает строку версии
+func ParseVersion(raw string) (Version, error) {
+	v := Version{Raw: strings.TrimSpace(raw)}
+	s := strings.TrimPrefix(strings.TrimPrefix(v.Raw, "v"), "V")
+	if s == "" {
+		return v, fmt.Errorf("empty version")
+	}
+
+	if i := strings.Index(s, "+"); i >= 0 {
+		v.Build = s[i+1:]
+		s = s[:i]
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		pre := s[i+1:]
+		s = s[:i]
+		if pre == "" {
+			return v, fmt.Errorf("invalid version %q: empty prerelease", raw)
+		}
+		v.Prerelease = strings.Split(pre, ".")
+		for _, id := range v.Prerelease {
+			if id == "" {
+				return v, fmt.Errorf("invalid version %q: empty prerelease identifier", raw)
+			}
+		}
+	}
+
+	for _, part := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", raw)
+		}
+		v.Parts = append(v.Parts, n)
+	}
+	return v, nil
+}
+
+// String возвращает исходную строку версии
+func (v Version) String() string {
+	return v.Raw
+}
+
+// Compare сравнивает версии: -1 если v < other, 0 если равны, 1 если v > other.
+// Пререлиз младше релиза той же версии (1.2.0-beta < 1.2.0).
+func (v Version) Compare(other Version) int {
+	n := len(v.Parts)
+	if len(other.Parts) > n {
+		n = len(other.Parts)
+	}
+	for i := 0; i < n; i++ {
+		a, b := 0, 0
+		if i < len(v.Parts) {
+			a = v.Parts[i]
+		}
+		if i < len(other.Parts) {
+			b = other.Parts[i]
+		}
+		if a != b {
+			return compareInts(a, b)
+		}
+	}
+
+	switch {
+	case len(v.Prerelease) == 0 && len(other.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(other.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.Prerelease) && i < len(other.Prerelease); i++ {
+		a, b := v.Prerelease[i], other.Prerelease[i]
+		if a == b {
+			continue
+		}
+		an, aErr := strconv.Atoi(a)
+		bn, bErr := strconv.Atoi(b)
+		switch {
+		case aErr == nil && bErr == nil:
+			return compareInts(an, bn)
+		case aErr == nil: // Числовые идентификаторы младше строковых
+			return -1
+		case bErr == nil:
+			return 1
+		case a < b:
+			return -1
+		default:
+			return 1
+		}
+	}
+	return compareInts(len(v.Prerelease), len(other.Prerelease))
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// CompareVersions сравнивает две строки версий. Неразбираемая версия считается
+// младше любой корректной; две неразбираемые сравниваются как строки.
+func CompareVersions(a, b string) int {
+	va, errA := ParseVersion(a)
+	vb, errB := ParseVersion(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(strings.TrimSpace(a), strings.TrimSpace(b))
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	return va.Compare(vb)
+}